@@ -6,15 +6,25 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/btcsuite/btcutil"
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	"github.com/pkg/errors"
+	"github.com/qtumproject/janus/pkg/auth"
+	"github.com/qtumproject/janus/pkg/cache"
+	"github.com/qtumproject/janus/pkg/config"
 	"github.com/qtumproject/janus/pkg/notifier"
 	"github.com/qtumproject/janus/pkg/params"
 	"github.com/qtumproject/janus/pkg/qtum"
+	"github.com/qtumproject/janus/pkg/ratelimit"
 	"github.com/qtumproject/janus/pkg/server"
+	"github.com/qtumproject/janus/pkg/tracing"
 	"github.com/qtumproject/janus/pkg/transformer"
 	"gopkg.in/alecthomas/kingpin.v2"
 )
@@ -22,16 +32,62 @@ import (
 var (
 	app = kingpin.New("janus", "Qtum adapter to Ethereum JSON RPC")
 
-	accountsFile = app.Flag("accounts", "account private keys (in WIF) returned by eth_accounts").Envar("ACCOUNTS").File()
+	accountsFile = app.Flag("accounts", "file of account private keys (in WIF) returned by eth_accounts").Envar("ACCOUNTS").Default("").String()
+	configFile   = app.Flag("config", "YAML file of settings keyed by flag name, flags and environment variables take precedence over it").Envar("CONFIG_FILE").Default("").String()
 
-	qtumRPC             = app.Flag("qtum-rpc", "URL of qtum RPC service").Envar("QTUM_RPC").Default("").String()
+	qtumRPC             = app.Flag("qtum-rpc", "URL of qtum RPC service, or a comma separated list of them to spread reads over (the first one gets wallet and send calls)").Envar("QTUM_RPC").Default("").String()
+	qtumRPCMaxLag       = app.Flag("qtum-rpc-max-lag", "with several qtum RPC services, how many blocks one can fall behind before reads stop going to it").Envar("QTUM_RPC_MAX_LAG").Default("2").Int64()
+	qtumRPCCheck        = app.Flag("qtum-rpc-check-interval", "with several qtum RPC services, how often their block heights are checked").Envar("QTUM_RPC_CHECK_INTERVAL").Default("5s").Duration()
+	qtumRPCKeepAlive    = app.Flag("qtum-rpc-keepalive", "reuse connections to qtum RPC services between requests").Envar("QTUM_RPC_KEEPALIVE").Default("true").Bool()
+	qtumRPCMaxIdle      = app.Flag("qtum-rpc-max-idle-conns", "idle connections to keep open to each qtum RPC service").Envar("QTUM_RPC_MAX_IDLE_CONNS").Default("16").Int()
+	qtumRPCMaxConns     = app.Flag("qtum-rpc-max-conns", "maximum connections to each qtum RPC service, requests over the limit wait for a free connection (0 means no limit)").Envar("QTUM_RPC_MAX_CONNS").Default("0").Int()
+	qtumRPCDialTimeout  = app.Flag("qtum-rpc-dial-timeout", "how long to wait to connect to a qtum RPC service").Envar("QTUM_RPC_DIAL_TIMEOUT").Default("10s").Duration()
+	qtumRPCRespTimeout  = app.Flag("qtum-rpc-response-timeout", "how long to wait for a qtum RPC service to start responding, must be longer than waitforlogs takes (0 waits forever)").Envar("QTUM_RPC_RESPONSE_TIMEOUT").Default("0s").Duration()
+	qtumRPCIdleTimeout  = app.Flag("qtum-rpc-idle-timeout", "how long idle connections to qtum RPC services are kept open, keep this below qtumd's -rpcservertimeout").Envar("QTUM_RPC_IDLE_TIMEOUT").Default("20s").Duration()
+	qtumRPCCAFile       = app.Flag("qtum-rpc-ca-file", "PEM file with certificate authorities to trust for https qtum RPC services").Envar("QTUM_RPC_CA_FILE").Default("").String()
+	qtumRPCBatchWindow  = app.Flag("qtum-rpc-batch-window", "send requests made within this long of each other to qtum RPC services as one JSON-RPC batch, every request waits up to this long (0 disables batching)").Envar("QTUM_RPC_BATCH_WINDOW").Default("0s").Duration()
+	recordQtumd         = app.Flag("record-qtumd", "[Development] append every request to qtum RPC services and the response to it to this file, for replaying in tests (it holds every request body sent to qtumd, check it before sharing)").Envar("RECORD_QTUMD").Default("").String()
 	qtumNetwork         = app.Flag("qtum-network", "if 'regtest' (or connected to a regtest node with 'auto') Janus will generate blocks").Envar("QTUM_NETWORK").Default("auto").String()
 	generateToAddressTo = app.Flag("generateToAddressTo", "[regtest only] configure address to mine blocks to when mining new transactions in blocks").Envar("GENERATE_TO_ADDRESS").Default("").String()
 	bind                = app.Flag("bind", "network interface to bind to (e.g. 0.0.0.0) ").Default("localhost").String()
 	port                = app.Flag("port", "port to serve proxy").Default("23889").Int()
+	ipcPath             = app.Flag("ipc-path", "also serve newline delimited JSON-RPC on a unix socket at this path, only the user Janus runs as can connect to it (empty disables IPC)").Envar("IPC_PATH").Default("").String()
+	ipcOnlySigning      = app.Flag("ipc-only-signing", "only allow eth_sendTransaction, eth_signTransaction, eth_sign and personal_unlockAccount over IPC").Envar("IPC_ONLY_SIGNING").Default("false").Bool()
 	httpsKey            = app.Flag("https-key", "https keyfile").Default("").String()
 	httpsCert           = app.Flag("https-cert", "https certificate").Default("").String()
 	logFile             = app.Flag("log-file", "write logs to a file").Envar("LOG_FILE").Default("").String()
+	logLevel            = app.Flag("log-level", "debug, info, warn or error (empty logs everything in dev mode and warnings and errors otherwise)").Envar("LOG_LEVEL").Default("").Enum("", "debug", "info", "warn", "error")
+	logFormat           = app.Flag("log-format", "logfmt, or json to write every log (request and response dumps included) as a line of JSON").Envar("LOG_FORMAT").Default("logfmt").Enum("logfmt", "json")
+	traceExporter       = app.Flag("trace-exporter", "where to send request traces, stdout or otlp (empty disables tracing)").Envar("TRACE_EXPORTER").Default("").Enum("", "stdout", "otlp")
+	otlpEndpoint        = app.Flag("otlp-endpoint", "OpenTelemetry collector to send traces to with --trace-exporter=otlp, over OTLP/HTTP").Envar("OTEL_EXPORTER_OTLP_ENDPOINT").Default("http://localhost:4318").String()
+	traceServiceName    = app.Flag("trace-service-name", "service name traces are sent with").Envar("OTEL_SERVICE_NAME").Default("janus").String()
+	traceSampleRatio    = app.Flag("trace-sample-ratio", "share of requests to trace, between 0 and 1, requests with a traceparent header follow its sampling decision").Envar("TRACE_SAMPLE_RATIO").Default("1").Float64()
+	newHeadsInterval    = app.Flag("newheads-interval", "how often to poll qtumd for new blocks to send to newHeads subscriptions").Envar("NEWHEADS_INTERVAL").Default("10s").Duration()
+	wsResumeGrace       = app.Flag("ws-resume-grace", "how long websocket subscriptions in a qtum_createSession session are kept after a disconnect so the client can resume them (0 disables sessions)").Envar("WS_RESUME_GRACE").Default("0s").Duration()
+	wsResumeBuffer      = app.Flag("ws-resume-buffer", "how many notifications are kept for a disconnected session, older ones are dropped").Envar("WS_RESUME_BUFFER").Default("1000").Int()
+	adminToken          = app.Flag("admin-token", "enables the admin_ methods, including the webhook ones, for requests with this token in the X-Admin-Token header").Envar("ADMIN_TOKEN").Default("").String()
+	webhooks            = app.Flag("webhooks", "allow registering webhooks with admin_addWebhook, needs --admin-token as whoever has the token can make Janus POST to any url").Envar("WEBHOOKS").Default("false").Bool()
+	cacheSize           = app.Flag("cache-size", "megabytes of block, transaction and receipt responses to cache in memory (0 disables the cache)").Envar("CACHE_SIZE").Default("0").Int64()
+	cacheDir            = app.Flag("cache-dir", "also cache responses for blocks deeper than --cache-reorg-depth in this directory").Envar("CACHE_DIR").Default("").String()
+	cacheReorgDepth     = app.Flag("cache-reorg-depth", "how many confirmations a block needs before cached responses for it stop being checked for reorgs").Envar("CACHE_REORG_DEPTH").Default("10").Int64()
+	qtumdCoalesce       = app.Flag("qtumd-coalesce", "share one qtumd request between identical read only requests made at the same time").Envar("QTUMD_COALESCE").Default("true").Bool()
+	qtumdCoalesceTTL    = app.Flag("qtumd-coalesce-ttl", "also reuse coalesced qtumd responses for this long after they complete (0 only shares requests that are in flight)").Envar("QTUMD_COALESCE_TTL").Default("0s").Duration()
+	requestTimeout      = app.Flag("request-timeout", "how long a request can take before its qtumd calls are cancelled and it fails with a timeout error (0 means no limit)").Envar("REQUEST_TIMEOUT").Default("0s").Duration()
+	methodTimeouts      = app.Flag("method-timeouts", "comma separated per method overrides of --request-timeout, e.g. eth_getLogs=5m,eth_call=30s").Envar("METHOD_TIMEOUTS").Default("").String()
+	rateLimit           = app.Flag("rate-limit", "tokens each client IP gets a second, a request costs 1 token unless its method costs more (0 disables rate limiting)").Envar("RATE_LIMIT").Default("0").Float64()
+	rateLimitBurst      = app.Flag("rate-limit-burst", "most tokens a client IP can save up and spend at once").Envar("RATE_LIMIT_BURST").Default("50").Float64()
+	rateLimitCosts      = app.Flag("rate-limit-costs", "comma separated token costs of methods, e.g. eth_getLogs=10,eth_getBlockByNumber:full=10 (:full is the cost when full transactions are requested)").Envar("RATE_LIMIT_COSTS").Default("").String()
+	trustedProxies      = app.Flag("trusted-proxies", "comma separated IP addresses or CIDR ranges of reverse proxies, the client IP is only taken from X-Forwarded-For/X-Real-IP on requests from them").Envar("TRUSTED_PROXIES").Default("").String()
+	batchConcurrency    = app.Flag("batch-concurrency", "how many requests of a batch are run at the same time").Envar("BATCH_CONCURRENCY").Default("10").Int()
+	maxBatchSize        = app.Flag("max-batch-size", "most requests a batch can have (0 means no limit)").Envar("MAX_BATCH_SIZE").Default("1000").Int()
+	shutdownTimeout     = app.Flag("shutdown-timeout", "on SIGINT/SIGTERM, how long in flight requests get to finish before Janus exits").Envar("SHUTDOWN_TIMEOUT").Default("30s").Duration()
+	shutdownDelay       = app.Flag("shutdown-delay", "on SIGINT/SIGTERM, how long /ready fails before connections start being closed, so load balancers can stop sending requests").Envar("SHUTDOWN_DELAY").Default("0s").Duration()
+	readyMaxBlockLag    = app.Flag("ready-max-block-lag", "how many blocks qtumd can be behind the headers it has before /ready fails").Envar("READY_MAX_BLOCK_LAG").Default("10").Int64()
+	readyMaxTipAge      = app.Flag("ready-max-tip-age", "how old qtumd's latest block can be before /ready fails, ignored on regtest (0 disables the check)").Envar("READY_MAX_TIP_AGE").Default("10m").Duration()
+	apiKeys             = app.Flag("api-keys", "JSON file of API keys, what methods and accounts each one can use and their rate limits (empty disables API keys)").Envar("API_KEYS").Default("").String()
+	gasBuffer           = app.Flag("gas-buffer", "eth_estimateGas multiplies the gas qtumd used by this").Envar("GAS_BUFFER").Default("1.10").Float64()
+	minimumGasLimit     = app.Flag("minimum-gas-limit", "eth_call and eth_sendTransaction requests with a lower gas limit are logged").Envar("MINIMUM_GAS_LIMIT").Default("22000").Int64()
+	callGasCap          = app.Flag("call-gas-cap", "eth_call requests with a higher gas limit return 0x without going to qtumd").Envar("CALL_GAS_CAP").Default("40000000").Int64()
 	matureBlockHeight   = app.Flag("mature-block-height-override", "override how old a coinbase/coinstake needs to be to be considered mature enough for spending (QTUM uses 2000 blocks after the 32s block fork) - if this value is incorrect transactions can be rejected").Int()
 
 	devMode        = app.Flag("dev", "[Insecure] Developer mode").Envar("DEV").Default("false").Bool()
@@ -42,22 +98,33 @@ var (
 	hideQtumdLogs             = app.Flag("hideQtumdLogs", "[Development] Hide QTUMD debug logs").Envar("HIDE_QTUMD_LOGS").Default("false").Bool()
 )
 
+// loadAccountsFile loads the accounts in the file at path, an empty path has none
+func loadAccountsFile(path string, l log.Logger) (qtum.Accounts, error) {
+	if path == "" {
+		return nil, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to open accounts file")
+	}
+	defer file.Close()
+	return loadAccounts(file, l), nil
+}
+
 func loadAccounts(r io.Reader, l log.Logger) qtum.Accounts {
 	var accounts qtum.Accounts
 
-	if accountsFile != nil {
-		s := bufio.NewScanner(*accountsFile)
-		for s.Scan() {
-			line := s.Text()
-
-			wif, err := btcutil.DecodeWIF(line)
-			if err != nil {
-				level.Error(l).Log("msg", "Failed to parse account", "err", err.Error())
-				continue
-			}
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := s.Text()
 
-			accounts = append(accounts, wif)
+		wif, err := btcutil.DecodeWIF(line)
+		if err != nil {
+			level.Error(l).Log("msg", "Failed to parse account", "err", err.Error())
+			continue
 		}
+
+		accounts = append(accounts, wif)
 	}
 
 	if len(accounts) > 0 {
@@ -70,6 +137,15 @@ func loadAccounts(r io.Reader, l log.Logger) qtum.Accounts {
 }
 
 func action(pc *kingpin.ParseContext) error {
+	// everything running in the background stops with ctx once the server has shut down
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// without the token anyone who can reach Janus could make it POST to any url
+	if *webhooks && *adminToken == "" {
+		return errors.New("--webhooks needs --admin-token, the webhook methods are only available to requests with the admin token")
+	}
+
 	addr := fmt.Sprintf("%s:%d", *bind, *port)
 	writers := []io.Writer{os.Stdout}
 
@@ -93,33 +169,63 @@ func action(pc *kingpin.ParseContext) error {
 	}
 
 	logWriter := io.MultiWriter(writers...)
-	logger := log.NewLogfmtLogger(logWriter)
+	jsonLogs := *logFormat == "json"
+	var baseLogger log.Logger
+	if jsonLogs {
+		baseLogger = log.With(log.NewJSONLogger(logWriter), "ts", log.DefaultTimestampUTC)
+	} else {
+		baseLogger = log.NewLogfmtLogger(logWriter)
+	}
+	logger := newLevelLogger(baseLogger, *logLevel, *devMode)
 
-	if !*devMode {
-		logger = level.NewFilter(logger, level.AllowWarn())
+	accounts, err := loadAccountsFile(*accountsFile, logger)
+	if err != nil {
+		return err
 	}
 
-	var accounts qtum.Accounts
-	if *accountsFile != nil {
-		accounts = loadAccounts(*accountsFile, logger)
-		(*accountsFile).Close()
+	if err := transformer.ReloadGasSettings(gasSettings()); err != nil {
+		return err
 	}
 
 	isMain := *qtumNetwork == qtum.ChainMain
 
+	qtumRPCURLs := strings.Split(*qtumRPC, ",")
+	for i := range qtumRPCURLs {
+		qtumRPCURLs[i] = strings.TrimSpace(qtumRPCURLs[i])
+	}
+
+	transportConfig := qtum.DefaultTransportConfig
+	transportConfig.KeepAlive = *qtumRPCKeepAlive
+	transportConfig.MaxIdleConns = *qtumRPCMaxIdle * len(qtumRPCURLs)
+	transportConfig.MaxIdleConnsPerHost = *qtumRPCMaxIdle
+	transportConfig.MaxConnsPerHost = *qtumRPCMaxConns
+	transportConfig.DialTimeout = *qtumRPCDialTimeout
+	transportConfig.ResponseTimeout = *qtumRPCRespTimeout
+	transportConfig.IdleConnTimeout = *qtumRPCIdleTimeout
+	transportConfig.CAFile = *qtumRPCCAFile
+
 	qtumJSONRPC, err := qtum.NewClient(
 		isMain,
-		*qtumRPC,
+		qtumRPCURLs[0],
+		qtum.SetTransport(transportConfig),
 		qtum.SetDebug(*devMode),
 		qtum.SetLogWriter(logWriter),
 		qtum.SetLogger(logger),
+		qtum.SetJSONLogs(jsonLogs),
 		qtum.SetAccounts(accounts),
 		qtum.SetGenerateToAddress(*generateToAddressTo),
 		qtum.SetIgnoreUnknownTransactions(*ignoreUnknownTransactions),
 		qtum.SetDisableSnippingQtumRpcOutput(*disableSnipping),
 		qtum.SetHideQtumdLogs(*hideQtumdLogs),
 		qtum.SetMatureBlockHeight(matureBlockHeight),
-		qtum.SetContext(context.Background()),
+		qtum.SetCoalescing(*qtumdCoalesce),
+		qtum.SetCoalesceTTL(*qtumdCoalesceTTL),
+		qtum.SetBatchWindow(*qtumRPCBatchWindow),
+		qtum.SetUpstreams(qtumRPCURLs[1:]),
+		qtum.SetUpstreamMaxLag(*qtumRPCMaxLag),
+		qtum.SetUpstreamCheckInterval(*qtumRPCCheck),
+		qtum.SetRecording(*recordQtumd),
+		qtum.SetContext(ctx),
 	)
 	if err != nil {
 		return errors.Wrap(err, "Failed to setup QTUM client")
@@ -129,14 +235,49 @@ func action(pc *kingpin.ParseContext) error {
 	if err != nil {
 		return errors.Wrap(err, "Failed to setup QTUM chain")
 	}
+	reportCapabilities(ctx, qtumClient, logger)
+
+	agent := notifier.NewAgent(
+		ctx,
+		qtumClient,
+		nil,
+		notifier.SetNewHeadsInterval(*newHeadsInterval),
+		notifier.SetResumableSessions(*wsResumeGrace, *wsResumeBuffer),
+		notifier.SetWebhooks(*webhooks),
+	)
+
+	parsedMethodTimeouts, err := parseMethodTimeouts(*methodTimeouts)
+	if err != nil {
+		return err
+	}
+
+	transformerOptions := []transformer.Option{
+		transformer.SetDebug(*devMode),
+		transformer.SetDebugReloaded(logger.setDebug),
+		transformer.SetLogger(logger),
+		transformer.SetRequestTimeout(*requestTimeout),
+		transformer.SetMethodTimeouts(parsedMethodTimeouts),
+	}
+	if *cacheSize > 0 {
+		responseCache, err := cache.New(
+			ctx,
+			qtumClient,
+			cache.SetMaxBytes(*cacheSize*1024*1024),
+			cache.SetReorgDepth(*cacheReorgDepth),
+			cache.SetDiskPath(*cacheDir),
+			cache.SetLogger(logger),
+		)
+		if err != nil {
+			return errors.Wrap(err, "cache#New")
+		}
+		transformerOptions = append(transformerOptions, transformer.SetResponseCache(responseCache))
+	}
 
-	agent := notifier.NewAgent(context.Background(), qtumClient, nil)
 	proxies := transformer.DefaultProxies(qtumClient, agent)
 	t, err := transformer.New(
 		qtumClient,
 		proxies,
-		transformer.SetDebug(*devMode),
-		transformer.SetLogger(logger),
+		transformerOptions...,
 	)
 	if err != nil {
 		return errors.Wrap(err, "transformer#New")
@@ -146,21 +287,247 @@ func action(pc *kingpin.ParseContext) error {
 	httpsKeyFile := getEmptyStringIfFileDoesntExist(*httpsKey, logger)
 	httpsCertFile := getEmptyStringIfFileDoesntExist(*httpsCert, logger)
 
-	s, err := server.New(
-		qtumClient,
-		t,
-		addr,
+	serverOptions := []server.Option{
 		server.SetLogWriter(logWriter),
 		server.SetLogger(logger),
-		server.SetDebug(*devMode),
+		server.SetJSONLogs(jsonLogs),
 		server.SetSingleThreaded(*singleThreaded),
 		server.SetHttps(httpsKeyFile, httpsCertFile),
+		server.SetAgent(agent),
+		server.SetAdminToken(*adminToken),
+		server.SetTrustedProxies(strings.Split(*trustedProxies, ",")),
+		server.SetBatchConcurrency(*batchConcurrency),
+		server.SetMaxBatchSize(*maxBatchSize),
+		server.SetShutdownDelay(*shutdownDelay),
+		server.SetReadyMaxBlockLag(*readyMaxBlockLag),
+		server.SetReadyMaxTipAge(*readyMaxTipAge),
+		server.SetIPCPath(*ipcPath),
+		server.SetSigningOverIPCOnly(*ipcOnlySigning),
+	}
+	costs, err := parseRateLimitCosts(*rateLimitCosts)
+	if err != nil {
+		return err
+	}
+	if *rateLimit > 0 {
+		limiter, err := ratelimit.New(
+			ctx,
+			*rateLimit,
+			ratelimit.SetBurst(*rateLimitBurst),
+			ratelimit.SetCosts(costs),
+		)
+		if err != nil {
+			return errors.Wrap(err, "ratelimit#New")
+		}
+		serverOptions = append(serverOptions, server.SetRateLimiter(limiter))
+	}
+	if *apiKeys != "" {
+		authOptions, err := loadAPIKeys(ctx, *apiKeys, costs)
+		if err != nil {
+			return err
+		}
+		serverOptions = append(serverOptions, authOptions...)
+	}
+
+	tracer, err := newTracer(logWriter, logger)
+	if err != nil {
+		return err
+	}
+	if tracer != nil {
+		serverOptions = append(serverOptions, server.SetTracer(tracer))
+	}
+
+	s, err := server.New(
+		qtumClient,
+		t,
+		addr,
+		serverOptions...,
 	)
 	if err != nil {
 		return errors.Wrap(err, "server#New")
 	}
 
-	return s.Start()
+	started := make(chan error, 1)
+	go func() {
+		started <- s.Start()
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	// SIGHUP or a change to the config file reloads it
+	hangups := make(chan os.Signal, 1)
+	configChanged := make(chan struct{}, 1)
+	var reloader *configReloader
+	if *configFile != "" {
+		reloader = newConfigReloader(pc, *configFile, logger, qtumClient, t)
+		signal.Notify(hangups, syscall.SIGHUP)
+		defer signal.Stop(hangups)
+		go config.Watch(ctx, *configFile, config.DefaultWatchInterval, func() {
+			select {
+			case configChanged <- struct{}{}:
+			default:
+			}
+		})
+	}
+
+waitForSignal:
+	for {
+		select {
+		case err := <-started:
+			return err
+		case <-hangups:
+			reloader.reload()
+		case <-configChanged:
+			reloader.reload()
+		case sig := <-signals:
+			level.Info(logger).Log("msg", "Received signal, shutting down", "signal", sig)
+			break waitForSignal
+		}
+	}
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), *shutdownDelay+*shutdownTimeout)
+	defer cancelShutdown()
+	err = s.Shutdown(shutdownCtx)
+	agent.Stop()
+	if closeErr := qtumJSONRPC.Close(); closeErr != nil {
+		level.Warn(logger).Log("msg", "Failed to close qtumd client", "err", closeErr)
+	}
+	if tracer != nil {
+		if traceErr := tracer.Shutdown(shutdownCtx); traceErr != nil {
+			level.Warn(logger).Log("msg", "Failed to send the last traces", "err", traceErr)
+		}
+	}
+	if startErr := <-started; err == nil {
+		err = startErr
+	}
+	return err
+}
+
+// newTracer makes the tracer --trace-exporter asks for, nil if tracing is off
+// stdout traces go to the log writer, so they end up in the log file too
+func newTracer(logWriter io.Writer, logger log.Logger) (*tracing.Tracer, error) {
+	var exporter tracing.Exporter
+	switch *traceExporter {
+	case "":
+		return nil, nil
+	case "stdout":
+		exporter = tracing.NewStdoutExporter(logWriter)
+	case "otlp":
+		url := strings.TrimSuffix(*otlpEndpoint, "/") + "/v1/traces"
+		exporter = tracing.NewOTLPExporter(url, *traceServiceName, logger)
+	}
+
+	tracer, err := tracing.New(exporter, tracing.SetSampleRatio(*traceSampleRatio))
+	if err != nil {
+		return nil, errors.Wrap(err, "tracing#New")
+	}
+	level.Info(logger).Log("msg", "Tracing requests", "exporter", *traceExporter, "sampleRatio", *traceSampleRatio)
+	return tracer, nil
+}
+
+// reportCapabilities finds out what qtumd supports before requests are served, and logs the methods that won't work
+// the qtumd-features readiness check keeps the capabilities up to date after that
+func reportCapabilities(ctx context.Context, qtumClient *qtum.Qtum, logger log.Logger) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	capabilities, err := qtumClient.DetectCapabilities(ctx)
+	if err != nil {
+		level.Warn(logger).Log("msg", "Failed to detect what qtumd supports, every method is enabled until it can be", "err", err)
+		return
+	}
+
+	keyvals := []interface{}{"msg", "Detected qtumd capabilities", "version", capabilities.Version, "subversion", capabilities.Subversion}
+	for _, capability := range qtum.AllCapabilities {
+		keyvals = append(keyvals, strings.TrimPrefix(string(capability), "-"), capabilities.Enabled[capability])
+	}
+	level.Info(logger).Log(keyvals...)
+
+	for _, capability := range capabilities.Missing() {
+		methods := transformer.MethodsRequiring(capability)
+		if len(methods) == 0 {
+			level.Warn(logger).Log("msg", "qtumd wasn't started with "+string(capability)+", some requests might fail")
+			continue
+		}
+		level.Warn(logger).Log("msg", "qtumd wasn't started with "+string(capability)+", methods that need it are disabled", "methods", strings.Join(methods, ","))
+	}
+}
+
+// loadAPIKeys loads the API keys file and makes a limiter for each of its rate classes
+func loadAPIKeys(ctx context.Context, path string, costs map[string]float64) ([]server.Option, error) {
+	config, err := auth.LoadConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	authenticator, err := auth.New(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "auth#New")
+	}
+
+	limiters := make(map[string]*ratelimit.Limiter, len(config.RateClasses))
+	for name, class := range config.RateClasses {
+		if class.Rate <= 0 {
+			limiters[name] = nil
+			continue
+		}
+		opts := []ratelimit.Option{ratelimit.SetCosts(costs)}
+		if class.Burst > 0 {
+			opts = append(opts, ratelimit.SetBurst(class.Burst))
+		}
+		limiter, err := ratelimit.New(ctx, class.Rate, opts...)
+		if err != nil {
+			return nil, errors.Wrapf(err, "rate class %s", name)
+		}
+		limiters[name] = limiter
+	}
+
+	return []server.Option{
+		server.SetAuthenticator(authenticator),
+		server.SetRateClasses(limiters),
+	}, nil
+}
+
+// parseMethodTimeouts parses method=duration pairs separated by commas
+func parseMethodTimeouts(timeouts string) (map[string]time.Duration, error) {
+	parsed := make(map[string]time.Duration)
+	for _, pair := range strings.Split(timeouts, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return nil, errors.Errorf("Method timeout %q should look like method=duration", pair)
+		}
+		timeout, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to parse timeout for %s", parts[0])
+		}
+		parsed[strings.TrimSpace(parts[0])] = timeout
+	}
+	return parsed, nil
+}
+
+// parseRateLimitCosts parses method=cost pairs separated by commas
+func parseRateLimitCosts(costs string) (map[string]float64, error) {
+	parsed := make(map[string]float64)
+	for _, pair := range strings.Split(costs, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return nil, errors.Errorf("Rate limit cost %q should look like method=cost", pair)
+		}
+		cost, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to parse rate limit cost for %s", parts[0])
+		}
+		parsed[strings.TrimSpace(parts[0])] = cost
+	}
+	return parsed, nil
 }
 
 func getEmptyStringIfFileDoesntExist(file string, l log.Logger) string {
@@ -174,6 +541,9 @@ func getEmptyStringIfFileDoesntExist(file string, l log.Logger) string {
 
 func Run() {
 	app.Version(params.VersionWithGitSha)
+	if err := applyConfigFile(os.Args[1:]); err != nil {
+		app.Fatalf("%s", err)
+	}
 	kingpin.MustParse(app.Parse(os.Args[1:]))
 }
 
@@ -0,0 +1,303 @@
+package cli
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/qtumproject/janus/pkg/config"
+	"github.com/qtumproject/janus/pkg/qtum"
+	"github.com/qtumproject/janus/pkg/transformer"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// reloadableFlags are the settings reloaded on SIGHUP or when the config file changes,
+// the rest of the config file only takes effect on a restart
+var reloadableFlags = []string{
+	"dev",
+	"log-level",
+	"accounts",
+	"gas-buffer",
+	"minimum-gas-limit",
+	"call-gas-cap",
+	"request-timeout",
+	"method-timeouts",
+}
+
+// builtInDefaults are the defaults of the flags the config file changed, for when a reload removes them from the file
+var builtInDefaults = make(map[string]string)
+
+// applyConfigFile makes the settings in the config file the defaults of their flags, so command line
+// flags and environment variables still take precedence
+// it runs before the command line is parsed, so it finds --config in args itself
+func applyConfigFile(args []string) error {
+	path := findConfigFile(args)
+	if path == "" {
+		return nil
+	}
+
+	values, err := loadConfigFile(path)
+	if err != nil {
+		return err
+	}
+	for name, value := range values {
+		flag := app.GetFlag(name)
+		builtInDefaults[name] = strings.Join(flag.Model().Default, ",")
+		flag.Default(value)
+	}
+	return nil
+}
+
+// findConfigFile is the value of --config in args, or CONFIG_FILE
+func findConfigFile(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if strings.HasPrefix(arg, "--config=") {
+			return strings.TrimPrefix(arg, "--config=")
+		}
+		if arg == "--config" && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return os.Getenv(app.GetFlag("config").Model().Envar)
+}
+
+// loadConfigFile loads the config file, every setting in it has to be a flag
+func loadConfigFile(path string) (map[string]string, error) {
+	values, err := config.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	for name := range values {
+		if name == "config" || app.GetFlag(name) == nil {
+			return nil, errors.Errorf("Unknown setting %s in config file %s", name, path)
+		}
+	}
+	return values, nil
+}
+
+// builtInDefault is the default of a flag before the config file changed it
+func builtInDefault(name string) string {
+	if value, ok := builtInDefaults[name]; ok {
+		return value
+	}
+	return strings.Join(app.GetFlag(name).Model().Default, ",")
+}
+
+func allowedLogLevel(logLevel string, dev bool) level.Option {
+	switch logLevel {
+	case "debug":
+		return level.AllowDebug()
+	case "info":
+		return level.AllowInfo()
+	case "warn":
+		return level.AllowWarn()
+	case "error":
+		return level.AllowError()
+	}
+	if dev {
+		return level.AllowAll()
+	}
+	return level.AllowWarn()
+}
+
+func gasSettings() transformer.GasSettings {
+	return transformer.GasSettings{
+		EstimateBuffer:  *gasBuffer,
+		MinimumGasLimit: *minimumGasLimit,
+		CallGasCap:      *callGasCap,
+	}
+}
+
+// levelLogger filters logs by a level that can be changed while Janus is running
+// debug mode allows every level unless --log-level says otherwise
+type levelLogger struct {
+	base log.Logger
+
+	mutex    sync.RWMutex
+	logLevel string
+	debug    bool
+	filtered log.Logger
+}
+
+func newLevelLogger(base log.Logger, logLevel string, debug bool) *levelLogger {
+	l := &levelLogger{base: base, logLevel: logLevel, debug: debug}
+	l.filtered = level.NewFilter(base, allowedLogLevel(logLevel, debug))
+	return l
+}
+
+func (l *levelLogger) Log(keyvals ...interface{}) error {
+	l.mutex.RLock()
+	filtered := l.filtered
+	l.mutex.RUnlock()
+	return filtered.Log(keyvals...)
+}
+
+func (l *levelLogger) setLogLevel(logLevel string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.logLevel = logLevel
+	l.filtered = level.NewFilter(l.base, allowedLogLevel(l.logLevel, l.debug))
+}
+
+// setDebug is called whenever the transformer's debug mode is reloaded
+func (l *levelLogger) setDebug(debug bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.debug = debug
+	l.filtered = level.NewFilter(l.base, allowedLogLevel(l.logLevel, l.debug))
+}
+
+// configReloader applies changes to the reloadable settings of the config file to the running components
+type configReloader struct {
+	path        string
+	logger      *levelLogger
+	qtumClient  *qtum.Qtum
+	transformer *transformer.Transformer
+	// flags set on the command line or in the environment, the config file can't change them
+	pinned map[string]bool
+}
+
+func newConfigReloader(pc *kingpin.ParseContext, path string, logger *levelLogger, qtumClient *qtum.Qtum, t *transformer.Transformer) *configReloader {
+	pinned := make(map[string]bool)
+	for _, element := range pc.Elements {
+		if flag, ok := element.Clause.(*kingpin.FlagClause); ok {
+			pinned[flag.Model().Name] = true
+		}
+	}
+	for _, name := range reloadableFlags {
+		if envar := app.GetFlag(name).Model().Envar; envar != "" && os.Getenv(envar) != "" {
+			pinned[name] = true
+		}
+	}
+
+	return &configReloader{
+		path:        path,
+		logger:      logger,
+		qtumClient:  qtumClient,
+		transformer: t,
+		pinned:      pinned,
+	}
+}
+
+// reload reads the config file again and applies the reloadable settings, a config file
+// with a mistake in it is logged and changes nothing
+func (r *configReloader) reload() {
+	if err := r.apply(); err != nil {
+		level.Error(r.logger).Log("msg", "Failed to reload config file, keeping the current settings", "file", r.path, "err", err)
+		return
+	}
+	level.Info(r.logger).Log("msg", "Reloaded config file", "file", r.path)
+}
+
+func (r *configReloader) apply() error {
+	values, err := loadConfigFile(r.path)
+	if err != nil {
+		return err
+	}
+
+	// the new settings are parsed and checked before any flag changes, so a bad file leaves everything as it was
+	settings := currentSettings()
+	changed := make(map[string]string)
+	for _, name := range reloadableFlags {
+		if r.pinned[name] {
+			continue
+		}
+		value, ok := values[name]
+		if !ok {
+			// taken out of the file
+			value = builtInDefault(name)
+		}
+		if err := settings.set(name, value); err != nil {
+			return err
+		}
+		changed[name] = value
+	}
+
+	accounts, err := loadAccountsFile(settings.accountsFile, r.logger)
+	if err != nil {
+		return err
+	}
+	parsedMethodTimeouts, err := parseMethodTimeouts(settings.methodTimeouts)
+	if err != nil {
+		return err
+	}
+	if err := settings.gas.Validate(); err != nil {
+		return err
+	}
+	if err := r.transformer.ReloadTimeouts(settings.requestTimeout, parsedMethodTimeouts); err != nil {
+		return err
+	}
+
+	for name, value := range changed {
+		if err := app.GetFlag(name).Model().Value.Set(value); err != nil {
+			// it was parsed the same way above
+			level.Error(r.logger).Log("msg", "Failed to update flag", "flag", name, "err", err)
+		}
+	}
+	transformer.ReloadGasSettings(settings.gas)
+	r.logger.setLogLevel(settings.logLevel)
+	// also reloads the qtum client's and the logger's debug mode
+	r.transformer.ReloadDebug(settings.dev)
+	r.qtumClient.ReloadAccounts(accounts)
+	return nil
+}
+
+// reloadableSettings are the values of the reloadable flags, parsed without changing the flags
+type reloadableSettings struct {
+	dev            bool
+	logLevel       string
+	accountsFile   string
+	gas            transformer.GasSettings
+	requestTimeout time.Duration
+	methodTimeouts string
+}
+
+func currentSettings() reloadableSettings {
+	return reloadableSettings{
+		dev:            *devMode,
+		logLevel:       *logLevel,
+		accountsFile:   *accountsFile,
+		gas:            gasSettings(),
+		requestTimeout: *requestTimeout,
+		methodTimeouts: *methodTimeouts,
+	}
+}
+
+// set parses value the way the flag called name does
+func (s *reloadableSettings) set(name string, value string) error {
+	var err error
+	switch name {
+	case "dev":
+		s.dev, err = strconv.ParseBool(value)
+	case "log-level":
+		switch value {
+		case "", "debug", "info", "warn", "error":
+			s.logLevel = value
+		default:
+			err = errors.Errorf("enum value must be one of debug,info,warn,error, got '%s'", value)
+		}
+	case "accounts":
+		s.accountsFile = value
+	case "gas-buffer":
+		s.gas.EstimateBuffer, err = strconv.ParseFloat(value, 64)
+	case "minimum-gas-limit":
+		s.gas.MinimumGasLimit, err = strconv.ParseInt(value, 0, 64)
+	case "call-gas-cap":
+		s.gas.CallGasCap, err = strconv.ParseInt(value, 0, 64)
+	case "request-timeout":
+		s.requestTimeout, err = time.ParseDuration(value)
+	case "method-timeouts":
+		s.methodTimeouts = value
+	default:
+		err = errors.New("it can't be reloaded")
+	}
+	return errors.Wrapf(err, "Invalid %s", name)
+}
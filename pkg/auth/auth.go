@@ -0,0 +1,246 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/qtumproject/janus/pkg/eth"
+	"github.com/qtumproject/janus/pkg/utils"
+)
+
+// AuthenticateMethod is the websocket method for sending an API key as the first message
+// {"jsonrpc": "2.0", "id": 1, "method": "qtum_authenticate", "params": ["<api key>"]}
+const AuthenticateMethod = "qtum_authenticate"
+
+// APIKeyHeader and APIKeyQueryParam are where HTTP and websocket requests can send their API key
+// it can also be sent as "Authorization: Bearer <api key>"
+const (
+	APIKeyHeader     = "X-Api-Key"
+	APIKeyQueryParam = "apikey"
+)
+
+var (
+	ErrMissingAPIKey = errors.New("an API key is required")
+	ErrInvalidAPIKey = errors.New("invalid API key")
+)
+
+// methods that act as one of the loaded accounts, and where in their first parameter the account is
+// an empty field means the account is the first parameter itself
+var accountMethods = map[string]string{
+	"eth_sendTransaction": "from",
+	"eth_signTransaction": "from",
+	"eth_sign":            "",
+}
+
+// Key is what a holder of an API key can do
+type Key struct {
+	// shown in logs instead of the key itself
+	Name string `json:"name"`
+	Key  string `json:"key"`
+	// allowed methods, "*" allows everything and a trailing * allows every method with that prefix, eg "eth_*"
+	Methods []string `json:"methods"`
+	// hex addresses of the loaded accounts this key can send and sign with, "*" allows all of them
+	Accounts []string `json:"accounts"`
+	// requests are rate limited by this class's limits, the default limits apply if it is empty
+	RateClass string `json:"rateClass"`
+
+	// identifies the key without giving it away, empty for anonymous requests
+	id string
+}
+
+// RateClass is the rate limit shared by every key in the class, each key gets its own bucket
+// a rate of 0 means no limit
+type RateClass struct {
+	Rate  float64 `json:"rate"`
+	Burst float64 `json:"burst"`
+}
+
+// Config is the API keys file
+type Config struct {
+	Keys []Key `json:"keys"`
+	// what requests without an API key can do, nil means nothing
+	Anonymous   *Key                 `json:"anonymous"`
+	RateClasses map[string]RateClass `json:"rateClasses"`
+}
+
+// LoadConfig reads an API keys file
+func LoadConfig(path string) (*Config, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to read API keys file %s", path)
+	}
+
+	var config Config
+	if err := json.Unmarshal(contents, &config); err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse API keys file %s", path)
+	}
+	return &config, nil
+}
+
+// Authenticator looks up API keys
+type Authenticator struct {
+	// keys are looked up by their hash so how long a lookup takes says nothing about the key
+	keys      map[[sha256.Size]byte]*Key
+	anonymous *Key
+}
+
+func New(config *Config) (*Authenticator, error) {
+	a := &Authenticator{
+		keys:      make(map[[sha256.Size]byte]*Key, len(config.Keys)),
+		anonymous: config.Anonymous,
+	}
+
+	for i := range config.Keys {
+		key := &config.Keys[i]
+		if key.Key == "" {
+			return nil, errors.Errorf("API key %q is empty", key.Name)
+		}
+		if key.RateClass != "" {
+			if _, ok := config.RateClasses[key.RateClass]; !ok {
+				return nil, errors.Errorf("API key %q has unknown rate class %q", key.Name, key.RateClass)
+			}
+		}
+		hash := sha256.Sum256([]byte(key.Key))
+		if _, ok := a.keys[hash]; ok {
+			return nil, errors.Errorf("API key %q is used more than once", key.Name)
+		}
+		key.id = "key:" + hex.EncodeToString(hash[:8])
+		a.keys[hash] = key
+	}
+
+	if a.anonymous != nil && a.anonymous.Name == "" {
+		a.anonymous.Name = "anonymous"
+	}
+
+	return a, nil
+}
+
+// Authenticate finds the key for apiKey, an empty apiKey gets what anonymous requests can do
+func (a *Authenticator) Authenticate(apiKey string) (*Key, error) {
+	if apiKey == "" {
+		if a.anonymous == nil {
+			return nil, ErrMissingAPIKey
+		}
+		return a.anonymous, nil
+	}
+
+	key, ok := a.keys[sha256.Sum256([]byte(apiKey))]
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+	return key, nil
+}
+
+// APIKeyFromRequest gets the API key sent with an HTTP or websocket request, if any
+func APIKeyFromRequest(req *http.Request) string {
+	if key := req.Header.Get(APIKeyHeader); key != "" {
+		return key
+	}
+	if authorization := req.Header.Get("Authorization"); strings.HasPrefix(authorization, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(authorization, "Bearer "))
+	}
+	return req.URL.Query().Get(APIKeyQueryParam)
+}
+
+// RateLimitKey is who requests made with the key are rate limited as
+// every API key has its own limit, anonymous requests are limited by IP address
+func (k *Key) RateLimitKey(ip string) string {
+	if k.id == "" {
+		return ip
+	}
+	return k.id
+}
+
+// AllowsMethod is true if the key can call method
+func (k *Key) AllowsMethod(method string) bool {
+	for _, allowed := range k.Methods {
+		if allowed == method || allowed == "*" {
+			return true
+		}
+		if strings.HasSuffix(allowed, "*") && strings.HasPrefix(method, strings.TrimSuffix(allowed, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsAccount is true if the key can send and sign with the account at hex address
+func (k *Key) AllowsAccount(address string) bool {
+	address = strings.ToLower(utils.RemoveHexPrefix(address))
+	for _, allowed := range k.Accounts {
+		if allowed == "*" || strings.ToLower(utils.RemoveHexPrefix(allowed)) == address {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorize checks the key can make req, both the method and the account it acts as
+func (k *Key) Authorize(req *eth.JSONRPCRequest) eth.JSONRPCError {
+	if !k.AllowsMethod(req.Method) {
+		return eth.NewJSONRPCError(
+			eth.MethodNotFoundErrorCode,
+			"The method "+req.Method+" is not available with this API key",
+			nil,
+		)
+	}
+
+	field, ok := accountMethods[req.Method]
+	if !ok {
+		return nil
+	}
+	account := requestAccount(req.Params, field)
+	if account == "" {
+		// without an account qtumd picks one from its wallet, so that needs access to all of them
+		if !k.allowsAllAccounts() {
+			return eth.NewInvalidRequestError("An account has to be given with this API key")
+		}
+	} else if !k.AllowsAccount(account) {
+		return eth.NewInvalidRequestError("The account " + account + " is not available with this API key")
+	}
+	return nil
+}
+
+func (k *Key) allowsAllAccounts() bool {
+	for _, allowed := range k.Accounts {
+		if allowed == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterAccounts keeps the accounts the key is allowed to use
+func (k *Key) FilterAccounts(accounts eth.AccountsResponse) eth.AccountsResponse {
+	filtered := eth.AccountsResponse{}
+	for _, account := range accounts {
+		if k.AllowsAccount(account) {
+			filtered = append(filtered, account)
+		}
+	}
+	return filtered
+}
+
+// requestAccount gets the account from the first parameter of params
+func requestAccount(params json.RawMessage, field string) string {
+	var args []json.RawMessage
+	if err := json.Unmarshal(params, &args); err != nil || len(args) == 0 {
+		return ""
+	}
+
+	if field == "" {
+		var account string
+		json.Unmarshal(args[0], &account)
+		return account
+	}
+
+	var fields map[string]interface{}
+	json.Unmarshal(args[0], &fields)
+	account, _ := fields[field].(string)
+	return account
+}
@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/qtumproject/janus/pkg/eth"
+)
+
+func request(method string, params ...interface{}) *eth.JSONRPCRequest {
+	rawParams, _ := json.Marshal(params)
+	return &eth.JSONRPCRequest{Method: method, Params: rawParams}
+}
+
+func TestKeyAllowsMethods(t *testing.T) {
+	key := &Key{Methods: []string{"eth_getBalance", "net_*"}}
+
+	allowed := map[string]bool{
+		"eth_getBalance":  true,
+		"net_version":     true,
+		"net_listening":   true,
+		"eth_getLogs":     false,
+		"eth_getBalances": false,
+	}
+	for method, want := range allowed {
+		if got := key.AllowsMethod(method); got != want {
+			t.Errorf("expected AllowsMethod(%s) to be %v", method, want)
+		}
+	}
+
+	if !(&Key{Methods: []string{"*"}}).AllowsMethod("eth_sendTransaction") {
+		t.Error("expected * to allow every method")
+	}
+
+	err := key.Authorize(request("eth_getLogs"))
+	if err == nil || err.Code() != eth.MethodNotFoundErrorCode {
+		t.Errorf("expected a disallowed method to not be found, got %v", err)
+	}
+}
+
+func TestKeyAuthorizesAccounts(t *testing.T) {
+	key := &Key{
+		Methods:  []string{"*"},
+		Accounts: []string{"0x7926223070547D2D15b2eF5e7383E541c338FfE9"},
+	}
+
+	tests := []struct {
+		req     *eth.JSONRPCRequest
+		allowed bool
+	}{
+		{request("eth_sendTransaction", map[string]string{"from": "0x7926223070547d2d15b2ef5e7383e541c338ffe9"}), true},
+		{request("eth_sendTransaction", map[string]string{"from": "0x1111111111111111111111111111111111111111"}), false},
+		// qtumd would pick the account itself
+		{request("eth_sendTransaction", map[string]string{"to": "0x1111111111111111111111111111111111111111"}), false},
+		{request("eth_sign", "7926223070547d2d15b2ef5e7383e541c338ffe9", "0x00"), true},
+		{request("eth_sign", "0x1111111111111111111111111111111111111111", "0x00"), false},
+		{request("eth_getBalance", "0x1111111111111111111111111111111111111111", "latest"), true},
+	}
+	for _, test := range tests {
+		err := key.Authorize(test.req)
+		if test.allowed && err != nil {
+			t.Errorf("expected %s%s to be allowed, got %v", test.req.Method, test.req.Params, err)
+		}
+		if !test.allowed && err == nil {
+			t.Errorf("expected %s%s to be refused", test.req.Method, test.req.Params)
+		}
+	}
+
+	all := &Key{Methods: []string{"*"}, Accounts: []string{"*"}}
+	if err := all.Authorize(request("eth_sendTransaction", map[string]string{})); err != nil {
+		t.Errorf("expected a key with all accounts to send without a from, got %v", err)
+	}
+}
+
+func TestKeyFiltersAccounts(t *testing.T) {
+	key := &Key{Accounts: []string{"7926223070547d2d15b2ef5e7383e541c338ffe9"}}
+
+	got := key.FilterAccounts(eth.AccountsResponse{
+		"0x7926223070547d2d15b2ef5e7383e541c338ffe9",
+		"0x2352be3db3177f0a07efbe6da5857615b8c9901d",
+	})
+	want := eth.AccountsResponse{"0x7926223070547d2d15b2ef5e7383e541c338ffe9"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	config := &Config{
+		Keys: []Key{
+			{Name: "internal", Key: "secret", Methods: []string{"*"}, RateClass: "internal"},
+		},
+		RateClasses: map[string]RateClass{"internal": {Rate: 100}},
+	}
+	a, err := New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := a.Authenticate("secret")
+	if err != nil || key.Name != "internal" {
+		t.Errorf("expected the internal key, got %v %v", key, err)
+	}
+	if key.RateLimitKey("127.0.0.1") == "127.0.0.1" {
+		t.Error("expected a key to be rate limited by key and not IP address")
+	}
+	if _, err := a.Authenticate("wrong"); err != ErrInvalidAPIKey {
+		t.Errorf("expected an invalid key error, got %v", err)
+	}
+	if _, err := a.Authenticate(""); err != ErrMissingAPIKey {
+		t.Errorf("expected a missing key error, got %v", err)
+	}
+
+	config.Anonymous = &Key{Methods: []string{"eth_blockNumber"}}
+	a, err = New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err = a.Authenticate("")
+	if err != nil || key.Name != "anonymous" {
+		t.Errorf("expected the anonymous key, got %v %v", key, err)
+	}
+	if key.RateLimitKey("127.0.0.1") != "127.0.0.1" {
+		t.Error("expected anonymous requests to be rate limited by IP address")
+	}
+}
+
+func TestNewValidatesKeys(t *testing.T) {
+	configs := map[string]*Config{
+		"empty key": {Keys: []Key{{Name: "empty"}}},
+		"unknown rate class": {Keys: []Key{
+			{Name: "a", Key: "a", RateClass: "missing"},
+		}},
+		"duplicate key": {Keys: []Key{
+			{Name: "a", Key: "a"},
+			{Name: "b", Key: "a"},
+		}},
+	}
+	for name, config := range configs {
+		if _, err := New(config); err == nil {
+			t.Errorf("expected an error for %s", name)
+		}
+	}
+}
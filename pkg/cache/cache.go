@@ -0,0 +1,358 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+	"github.com/qtumproject/janus/pkg/metrics"
+	"github.com/qtumproject/janus/pkg/qtum"
+)
+
+var (
+	// DefaultMaxBytes is how much response data is kept in memory
+	DefaultMaxBytes int64 = 64 * 1024 * 1024
+	// DefaultReorgDepth is how many confirmations a block needs before its responses are considered immutable
+	DefaultReorgDepth int64 = 10
+	// DefaultReorgCheckInterval is how often shallow blocks are checked for reorgs
+	DefaultReorgCheckInterval = 5 * time.Second
+)
+
+// Chain is the part of the qtum client the cache uses to detect reorgs
+type Chain interface {
+	GetBlockCount(context.Context) (*qtum.GetBlockCountResponse, error)
+	GetBlockHash(context.Context, *big.Int) (qtum.GetBlockHashResponse, error)
+}
+
+type entry struct {
+	key    string
+	value  []byte
+	height int64
+}
+
+// ResponseCache is an LRU cache of responses that belong to a block
+// responses for blocks that are less than reorgDepth deep are dropped if their block is reorged out
+// once a block is deep enough its responses are written to the disk tier, if there is one
+type ResponseCache struct {
+	ctx    context.Context
+	chain  Chain
+	logger log.Logger
+
+	maxBytes           int64
+	reorgDepth         int64
+	reorgCheckInterval time.Duration
+	disk               *diskTier
+
+	mutex   sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	size    int64
+	// last block count seen, 0 until the first reorg check
+	tip int64
+	// blocks that could still be reorged out, height => block hash
+	shallow map[int64]string
+	// keys of the entries for each shallow block
+	shallowKeys map[int64]map[string]bool
+}
+
+type Option func(*ResponseCache) error
+
+func New(ctx context.Context, chain Chain, opts ...Option) (*ResponseCache, error) {
+	if chain == nil {
+		return nil, errors.New("chain cannot be nil")
+	}
+
+	c := &ResponseCache{
+		ctx:                ctx,
+		chain:              chain,
+		logger:             log.NewNopLogger(),
+		maxBytes:           DefaultMaxBytes,
+		reorgDepth:         DefaultReorgDepth,
+		reorgCheckInterval: DefaultReorgCheckInterval,
+		entries:            make(map[string]*list.Element),
+		lru:                list.New(),
+		shallow:            make(map[int64]string),
+		shallowKeys:        make(map[int64]map[string]bool),
+	}
+
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+
+	go c.watchForReorgs()
+
+	return c, nil
+}
+
+func SetMaxBytes(maxBytes int64) Option {
+	return func(c *ResponseCache) error {
+		if maxBytes <= 0 {
+			return errors.New("cache size must be positive")
+		}
+		c.maxBytes = maxBytes
+		return nil
+	}
+}
+
+func SetReorgDepth(depth int64) Option {
+	return func(c *ResponseCache) error {
+		if depth < 1 {
+			return errors.New("reorg depth must be at least 1")
+		}
+		c.reorgDepth = depth
+		return nil
+	}
+}
+
+func SetReorgCheckInterval(interval time.Duration) Option {
+	return func(c *ResponseCache) error {
+		c.reorgCheckInterval = interval
+		return nil
+	}
+}
+
+// SetDiskPath keeps responses for blocks deeper than the reorg depth on disk as well
+// entries on disk are never pruned, they only ever hold immutable chain data
+func SetDiskPath(path string) Option {
+	return func(c *ResponseCache) error {
+		if path == "" {
+			return nil
+		}
+		disk, err := newDiskTier(path)
+		if err != nil {
+			return err
+		}
+		c.disk = disk
+		return nil
+	}
+}
+
+func SetLogger(l log.Logger) Option {
+	return func(c *ResponseCache) error {
+		c.logger = log.WithPrefix(l, "component", "cache")
+		return nil
+	}
+}
+
+// Get returns the cached response for key
+func (c *ResponseCache) Get(key string) ([]byte, bool) {
+	c.mutex.Lock()
+	if element, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(element)
+		value := element.Value.(*entry).value
+		c.mutex.Unlock()
+		return value, true
+	}
+	c.mutex.Unlock()
+
+	if c.disk == nil {
+		return nil, false
+	}
+
+	value, err := c.disk.get(key)
+	if err != nil {
+		c.logger.Log("msg", "Failed to read cached response from disk", "err", err)
+		return nil, false
+	}
+	if value == nil {
+		return nil, false
+	}
+
+	// only deep blocks are written to disk so this never needs to be checked for reorgs
+	c.mutex.Lock()
+	c.storeLocked(&entry{key: key, value: value, height: -1})
+	c.mutex.Unlock()
+
+	return value, true
+}
+
+// Put caches a response that belongs to the block at height with the given hash
+func (c *ResponseCache) Put(key string, value []byte, height int64, hash string) {
+	hash = normalizeHash(hash)
+
+	c.mutex.Lock()
+	if c.tip != 0 && c.tip-height >= c.reorgDepth {
+		c.storeLocked(&entry{key: key, value: value, height: -1})
+		c.mutex.Unlock()
+		c.writeToDisk(key, value)
+		return
+	}
+	defer c.mutex.Unlock()
+
+	if known, ok := c.shallow[height]; ok && known != hash {
+		// we've seen a different block at this height, one of them has been reorged out
+		c.logger.Log("msg", "Block hash changed, dropping cached responses", "height", height, "was", known, "now", hash)
+		c.invalidateFromLocked(height)
+	}
+
+	c.shallow[height] = hash
+	if c.shallowKeys[height] == nil {
+		c.shallowKeys[height] = make(map[string]bool)
+	}
+	c.shallowKeys[height][key] = true
+	c.storeLocked(&entry{key: key, value: value, height: height})
+}
+
+func (c *ResponseCache) storeLocked(e *entry) {
+	if int64(len(e.value)) > c.maxBytes {
+		return
+	}
+
+	if element, ok := c.entries[e.key]; ok {
+		c.removeLocked(element)
+	}
+
+	c.entries[e.key] = c.lru.PushFront(e)
+	c.size = c.size + int64(len(e.value))
+
+	for c.size > c.maxBytes {
+		c.removeLocked(c.lru.Back())
+	}
+}
+
+func (c *ResponseCache) removeLocked(element *list.Element) {
+	e := element.Value.(*entry)
+	c.lru.Remove(element)
+	delete(c.entries, e.key)
+	c.size = c.size - int64(len(e.value))
+
+	if keys, ok := c.shallowKeys[e.height]; ok {
+		delete(keys, e.key)
+	}
+}
+
+// invalidateFromLocked drops every response for shallow blocks at or above height
+func (c *ResponseCache) invalidateFromLocked(height int64) {
+	metrics.CacheInvalidations.Inc()
+
+	for shallowHeight, keys := range c.shallowKeys {
+		if shallowHeight < height {
+			continue
+		}
+		for key := range keys {
+			if element, ok := c.entries[key]; ok {
+				c.removeLocked(element)
+			}
+		}
+		delete(c.shallowKeys, shallowHeight)
+		delete(c.shallow, shallowHeight)
+	}
+}
+
+func (c *ResponseCache) writeToDisk(key string, value []byte) {
+	if c.disk == nil {
+		return
+	}
+	if err := c.disk.put(key, value); err != nil {
+		c.logger.Log("msg", "Failed to write cached response to disk", "err", err)
+	}
+}
+
+func (c *ResponseCache) watchForReorgs() {
+	ticker := time.NewTicker(c.reorgCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.checkForReorgs(); err != nil {
+				c.logger.Log("msg", "Failed to check cached responses for reorgs", "err", err)
+			}
+		}
+	}
+}
+
+// checkForReorgs compares the shallow blocks we have responses for with the chain
+// blocks that are now deep enough are kept for good, everything from the first mismatch up is dropped
+func (c *ResponseCache) checkForReorgs() error {
+	c.mutex.Lock()
+	heights := make([]int64, 0, len(c.shallow))
+	hashes := make(map[int64]string, len(c.shallow))
+	for height, hash := range c.shallow {
+		heights = append(heights, height)
+		hashes[height] = hash
+	}
+	c.mutex.Unlock()
+
+	if len(heights) == 0 {
+		return nil
+	}
+	sort.Slice(heights, func(i, j int) bool { return heights[i] < heights[j] })
+
+	blockCount, err := c.chain.GetBlockCount(c.ctx)
+	if err != nil {
+		return errors.Wrap(err, "Failed to getblockcount")
+	}
+	tip := blockCount.Int64()
+
+	reorgedFrom := int64(-1)
+	confirmed := []int64{}
+	for _, height := range heights {
+		if height > tip {
+			reorgedFrom = height
+			break
+		}
+		hash, err := c.chain.GetBlockHash(c.ctx, big.NewInt(height))
+		if err != nil {
+			return errors.Wrapf(err, "Failed to getblockhash %d", height)
+		}
+		if normalizeHash(string(hash)) != hashes[height] {
+			reorgedFrom = height
+			break
+		}
+		if tip-height >= c.reorgDepth {
+			confirmed = append(confirmed, height)
+		}
+	}
+
+	toWrite := []*entry{}
+	defer func() {
+		for _, e := range toWrite {
+			c.writeToDisk(e.key, e.value)
+		}
+	}()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.tip = tip
+
+	for _, height := range confirmed {
+		if c.shallow[height] != hashes[height] {
+			// replaced by Put while we were checking
+			continue
+		}
+		for key := range c.shallowKeys[height] {
+			element, ok := c.entries[key]
+			if !ok {
+				continue
+			}
+			e := element.Value.(*entry)
+			e.height = -1
+			toWrite = append(toWrite, e)
+		}
+		delete(c.shallowKeys, height)
+		delete(c.shallow, height)
+	}
+
+	if reorgedFrom != -1 {
+		c.logger.Log("msg", "Reorg detected, dropping cached responses", "height", reorgedFrom)
+		c.invalidateFromLocked(reorgedFrom)
+	}
+
+	return nil
+}
+
+func normalizeHash(hash string) string {
+	return strings.ToLower(strings.TrimPrefix(hash, "0x"))
+}
@@ -0,0 +1,144 @@
+package cache
+
+import (
+	"context"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qtumproject/janus/pkg/qtum"
+)
+
+type testChain struct {
+	mutex  sync.Mutex
+	tip    int64
+	hashes map[int64]string
+}
+
+func (c *testChain) GetBlockCount(ctx context.Context) (*qtum.GetBlockCountResponse, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return &qtum.GetBlockCountResponse{Int: big.NewInt(c.tip)}, nil
+}
+
+func (c *testChain) GetBlockHash(ctx context.Context, height *big.Int) (qtum.GetBlockHashResponse, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return qtum.GetBlockHashResponse(c.hashes[height.Int64()]), nil
+}
+
+func (c *testChain) set(tip int64, hashes map[int64]string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.tip = tip
+	c.hashes = hashes
+}
+
+func newTestCache(t *testing.T, chain Chain, opts ...Option) *ResponseCache {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	// reorg checks are run by the tests themselves
+	opts = append(opts, SetReorgCheckInterval(time.Hour))
+	c, err := New(ctx, chain, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newTestCache(t, &testChain{}, SetMaxBytes(10))
+
+	c.Put("a", []byte("aaaa"), 1, "0x01")
+	c.Put("b", []byte("bbbb"), 2, "0x02")
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("expected a to be cached")
+	}
+	c.Put("c", []byte("cccc"), 3, "0x03")
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := c.Get(key); !ok {
+			t.Errorf("expected %s to be cached", key)
+		}
+	}
+}
+
+func TestCacheDropsReorgedBlocks(t *testing.T) {
+	chain := &testChain{}
+	chain.set(3, map[int64]string{1: "01", 2: "02", 3: "03"})
+	c := newTestCache(t, chain)
+
+	c.Put("block1", []byte("1"), 1, "0x01")
+	c.Put("block2", []byte("2"), 2, "0x02")
+	c.Put("block3", []byte("3"), 3, "0x03")
+
+	chain.set(3, map[int64]string{1: "01", 2: "aa", 3: "bb"})
+	if err := c.checkForReorgs(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := c.Get("block1"); !ok {
+		t.Error("expected block1 to still be cached")
+	}
+	for _, key := range []string{"block2", "block3"} {
+		if _, ok := c.Get(key); ok {
+			t.Errorf("expected %s to be dropped", key)
+		}
+	}
+}
+
+func TestCacheDropsBlockReplacedAtSameHeight(t *testing.T) {
+	c := newTestCache(t, &testChain{})
+
+	c.Put("block", []byte("old"), 5, "0x05")
+	c.Put("transaction", []byte("old"), 5, "0x05")
+	c.Put("block", []byte("new"), 5, "0xaa")
+
+	if _, ok := c.Get("transaction"); ok {
+		t.Error("expected responses for the old block to be dropped")
+	}
+	value, ok := c.Get("block")
+	if !ok || string(value) != "new" {
+		t.Errorf("expected the new block to be cached, got %q", value)
+	}
+}
+
+func TestCacheWritesConfirmedBlocksToDisk(t *testing.T) {
+	dir, err := ioutil.TempDir("", "janus-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	chain := &testChain{}
+	chain.set(20, map[int64]string{5: "05"})
+	c := newTestCache(t, chain, SetReorgDepth(10), SetDiskPath(dir))
+
+	c.Put("block", []byte("5"), 5, "0x05")
+	if err := c.checkForReorgs(); err != nil {
+		t.Fatal(err)
+	}
+
+	// a fresh cache only has the disk tier to go on
+	restarted := newTestCache(t, chain, SetDiskPath(dir))
+	value, ok := restarted.Get("block")
+	if !ok || string(value) != "5" {
+		t.Fatalf("expected block to be read from disk, got %q", value)
+	}
+
+	// deep blocks are never checked again
+	chain.set(20, map[int64]string{5: "aa"})
+	if err := c.checkForReorgs(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := c.Get("block"); !ok {
+		t.Error("expected confirmed block to stay cached")
+	}
+}
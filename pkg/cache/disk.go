@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// diskTier keeps one file per response, named after the hash of its key
+type diskTier struct {
+	path string
+}
+
+func newDiskTier(path string) (*diskTier, error) {
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, errors.Wrap(err, "Failed to create cache directory")
+	}
+	return &diskTier{path: path}, nil
+}
+
+func (d *diskTier) filename(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+	// spread files over subdirectories so no single directory gets too big
+	return filepath.Join(d.path, name[:2], name)
+}
+
+// get returns nil if there is nothing cached for key
+func (d *diskTier) get(key string) ([]byte, error) {
+	value, err := ioutil.ReadFile(d.filename(key))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return value, err
+}
+
+func (d *diskTier) put(key string, value []byte) error {
+	filename := d.filename(key)
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+
+	// write somewhere else first so a reader never sees a partially written file
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), ".tmp-")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(value); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filename)
+}
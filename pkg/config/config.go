@@ -0,0 +1,120 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultWatchInterval is how often Watch checks the config file for changes
+var DefaultWatchInterval = 2 * time.Second
+
+// Load reads a YAML config file, its keys are the names of the command line flags (e.g. qtum-rpc, cache-size)
+// values come back the way the flags take them, lists are joined with commas and maps become comma separated key=value pairs
+func Load(path string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read config file")
+	}
+	return Parse(data)
+}
+
+// Parse is Load for a config file that has already been read
+func Parse(data []byte) (map[string]string, error) {
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, errors.Wrap(err, "Failed to parse config file")
+	}
+
+	values := make(map[string]string, len(raw))
+	for key, value := range raw {
+		flagValue, err := toFlagValue(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid value for %s", key)
+		}
+		values[key] = flagValue
+	}
+	return values, nil
+}
+
+func toFlagValue(value interface{}) (string, error) {
+	switch value := value.(type) {
+	case nil:
+		return "", nil
+	case []interface{}:
+		items := make([]string, 0, len(value))
+		for _, item := range value {
+			if !isScalar(item) {
+				return "", errors.New("lists can only have plain values")
+			}
+			items = append(items, fmt.Sprint(item))
+		}
+		return strings.Join(items, ","), nil
+	case map[string]interface{}:
+		pairs := make([]string, 0, len(value))
+		for key, item := range value {
+			if !isScalar(item) {
+				return "", errors.Errorf("%s can only have a plain value", key)
+			}
+			pairs = append(pairs, key+"="+fmt.Sprint(item))
+		}
+		// map order is random, sort so the same file always gives the same value
+		sort.Strings(pairs)
+		return strings.Join(pairs, ","), nil
+	default:
+		if !isScalar(value) {
+			return "", errors.Errorf("unsupported value %v", value)
+		}
+		return fmt.Sprint(value), nil
+	}
+}
+
+func isScalar(value interface{}) bool {
+	switch value.(type) {
+	case string, bool, int, int64, uint64, float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// Watch calls changed whenever the config file at path is modified, until ctx is done
+// the file is polled every interval, so it also notices a Kubernetes ConfigMap being swapped out underneath it
+func Watch(ctx context.Context, path string, interval time.Duration, changed func()) {
+	last, _ := fileVersion(path)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			version, err := fileVersion(path)
+			if err != nil {
+				// being replaced, check again next time
+				continue
+			}
+			if version != last {
+				last = version
+				changed()
+			}
+		}
+	}
+}
+
+// fileVersion changes whenever the file is written to
+func fileVersion(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size()), nil
+}
@@ -3,6 +3,7 @@ package eth
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 )
 
 // unknown service
@@ -17,6 +18,15 @@ var InvalidParamsErrorCode = -32602
 // logic error
 var CallbackErrorCode = -32000
 
+// request took longer than its deadline
+var TimeoutErrorCode = -32002
+
+// qtumd wasn't started with an option the method needs, EIP-1474's "method not supported"
+var MethodNotSupportedErrorCode = -32004
+
+// too many requests, EIP-1474's "limit exceeded"
+var LimitExceededErrorCode = -32005
+
 // shutdown error
 // "server is shutting down"
 var ShutdownErrorCode = -32000
@@ -46,6 +56,34 @@ func NewCallbackError(message string) JSONRPCError {
 	return NewJSONRPCError(CallbackErrorCode, message, nil)
 }
 
+func NewTimeoutError(method string, timeout time.Duration) JSONRPCError {
+	return NewJSONRPCError(
+		TimeoutErrorCode,
+		fmt.Sprintf("%s timed out after %s", method, timeout),
+		nil,
+	)
+}
+
+// NewNotSupportedError tells the client what it asked for needs qtumd to be started with option
+func NewNotSupportedError(what string, option string) JSONRPCError {
+	return NewJSONRPCError(
+		MethodNotSupportedErrorCode,
+		fmt.Sprintf("%s is not supported by the backend, qtumd needs to be started with %s", what, option),
+		nil,
+	)
+}
+
+// NewLimitExceededError tells the client it is being rate limited and when it can try again
+func NewLimitExceededError(retryAfter time.Duration) JSONRPCError {
+	return &GenericJSONRPCError{
+		code:    LimitExceededErrorCode,
+		message: fmt.Sprintf("rate limit exceeded, retry in %s", retryAfter.Round(time.Millisecond)),
+		data: map[string]interface{}{
+			"retryAfter": retryAfter.Seconds(),
+		},
+	}
+}
+
 type JSONRPCError interface {
 	Code() int
 	Message() string
@@ -65,6 +103,8 @@ type GenericJSONRPCError struct {
 	code    int
 	message string
 	err     error
+	// extra information about the error sent to the client, optional
+	data interface{}
 }
 
 func (err *GenericJSONRPCError) Code() int {
@@ -81,10 +121,12 @@ func (err *GenericJSONRPCError) Error() error {
 
 func (err *GenericJSONRPCError) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
-		Code    int    `json:"code"`
-		Message string `json:"message"`
+		Code    int         `json:"code"`
+		Message string      `json:"message"`
+		Data    interface{} `json:"data,omitempty"`
 	}{
 		Code:    err.code,
 		Message: err.message,
+		Data:    err.data,
 	})
 }
@@ -2,8 +2,13 @@ package eth
 
 import (
 	"math/big"
+	"sort"
 	"sync"
 	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/qtumproject/janus/pkg/metrics"
 )
 
 type FilterType int
@@ -14,12 +19,28 @@ const (
 	NewPendingTransactionFilterTy
 )
 
+func (ty FilterType) String() string {
+	switch ty {
+	case NewFilterTy:
+		return "log"
+	case NewBlockFilterTy:
+		return "block"
+	case NewPendingTransactionFilterTy:
+		return "pendingTransaction"
+	default:
+		return "unknown"
+	}
+}
+
 type Filter struct {
 	ID           uint64
 	Type         FilterType
 	Request      interface{}
 	LastBlockNum *big.Int
 	Data         sync.Map
+	CreatedAt    time.Time
+	// set once uninstalled so the metric is only decremented once
+	uninstalled int32
 }
 
 type FilterSimulator struct {
@@ -36,20 +57,69 @@ func NewFilterSimulator() *FilterSimulator {
 
 func (f *FilterSimulator) New(ty FilterType, req ...interface{}) *Filter {
 	id := atomic.AddUint64(f.maxFilterID, 1)
-	filter := &Filter{ID: id, Type: ty}
+	filter := &Filter{ID: id, Type: ty, CreatedAt: time.Now()}
 	if ty == NewFilterTy {
 		filter.Request = req[0]
 	}
 
 	f.filters.Store(id, filter)
+	metrics.Filters.WithLabelValues(ty.String()).Inc()
 
 	return filter
 }
 
-func (f *FilterSimulator) Uninstall(filterID uint64) {
+// Uninstall removes a filter, returns false if there wasn't one with filterID
+func (f *FilterSimulator) Uninstall(filterID uint64) bool {
+	value, ok := f.filters.Load(filterID)
 	f.filters.Delete(filterID)
+	if !ok {
+		return false
+	}
+
+	filter := value.(*Filter)
+	if atomic.CompareAndSwapInt32(&filter.uninstalled, 0, 1) {
+		metrics.Filters.WithLabelValues(filter.Type.String()).Dec()
+	}
+	return true
 }
 
 func (f *FilterSimulator) Filter(filterID uint64) (value interface{}, ok bool) {
 	return f.filters.Load(filterID)
 }
+
+// FilterStatus is what admin_listFilters shows about a filter
+type FilterStatus struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"createdAt"`
+	// the block eth_getFilterChanges continues from
+	LastBlockNumber string `json:"lastBlockNumber,omitempty"`
+}
+
+// List returns the installed filters, oldest first
+func (f *FilterSimulator) List() []FilterStatus {
+	filters := []*Filter{}
+	f.filters.Range(func(_, value interface{}) bool {
+		filters = append(filters, value.(*Filter))
+		return true
+	})
+	sort.Slice(filters, func(i, j int) bool {
+		return filters[i].ID < filters[j].ID
+	})
+
+	statuses := make([]FilterStatus, 0, len(filters))
+	for _, filter := range filters {
+		status := FilterStatus{
+			ID:        hexutil.EncodeUint64(filter.ID),
+			Type:      filter.Type.String(),
+			CreatedAt: filter.CreatedAt,
+		}
+		if lastBlockNumber, ok := filter.Data.Load("lastBlockNumber"); ok {
+			if lastBlockNumber, ok := lastBlockNumber.(uint64); ok {
+				status.LastBlockNumber = hexutil.EncodeUint64(lastBlockNumber)
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
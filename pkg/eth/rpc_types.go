@@ -1105,6 +1105,120 @@ func (utxo QtumUTXO) GetType() UTXOScriptType {
 	// TODO: OP_RETURN
 }
 
+// ======= qtum_createSession ============= //
+
+type QtumCreateSessionResponse string
+
+// ======= qtum_resumeSession ============= //
+
+type QtumResumeSessionRequest []string
+
+type QtumResumeSessionResponse struct {
+	Session string `json:"session"`
+	// Dropped is how many notifications were discarded because too many were sent while disconnected
+	Dropped int `json:"dropped"`
+}
+
+// ======= admin_addWebhook ============= //
+
+type AdminAddWebhookRequest struct {
+	URL    string `json:"url"`
+	Secret string `json:"secret"`
+	// "logs" and/or "newHeads"
+	Events  []string      `json:"events"`
+	Address interface{}   `json:"address"`
+	Topics  []interface{} `json:"topics"`
+}
+
+func (r *AdminAddWebhookRequest) UnmarshalJSON(data []byte) error {
+	var params []json.RawMessage
+	if err := json.Unmarshal(data, &params); err != nil {
+		return errors.Wrap(err, "json unmarshalling")
+	}
+
+	if len(params) != 1 {
+		return errors.New("requires one argument: the webhook")
+	}
+	type Req AdminAddWebhookRequest
+	var req Req
+
+	if err := json.Unmarshal(params[0], &req); err != nil {
+		return errors.Wrap(err, "json unmarshalling")
+	}
+
+	*r = AdminAddWebhookRequest(req)
+
+	return nil
+}
+
+func (r *AdminAddWebhookRequest) Filter() *EthLogSubscriptionParameter {
+	return &EthLogSubscriptionParameter{
+		Address: r.Address,
+		Topics:  r.Topics,
+	}
+}
+
+type AdminAddWebhookResponse struct {
+	ID string `json:"id"`
+	// deliveries are signed with this, it isn't returned again
+	Secret string `json:"secret"`
+}
+
+// ======= admin_removeWebhook ============= //
+
+type (
+	AdminRemoveWebhookRequest []string
+
+	AdminRemoveWebhookResponse bool
+)
+
+// ======= admin_getWebhookStatus ============= //
+
+type AdminGetWebhookStatusRequest []string
+
+// ======= admin_disconnect ============= //
+
+type (
+	AdminDisconnectRequest []string
+
+	AdminDisconnectResponse bool
+)
+
+// ======= admin_deleteFilter ============= //
+
+type (
+	AdminDeleteFilterRequest []string
+
+	AdminDeleteFilterResponse bool
+)
+
+// ======= admin_setFlag ============= //
+
+type AdminSetFlagRequest struct {
+	Name string
+	// null unsets the flag
+	Value json.RawMessage
+}
+
+func (r *AdminSetFlagRequest) UnmarshalJSON(data []byte) error {
+	var params []json.RawMessage
+	if err := json.Unmarshal(data, &params); err != nil {
+		return errors.Wrap(err, "json unmarshalling")
+	}
+	if len(params) != 2 {
+		return errors.New("requires two arguments: the flag name and its value")
+	}
+	if err := json.Unmarshal(params[0], &r.Name); err != nil {
+		return errors.Wrap(err, "flag name")
+	}
+	r.Value = params[1]
+	return nil
+}
+
+// ======= admin_setDebug ============= //
+
+type AdminSetDebugRequest []bool
+
 type (
 	StringsArguments []string
 	StringResponse   string
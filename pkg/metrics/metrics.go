@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Prometheus metrics, served at /metrics
+// they are registered with the default prometheus registry so the go runtime and process metrics are included too
+
+const namespace = "janus"
+
+// UnknownMethod is the method label for requests to methods Janus doesn't support
+// using the requested name would let clients create unlimited label values
+const UnknownMethod = "unknown"
+
+var (
+	RPCRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rpc_requests_total",
+		Help:      "ETH JSON-RPC requests handled, by method",
+	}, []string{"method"})
+
+	RPCErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rpc_errors_total",
+		Help:      "ETH JSON-RPC requests that returned an error, by method and JSON-RPC error code",
+	}, []string{"method", "code"})
+
+	RPCDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "rpc_request_duration_seconds",
+		Help:      "How long ETH JSON-RPC requests took to handle, by method",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	QtumdRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "qtumd_requests_total",
+		Help:      "Requests made to qtumd, by method and result (success, error or work_queue_depth)",
+	}, []string{"method", "result"})
+
+	QtumdDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "qtumd_request_duration_seconds",
+		Help:      "How long requests to qtumd took, by method",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	QtumdWorkQueueRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "qtumd_work_queue_retries_total",
+		Help:      "Requests retried because qtumd's work queue depth was exceeded, by method",
+	}, []string{"method"})
+
+	QtumdCoalescedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "qtumd_coalesced_requests_total",
+		Help:      "Requests that shared a response with an identical request instead of going to qtumd, by method",
+	}, []string{"method"})
+
+	QtumdBatchSize = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "qtumd_batch_size",
+		Help:      "How many requests were sent to qtumd in each JSON-RPC batch",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 8),
+	})
+
+	QtumdUpstreamHealthy = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "qtumd_upstream_healthy",
+		Help:      "1 if a qtumd node is getting reads, 0 if it is down, stalled or failing, by node",
+	}, []string{"upstream"})
+
+	QtumdUpstreamHeight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "qtumd_upstream_block_height",
+		Help:      "Block height of each qtumd node, by node",
+	}, []string{"upstream"})
+
+	WebsocketConnections = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "websocket_connections",
+		Help:      "Open websocket connections",
+	})
+
+	SSEStreams = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "sse_streams",
+		Help:      "Open server-sent event streams",
+	})
+
+	Subscriptions = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "subscriptions",
+		Help:      "Live eth_subscribe subscriptions, by subscription type",
+	}, []string{"type"})
+
+	CacheHits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_hits_total",
+		Help:      "Responses served from the response cache, by method",
+	}, []string{"method"})
+
+	CacheMisses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_misses_total",
+		Help:      "Cacheable requests that weren't in the response cache, by method",
+	}, []string{"method"})
+
+	CacheInvalidations = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_invalidations_total",
+		Help:      "Times cached responses were dropped because of a reorg",
+	})
+
+	Filters = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "filters",
+		Help:      "Live eth_newFilter, eth_newBlockFilter and eth_newPendingTransactionFilter filters, by type",
+	}, []string{"type"})
+
+	RateLimitedRequests = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_requests_total",
+		Help:      "Requests turned away because the client was over its rate limit",
+	})
+)
+
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math/big"
 	"strings"
 	"sync"
 	"time"
@@ -11,20 +12,41 @@ import (
 	"github.com/labstack/echo"
 	"github.com/pkg/errors"
 	"github.com/qtumproject/janus/pkg/eth"
+	"github.com/qtumproject/janus/pkg/metrics"
 	"github.com/qtumproject/janus/pkg/qtum"
 	"github.com/qtumproject/janus/pkg/utils"
 )
 
 var agentConfigNewHeadsKey = "newHeadsInterval"
-var agentConfigNewHeadsInterval = 10 * time.Second
+
+// DefaultNewHeadsInterval is how often qtumd is polled for new blocks when there are newHeads subscriptions
+var DefaultNewHeadsInterval = 10 * time.Second
+
+// maxNewHeadsPerPoll caps how many blocks are fetched in one poll when catching up, the rest are sent on the following polls
+const maxNewHeadsPerPoll = 100
 
 // Allows dependency injection of eth rpc calls as the transformer package imports this package
 type Transformer interface {
 	Transform(req *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError)
 }
 
-func NewAgent(ctx context.Context, qtum *qtum.Qtum, transformer Transformer) *Agent {
-	return newAgentWithConfiguration(ctx, qtum, transformer, make(map[string]interface{}))
+type AgentOption func(map[string]interface{})
+
+// SetNewHeadsInterval configures how often qtumd is polled for new blocks
+func SetNewHeadsInterval(interval time.Duration) AgentOption {
+	return func(config map[string]interface{}) {
+		if interval > 0 {
+			config[agentConfigNewHeadsKey] = interval
+		}
+	}
+}
+
+func NewAgent(ctx context.Context, qtum *qtum.Qtum, transformer Transformer, opts ...AgentOption) *Agent {
+	configuration := make(map[string]interface{})
+	for _, opt := range opts {
+		opt(configuration)
+	}
+	return newAgentWithConfiguration(ctx, qtum, transformer, configuration)
 }
 
 func newAgentWithConfiguration(ctx context.Context, qtum *qtum.Qtum, transformer Transformer, configuration map[string]interface{}) *Agent {
@@ -34,18 +56,24 @@ func newAgentWithConfiguration(ctx context.Context, qtum *qtum.Qtum, transformer
 	if qtum == nil {
 		panic("qtum cannot be nil")
 	}
+	ctx, cancel := context.WithCancel(ctx)
 	agent := &Agent{
 		qtum:          qtum,
 		transformer:   transformer,
 		ctx:           ctx,
+		cancel:        cancel,
 		mutex:         sync.RWMutex{},
 		running:       false,
 		config:        configuration,
 		stop:          make(chan interface{}, 1000),
-		newHeads:      newSubscriptionRegistry(),
-		logs:          newSubscriptionRegistry(),
-		newPendingTxs: newSubscriptionRegistry(),
-		syncing:       newSubscriptionRegistry(),
+		newBlock:      make(chan interface{}, 1),
+		newHeads:      newSubscriptionRegistry("newHeads"),
+		logs:          newSubscriptionRegistry("logs"),
+		newPendingTxs: newSubscriptionRegistry("newPendingTransactions"),
+		syncing:       newSubscriptionRegistry("syncing"),
+		sessions:      newSessionRegistry(),
+		webhooks:      newWebhookRegistry(),
+		clients:       newClientRegistry(),
 	}
 
 	go agent.run()
@@ -53,13 +81,16 @@ func newAgentWithConfiguration(ctx context.Context, qtum *qtum.Qtum, transformer
 }
 
 type subscriptionRegistry struct {
+	// subscription type, used as the metrics label
+	name              string
 	mutex             sync.RWMutex
 	subscriptionCount int
 	subscriptions     map[string]*subscriptionInformation
 }
 
-func newSubscriptionRegistry() *subscriptionRegistry {
+func newSubscriptionRegistry(name string) *subscriptionRegistry {
 	return &subscriptionRegistry{
+		name:              name,
 		mutex:             sync.RWMutex{},
 		subscriptionCount: 0,
 		subscriptions:     make(map[string]*subscriptionInformation),
@@ -86,27 +117,23 @@ func (s *subscriptionRegistry) Count() int {
 	return s.subscriptionCount
 }
 
-func (s *subscriptionRegistry) SendAll(message interface{}) {
+// SendAll sends messages to every subscription in the registry, each subscription receives them in the order given
+// and after anything sent to it by earlier calls
+func (s *subscriptionRegistry) SendAll(messages ...interface{}) {
 	send := func(s *subscriptionInformation) {
-		// send writes to a queue that can block when full if a client has a lot of responses queued up
-		// that could potentially affect other clients so we run this in a goroutine
-		// params := struct {
-		// 	Subscription string      `json:"subscription"`
-		// 	Result       interface{} `json:"result"`
-		// }{
-		// 	Subscription: s.Subscription.id,
-		// 	Result:       message,
-		// }
-		params := eth.EthSubscriptionParams{
-			SubscriptionID: s.Subscription.id,
-			Result:         message,
-		}
-		subscription := &eth.EthSubscription{
-			Version: "2.0",
-			Method:  "eth_subscription",
-			Params:  params,
+		subscriptions := make([]interface{}, 0, len(messages))
+		for _, message := range messages {
+			params := eth.EthSubscriptionParams{
+				SubscriptionID: s.Subscription.id,
+				Result:         message,
+			}
+			subscriptions = append(subscriptions, &eth.EthSubscription{
+				Version: "2.0",
+				Method:  "eth_subscription",
+				Params:  params,
+			})
 		}
-		go s.Send(subscription)
+		s.enqueue(subscriptions...)
 	}
 	s.forEach(send)
 }
@@ -115,14 +142,21 @@ type Agent struct {
 	qtum          *qtum.Qtum
 	transformer   Transformer
 	ctx           context.Context
+	cancel        context.CancelFunc
 	mutex         sync.RWMutex
 	running       bool
+	logsRunning   bool
+	logsCancel    context.CancelFunc
 	stop          chan interface{}
+	newBlock      chan interface{}
 	config        map[string]interface{}
 	newHeads      *subscriptionRegistry
 	logs          *subscriptionRegistry
 	newPendingTxs *subscriptionRegistry
 	syncing       *subscriptionRegistry
+	sessions      *sessionRegistry
+	webhooks      *webhookRegistry
+	clients       *clientRegistry
 }
 
 func (a *Agent) SetTransformer(transformer Transformer) {
@@ -131,7 +165,11 @@ func (a *Agent) SetTransformer(transformer Transformer) {
 	a.mutex.Unlock()
 }
 
+// Stop cancels every subscription and session and stops the agent's goroutines, including webhook deliveries
 func (a *Agent) Stop() {
+	a.cancel()
+	a.sessions.closeAll()
+
 	a.mutex.Lock()
 	a.lockAllRegistries(false)
 	defer a.unlockAllRegistries(false)
@@ -143,6 +181,16 @@ func (a *Agent) Stop() {
 	closeSubscriptionRegistry(a.syncing)
 }
 
+// NotifyNewBlock tells the agent a new block has arrived so that newHeads subscriptions are processed immediately
+// instead of waiting for the next poll, multiple notifications before the next poll are collapsed into one
+func (a *Agent) NotifyNewBlock() {
+	select {
+	case a.newBlock <- nil:
+	default:
+		// a poll is already pending
+	}
+}
+
 func (a *Agent) setConfigValue(key string, value interface{}) {
 	a.config[key] = value
 }
@@ -205,6 +253,16 @@ func (a *Agent) unsubscribe(id string) {
 	removeSubscription(id, a.logs)
 	removeSubscription(id, a.newPendingTxs)
 	removeSubscription(id, a.syncing)
+
+	if a.logs.Count() == 0 {
+		// stop waiting on qtumd for logs nobody is listening for
+		a.mutex.RLock()
+		logsCancel := a.logsCancel
+		a.mutex.RUnlock()
+		if logsCancel != nil {
+			logsCancel()
+		}
+	}
 }
 
 func addSubscription(subscription *subscriptionInformation, registry *subscriptionRegistry) {
@@ -215,9 +273,8 @@ func addSubscription(subscription *subscriptionInformation, registry *subscripti
 	registry.subscriptions[subscription.id] = subscription
 	if !collision {
 		registry.subscriptionCount = registry.subscriptionCount + 1
+		metrics.Subscriptions.WithLabelValues(registry.name).Inc()
 	}
-
-	go subscription.run()
 }
 
 func removeSubscription(id string, registry *subscriptionRegistry) {
@@ -230,6 +287,7 @@ func removeSubscription(id string, registry *subscriptionRegistry) {
 		if exists {
 			delete(registry.subscriptions, id)
 			registry.subscriptionCount = registry.subscriptionCount - 1
+			metrics.Subscriptions.WithLabelValues(registry.name).Dec()
 		}
 		registry.mutex.Unlock()
 	}
@@ -247,19 +305,19 @@ func (a *Agent) NewSubscription(notifier *Notifier, params *eth.EthSubscriptionR
 
 	wrappedContext, cancel := context.WithCancel(notifier.Context())
 
-	wrappedSubscription := &subscriptionInformation{
-		subscription,
-		params,
-		sync.RWMutex{},
-		wrappedContext,
-		cancel,
-		false,
-		a.qtum,
-	}
+	wrappedSubscription := newSubscriptionInformation(subscription, params, wrappedContext, cancel)
 
 	switch strings.ToLower(params.Method) {
 	case "logs":
+		logsFilter, err := newLogsFilter(params.Params)
+		if err != nil {
+			cancel()
+			notifier.Unsubscribe(subscription.id)
+			return "", errors.Wrap(err, "Invalid logs filter")
+		}
+		wrappedSubscription.logsFilter = logsFilter
 		addSubscription(wrappedSubscription, a.logs)
+		go a.runLogs()
 	case "newheads":
 		addSubscription(wrappedSubscription, a.newHeads)
 	case "newpendingtransactions":
@@ -267,6 +325,8 @@ func (a *Agent) NewSubscription(notifier *Notifier, params *eth.EthSubscriptionR
 	case "syncing":
 		addSubscription(wrappedSubscription, a.syncing)
 	default:
+		cancel()
+		notifier.Unsubscribe(subscription.id)
 		return "", errors.New(fmt.Sprintf("Unknown subscription type %s", params.Method))
 	}
 
@@ -317,6 +377,8 @@ func (a *Agent) run() {
 		select {
 		case <-a.stop:
 			// drain
+		case <-a.newBlock:
+			// drain, we are about to poll anyway
 		case <-a.ctx.Done():
 			return
 		default:
@@ -324,7 +386,7 @@ func (a *Agent) run() {
 		}
 	}
 
-	newHeadsIntervalValue := a.getConfigValue(agentConfigNewHeadsKey, agentConfigNewHeadsInterval)
+	newHeadsIntervalValue := a.getConfigValue(agentConfigNewHeadsKey, DefaultNewHeadsInterval)
 	newHeadsInterval, ok := newHeadsIntervalValue.(time.Duration)
 	if !ok {
 		panic(fmt.Sprintf("Unexpected %s type", agentConfigNewHeadsKey))
@@ -346,7 +408,7 @@ func (a *Agent) run() {
 		if transformer == nil {
 			a.qtum.GetErrorLogger().Log("msg", "Agent does not have access to eth transformer, cannot process 'newHeads' subscriptions")
 		} else {
-			blockchainInfo, err := a.qtum.GetBlockChainInfo()
+			blockchainInfo, err := a.qtum.GetBlockChainInfo(a.ctx)
 			if err != nil {
 				a.qtum.GetErrorLogger().Log("msg", "Failure getting blockchaininfo", "err", err)
 			} else {
@@ -356,32 +418,25 @@ func (a *Agent) run() {
 					lastBlock = latestBlock
 					a.qtum.GetDebugLogger().Log("msg", "Got getblockchaininfo response for same block", "block", lastBlock)
 				} else if latestBlock > lastBlock {
-					a.qtum.GetDebugLogger().Log("msg", "New head detected", "block", latestBlock)
-					// get the latest block as an eth_getBlockByHash request
-					params, err := json.Marshal([]interface{}{
-						utils.AddHexPrefix(blockchainInfo.Bestblockhash),
-						false,
-					})
-					if err != nil {
-						panic(fmt.Sprintf("Failed to serialize eth_getBlockByHash request parameters: %s", err))
+					a.qtum.GetDebugLogger().Log("msg", "New head detected", "block", latestBlock, "previous", lastBlock)
+					// emit every block between the last one we sent and the tip so clients don't miss any heads
+					toBlock := latestBlock
+					if toBlock-lastBlock > maxNewHeadsPerPoll {
+						toBlock = lastBlock + maxNewHeadsPerPoll
+						a.qtum.GetDebugLogger().Log("msg", "Catching up on new heads over several polls", "from", lastBlock+1, "to", toBlock, "tip", latestBlock)
 					}
-					result, jsonErr := transformer.Transform(&eth.JSONRPCRequest{
-						JSONRPC: "2.0",
-						Method:  "eth_getBlockByHash",
-						Params:  params,
-					}, nil)
-					if jsonErr != nil {
-						a.qtum.GetErrorLogger().Log("msg", "Failed to eth_getBlockByHash", "hash", blockchainInfo.Bestblockhash, "err", jsonErr)
-					} else {
-						getBlockByHashResponse, ok := result.(*eth.GetBlockByHashResponse)
-						if !ok {
-							a.qtum.GetErrorLogger().Log("msg", "Failed to eth_getBlockByHash, unexpected response type", "hash", blockchainInfo.Bestblockhash)
-						} else {
-							lastBlock = latestBlock
-							// notify newHead
-							newHeadRespose := eth.NewEthSubscriptionNewHeadResponse(getBlockByHashResponse)
-							a.newHeads.SendAll(newHeadRespose)
+					newHeads := []interface{}{}
+					for height := lastBlock + 1; height <= toBlock; height++ {
+						newHead, err := a.getNewHead(transformer, height)
+						if err != nil {
+							a.qtum.GetErrorLogger().Log("msg", "Failed to get new head, will retry on next poll", "block", height, "err", err)
+							break
 						}
+						newHeads = append(newHeads, newHead)
+						lastBlock = height
+					}
+					if len(newHeads) != 0 {
+						a.newHeads.SendAll(newHeads...)
 					}
 				} else {
 					a.qtum.GetDebugLogger().Log("msg", "Detected same head", "block", latestBlock)
@@ -392,6 +447,8 @@ func (a *Agent) run() {
 		select {
 		case <-time.After(newHeadsInterval):
 			// continue
+		case <-a.newBlock:
+			a.qtum.GetDebugLogger().Log("msg", "Notified of new block, polling immediately")
 		case <-a.ctx.Done():
 			return
 		case <-a.stop:
@@ -399,3 +456,34 @@ func (a *Agent) run() {
 		}
 	}
 }
+
+// getNewHead converts the block at height into a newHeads subscription result
+func (a *Agent) getNewHead(transformer Transformer, height int64) (*eth.EthSubscriptionNewHeadResponse, error) {
+	blockHash, err := a.qtum.GetBlockHash(a.ctx, big.NewInt(height))
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to getblockhash")
+	}
+
+	// get the block as an eth_getBlockByHash request
+	params, err := json.Marshal([]interface{}{
+		utils.AddHexPrefix(string(blockHash)),
+		false,
+	})
+	if err != nil {
+		panic(fmt.Sprintf("Failed to serialize eth_getBlockByHash request parameters: %s", err))
+	}
+	result, jsonErr := transformer.Transform(&eth.JSONRPCRequest{
+		JSONRPC: "2.0",
+		Method:  "eth_getBlockByHash",
+		Params:  params,
+	}, nil)
+	if jsonErr != nil {
+		return nil, errors.Errorf("Failed to eth_getBlockByHash %s: %s", blockHash, jsonErr.Message())
+	}
+	getBlockByHashResponse, ok := result.(*eth.GetBlockByHashResponse)
+	if !ok {
+		return nil, errors.Errorf("Failed to eth_getBlockByHash %s, unexpected response type", blockHash)
+	}
+
+	return eth.NewEthSubscriptionNewHeadResponse(getBlockByHashResponse), nil
+}
@@ -1,15 +1,20 @@
 package notifier
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"net/http"
 	"os"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/go-kit/kit/log"
+	"github.com/labstack/echo"
 	"github.com/qtumproject/janus/pkg/eth"
 	"github.com/qtumproject/janus/pkg/internal"
 	"github.com/qtumproject/janus/pkg/qtum"
@@ -189,6 +194,7 @@ func TestAgentAddSubscriptionNewHeads(t *testing.T) {
 			Bestblockhash: "0x1",
 		})
 	}
+	doer.AddResponse(qtum.MethodGetBlockHash, qtum.GetBlockHashResponse(internal.GetTransactionByHashBlockHash))
 
 	mockedClient, err := internal.CreateMockedClient(doer)
 	if err != nil {
@@ -342,3 +348,354 @@ func TestAgentAddSubscriptionNewHeads(t *testing.T) {
 		t.Fatalf("agent newHeads loop has not exited yet")
 	}
 }
+
+// echoes the requested block hash back so tests can check which blocks were sent
+type blockHashEchoProxy struct{}
+
+func (p *blockHashEchoProxy) Method() string {
+	return "eth_getBlockByHash"
+}
+
+func (p *blockHashEchoProxy) Request(ctx context.Context, req *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
+	var params []interface{}
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return nil, eth.NewInvalidParamsError(err.Error())
+	}
+	response := internal.CreateTransactionByHashResponse()
+	response.Hash = params[0].(string)
+	return &response, nil
+}
+
+func TestAgentNewHeadsSendsEveryBlockInOrder(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	doer := internal.NewDoerMappedMock()
+	doer.AddResponse(qtum.MethodGetBlockChainInfo, qtum.GetBlockChainInfoResponse{Blocks: 1})
+	// three blocks arrive between polls
+	doer.AddResponse(qtum.MethodGetBlockChainInfo, qtum.GetBlockChainInfoResponse{Blocks: 4})
+	doer.AddResponse(qtum.MethodGetBlockHash, qtum.GetBlockHashResponse("02"))
+	doer.AddResponse(qtum.MethodGetBlockHash, qtum.GetBlockHashResponse("03"))
+	doer.AddResponse(qtum.MethodGetBlockHash, qtum.GetBlockHashResponse("04"))
+
+	mockedClient, err := internal.CreateMockedClient(doer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	agent := NewAgent(ctx, mockedClient, internal.NewMockTransformer([]internal.ETHProxy{
+		&blockHashEchoProxy{},
+	}), SetNewHeadsInterval(100*time.Millisecond))
+
+	notifierContext, cancelNotifierContext := context.WithCancel(ctx)
+
+	sentValuesChannel := make(chan []byte, 10)
+	send := func(v []byte) error {
+		sentValuesChannel <- v
+		return nil
+	}
+
+	notifier := NewNotifier(notifierContext, cancelNotifierContext, send, log.NewLogfmtLogger(os.Stdout))
+
+	_, err = agent.NewSubscription(notifier, &eth.EthSubscriptionRequest{
+		Method: "newHeads",
+		Params: &eth.EthLogSubscriptionParameter{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	notifier.ResponseSent()
+
+	for _, want := range []string{"0x02", "0x03", "0x04"} {
+		select {
+		case gotBytes := <-sentValuesChannel:
+			var received eth.EthSubscription
+			if err := json.Unmarshal(gotBytes, &received); err != nil {
+				t.Fatalf("Failed to unmarshal: %s: %s", string(gotBytes), err)
+			}
+			head, ok := received.Params.Result.(map[string]interface{})
+			if !ok {
+				t.Fatalf("Unexpected newHeads result: %s", string(gotBytes))
+			}
+			if head["hash"] != want {
+				t.Fatalf("newHeads out of order\nwant: %s\ngot: %v", want, head["hash"])
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for newHead %s", want)
+		}
+	}
+}
+
+func TestSubscriptionRegistrySendAllKeepsOrderAcrossCalls(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sentValuesChannel := make(chan []byte, 200)
+	send := func(v []byte) error {
+		sentValuesChannel <- v
+		return nil
+	}
+	notifier := NewNotifier(ctx, cancel, send, log.NewLogfmtLogger(os.Stdout))
+	subscription, err := notifier.Subscribe(func(string) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscriptionContext, cancelSubscription := context.WithCancel(notifier.Context())
+	defer cancelSubscription()
+	registry := newSubscriptionRegistry("newHeads")
+	addSubscription(newSubscriptionInformation(subscription, &eth.EthSubscriptionRequest{}, subscriptionContext, cancelSubscription), registry)
+
+	// the notifier holds everything back until the subscribe response is sent, so its queue fills up
+	count := 150
+	for i := 0; i < count; i++ {
+		registry.SendAll(i)
+	}
+	notifier.ResponseSent()
+
+	for want := 0; want < count; want++ {
+		select {
+		case gotBytes := <-sentValuesChannel:
+			var received eth.EthSubscription
+			if err := json.Unmarshal(gotBytes, &received); err != nil {
+				t.Fatalf("Failed to unmarshal: %s: %s", string(gotBytes), err)
+			}
+			if received.Params.Result != float64(want) {
+				t.Fatalf("Notifications out of order\nwant: %d\ngot: %v", want, received.Params.Result)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for notification %d", want)
+		}
+	}
+}
+
+func TestAgentNewHeadsCatchUpIsCapped(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	doer := &countingDoer{
+		Doer:   internal.NewDoerMappedMock(),
+		counts: make(map[string]int),
+	}
+	doer.AddResponse(qtum.MethodGetBlockChainInfo, qtum.GetBlockChainInfoResponse{Blocks: 1})
+	doer.AddResponse(qtum.MethodGetBlockChainInfo, qtum.GetBlockChainInfoResponse{Blocks: 1 + 3*maxNewHeadsPerPoll})
+	doer.AddResponse(qtum.MethodGetBlockHash, qtum.GetBlockHashResponse("02"))
+
+	mockedClient, err := internal.CreateMockedClient(doer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	agent := NewAgent(ctx, mockedClient, internal.NewMockTransformer([]internal.ETHProxy{
+		&blockHashEchoProxy{},
+	}), SetNewHeadsInterval(time.Minute))
+
+	notifierContext, cancelNotifierContext := context.WithCancel(ctx)
+	sentValuesChannel := make(chan []byte, 10*maxNewHeadsPerPoll)
+	send := func(v []byte) error {
+		sentValuesChannel <- v
+		return nil
+	}
+	notifier := NewNotifier(notifierContext, cancelNotifierContext, send, log.NewLogfmtLogger(os.Stdout))
+
+	_, err = agent.NewSubscription(notifier, &eth.EthSubscriptionRequest{
+		Method: "newHeads",
+		Params: &eth.EthLogSubscriptionParameter{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	notifier.ResponseSent()
+
+	time.Sleep(100 * time.Millisecond)
+	agent.NotifyNewBlock()
+
+	for i := 0; i < maxNewHeadsPerPoll; i++ {
+		select {
+		case <-sentValuesChannel:
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for newHead %d", i)
+		}
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if count := doer.count(qtum.MethodGetBlockHash); count != maxNewHeadsPerPoll {
+		t.Fatalf("Expected one poll to fetch %d blocks, not %d", maxNewHeadsPerPoll, count)
+	}
+	if len(sentValuesChannel) != 0 {
+		t.Fatalf("Expected %d newHeads from one poll, got %d more", maxNewHeadsPerPoll, len(sentValuesChannel))
+	}
+}
+
+func TestAgentNotifyNewBlockPollsImmediately(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	doer := internal.NewDoerMappedMock()
+	doer.AddResponse(qtum.MethodGetBlockChainInfo, qtum.GetBlockChainInfoResponse{Blocks: 1})
+	doer.AddResponse(qtum.MethodGetBlockChainInfo, qtum.GetBlockChainInfoResponse{Blocks: 2})
+	doer.AddResponse(qtum.MethodGetBlockHash, qtum.GetBlockHashResponse("02"))
+
+	mockedClient, err := internal.CreateMockedClient(doer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// an interval long enough that only a notification can trigger the second poll
+	agent := NewAgent(ctx, mockedClient, internal.NewMockTransformer([]internal.ETHProxy{
+		&blockHashEchoProxy{},
+	}), SetNewHeadsInterval(time.Minute))
+
+	notifierContext, cancelNotifierContext := context.WithCancel(ctx)
+
+	sentValuesChannel := make(chan []byte, 10)
+	send := func(v []byte) error {
+		sentValuesChannel <- v
+		return nil
+	}
+
+	notifier := NewNotifier(notifierContext, cancelNotifierContext, send, log.NewLogfmtLogger(os.Stdout))
+
+	_, err = agent.NewSubscription(notifier, &eth.EthSubscriptionRequest{
+		Method: "newHeads",
+		Params: &eth.EthLogSubscriptionParameter{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	notifier.ResponseSent()
+
+	// wait for the first poll to pick up the current head
+	time.Sleep(100 * time.Millisecond)
+	agent.NotifyNewBlock()
+
+	select {
+	case gotBytes := <-sentValuesChannel:
+		if !strings.Contains(string(gotBytes), `"hash":"0x02"`) {
+			t.Fatalf("Unexpected newHeads notification: %s", string(gotBytes))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for newHead after notifying of a new block")
+	}
+}
+
+// counts the qtum RPC calls made through a mocked doer
+type countingDoer struct {
+	internal.Doer
+	mutex  sync.Mutex
+	counts map[string]int
+}
+
+func (d *countingDoer) Do(request *http.Request) (*http.Response, error) {
+	body, err := ioutil.ReadAll(request.Body)
+	if err != nil {
+		return nil, err
+	}
+	var rpcRequest qtum.JSONRPCRequest
+	if err := json.Unmarshal(body, &rpcRequest); err != nil {
+		return nil, err
+	}
+	d.mutex.Lock()
+	d.counts[rpcRequest.Method]++
+	d.mutex.Unlock()
+	request.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return d.Doer.Do(request)
+}
+
+func (d *countingDoer) count(method string) int {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.counts[method]
+}
+
+func TestAgentLogsSubscriptionsShareOneFetcher(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	topic1 := "d8d7ecc4800d25fa53ce0372f13a416d98907a7ef3d8d3bdd79cf4fe75529c65"
+	topic2 := "ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+	data := "00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000"
+
+	doer := &countingDoer{
+		Doer:   internal.NewDoerMappedMock(),
+		counts: make(map[string]int),
+	}
+	doer.AddResponse(qtum.MethodWaitForLogs, qtum.WaitForLogsResponse{
+		Entries: []qtum.WaitForLogsEntry{
+			internal.QtumWaitForLogsEntry(qtum.Log{
+				Address: internal.QtumTransactionReceipt(nil).ContractAddress,
+				Topics:  []string{topic1},
+				Data:    data,
+			}),
+		},
+		Count:     1,
+		NextBlock: internal.QtumTransactionReceipt(nil).BlockNumber + 1,
+	})
+	doer.AddResponse(qtum.MethodSearchLogs, qtum.SearchLogsResponse{
+		internal.QtumTransactionReceipt([]qtum.Log{
+			{
+				Address: internal.QtumTransactionReceipt(nil).ContractAddress,
+				Topics:  []string{topic1},
+				Data:    data,
+			},
+		}),
+	})
+
+	mockedClient, err := internal.CreateMockedClient(doer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	agent := NewAgent(ctx, mockedClient, nil)
+
+	subscribe := func(topic string) chan []byte {
+		notifierContext, cancelNotifierContext := context.WithCancel(ctx)
+		sentValuesChannel := make(chan []byte, 10)
+		send := func(v []byte) error {
+			sentValuesChannel <- v
+			return nil
+		}
+		notifier := NewNotifier(notifierContext, cancelNotifierContext, send, log.NewLogfmtLogger(os.Stdout))
+		_, err := agent.NewSubscription(notifier, &eth.EthSubscriptionRequest{
+			Method: "logs",
+			Params: &eth.EthLogSubscriptionParameter{
+				Address: internal.QtumTransactionReceipt(nil).ContractAddress,
+				Topics:  []interface{}{topic},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		notifier.ResponseSent()
+		return sentValuesChannel
+	}
+
+	matching := []chan []byte{}
+	for i := 0; i < 20; i++ {
+		matching = append(matching, subscribe(topic1))
+	}
+	notMatching := subscribe(topic2)
+
+	for i, sentValuesChannel := range matching {
+		select {
+		case gotBytes := <-sentValuesChannel:
+			if !strings.Contains(string(gotBytes), "0x"+topic1) {
+				t.Fatalf("Unexpected log for subscription %d: %s", i, string(gotBytes))
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Timed out waiting for logs on subscription %d", i)
+		}
+	}
+
+	select {
+	case gotBytes := <-notMatching:
+		t.Fatalf("Received log for a filter that doesn't match: %s", string(gotBytes))
+	case <-time.After(150 * time.Millisecond):
+	}
+
+	// with a subscription per waitforlogs call this would be at least 21
+	if calls := doer.count(qtum.MethodWaitForLogs); calls > 10 {
+		t.Fatalf("Expected subscriptions to share waitforlogs calls, got %d calls", calls)
+	}
+	if calls := doer.count(qtum.MethodSearchLogs); calls != 1 {
+		t.Fatalf("Expected one searchlogs call for one block, got %d calls", calls)
+	}
+}
@@ -0,0 +1,215 @@
+package notifier
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Client is a websocket or server-sent events connection, kept so admins can see who is connected
+type Client struct {
+	id            string
+	transport     string
+	remoteAddress string
+	connectedAt   time.Time
+
+	mutex    sync.RWMutex
+	apiKey   string
+	notifier *Notifier
+}
+
+// SetAPIKey records the name of the API key the client authenticated with
+func (c *Client) SetAPIKey(name string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.apiKey = name
+}
+
+func (c *Client) getNotifier() *Notifier {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.notifier
+}
+
+type ClientSubscription struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+// ClientStatus is what admin_listConnections shows about a client, session tokens are never included
+type ClientStatus struct {
+	ID            string               `json:"id"`
+	Transport     string               `json:"transport"`
+	RemoteAddress string               `json:"remoteAddress"`
+	APIKey        string               `json:"apiKey,omitempty"`
+	ConnectedAt   time.Time            `json:"connectedAt"`
+	Resumable     bool                 `json:"resumable"`
+	Subscriptions []ClientSubscription `json:"subscriptions"`
+}
+
+type clientRegistry struct {
+	mutex   sync.RWMutex
+	clients map[string]*Client
+}
+
+func newClientRegistry() *clientRegistry {
+	return &clientRegistry{
+		mutex:   sync.RWMutex{},
+		clients: make(map[string]*Client),
+	}
+}
+
+func (r *clientRegistry) get(id string) *Client {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.clients[id]
+}
+
+func (r *clientRegistry) add(c *Client) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.clients[c.id] = c
+}
+
+func (r *clientRegistry) remove(id string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.clients, id)
+}
+
+func (r *clientRegistry) all() []*Client {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	clients := make([]*Client, 0, len(r.clients))
+	for _, c := range r.clients {
+		clients = append(clients, c)
+	}
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].connectedAt.Before(clients[j].connectedAt)
+	})
+	return clients
+}
+
+// resumed moves the clients of a notifier that was resumed into another one over to it
+func (r *clientRegistry) resumed(from *Notifier, into *Notifier) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	for _, c := range r.clients {
+		c.mutex.Lock()
+		if c.notifier == from {
+			c.notifier = into
+		}
+		c.mutex.Unlock()
+	}
+}
+
+// AddClient tracks a connection until RemoveClient is called with the returned client
+// transport is how the client is connected, websocket or sse
+func (a *Agent) AddClient(notifier *Notifier, transport string, remoteAddress string) (*Client, error) {
+	id, err := getRandomSubscriptionId()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		id:            id,
+		transport:     transport,
+		remoteAddress: remoteAddress,
+		connectedAt:   time.Now(),
+		notifier:      notifier,
+	}
+	a.clients.add(c)
+
+	return c, nil
+}
+
+func (a *Agent) RemoveClient(c *Client) {
+	a.clients.remove(c.id)
+}
+
+// ClientCount is how many connections are being tracked
+func (a *Agent) ClientCount() int {
+	a.clients.mutex.RLock()
+	defer a.clients.mutex.RUnlock()
+	return len(a.clients.clients)
+}
+
+// SubscriptionCount is how many subscriptions there are, including the ones of webhooks and detached sessions
+func (a *Agent) SubscriptionCount() int {
+	return a.subscriptionCount(true)
+}
+
+// SessionCount is how many resumable sessions there are, attached or not
+func (a *Agent) SessionCount() int {
+	return a.sessions.Count()
+}
+
+// ClientStatuses lists the tracked connections and their subscriptions, oldest first
+func (a *Agent) ClientStatuses() []ClientStatus {
+	subscriptions := make(map[*Notifier][]ClientSubscription)
+	for _, registry := range []*subscriptionRegistry{a.newHeads, a.logs, a.newPendingTxs, a.syncing} {
+		registry.forEach(func(subscription *subscriptionInformation) {
+			notifier := subscription.Notifier
+			subscriptions[notifier] = append(subscriptions[notifier], ClientSubscription{
+				ID:   subscription.id,
+				Type: registry.name,
+			})
+		})
+	}
+
+	statuses := []ClientStatus{}
+	for _, c := range a.clients.all() {
+		c.mutex.RLock()
+		apiKey := c.apiKey
+		notifier := c.notifier
+		c.mutex.RUnlock()
+
+		notifier.mutex.RLock()
+		resumable := notifier.session != nil
+		notifier.mutex.RUnlock()
+
+		clientSubscriptions := subscriptions[notifier]
+		if clientSubscriptions == nil {
+			clientSubscriptions = []ClientSubscription{}
+		}
+		sort.Slice(clientSubscriptions, func(i, j int) bool {
+			return clientSubscriptions[i].ID < clientSubscriptions[j].ID
+		})
+
+		statuses = append(statuses, ClientStatus{
+			ID:            c.id,
+			Transport:     c.transport,
+			RemoteAddress: c.remoteAddress,
+			APIKey:        apiKey,
+			ConnectedAt:   c.connectedAt,
+			Resumable:     resumable,
+			Subscriptions: clientSubscriptions,
+		})
+	}
+	return statuses
+}
+
+// DisconnectClient closes a client's connection and drops its subscriptions, ending its session so it can't be resumed
+func (a *Agent) DisconnectClient(id string) bool {
+	c := a.clients.get(id)
+	if c == nil {
+		return false
+	}
+
+	notifier := c.getNotifier()
+	notifier.mutex.RLock()
+	session := notifier.session
+	notifier.mutex.RUnlock()
+
+	if session != nil {
+		session.end()
+		a.sessions.remove(session.token)
+	}
+	// the notifier closes the connection when it stops
+	notifier.cancel()
+	a.clients.remove(id)
+
+	a.qtum.GetDebugLogger().Log("msg", "Disconnected client", "client", id)
+
+	return true
+}
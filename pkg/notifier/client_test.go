@@ -0,0 +1,103 @@
+package notifier
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/qtumproject/janus/pkg/eth"
+)
+
+func TestClientStatusesListSubscriptions(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	agent := newSessionTestAgent(t, ctx, time.Minute, 10)
+
+	first, _ := newSessionTestNotifier(ctx)
+	firstClient, err := agent.AddClient(first, "websocket", "127.0.0.1:1000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	firstClient.SetAPIKey("dapp")
+	subscriptionID, err := agent.NewSubscription(first, &eth.EthSubscriptionRequest{Method: "syncing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, _ := newSessionTestNotifier(ctx)
+	// make sure the clients are listed in order
+	time.Sleep(time.Millisecond)
+	if _, err := agent.AddClient(second, "sse", "127.0.0.1:2000"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := agent.NewSession(second); err != nil {
+		t.Fatal(err)
+	}
+
+	statuses := agent.ClientStatuses()
+	if len(statuses) != 2 || agent.ClientCount() != 2 {
+		t.Fatalf("expected 2 clients, got %d", len(statuses))
+	}
+	if statuses[0].APIKey != "dapp" || statuses[0].Transport != "websocket" || statuses[0].Resumable {
+		t.Errorf("unexpected first client %+v", statuses[0])
+	}
+	if len(statuses[0].Subscriptions) != 1 || statuses[0].Subscriptions[0] != (ClientSubscription{ID: subscriptionID, Type: "syncing"}) {
+		t.Errorf("expected the first client's syncing subscription, got %+v", statuses[0].Subscriptions)
+	}
+	if statuses[1].RemoteAddress != "127.0.0.1:2000" || !statuses[1].Resumable || len(statuses[1].Subscriptions) != 0 {
+		t.Errorf("unexpected second client %+v", statuses[1])
+	}
+
+	agent.RemoveClient(firstClient)
+	if agent.ClientCount() != 1 {
+		t.Errorf("expected the removed client to no longer be listed")
+	}
+}
+
+func TestDisconnectClientEndsSession(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	agent := newSessionTestAgent(t, ctx, time.Minute, 10)
+
+	closed := make(chan struct{})
+	notifier := NewNotifier(ctx, func() { close(closed) }, func([]byte) error { return nil }, log.NewNopLogger())
+	client, err := agent.AddClient(notifier, "websocket", "127.0.0.1:1000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := agent.NewSession(notifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := agent.NewSubscription(notifier, &eth.EthSubscriptionRequest{Method: "newPendingTransactions"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if agent.DisconnectClient("0x00") {
+		t.Error("expected an unknown client to not be disconnected")
+	}
+	if !agent.DisconnectClient(client.id) {
+		t.Fatal("expected the client to be disconnected")
+	}
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the connection to be closed")
+	}
+	if agent.ClientCount() != 0 || agent.SessionCount() != 0 {
+		t.Error("expected the client and its session to be gone")
+	}
+	if _, _, err := agent.ResumeSession(token, NewNotifier(ctx, func() {}, func([]byte) error { return nil }, log.NewNopLogger())); err != ErrUnknownSession {
+		t.Errorf("expected the session to not be resumable, got %v", err)
+	}
+	for i := 0; agent.SubscriptionCount() != 0; i++ {
+		if i > 100 {
+			t.Fatal("expected the subscription to be dropped")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,244 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/qtumproject/janus/pkg/conversion"
+	"github.com/qtumproject/janus/pkg/eth"
+	"github.com/qtumproject/janus/pkg/qtum"
+)
+
+// logsFilter is the address and topic filter of a single logs subscription
+type logsFilter struct {
+	addresses map[string]bool
+	topics    []qtum.SearchLogsTopic
+}
+
+func newLogsFilter(params *eth.EthLogSubscriptionParameter) (*logsFilter, error) {
+	filter := &logsFilter{
+		addresses: make(map[string]bool),
+	}
+	if params == nil {
+		return filter, nil
+	}
+
+	translatedTopics, err := eth.TranslateTopics(params.Topics)
+	if err != nil {
+		return nil, err
+	}
+	filter.topics = qtum.NewSearchLogsTopics(translatedTopics)
+
+	ethAddresses, err := params.GetAddresses()
+	if err != nil {
+		return nil, err
+	}
+	for _, ethAddress := range ethAddresses {
+		filter.addresses[normalizeLogAddress(ethAddress.String())] = true
+	}
+
+	return filter, nil
+}
+
+func normalizeLogAddress(address string) string {
+	return strings.ToLower(strings.TrimPrefix(address, "0x"))
+}
+
+func (f *logsFilter) matches(log qtum.Log) bool {
+	if len(f.addresses) != 0 && !f.addresses[normalizeLogAddress(log.Address)] {
+		return false
+	}
+
+	return conversion.DoFiltersMatch(f.topics, log.Topics)
+}
+
+// runLogs is the single waitforlogs loop shared by every logs subscription
+// it fetches all logs once per block and fans them out to subscriptions with a matching filter
+// so the load on qtumd stays the same no matter how many clients are subscribed
+func (a *Agent) runLogs() {
+	if a.logs.Count() == 0 {
+		return
+	}
+
+	a.mutex.Lock()
+	if a.logsRunning {
+		a.mutex.Unlock()
+		return
+	}
+	ctx, cancel := context.WithCancel(a.ctx)
+	a.logsRunning = true
+	a.logsCancel = cancel
+	a.mutex.Unlock()
+
+	defer func() {
+		a.mutex.Lock()
+		cancel()
+		a.logsRunning = false
+		a.logsCancel = nil
+		a.mutex.Unlock()
+
+		a.qtum.GetDebugLogger().Log("msg", "Agent exited logs processing thread")
+
+		// a subscription could have been added while we were exiting
+		if a.logs.Count() != 0 && a.ctx.Err() == nil {
+			go a.runLogs()
+		}
+	}()
+
+	a.qtum.GetDebugLogger().Log("msg", "Agent started logs processing thread")
+
+	// waitforlogs without a filter returns as soon as there are any logs in a new block
+	var nextBlock interface{}
+	nextBlock = nil
+	req := &qtum.WaitForLogsRequest{
+		FromBlock: nextBlock,
+		ToBlock:   nil,
+		Filter:    qtum.WaitForLogsFilter{},
+	}
+
+	// this throttles QTUM api calls if waitforlogs is returning very quickly a lot
+	limitToXApiCalls := 5
+	inYSeconds := 10 * time.Second
+	// if a QTUM API call returns quicker than this, we will wait until this time is reached
+	// this prevents spamming the QTUM node too much
+	minimumTimeBetweenCalls := 100 * time.Millisecond
+
+	rolling := newRollingLimit(limitToXApiCalls)
+
+	failures := 0
+	for {
+		if a.logs.Count() == 0 {
+			return
+		}
+
+		req.FromBlock = nextBlock
+		timeBeforeCall := time.Now()
+		rolling.Push(&timeBeforeCall)
+		resp, err := a.qtum.WaitForLogs(ctx, req)
+		timeAfterCall := time.Now()
+		if err == nil {
+			// waitforlogs can return the same range again, only process blocks we haven't seen
+			if next, ok := nextBlock.(int); !ok || int(resp.NextBlock) > next {
+				err = a.fetchAndSendLogs(ctx, resp)
+			}
+		}
+
+		if err == nil {
+			nextBlock = int(resp.NextBlock)
+			oldest := rolling.Oldest()
+			now := time.Now()
+			if oldest != nil && now.Sub(*oldest.(*time.Time)) < inYSeconds {
+				// too many request returning successfully too quickly, slow them down
+				failures = failures + 1
+			} else {
+				failures = 0
+			}
+		} else {
+			// error occurred, the same range will be fetched again
+			a.qtum.GetDebugLogger().Log("msg", "Error fetching logs", "err", err)
+			failures = failures + 1
+		}
+
+		done := ctx.Done()
+
+		select {
+		case <-done:
+			// err is wrapped so we can't detect (err == context.Cancelled)
+			a.qtum.GetDebugLogger().Log("msg", "context closed, stopping logs processing")
+			return
+		default:
+		}
+
+		backoffTime := getBackoff(failures, 0, 15*time.Second)
+
+		timeCallTook := timeAfterCall.Sub(timeBeforeCall)
+		if timeCallTook < minimumTimeBetweenCalls {
+			timeLeftUntilMinimumTimeBetweenCallsReached := minimumTimeBetweenCalls - timeCallTook
+			backoffTime = time.Duration(math.Max(float64(backoffTime), float64(timeLeftUntilMinimumTimeBetweenCallsReached)))
+		}
+
+		if backoffTime > 0 {
+			a.qtum.GetDebugLogger().Log("msg", fmt.Sprintf("backing off for %d miliseconds", backoffTime/time.Millisecond))
+		}
+
+		select {
+		case <-done:
+			return
+		case <-time.After(backoffTime):
+			// ok, try again
+		}
+	}
+}
+
+// fetchAndSendLogs gets the full receipts for the blocks waitforlogs returned logs for and sends them to subscribers
+func (a *Agent) fetchAndSendLogs(ctx context.Context, resp *qtum.WaitForLogsResponse) error {
+	if len(resp.Entries) == 0 {
+		return nil
+	}
+
+	fromBlock := resp.Entries[0].BlockNumber
+	toBlock := resp.Entries[0].BlockNumber
+	for _, entry := range resp.Entries {
+		if entry.BlockNumber < fromBlock {
+			fromBlock = entry.BlockNumber
+		}
+		if entry.BlockNumber > toBlock {
+			toBlock = entry.BlockNumber
+		}
+	}
+
+	// searchlogs gives us the position of each log inside of its transaction receipt
+	receipts, err := a.qtum.SearchLogs(ctx, &qtum.SearchLogsRequest{
+		FromBlock: new(big.Int).SetUint64(fromBlock),
+		ToBlock:   new(big.Int).SetUint64(toBlock),
+	})
+	if err != nil {
+		return err
+	}
+
+	a.sendLogs(receipts)
+
+	return nil
+}
+
+func (a *Agent) sendLogs(receipts qtum.SearchLogsResponse) {
+	a.logs.forEach(func(subscription *subscriptionInformation) {
+		if subscription.logsFilter == nil {
+			return
+		}
+
+		notifications := []interface{}{}
+		for _, receipt := range receipts {
+			logs := []qtum.Log{}
+			for index, log := range receipt.Log {
+				log.Index = index
+				if subscription.logsFilter.matches(log) {
+					logs = append(logs, log)
+				}
+			}
+
+			for _, ethLog := range conversion.ExtractETHLogsFromTransactionReceipt(receipt, logs) {
+				jsonRpcNotification, err := eth.NewJSONRPCNotification("eth_subscription", &eth.EthSubscription{
+					SubscriptionID: subscription.id,
+					Result:         ethLog,
+				})
+				if err != nil {
+					a.qtum.GetErrorLogger().Log("subscriptionId", subscription.id, "err", err)
+					continue
+				}
+				notifications = append(notifications, jsonRpcNotification)
+			}
+		}
+
+		if len(notifications) == 0 {
+			return
+		}
+
+		a.qtum.GetDebugLogger().Log("subscriptionId", subscription.id, "msg", "notifying of logs", "count", len(notifications))
+		subscription.enqueue(notifications...)
+	})
+}
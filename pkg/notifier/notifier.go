@@ -14,6 +14,10 @@ import (
 
 var UnsubSignal = new(struct{})
 
+// sends anything a resumed session buffered while disconnected
+// this can't be a new(struct{}) like UnsubSignal, pointers to zero sized values aren't guaranteed to be distinct
+var flushSignal = new(byte)
+
 type UnsubscribeCallback func(string)
 
 type Subscription struct {
@@ -59,8 +63,11 @@ type Notifier struct {
 	runMutex              sync.Mutex
 	mutex                 sync.RWMutex
 	ctx                   context.Context
-	close                 func()
-	send                  func([]byte) error
+	cancel                context.CancelFunc
+	connection            *connection
+	session               *Session
+	resumedInto           *Notifier
+	released              bool
 	logger                log.Logger
 	queue                 chan interface{}
 	subscriptionIdPending *chan interface{}
@@ -71,12 +78,13 @@ type Notifier struct {
 func NewNotifier(ctx context.Context, close func(), send func([]byte) error, logger log.Logger) *Notifier {
 	pending := make(chan interface{}, 10)
 	flushed := make(chan interface{}, 10)
+	ctx, cancel := context.WithCancel(ctx)
 	notifier := &Notifier{
 		runMutex:              sync.Mutex{},
 		mutex:                 sync.RWMutex{},
 		ctx:                   ctx,
-		close:                 close,
-		send:                  send,
+		cancel:                cancel,
+		connection:            &connection{send: send, close: close},
 		logger:                log.WithPrefix(logger, "component", "notifier"),
 		queue:                 make(chan interface{}, 50),
 		subscriptionIdPending: &pending,
@@ -91,6 +99,63 @@ func (n *Notifier) Context() context.Context {
 	return n.ctx
 }
 
+// Disconnected is called when the connection that created this notifier is gone
+// subscriptions are dropped unless they belong to a resumable session
+func (n *Notifier) Disconnected() {
+	n.mutex.RLock()
+	session := n.session
+	resumedInto := n.resumedInto
+	n.mutex.RUnlock()
+
+	if resumedInto != nil {
+		resumedInto.mutex.RLock()
+		session = resumedInto.session
+		resumedInto.mutex.RUnlock()
+	}
+
+	if session != nil && session.detach(n.connection) {
+		return
+	}
+
+	n.cancel()
+}
+
+// release hands this notifier's connection over to a resumed session's notifier and stops it without closing the connection
+func (n *Notifier) release(into *Notifier) {
+	n.mutex.Lock()
+	n.resumedInto = into
+	n.released = true
+	n.mutex.Unlock()
+
+	n.cancel()
+}
+
+// write sends to the session if there is one, otherwise straight to the connection
+func (n *Notifier) write(message []byte) error {
+	n.mutex.RLock()
+	session := n.session
+	n.mutex.RUnlock()
+
+	if session != nil {
+		return session.deliver(message)
+	}
+
+	return n.connection.send(message)
+}
+
+// closeConnection must be called holding n.mutex
+func (n *Notifier) closeConnection() {
+	if n.released {
+		return
+	}
+
+	if n.session != nil {
+		n.session.close()
+	} else {
+		n.connection.close()
+	}
+}
+
 func (n *Notifier) Subscribe(unsubscribeCallback UnsubscribeCallback) (*Subscription, error) {
 	sub, err := NewSubscription(n, unsubscribeCallback)
 	if err != nil {
@@ -171,7 +236,7 @@ func (n *Notifier) run() {
 			log.With(level.Debug(n.logger)).Log("msg", "Notifier loop exited")
 		}()
 
-		n.close()
+		n.closeConnection()
 		close(n.queue)
 		n.closeSubscriptionsFlushed()
 		for _, sub := range n.subscriptions {
@@ -201,16 +266,25 @@ func (n *Notifier) run() {
 				n.mutex.Lock()
 				n.closeSubscriptionsFlushed()
 				n.mutex.Unlock()
+			} else if event == flushSignal {
+				n.mutex.RLock()
+				session := n.session
+				n.mutex.RUnlock()
+				if session != nil {
+					session.flush()
+				}
 			} else {
 				bytes, err := json.Marshal(event)
 				if err != nil {
 					panic(err)
 				}
-				err = n.send(bytes)
+				err = n.write(bytes)
 				if err != nil {
 					// write failure, close connection and unsubscribe
 					log.With(level.Debug(n.logger)).Log("msg", "Error writing response to websocket, closing it", "err", err)
-					n.close()
+					n.mutex.RLock()
+					n.closeConnection()
+					n.mutex.RUnlock()
 					return
 				}
 			}
@@ -0,0 +1,303 @@
+package notifier
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrSessionsDisabled = errors.New("Resumable sessions are disabled")
+var ErrUnknownSession = errors.New("Unknown or expired session")
+var ErrSessionHasSubscriptions = errors.New("Cannot resume a session on a connection that already has subscriptions")
+
+var agentConfigSessionGracePeriodKey = "sessionGracePeriod"
+var agentConfigSessionBufferSizeKey = "sessionBufferSize"
+
+// DefaultSessionBufferSize is how many notifications are kept for a disconnected session
+var DefaultSessionBufferSize = 1000
+
+// SetResumableSessions lets websocket clients reattach to their subscriptions if they reconnect within gracePeriod
+// notifications sent while disconnected are buffered, keeping at most bufferSize of the most recent ones
+func SetResumableSessions(gracePeriod time.Duration, bufferSize int) AgentOption {
+	return func(config map[string]interface{}) {
+		if gracePeriod > 0 {
+			config[agentConfigSessionGracePeriodKey] = gracePeriod
+		}
+		if bufferSize > 0 {
+			config[agentConfigSessionBufferSizeKey] = bufferSize
+		}
+	}
+}
+
+// connection is how a Notifier writes to a single websocket
+type connection struct {
+	send  func([]byte) error
+	close func()
+}
+
+// Session keeps a Notifier and its subscriptions alive while the client is disconnected
+type Session struct {
+	token       string
+	notifier    *Notifier
+	registry    *sessionRegistry
+	mutex       sync.Mutex
+	current     *connection
+	buffer      [][]byte
+	dropped     int
+	expiry      *time.Timer
+	expired     bool
+	gracePeriod time.Duration
+	bufferSize  int
+}
+
+// deliver writes to the attached connection, or buffers if there isn't one
+// it never fails, a failed write detaches the connection so the client can resume
+func (s *Session) deliver(message []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.buffer = append(s.buffer, message)
+	s.writeBufferLocked()
+
+	return nil
+}
+
+// flush sends anything buffered while the session was detached
+func (s *Session) flush() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.writeBufferLocked()
+}
+
+func (s *Session) writeBufferLocked() {
+	if s.current == nil {
+		if len(s.buffer) > s.bufferSize {
+			s.dropped = s.dropped + len(s.buffer) - s.bufferSize
+			s.buffer = s.buffer[len(s.buffer)-s.bufferSize:]
+		}
+		return
+	}
+
+	for len(s.buffer) != 0 {
+		if err := s.current.send(s.buffer[0]); err != nil {
+			s.notifier.logger.Log("msg", "Error writing to websocket, keeping session for resume", "err", err)
+			s.current.close()
+			s.detachLocked()
+			return
+		}
+		s.buffer = s.buffer[1:]
+	}
+}
+
+func (s *Session) attach(conn *connection) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.expired {
+		return 0, ErrUnknownSession
+	}
+
+	if s.expiry != nil {
+		s.expiry.Stop()
+		s.expiry = nil
+	}
+
+	if s.current != nil && s.current != conn {
+		// the old connection is probably dead and we just haven't noticed yet
+		s.current.close()
+	}
+	s.current = conn
+
+	dropped := s.dropped
+	s.dropped = 0
+
+	return dropped, nil
+}
+
+// detach is called when conn disconnects, returns false if the session has already expired
+func (s *Session) detach(conn *connection) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.expired {
+		return false
+	}
+
+	if s.current == conn {
+		s.detachLocked()
+	}
+
+	return true
+}
+
+func (s *Session) detachLocked() {
+	s.current = nil
+	if s.expiry == nil {
+		s.expiry = time.AfterFunc(s.gracePeriod, s.expire)
+	}
+}
+
+func (s *Session) expire() {
+	s.mutex.Lock()
+	if s.current != nil || s.expired {
+		s.mutex.Unlock()
+		return
+	}
+	s.expired = true
+	s.buffer = nil
+	s.mutex.Unlock()
+
+	s.registry.remove(s.token)
+	s.notifier.logger.Log("msg", "Session expired, dropping subscriptions")
+	s.notifier.cancel()
+}
+
+// end expires the session without waiting for the grace period
+func (s *Session) end() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.expired = true
+	s.buffer = nil
+	if s.expiry != nil {
+		s.expiry.Stop()
+		s.expiry = nil
+	}
+}
+
+func (s *Session) close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.current != nil {
+		s.current.close()
+	}
+}
+
+type sessionRegistry struct {
+	mutex    sync.RWMutex
+	sessions map[string]*Session
+}
+
+func newSessionRegistry() *sessionRegistry {
+	return &sessionRegistry{
+		mutex:    sync.RWMutex{},
+		sessions: make(map[string]*Session),
+	}
+}
+
+func (r *sessionRegistry) get(token string) *Session {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.sessions[token]
+}
+
+func (r *sessionRegistry) add(session *Session) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.sessions[session.token] = session
+}
+
+func (r *sessionRegistry) remove(token string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.sessions, token)
+}
+
+// closeAll ends every session without waiting for the grace period, dropping their subscriptions
+func (r *sessionRegistry) closeAll() {
+	r.mutex.Lock()
+	sessions := r.sessions
+	r.sessions = make(map[string]*Session)
+	r.mutex.Unlock()
+
+	for _, session := range sessions {
+		session.end()
+		session.notifier.cancel()
+	}
+}
+
+func (r *sessionRegistry) Count() int {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return len(r.sessions)
+}
+
+func (a *Agent) sessionsEnabled() bool {
+	gracePeriod, ok := a.getConfigValue(agentConfigSessionGracePeriodKey, time.Duration(0)).(time.Duration)
+	return ok && gracePeriod > 0
+}
+
+// NewSession makes the notifier's subscriptions resumable, returning the token the client needs to resume them
+func (a *Agent) NewSession(notifier *Notifier) (string, error) {
+	if !a.sessionsEnabled() {
+		return "", ErrSessionsDisabled
+	}
+
+	notifier.mutex.Lock()
+	defer notifier.mutex.Unlock()
+
+	if notifier.session != nil {
+		return notifier.session.token, nil
+	}
+
+	token, err := getRandomSubscriptionId()
+	if err != nil {
+		return "", err
+	}
+
+	session := &Session{
+		token:       token,
+		notifier:    notifier,
+		registry:    a.sessions,
+		current:     notifier.connection,
+		gracePeriod: a.getConfigValue(agentConfigSessionGracePeriodKey, time.Duration(0)).(time.Duration),
+		bufferSize:  a.getConfigValue(agentConfigSessionBufferSizeKey, DefaultSessionBufferSize).(int),
+	}
+	notifier.session = session
+	a.sessions.add(session)
+
+	return token, nil
+}
+
+// ResumeSession moves the websocket behind notifier onto the session's notifier
+// the returned notifier must be used for the connection from now on, buffered notifications are sent after the next response
+func (a *Agent) ResumeSession(token string, notifier *Notifier) (*Notifier, int, error) {
+	if !a.sessionsEnabled() {
+		return nil, 0, ErrSessionsDisabled
+	}
+
+	session := a.sessions.get(token)
+	if session == nil {
+		return nil, 0, ErrUnknownSession
+	}
+
+	if session.notifier == notifier {
+		// already attached on this connection
+		return notifier, 0, nil
+	}
+
+	notifier.mutex.Lock()
+	subscriptions := len(notifier.subscriptions)
+	ownSession := notifier.session
+	notifier.mutex.Unlock()
+	if subscriptions != 0 || ownSession != nil {
+		return nil, 0, ErrSessionHasSubscriptions
+	}
+
+	dropped, err := session.attach(notifier.connection)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	notifier.release(session.notifier)
+	a.clients.resumed(notifier, session.notifier)
+
+	// hold back the buffered notifications until the client has the response to resuming
+	session.notifier.ResponseRequired()
+	go session.notifier.Send(flushSignal)
+
+	return session.notifier, dropped, nil
+}
@@ -0,0 +1,186 @@
+package notifier
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/qtumproject/janus/pkg/internal"
+)
+
+func newSessionTestAgent(t *testing.T, ctx context.Context, gracePeriod time.Duration, bufferSize int) *Agent {
+	mockedClient, err := internal.CreateMockedClient(internal.NewDoerMappedMock())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewAgent(ctx, mockedClient, nil, SetResumableSessions(gracePeriod, bufferSize))
+}
+
+func newSessionTestNotifier(ctx context.Context) (*Notifier, chan string) {
+	sent := make(chan string, 10)
+	send := func(v []byte) error {
+		sent <- string(v)
+		return nil
+	}
+
+	return NewNotifier(ctx, func() {}, send, log.NewLogfmtLogger(os.Stdout)), sent
+}
+
+func expectSent(t *testing.T, sent chan string, want string) {
+	select {
+	case got := <-sent:
+		if got != want {
+			t.Fatalf("expected %s to be sent, got %s", want, got)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for %s to be sent", want)
+	}
+}
+
+func TestSessionResumeSendsBufferedNotifications(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	agent := newSessionTestAgent(t, ctx, time.Minute, 2)
+
+	first, firstSent := newSessionTestNotifier(ctx)
+	token, err := agent.NewSession(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the response to qtum_createSession
+	first.ResponseSent()
+
+	first.Send("one")
+	expectSent(t, firstSent, `"one"`)
+
+	first.Disconnected()
+
+	first.Send("two")
+	first.Send("three")
+	first.Send("four")
+
+	// wait for the notifier to buffer everything
+	session := agent.sessions.get(token)
+	for i := 0; ; i++ {
+		session.mutex.Lock()
+		buffered := len(session.buffer) + session.dropped
+		session.mutex.Unlock()
+		if buffered == 3 {
+			break
+		}
+		if i > 100 {
+			t.Fatal("notifications were not buffered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	second, secondSent := newSessionTestNotifier(ctx)
+	resumed, dropped, err := agent.ResumeSession(token, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumed != first {
+		t.Fatal("expected the session's notifier to be returned")
+	}
+	if dropped != 1 {
+		t.Fatalf("expected 1 dropped notification, got %d", dropped)
+	}
+
+	select {
+	case got := <-secondSent:
+		t.Fatalf("buffered notification %s sent before the response to resuming", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	resumed.ResponseSent()
+	expectSent(t, secondSent, `"three"`)
+	expectSent(t, secondSent, `"four"`)
+
+	resumed.Send("five")
+	expectSent(t, secondSent, `"five"`)
+
+	select {
+	case got := <-firstSent:
+		t.Fatalf("expected nothing sent to the old connection, got %s", got)
+	default:
+	}
+
+	if second.Context().Err() == nil {
+		t.Fatal("expected the resuming connection's own notifier to be stopped")
+	}
+	if first.Context().Err() != nil {
+		t.Fatal("expected the session's notifier to still be running")
+	}
+}
+
+func TestSessionExpiresAfterGracePeriod(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	agent := newSessionTestAgent(t, ctx, 20*time.Millisecond, 0)
+
+	first, _ := newSessionTestNotifier(ctx)
+	token, err := agent.NewSession(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first.Disconnected()
+
+	select {
+	case <-first.Context().Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected the session's notifier to stop after the grace period")
+	}
+
+	second, _ := newSessionTestNotifier(ctx)
+	if _, _, err := agent.ResumeSession(token, second); err != ErrUnknownSession {
+		t.Fatalf("expected %v, got %v", ErrUnknownSession, err)
+	}
+	if agent.sessions.Count() != 0 {
+		t.Fatalf("expected expired session to be removed, %d left", agent.sessions.Count())
+	}
+}
+
+func TestAgentStopEndsDetachedSessions(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	agent := newSessionTestAgent(t, ctx, time.Hour, 0)
+
+	first, _ := newSessionTestNotifier(ctx)
+	token, err := agent.NewSession(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first.Disconnected()
+
+	agent.Stop()
+
+	select {
+	case <-first.Context().Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected the session's notifier to stop with the agent")
+	}
+
+	second, _ := newSessionTestNotifier(ctx)
+	if _, _, err := agent.ResumeSession(token, second); err != ErrUnknownSession {
+		t.Fatalf("expected %v, got %v", ErrUnknownSession, err)
+	}
+}
+
+func TestSessionsDisabledByDefault(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	agent := newSessionTestAgent(t, ctx, 0, 0)
+
+	notifier, _ := newSessionTestNotifier(ctx)
+	if _, err := agent.NewSession(notifier); err != ErrSessionsDisabled {
+		t.Fatalf("expected %v, got %v", ErrSessionsDisabled, err)
+	}
+}
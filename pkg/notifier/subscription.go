@@ -2,211 +2,74 @@ package notifier
 
 import (
 	"context"
-	"crypto/md5"
-	"encoding/json"
-	"fmt"
-	"math"
-	"math/big"
-	"strings"
 	"sync"
 	"time"
 
-	"github.com/qtumproject/janus/pkg/conversion"
 	"github.com/qtumproject/janus/pkg/eth"
-	"github.com/qtumproject/janus/pkg/qtum"
 )
 
 type subscriptionInformation struct {
 	*Subscription
 	params     *eth.EthSubscriptionRequest
-	mutex      sync.RWMutex
 	ctx        context.Context
 	cancelFunc context.CancelFunc
-	running    bool
-	qtum       *qtum.Qtum
+	// only set for logs subscriptions
+	logsFilter *logsFilter
+	// notifications waiting for the subscription's sender, which delivers them in the order they were enqueued
+	pendingMutex sync.Mutex
+	pending      []interface{}
+	wake         chan struct{}
 }
 
-func (s *subscriptionInformation) run() {
-	if s.params == nil {
-		return
-	}
-
-	if strings.ToLower(s.params.Method) != "logs" {
-		return
-	}
-
-	s.mutex.Lock()
-	if s.running {
-		s.mutex.Unlock()
-		return
-	}
-	s.running = true
-	s.mutex.Unlock()
-
-	defer func() {
-		s.mutex.Lock()
-		defer s.mutex.Unlock()
-
-		s.running = false
-	}()
-
-	var nextBlock interface{}
-	nextBlock = nil
-	translatedTopics, err := eth.TranslateTopics(s.params.Params.Topics)
-	if err != nil {
-		s.qtum.GetDebugLogger().Log("msg", "Error translating logs topics", "error", err)
-		return
-	}
-	ethAddresses, err := s.params.Params.GetAddresses()
-	if err != nil {
-		s.qtum.GetDebugLogger().Log("msg", "Error translating logs addresses", "error", err)
-		return
-	}
-	stringAddresses := make([]string, len(ethAddresses))
-	for i, ethAddress := range ethAddresses {
-		if strings.HasPrefix(ethAddress.String(), "0x") {
-			stringAddresses[i] = strings.TrimPrefix(ethAddress.String(), "0x")
-		} else {
-			stringAddresses[i] = ethAddress.String()
-		}
-	}
-
-	qtumTopics := qtum.NewSearchLogsTopics(translatedTopics)
-	req := &qtum.WaitForLogsRequest{
-		FromBlock: nextBlock,
-		ToBlock:   nil,
-		Filter: qtum.WaitForLogsFilter{
-			Addresses: &stringAddresses,
-			Topics:    &qtumTopics,
-		},
+func newSubscriptionInformation(subscription *Subscription, params *eth.EthSubscriptionRequest, ctx context.Context, cancelFunc context.CancelFunc) *subscriptionInformation {
+	s := &subscriptionInformation{
+		Subscription: subscription,
+		params:       params,
+		ctx:          ctx,
+		cancelFunc:   cancelFunc,
+		wake:         make(chan struct{}, 1),
 	}
+	go s.runSender()
+	return s
+}
 
-	if s.qtum.Chain() == qtum.ChainRegTest || s.qtum.Chain() == qtum.ChainTest {
-		req.MinimumConfirmations = 0
+// enqueue hands notifications to the subscription's sender without blocking
+// the notifier's queue can fill up when a client is slow, which shouldn't hold up polling or other subscriptions
+func (s *subscriptionInformation) enqueue(notifications ...interface{}) {
+	s.pendingMutex.Lock()
+	s.pending = append(s.pending, notifications...)
+	s.pendingMutex.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+		// the sender is already going to pick these up
 	}
+}
 
-	// this throttles QTUM api calls if waitforlogs is returning very quickly a lot
-	limitToXApiCalls := 5
-	inYSeconds := 10 * time.Second
-	// if a QTUM API call returns quicker than this, we will wait until this time is reached
-	// this prevents spamming the QTUM node too much
-	minimumTimeBetweenCalls := 100 * time.Millisecond
-
-	rolling := newRollingLimit(limitToXApiCalls)
-
-	// duplicate logs are only to be sent on a reorg
-	// the previous log that was sent on the old chain is sent with a `removed: true`
-	// then the new log is sent
-	// that functionality isn't supported in this implementation yet
-	// however, in order to not send duplicate logs we can do that with a simple hash map
-	// each hash is a 128bit MD5 hash, the hashing algorithim doesn't really matter here
-	// as this is only for preventing duplicate logs being sent over a websocket
-	// there are 8000 bits in a kilobyte, thats enough for 62.5 hashes
-	// 1MB = 1024KB = 1024KB/1KB = 1024 * 62.5 = 64,000 hashes
-	// when proving this as a service, that can add up if tens of thousands are using the service
-	// we want to put an upper limit on ram usage for an ip/connection
-	// we could also put an absolute upper limit on ram usage for this feature
-	// TODO: Deal with RAM usage here when Janus gets large enough
-	// some kind of FIFO hashmap?
-	sentHashes := make(map[string]bool)
-
-	failures := 0
+// runSender is the only goroutine that sends the subscription's notifications, so they arrive in order
+func (s *subscriptionInformation) runSender() {
 	for {
-		req.FromBlock = nextBlock
-		timeBeforeCall := time.Now()
-		rolling.Push(&timeBeforeCall)
-		resp, err := s.qtum.WaitForLogsWithContext(s.ctx, req)
-		timeAfterCall := time.Now()
-		if err == nil {
-			nextBlock = int(resp.NextBlock)
-			reqSearchLogs := qtum.SearchLogsRequest{
-				FromBlock: big.NewInt(int64(resp.NextBlock - 1)),
-				ToBlock:   big.NewInt(int64(resp.NextBlock - 1)),
-				Addresses: *req.Filter.Addresses,
-				Topics:    *req.Filter.Topics,
-			}
-			receiptsSearchLogs, err := s.qtum.SearchLogs(&reqSearchLogs)
-			if err != nil {
-				s.qtum.GetErrorLogger().Log("msg", "Error calling searchLogs", "subscriptionId", s.id, "error", err)
-				return
-			}
-			for _, qtumLog := range receiptsSearchLogs {
-				qtumLogs := qtumLog.Log
-				logs := conversion.FilterQtumLogs(stringAddresses, qtumTopics, qtumLogs)
-				ethLogs := conversion.ExtractETHLogsFromTransactionReceipt(qtumLog, logs)
-				for _, ethLog := range ethLogs {
-					subscription := &eth.EthSubscription{
-						SubscriptionID: s.Subscription.id,
-						Result:         ethLog,
-					}
-					hash := computeHash(subscription)
-					if _, ok := sentHashes[hash]; !ok {
-						sentHashes[hash] = true
-						s.qtum.GetDebugLogger().Log("subscriptionId", s.id, "msg", "notifying of logs")
-						jsonRpcNotification, err := eth.NewJSONRPCNotification("eth_subscription", subscription)
-						if err != nil {
-							s.qtum.GetErrorLogger().Log("subscriptionId", s.id, "err", err)
-							return
-						}
-						s.Send(jsonRpcNotification)
-					}
-				}
-			}
-			oldest := rolling.Oldest()
-			a := time.Now()
-			if oldest != nil && a.Sub(*oldest.(*time.Time)) < inYSeconds {
-				// too many request returning successfully too quickly, slow them down
-				failures = failures + 1
-			} else {
-				failures = 0
-			}
-		} else {
-			// error occurred
-			s.qtum.GetDebugLogger().Log("subscriptionId", s.id, "err", err)
-			failures = failures + 1
-		}
-
-		done := s.ctx.Done()
-
 		select {
-		case <-done:
-			// err is wrapped so we can't detect (err == context.Cancelled)
-			s.qtum.GetDebugLogger().Log("subscriptionId", s.id, "msg", "context closed, dropping subscription")
+		case <-s.ctx.Done():
 			return
-		default:
-		}
-
-		backoffTime := getBackoff(failures, 0, 15*time.Second)
-
-		timeCallTook := timeAfterCall.Sub(timeAfterCall)
-		if timeCallTook < minimumTimeBetweenCalls {
-			timeLeftUntilMinimumTimeBetweenCallsReached := minimumTimeBetweenCalls - timeCallTook
-			backoffTime = time.Duration(math.Max(float64(backoffTime), float64(timeLeftUntilMinimumTimeBetweenCallsReached)))
+		case <-s.wake:
 		}
 
-		if backoffTime > 0 {
-			s.qtum.GetDebugLogger().Log("subscriptionId", s.id, "msg", fmt.Sprintf("backing off for %d miliseconds", backoffTime/time.Millisecond))
-		}
+		s.pendingMutex.Lock()
+		pending := s.pending
+		s.pending = nil
+		s.pendingMutex.Unlock()
 
-		select {
-		case <-done:
-			return
-		case <-time.After(backoffTime):
-			// ok, try again
+		for _, notification := range pending {
+			if s.ctx.Err() != nil {
+				return
+			}
+			s.Send(notification)
 		}
 	}
 }
 
-// Compute hash for the json serialization of the passed in argument
-func computeHash(value interface{}) string {
-	b, err := json.Marshal(value)
-	if err != nil {
-		panic(err)
-	}
-	md5Hash := md5.Sum(b)
-	return string(md5Hash[:])
-}
-
 func getBackoff(count int, min time.Duration, max time.Duration) time.Duration {
 	maxFailures := 10
 	if count == 0 {
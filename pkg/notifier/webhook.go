@@ -0,0 +1,468 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+	"github.com/qtumproject/janus/pkg/eth"
+)
+
+var ErrWebhooksDisabled = errors.New("Webhooks are disabled")
+var ErrUnknownWebhook = errors.New("Unknown webhook")
+
+var agentConfigWebhooksKey = "webhooks"
+var agentConfigWebhookMaxAttemptsKey = "webhookMaxAttempts"
+var agentConfigWebhookMaxBackoffKey = "webhookMaxBackoff"
+var agentConfigWebhookTimeoutKey = "webhookTimeout"
+
+var (
+	// DefaultWebhookMaxAttempts is how many times a delivery is tried before it is dead lettered
+	DefaultWebhookMaxAttempts = 5
+	// DefaultWebhookMaxBackoff is the longest wait between delivery attempts
+	DefaultWebhookMaxBackoff = time.Minute
+	// DefaultWebhookTimeout is how long a single delivery attempt can take
+	DefaultWebhookTimeout = 10 * time.Second
+)
+
+const (
+	// deliveries waiting on a slow endpoint, anything past this is dead lettered straight away
+	webhookQueueSize = 1000
+	// dead letters kept per webhook, oldest are dropped first
+	webhookDeadLetterLimit = 100
+	// how much of an endpoint's error response is kept for the status
+	webhookErrorBodyLimit = 512
+)
+
+// headers sent with every delivery
+const (
+	WebhookSignatureHeader = "X-Janus-Signature"
+	WebhookTimestampHeader = "X-Janus-Timestamp"
+	WebhookIDHeader        = "X-Janus-Webhook"
+	WebhookDeliveryHeader  = "X-Janus-Delivery"
+	WebhookEventHeader     = "X-Janus-Event"
+)
+
+// events a webhook can register for, mapped to the subscription type that produces them
+var webhookEvents = map[string]string{
+	"logs":     "logs",
+	"newheads": "newHeads",
+}
+
+// SetWebhooks allows registering webhooks that POST logs and new blocks to a url
+// anyone who can call the admin_ webhook methods can make Janus send requests to any url
+func SetWebhooks(enabled bool) AgentOption {
+	return func(config map[string]interface{}) {
+		config[agentConfigWebhooksKey] = enabled
+	}
+}
+
+// SignWebhookPayload is the hex encoded HMAC-SHA256 of timestamp + "." + payload
+// receivers should compute it themselves and compare it to the X-Janus-Signature header
+func SignWebhookPayload(secret []byte, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+type WebhookDeadLetter struct {
+	DeliveryID string          `json:"deliveryId"`
+	Event      string          `json:"event"`
+	Payload    json.RawMessage `json:"payload"`
+	Attempts   int             `json:"attempts"`
+	Error      string          `json:"error"`
+	FailedAt   time.Time       `json:"failedAt"`
+}
+
+type WebhookStatus struct {
+	ID              string              `json:"id"`
+	URL             string              `json:"url"`
+	Events          []string            `json:"events"`
+	CreatedAt       time.Time           `json:"createdAt"`
+	Delivered       uint64              `json:"delivered"`
+	Retried         uint64              `json:"retried"`
+	Failed          uint64              `json:"failed"`
+	Pending         int                 `json:"pending"`
+	LastDeliveredAt *time.Time          `json:"lastDeliveredAt,omitempty"`
+	LastError       string              `json:"lastError,omitempty"`
+	LastErrorAt     *time.Time          `json:"lastErrorAt,omitempty"`
+	DeadLetters     []WebhookDeadLetter `json:"deadLetters"`
+}
+
+type webhookDelivery struct {
+	id      string
+	event   string
+	payload []byte
+}
+
+type webhook struct {
+	id          string
+	url         string
+	secret      []byte
+	events      []string
+	createdAt   time.Time
+	notifier    *Notifier
+	client      *http.Client
+	logger      log.Logger
+	maxAttempts int
+	maxBackoff  time.Duration
+	queue       chan *webhookDelivery
+
+	mutex sync.RWMutex
+	// subscription id => event name
+	subscriptions   map[string]string
+	delivered       uint64
+	retried         uint64
+	failed          uint64
+	lastDeliveredAt *time.Time
+	lastError       string
+	lastErrorAt     *time.Time
+	deadLetters     []WebhookDeadLetter
+}
+
+// enqueue is the webhook notifier's send function, it never fails so the notifier never closes
+func (w *webhook) enqueue(payload []byte) error {
+	delivery := &webhookDelivery{
+		event:   w.eventOf(payload),
+		payload: payload,
+	}
+	id, err := getRandomSubscriptionId()
+	if err != nil {
+		w.deadLetter(delivery, 0, err)
+		return nil
+	}
+	delivery.id = id
+
+	select {
+	case w.queue <- delivery:
+	default:
+		w.deadLetter(delivery, 0, errors.New("delivery queue is full"))
+	}
+
+	return nil
+}
+
+func (w *webhook) eventOf(payload []byte) string {
+	var notification struct {
+		Params struct {
+			Subscription string `json:"subscription"`
+		} `json:"params"`
+	}
+	if err := json.Unmarshal(payload, &notification); err != nil {
+		return ""
+	}
+
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	return w.subscriptions[notification.Params.Subscription]
+}
+
+func (w *webhook) run() {
+	ctx := w.notifier.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case delivery := <-w.queue:
+			w.deliver(ctx, delivery)
+		}
+	}
+}
+
+// deliver retries with a backoff until the endpoint accepts the delivery, then gives up and dead letters it
+func (w *webhook) deliver(ctx context.Context, delivery *webhookDelivery) {
+	for attempt := 1; ; attempt++ {
+		err := w.post(ctx, delivery)
+		if err == nil {
+			now := time.Now()
+			w.mutex.Lock()
+			w.delivered = w.delivered + 1
+			w.lastDeliveredAt = &now
+			w.mutex.Unlock()
+			return
+		}
+
+		if ctx.Err() != nil {
+			// webhook was removed
+			return
+		}
+
+		w.logger.Log("msg", "Failed to deliver webhook", "delivery", delivery.id, "attempt", attempt, "err", err)
+
+		if attempt >= w.maxAttempts {
+			w.deadLetter(delivery, attempt, err)
+			return
+		}
+
+		now := time.Now()
+		w.mutex.Lock()
+		w.retried = w.retried + 1
+		w.lastError = err.Error()
+		w.lastErrorAt = &now
+		w.mutex.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(getBackoff(attempt, 0, w.maxBackoff)):
+		}
+	}
+}
+
+func (w *webhook) post(ctx context.Context, delivery *webhookDelivery) error {
+	request, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(delivery.payload))
+	if err != nil {
+		return err
+	}
+	request = request.WithContext(ctx)
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "Janus")
+	request.Header.Set(WebhookIDHeader, w.id)
+	request.Header.Set(WebhookDeliveryHeader, delivery.id)
+	request.Header.Set(WebhookEventHeader, delivery.event)
+	request.Header.Set(WebhookTimestampHeader, timestamp)
+	request.Header.Set(WebhookSignatureHeader, "sha256="+SignWebhookPayload(w.secret, timestamp, delivery.payload))
+
+	response, err := w.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(io.LimitReader(response.Body, webhookErrorBodyLimit))
+		return errors.Errorf("%s: %s", response.Status, strings.TrimSpace(string(body)))
+	}
+
+	io.Copy(ioutil.Discard, response.Body)
+
+	return nil
+}
+
+func (w *webhook) deadLetter(delivery *webhookDelivery, attempts int, err error) {
+	now := time.Now()
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.failed = w.failed + 1
+	w.lastError = err.Error()
+	w.lastErrorAt = &now
+	w.deadLetters = append(w.deadLetters, WebhookDeadLetter{
+		DeliveryID: delivery.id,
+		Event:      delivery.event,
+		Payload:    json.RawMessage(delivery.payload),
+		Attempts:   attempts,
+		Error:      err.Error(),
+		FailedAt:   now,
+	})
+	if len(w.deadLetters) > webhookDeadLetterLimit {
+		w.deadLetters = w.deadLetters[len(w.deadLetters)-webhookDeadLetterLimit:]
+	}
+}
+
+func (w *webhook) status() WebhookStatus {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	deadLetters := make([]WebhookDeadLetter, len(w.deadLetters))
+	copy(deadLetters, w.deadLetters)
+
+	return WebhookStatus{
+		ID:              w.id,
+		URL:             w.url,
+		Events:          w.events,
+		CreatedAt:       w.createdAt,
+		Delivered:       w.delivered,
+		Retried:         w.retried,
+		Failed:          w.failed,
+		Pending:         len(w.queue),
+		LastDeliveredAt: w.lastDeliveredAt,
+		LastError:       w.lastError,
+		LastErrorAt:     w.lastErrorAt,
+		DeadLetters:     deadLetters,
+	}
+}
+
+type webhookRegistry struct {
+	mutex    sync.RWMutex
+	webhooks map[string]*webhook
+}
+
+func newWebhookRegistry() *webhookRegistry {
+	return &webhookRegistry{
+		mutex:    sync.RWMutex{},
+		webhooks: make(map[string]*webhook),
+	}
+}
+
+func (r *webhookRegistry) get(id string) *webhook {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.webhooks[id]
+}
+
+func (r *webhookRegistry) add(w *webhook) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.webhooks[w.id] = w
+}
+
+func (r *webhookRegistry) remove(id string) *webhook {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	w := r.webhooks[id]
+	delete(r.webhooks, id)
+	return w
+}
+
+func (r *webhookRegistry) all() []*webhook {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	webhooks := make([]*webhook, 0, len(r.webhooks))
+	for _, w := range r.webhooks {
+		webhooks = append(webhooks, w)
+	}
+	sort.Slice(webhooks, func(i, j int) bool {
+		return webhooks[i].createdAt.Before(webhooks[j].createdAt)
+	})
+	return webhooks
+}
+
+func (a *Agent) webhooksEnabled() bool {
+	enabled, ok := a.getConfigValue(agentConfigWebhooksKey, false).(bool)
+	return ok && enabled
+}
+
+// AddWebhook POSTs the events to callbackURL until the webhook is removed
+// logs are filtered the same way as a logs subscription, an empty secret generates one
+// returns the webhook id and the secret payloads are signed with
+func (a *Agent) AddWebhook(callbackURL string, secret string, events []string, filter *eth.EthLogSubscriptionParameter) (string, string, error) {
+	if !a.webhooksEnabled() {
+		return "", "", ErrWebhooksDisabled
+	}
+
+	parsedURL, err := url.Parse(callbackURL)
+	if err != nil {
+		return "", "", errors.Wrap(err, "Invalid webhook url")
+	}
+	if (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+		return "", "", errors.Errorf("Invalid webhook url %q, must be an absolute http or https url", callbackURL)
+	}
+
+	if len(events) == 0 {
+		return "", "", errors.New("At least one webhook event is required")
+	}
+	subscriptionTypes := []string{}
+	for _, event := range events {
+		subscriptionType, ok := webhookEvents[strings.ToLower(event)]
+		if !ok {
+			return "", "", errors.Errorf("Unknown webhook event %s", event)
+		}
+		subscriptionTypes = append(subscriptionTypes, subscriptionType)
+	}
+
+	if secret == "" {
+		var generated [32]byte
+		if n, _ := rand.Read(generated[:]); n != len(generated) {
+			return "", "", errors.New("Unable to generate webhook secret")
+		}
+		secret = hex.EncodeToString(generated[:])
+	}
+
+	id, err := getRandomSubscriptionId()
+	if err != nil {
+		return "", "", err
+	}
+
+	w := &webhook{
+		id:        id,
+		url:       callbackURL,
+		secret:    []byte(secret),
+		events:    subscriptionTypes,
+		createdAt: time.Now(),
+		client: &http.Client{
+			Timeout: a.getConfigValue(agentConfigWebhookTimeoutKey, DefaultWebhookTimeout).(time.Duration),
+		},
+		logger:        log.WithPrefix(a.qtum.GetLogger(), "webhook", id),
+		maxAttempts:   a.getConfigValue(agentConfigWebhookMaxAttemptsKey, DefaultWebhookMaxAttempts).(int),
+		maxBackoff:    a.getConfigValue(agentConfigWebhookMaxBackoffKey, DefaultWebhookMaxBackoff).(time.Duration),
+		queue:         make(chan *webhookDelivery, webhookQueueSize),
+		subscriptions: make(map[string]string),
+	}
+	// a webhook is a notifier without a connection, so it gets exactly what a websocket subscriber would
+	w.notifier = NewNotifier(a.ctx, func() {}, w.enqueue, w.logger)
+
+	for _, subscriptionType := range subscriptionTypes {
+		subscriptionID, err := a.NewSubscription(w.notifier, &eth.EthSubscriptionRequest{
+			Method: subscriptionType,
+			Params: filter,
+		})
+		if err != nil {
+			w.notifier.cancel()
+			return "", "", errors.Wrap(err, fmt.Sprintf("Failed to subscribe webhook to %s", subscriptionType))
+		}
+		w.mutex.Lock()
+		w.subscriptions[subscriptionID] = subscriptionType
+		w.mutex.Unlock()
+	}
+	w.notifier.ResponseSent()
+
+	go w.run()
+	a.webhooks.add(w)
+
+	a.qtum.GetDebugLogger().Log("msg", "Added webhook", "webhook", id, "url", callbackURL, "events", strings.Join(subscriptionTypes, ","))
+
+	return id, secret, nil
+}
+
+// RemoveWebhook unsubscribes the webhook, deliveries that haven't been made yet are dropped
+func (a *Agent) RemoveWebhook(id string) bool {
+	w := a.webhooks.remove(id)
+	if w == nil {
+		return false
+	}
+
+	w.notifier.cancel()
+	a.qtum.GetDebugLogger().Log("msg", "Removed webhook", "webhook", id)
+
+	return true
+}
+
+func (a *Agent) WebhookStatus(id string) (*WebhookStatus, error) {
+	w := a.webhooks.get(id)
+	if w == nil {
+		return nil, ErrUnknownWebhook
+	}
+
+	status := w.status()
+	return &status, nil
+}
+
+func (a *Agent) WebhookStatuses() []WebhookStatus {
+	statuses := []WebhookStatus{}
+	for _, w := range a.webhooks.all() {
+		statuses = append(statuses, w.status())
+	}
+	return statuses
+}
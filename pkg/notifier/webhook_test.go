@@ -0,0 +1,196 @@
+package notifier
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qtumproject/janus/pkg/eth"
+	"github.com/qtumproject/janus/pkg/internal"
+	"github.com/qtumproject/janus/pkg/qtum"
+)
+
+type webhookTestRequest struct {
+	header http.Header
+	body   []byte
+}
+
+func newWebhookTestAgent(t *testing.T, ctx context.Context, maxAttempts int) *Agent {
+	doer := internal.NewDoerMappedMock()
+	doer.AddResponse(qtum.MethodGetBlockChainInfo, qtum.GetBlockChainInfoResponse{Blocks: 1})
+	doer.AddResponse(qtum.MethodGetBlockChainInfo, qtum.GetBlockChainInfoResponse{Blocks: 2})
+	doer.AddResponse(qtum.MethodGetBlockHash, qtum.GetBlockHashResponse("02"))
+
+	mockedClient, err := internal.CreateMockedClient(doer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := make(map[string]interface{})
+	config[agentConfigNewHeadsKey] = 50 * time.Millisecond
+	config[agentConfigWebhooksKey] = true
+	config[agentConfigWebhookMaxAttemptsKey] = maxAttempts
+	config[agentConfigWebhookMaxBackoffKey] = 100 * time.Millisecond
+
+	return newAgentWithConfiguration(ctx, mockedClient, internal.NewMockTransformer([]internal.ETHProxy{
+		&blockHashEchoProxy{},
+	}), config)
+}
+
+func TestWebhookDeliversSignedNewHeads(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	requests := make(chan webhookTestRequest, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests <- webhookTestRequest{header: r.Header, body: body}
+	}))
+	defer server.Close()
+
+	agent := newWebhookTestAgent(t, ctx, 3)
+
+	id, secret, err := agent.AddWebhook(server.URL, "", []string{"newHeads"}, &eth.EthLogSubscriptionParameter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == "" {
+		t.Fatal("expected a secret to be generated")
+	}
+
+	var request webhookTestRequest
+	for {
+		select {
+		case request = <-requests:
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for webhook delivery")
+		}
+		// the first poll can deliver the head the webhook was registered at
+		if strings.Contains(string(request.body), `"hash":"0x02"`) {
+			break
+		}
+	}
+
+	if got := request.header.Get(WebhookIDHeader); got != id {
+		t.Fatalf("expected webhook id %s, got %s", id, got)
+	}
+	if got := request.header.Get(WebhookEventHeader); got != "newHeads" {
+		t.Fatalf("expected newHeads event, got %s", got)
+	}
+	timestamp := request.header.Get(WebhookTimestampHeader)
+	want := "sha256=" + SignWebhookPayload([]byte(secret), timestamp, request.body)
+	if got := request.header.Get(WebhookSignatureHeader); got != want {
+		t.Fatalf("invalid signature\nwant: %s\ngot: %s", want, got)
+	}
+
+	// the delivery is counted once the response has been read
+	for i := 0; ; i++ {
+		status, err := agent.WebhookStatus(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status.Delivered != 0 {
+			break
+		}
+		if i > 100 {
+			t.Fatal("expected delivery to be counted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if !agent.RemoveWebhook(id) {
+		t.Fatal("failed to remove webhook")
+	}
+	if _, err := agent.WebhookStatus(id); err != ErrUnknownWebhook {
+		t.Fatalf("expected %v, got %v", ErrUnknownWebhook, err)
+	}
+
+	for i := 0; agent.newHeads.Count() != 0; i++ {
+		if i > 100 {
+			t.Fatal("webhook subscription was not removed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWebhookDeadLettersAfterRetries(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	attempts := make(chan struct{}, 100)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts <- struct{}{}
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	agent := newWebhookTestAgent(t, ctx, 3)
+
+	id, _, err := agent.AddWebhook(server.URL, "secret", []string{"newHeads"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var status *WebhookStatus
+	for i := 0; ; i++ {
+		status, err = agent.WebhookStatus(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(status.DeadLetters) != 0 {
+			break
+		}
+		if i > 200 {
+			t.Fatal("timed out waiting for a dead letter")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	deadLetter := status.DeadLetters[0]
+	if deadLetter.Attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", deadLetter.Attempts)
+	}
+	if deadLetter.Event != "newHeads" {
+		t.Fatalf("expected newHeads event, got %s", deadLetter.Event)
+	}
+	if !strings.Contains(deadLetter.Error, "503") {
+		t.Fatalf("expected the endpoint's status in the error, got %s", deadLetter.Error)
+	}
+	if status.Failed == 0 || status.Retried < 2 || status.Delivered != 0 {
+		t.Fatalf("unexpected delivery counts %+v", status)
+	}
+	if len(attempts) < 3 {
+		t.Fatalf("expected at least 3 attempts, got %d", len(attempts))
+	}
+
+	agent.RemoveWebhook(id)
+}
+
+func TestWebhookValidation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	agent := newWebhookTestAgent(t, ctx, 3)
+
+	if _, _, err := agent.AddWebhook("localhost:1234", "", []string{"logs"}, nil); err == nil {
+		t.Fatal("expected relative url to be rejected")
+	}
+	if _, _, err := agent.AddWebhook("http://localhost:1234", "", nil, nil); err == nil {
+		t.Fatal("expected missing events to be rejected")
+	}
+	if _, _, err := agent.AddWebhook("http://localhost:1234", "", []string{"syncing"}, nil); err == nil {
+		t.Fatal("expected unknown event to be rejected")
+	}
+	if len(agent.WebhookStatuses()) != 0 {
+		t.Fatal("expected no webhooks to be registered")
+	}
+
+	disabled := NewAgent(ctx, agent.qtum, nil)
+	if _, _, err := disabled.AddWebhook("http://localhost:1234", "", []string{"logs"}, nil); err != ErrWebhooksDisabled {
+		t.Fatalf("expected %v, got %v", ErrWebhooksDisabled, err)
+	}
+}
@@ -0,0 +1,293 @@
+package qtum
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/qtumproject/janus/pkg/metrics"
+	"github.com/qtumproject/janus/pkg/tracing"
+)
+
+// DefaultMaxBatchSize is the most calls sent to qtumd in one batch
+var DefaultMaxBatchSize = 100
+
+// method label for batches in qtumd request metrics
+const batchMethod = "batch"
+
+// BatchRequest is one call in a batch sent with RequestBatch
+type BatchRequest struct {
+	Method string
+	Params interface{}
+	// the call's result is unmarshalled into this
+	Result interface{}
+	// set when this call failed, the rest of the batch can still have succeeded
+	Err error
+}
+
+// RequestBatch sends several calls to qtumd in one HTTP request, or one per DefaultMaxBatchSize calls
+// the returned error is only for the batch as a whole, each call's own error is set on it
+func (c *Client) RequestBatch(ctx context.Context, requests []*BatchRequest) error {
+	for len(requests) > DefaultMaxBatchSize {
+		if err := c.requestBatch(ctx, requests[:DefaultMaxBatchSize]); err != nil {
+			return err
+		}
+		requests = requests[DefaultMaxBatchSize:]
+	}
+	return c.requestBatch(ctx, requests)
+}
+
+func (c *Client) requestBatch(ctx context.Context, requests []*BatchRequest) error {
+	if len(requests) == 0 {
+		return nil
+	}
+
+	reqs := make([]*JSONRPCRequest, len(requests))
+	for i, request := range requests {
+		req, err := c.NewRPCRequest(request.Method, request.Params)
+		if err != nil {
+			return errors.WithMessage(err, "couldn't make new rpc request")
+		}
+		reqs[i] = req
+	}
+
+	var results []*JSONRPCResult
+	err := c.retryWhileBusy(ctx, batchMethod, reqs, func() error {
+		var err error
+		results, err = c.DoBatch(ctx, reqs)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, request := range requests {
+		rawResult, err := c.batchResult(results[i])
+		if err != nil {
+			request.Err = err
+			continue
+		}
+		if err := json.Unmarshal(rawResult, request.Result); err != nil {
+			request.Err = errors.Wrap(err, "couldn't unmarshal response result field")
+		}
+	}
+
+	return nil
+}
+
+// DoBatch sends reqs to qtumd as a JSON-RPC batch, results are in the same order as reqs
+// a result is nil if qtumd didn't answer that request
+func (c *Client) DoBatch(ctx context.Context, reqs []*JSONRPCRequest) (results []*JSONRPCResult, err error) {
+	defer func(start time.Time) {
+		observeRequest(batchMethod, start, err)
+	}(time.Now())
+	metrics.QtumdBatchSize.Observe(float64(len(reqs)))
+
+	reqBody, err := json.MarshalIndent(reqs, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	if c.IsDebugEnabled() && !c.GetFlagBool(FLAG_HIDE_QTUMD_LOGS) {
+		c.dumpBody(ctx, "=> qtum RPC batch request", reqBody)
+	}
+
+	respBody, err := c.doWithFailover(ctx, batchRoutingMethod(reqs), reqBody)
+	if err != nil {
+		return nil, errors.Wrap(err, "Client#do")
+	}
+
+	if c.IsDebugEnabled() && !c.GetFlagBool(FLAG_HIDE_QTUMD_LOGS) {
+		c.dumpBody(ctx, "<= qtum RPC batch response", respBody)
+	}
+
+	if string(respBody) == ErrQtumWorkQueueDepth.Error() {
+		return nil, ErrQtumWorkQueueDepth
+	}
+
+	var responses []*JSONRPCResult
+	if err := json.Unmarshal(respBody, &responses); err != nil {
+		// qtumd answers with a single error if it couldn't read the batch at all
+		if _, singleErr := c.responseBodyToResult(respBody); singleErr != nil {
+			return nil, singleErr
+		}
+		return nil, errors.Wrap(err, "couldn't unmarshal batch response")
+	}
+
+	byID := make(map[string]*JSONRPCResult, len(responses))
+	for _, response := range responses {
+		if response != nil {
+			byID[string(response.ID)] = response
+		}
+	}
+
+	results = make([]*JSONRPCResult, len(reqs))
+	for i, req := range reqs {
+		results[i] = byID[string(req.ID)]
+	}
+
+	return results, nil
+}
+
+func (c *Client) batchResult(result *JSONRPCResult) (json.RawMessage, error) {
+	if result == nil {
+		return nil, errors.New("qtumd didn't answer this request in the batch")
+	}
+	if result.Error != nil {
+		return nil, result.Error.TryGetKnownError()
+	}
+	return result.RawResult, nil
+}
+
+// batchRoutingMethod is the method a batch is routed by, wallet calls can only go to the wallet node so they win
+func batchRoutingMethod(reqs []*JSONRPCRequest) string {
+	for _, req := range reqs {
+		if !loadBalancedMethods[req.Method] {
+			return req.Method
+		}
+	}
+	return reqs[0].Method
+}
+
+type batchedCall struct {
+	// the context of the caller, only used for its request ID and span
+	ctx    context.Context
+	req    *JSONRPCRequest
+	done   chan struct{}
+	result json.RawMessage
+	err    error
+}
+
+// batcher collects requests made around the same time and sends them to qtumd as one batch
+type batcher struct {
+	client  *Client
+	window  time.Duration
+	maxSize int
+
+	mutex   sync.Mutex
+	pending []*batchedCall
+	timer   *time.Timer
+}
+
+// call waits up to the batch window for other requests to send along with req
+func (b *batcher) call(ctx context.Context, req *JSONRPCRequest) (json.RawMessage, error) {
+	call := &batchedCall{
+		ctx:  ctx,
+		req:  req,
+		done: make(chan struct{}),
+	}
+
+	b.mutex.Lock()
+	b.pending = append(b.pending, call)
+	if len(b.pending) >= b.maxSize {
+		b.flushLocked()
+	} else if len(b.pending) == 1 {
+		b.timer = time.AfterFunc(b.window, b.flush)
+	}
+	b.mutex.Unlock()
+
+	var cancelled <-chan struct{}
+	if ctx != nil {
+		cancelled = ctx.Done()
+	}
+
+	select {
+	case <-call.done:
+		return call.result, call.err
+	case <-cancelled:
+		return nil, ctx.Err()
+	}
+}
+
+func (b *batcher) flush() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.flushLocked()
+}
+
+func (b *batcher) flushLocked() {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	if len(b.pending) == 0 {
+		return
+	}
+
+	calls := b.pending
+	b.pending = nil
+	go b.send(calls)
+}
+
+func (b *batcher) send(calls []*batchedCall) {
+	defer func() {
+		for _, call := range calls {
+			close(call.done)
+		}
+	}()
+
+	// the batch is shared so it runs under the client's context rather than any one caller's
+	// it is logged and traced as part of the first call's request
+	ctx := tracing.WithValuesOf(b.client.GetContext(), calls[0].ctx)
+
+	if len(calls) == 1 {
+		call := calls[0]
+		var resp *SuccessJSONRPCResult
+		call.err = b.client.retryWhileBusy(ctx, call.req.Method, call.req, func() error {
+			var err error
+			resp, err = b.client.Do(ctx, call.req)
+			return err
+		})
+		if call.err == nil {
+			call.result = resp.RawResult
+		}
+		return
+	}
+
+	reqs := make([]*JSONRPCRequest, len(calls))
+	for i, call := range calls {
+		reqs[i] = call.req
+	}
+
+	var results []*JSONRPCResult
+	err := b.client.retryWhileBusy(ctx, batchMethod, reqs, func() error {
+		var err error
+		results, err = b.client.DoBatch(ctx, reqs)
+		return err
+	})
+
+	for i, call := range calls {
+		if err != nil {
+			call.err = err
+			continue
+		}
+		call.result, call.err = b.client.batchResult(results[i])
+	}
+}
+
+// SetBatchWindow sends requests made within window of each other to qtumd as one batch
+// every request waits up to window for others to join it, 0 sends every request on its own
+func SetBatchWindow(window time.Duration) func(*Client) error {
+	return func(c *Client) error {
+		if window < 0 {
+			return errors.New("batch window cannot be negative")
+		}
+		if window == 0 {
+			c.batcher = nil
+			return nil
+		}
+		c.batcher = &batcher{
+			client:  c,
+			window:  window,
+			maxSize: DefaultMaxBatchSize,
+		}
+		return nil
+	}
+}
+
+// IsBatching is true when requests made at the same time are sent to qtumd together
+func (c *Client) IsBatching() bool {
+	return c.batcher != nil
+}
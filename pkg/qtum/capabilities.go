@@ -0,0 +1,133 @@
+package qtum
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/pkg/errors"
+)
+
+// Capability is something Janus needs from qtumd that it only has when started with the option of the same name
+type Capability string
+
+const (
+	CapabilityLogEvents Capability = "-logevents"
+	CapabilityAddrIndex Capability = "-addrindex"
+	CapabilityTxIndex   Capability = "-txindex"
+)
+
+var AllCapabilities = []Capability{CapabilityLogEvents, CapabilityAddrIndex, CapabilityTxIndex}
+
+// Capabilities is what qtumd was found to support when it was last probed
+type Capabilities struct {
+	Version         int64
+	Subversion      string
+	ProtocolVersion int64
+	Enabled         map[Capability]bool
+}
+
+// Missing is the options qtumd wasn't started with
+func (c *Capabilities) Missing() []Capability {
+	var missing []Capability
+	for _, capability := range AllCapabilities {
+		if !c.Enabled[capability] {
+			missing = append(missing, capability)
+		}
+	}
+	return missing
+}
+
+// DetectCapabilities probes qtumd for its version and the options it was started with, Supports answers with the result
+func (c *Qtum) DetectCapabilities(ctx context.Context) (*Capabilities, error) {
+	networkInfo, err := c.GetNetworkInfo(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get qtumd's version")
+	}
+	capabilities := &Capabilities{
+		Version:         networkInfo.Version,
+		Subversion:      networkInfo.Subversion,
+		ProtocolVersion: networkInfo.ProtocolVersion,
+		Enabled:         make(map[Capability]bool),
+	}
+
+	probes := map[Capability]func(context.Context) (bool, error){
+		CapabilityLogEvents: c.probeLogEvents,
+		CapabilityAddrIndex: c.probeAddrIndex,
+		CapabilityTxIndex:   c.probeTxIndex,
+	}
+	for capability, probe := range probes {
+		enabled, err := probe(ctx)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to check if qtumd was started with %s", capability)
+		}
+		capabilities.Enabled[capability] = enabled
+	}
+
+	c.capabilitiesMutex.Lock()
+	c.capabilities = capabilities
+	c.capabilitiesMutex.Unlock()
+	return capabilities, nil
+}
+
+// Capabilities is what DetectCapabilities last found, nil until it succeeds
+func (c *Qtum) Capabilities() *Capabilities {
+	c.capabilitiesMutex.RLock()
+	defer c.capabilitiesMutex.RUnlock()
+	return c.capabilities
+}
+
+// Supports is whether qtumd has capability, it's assumed to until DetectCapabilities finds otherwise
+func (c *Qtum) Supports(capability Capability) bool {
+	capabilities := c.Capabilities()
+	return capabilities == nil || capabilities.Enabled[capability]
+}
+
+// probeLogEvents asks for a receipt that doesn't exist, qtumd fails with an internal error when events aren't indexed
+// and returns no receipts when they are
+func (c *Qtum) probeLogEvents(ctx context.Context) (bool, error) {
+	_, err := c.GetTransactionReceipt(ctx, "0000000000000000000000000000000000000000000000000000000000000000")
+	switch errors.Cause(err) {
+	case nil, EmptyResponseErr:
+		return true, nil
+	case ErrInternalError:
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// probeAddrIndex asks for the balance of an address, qtumd has no information on any address without the address index
+func (c *Qtum) probeAddrIndex(ctx context.Context) (bool, error) {
+	address, err := c.FromHexAddress(ctx, "0000000000000000000000000000000000000000")
+	if err != nil {
+		return false, err
+	}
+	_, err = c.GetAddressBalance(ctx, &GetAddressBalanceRequest{Address: address})
+	if errors.Cause(err) == ErrInvalidAddress {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// probeTxIndex looks up the coinbase of the first block, without the transaction index qtumd only finds mempool transactions
+// the genesis coinbase can't be found either way, and before the first block is mined there is nothing to tell
+func (c *Qtum) probeTxIndex(ctx context.Context) (bool, error) {
+	hash, err := c.GetBlockHash(ctx, big.NewInt(1))
+	if errors.Cause(err) == ErrInvalidParameter {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	block, err := c.GetBlock(ctx, string(hash))
+	if err != nil {
+		return false, err
+	}
+	if len(block.Txs) == 0 {
+		return true, nil
+	}
+	_, err = c.GetRawTransaction(ctx, block.Txs[0], false)
+	if errors.Cause(err) == ErrInvalidAddress {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -1,7 +1,6 @@
 package qtum
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -19,6 +18,8 @@ import (
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	"github.com/pkg/errors"
+	"github.com/qtumproject/janus/pkg/metrics"
+	"github.com/qtumproject/janus/pkg/tracing"
 )
 
 var FLAG_GENERATE_ADDRESS_TO = "REGTEST_GENERATE_ADDRESS_TO"
@@ -27,6 +28,23 @@ var FLAG_DISABLE_SNIPPING_LOGS = "DISABLE_SNIPPING_LOGS"
 var FLAG_HIDE_QTUMD_LOGS = "HIDE_QTUMD_LOGS"
 var FLAG_MATURE_BLOCK_HEIGHT_OVERRIDE = "FLAG_MATURE_BLOCK_HEIGHT_OVERRIDE"
 
+type FlagType string
+
+const (
+	FlagTypeString FlagType = "string"
+	FlagTypeBool   FlagType = "bool"
+	FlagTypeInt    FlagType = "int"
+)
+
+// FlagTypes are the flags admins can read and change while Janus is running, and the type of their values
+var FlagTypes = map[string]FlagType{
+	FLAG_GENERATE_ADDRESS_TO:          FlagTypeString,
+	FLAG_IGNORE_UNKNOWN_TX:            FlagTypeBool,
+	FLAG_DISABLE_SNIPPING_LOGS:        FlagTypeBool,
+	FLAG_HIDE_QTUMD_LOGS:              FlagTypeBool,
+	FLAG_MATURE_BLOCK_HEIGHT_OVERRIDE: FlagTypeInt,
+}
+
 var maximumRequestTime = 10000
 var maximumBackoff = (2 * time.Second).Milliseconds()
 
@@ -38,10 +56,14 @@ type Client struct {
 
 	// hex addresses to return for eth_accounts
 	Accounts Accounts
+	// guards Accounts and debug, which can be reloaded while requests are being handled
+	settingsMutex sync.RWMutex
 
 	logWriter io.Writer
 	logger    log.Logger
 	debug     bool
+	// request and response dumps are logged as fields of log lines instead of written to logWriter
+	jsonLogs bool
 
 	// is this client using the main network?
 	isMain bool
@@ -52,6 +74,22 @@ type Client struct {
 
 	mutex *sync.RWMutex
 	flags map[string]interface{}
+
+	// nil when request coalescing is disabled
+	coalescer *coalescer
+
+	// extra qtumd nodes to spread reads over, nil when there is only URL
+	upstreams             *upstreamPool
+	upstreamURLs          []string
+	upstreamMaxLag        int64
+	upstreamCheckInterval time.Duration
+
+	// nil when concurrent requests aren't batched together
+	batcher *batcher
+
+	// requests and responses are recorded to this fixture file when set
+	recordingPath string
+	recorder      *RecordingDoer
 }
 
 func ReformatJSON(input []byte) ([]byte, error) {
@@ -74,17 +112,26 @@ func NewClient(isMain bool, rpcURL string, opts ...func(*Client) error) (*Client
 		return nil, errors.Wrap(err, "Failed to parse rpc url")
 	}
 
+	transport, err := NewTransport(DefaultTransportConfig)
+	if err != nil {
+		return nil, err
+	}
+
 	c := &Client{
-		isMain: isMain,
-		doer:   http.DefaultClient,
-		URL:    rpcURL,
-		url:    url,
-		logger: log.NewNopLogger(),
-		debug:  false,
-		id:     big.NewInt(0),
-		idStep: big.NewInt(1),
-		mutex:  &sync.RWMutex{},
-		flags:  make(map[string]interface{}),
+		isMain:    isMain,
+		doer:      &http.Client{Transport: transport},
+		URL:       rpcURL,
+		url:       url,
+		logger:    log.NewNopLogger(),
+		debug:     false,
+		id:        big.NewInt(0),
+		idStep:    big.NewInt(1),
+		mutex:     &sync.RWMutex{},
+		flags:     make(map[string]interface{}),
+		coalescer: newCoalescer(),
+
+		upstreamMaxLag:        DefaultUpstreamMaxLag,
+		upstreamCheckInterval: DefaultUpstreamCheckInterval,
 	}
 
 	for _, opt := range opts {
@@ -93,9 +140,34 @@ func NewClient(isMain bool, rpcURL string, opts ...func(*Client) error) (*Client
 		}
 	}
 
+	if c.recordingPath != "" {
+		c.recorder, err = OpenRecordingDoer(c.doer, c.recordingPath, c.GetErrorLogger())
+		if err != nil {
+			return nil, err
+		}
+		c.doer = c.recorder
+	}
+
+	if len(c.upstreamURLs) != 0 {
+		c.upstreams, err = newUpstreamPool(append([]string{rpcURL}, c.upstreamURLs...), c.upstreamMaxLag, c.upstreamCheckInterval)
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+		go c.watchUpstreams()
+	}
+
 	return c, nil
 }
 
+// Close releases the files the client has open, call it once the client is no longer used
+func (c *Client) Close() error {
+	if c.recorder == nil {
+		return nil
+	}
+	return errors.Wrap(c.recorder.Close(), "Failed to close recording")
+}
+
 func (c *Client) GetURL() *url.URL {
 	return c.url
 }
@@ -108,57 +180,125 @@ func (c *Client) Request(method string, params interface{}, result interface{})
 	return c.RequestWithContext(c.GetContext(), method, params, result)
 }
 
-func (c *Client) RequestWithContext(ctx context.Context, method string, params interface{}, result interface{}) error {
+func (c *Client) RequestWithContext(ctx context.Context, method string, params interface{}, result interface{}) (err error) {
+	ctx, span := tracing.Start(ctx, "qtumd "+method, tracing.SpanKindClient, "rpc.system", "jsonrpc", "rpc.method", method)
+	defer func() {
+		if err != nil {
+			span.SetError(err.Error())
+		}
+		span.End()
+	}()
+
+	var rawResult json.RawMessage
+	if result, ok := prefetched(ctx, method, params); ok {
+		span.SetAttributes("janus.prefetched", true)
+		rawResult, err = result.result, result.err
+	} else if c.coalescer != nil && coalescableMethods[method] {
+		rawResult, err = c.coalescedRequest(ctx, method, params)
+	} else {
+		rawResult, err = c.request(ctx, method, params)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(rawResult, result)
+	if err != nil {
+		tracing.Logger(ctx, c.GetDebugLogger()).Log("method", method, "params", params, "result", result, "error", err)
+		return errors.Wrap(err, "couldn't unmarshal response result field")
+	}
+	return nil
+}
+
+// coalescedRequest shares one qtumd request between every identical request made while it is in flight
+// the shared request runs under the client's context, it is only cancelled once every caller has given up
+// it is logged and traced as part of the request that started it
+func (c *Client) coalescedRequest(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	paramsJSON, err := json.Marshal(params)
+	if err != nil {
+		return c.request(ctx, method, params)
+	}
+
+	rawResult, shared, err := c.coalescer.do(ctx, tracing.WithValuesOf(c.GetContext(), ctx), method+":"+string(paramsJSON), func(ctx context.Context) (json.RawMessage, error) {
+		return c.request(ctx, method, params)
+	})
+	if shared {
+		metrics.QtumdCoalescedRequests.WithLabelValues(method).Inc()
+	}
+	return rawResult, err
+}
+
+func (c *Client) request(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
 	req, err := c.NewRPCRequest(method, params)
 	if err != nil {
-		return errors.WithMessage(err, "couldn't make new rpc request")
+		return nil, errors.WithMessage(err, "couldn't make new rpc request")
+	}
+
+	if c.batcher != nil && method != MethodWaitForLogs {
+		// waitforlogs blocks until there are new logs, it would hold up everything batched with it
+		return c.batcher.call(ctx, req)
 	}
 
 	var resp *SuccessJSONRPCResult
+	err = c.retryWhileBusy(ctx, method, req, func() error {
+		resp, err = c.Do(ctx, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.RawResult, nil
+}
+
+// retryWhileBusy calls fn again, backing off, as long as qtumd's work queue is full
+func (c *Client) retryWhileBusy(ctx context.Context, method string, req interface{}, fn func() error) error {
+	var err error
+	logger := tracing.Logger(ctx, c.GetLogger())
 	max := int(math.Floor(math.Max(float64(maximumRequestTime/int(maximumBackoff)), 1)))
 	for i := 0; i < max; i++ {
-		resp, err = c.Do(ctx, req)
-		if err != nil {
-			if strings.Contains(err.Error(), ErrQtumWorkQueueDepth.Error()) && i != max-1 {
-				requestString := marshalToString(req)
-				backoffTime := computeBackoff(i, true)
-				c.GetLogger().Log("msg", fmt.Sprintf("QTUM process busy, backing off for %f seconds", backoffTime.Seconds()), "request", requestString)
-				time.Sleep(backoffTime)
-				c.GetLogger().Log("msg", "Retrying QTUM command")
-			} else {
-				if i != 0 {
-					c.GetLogger().Log("msg", fmt.Sprintf("Giving up on QTUM RPC call after %d tries since its busy", i+1))
-				}
+		err = fn()
+		if err == nil {
+			return nil
+		}
+		if strings.Contains(err.Error(), ErrQtumWorkQueueDepth.Error()) && i != max-1 {
+			metrics.QtumdWorkQueueRetries.WithLabelValues(method).Inc()
+			requestString := marshalToString(req)
+			backoffTime := computeBackoff(i, true)
+			logger.Log("msg", fmt.Sprintf("QTUM process busy, backing off for %f seconds", backoffTime.Seconds()), "request", requestString)
+			if err := sleepContext(ctx, backoffTime); err != nil {
 				return err
 			}
+			logger.Log("msg", "Retrying QTUM command")
 		} else {
-			break
+			if i != 0 {
+				logger.Log("msg", fmt.Sprintf("Giving up on QTUM RPC call after %d tries since its busy", i+1))
+			}
+			return err
 		}
 	}
-
-	err = json.Unmarshal(resp.RawResult, result)
-	if err != nil {
-		c.GetDebugLogger().Log("method", method, "params", params, "result", result, "error", err)
-		return errors.Wrap(err, "couldn't unmarshal response result field")
-	}
-	return nil
+	return err
 }
 
-func (c *Client) Do(ctx context.Context, req *JSONRPCRequest) (*SuccessJSONRPCResult, error) {
+func (c *Client) Do(ctx context.Context, req *JSONRPCRequest) (result *SuccessJSONRPCResult, err error) {
+	defer func(start time.Time) {
+		observeRequest(req.Method, start, err)
+	}(time.Now())
+
 	reqBody, err := json.MarshalIndent(req, "", "  ")
 	if err != nil {
 		return nil, err
 	}
 
-	debugLogger := c.GetDebugLogger()
+	debugLogger := tracing.Logger(ctx, c.GetDebugLogger())
 
 	debugLogger.Log("method", req.Method)
 
-	if c.IsDebugEnabled() && !c.GetFlagBool(FLAG_HIDE_QTUMD_LOGS) && c.logWriter != nil {
-		fmt.Fprintf(c.logWriter, "=> qtum RPC request\n%s\n", reqBody)
+	if c.IsDebugEnabled() && !c.GetFlagBool(FLAG_HIDE_QTUMD_LOGS) {
+		c.dumpBody(ctx, "=> qtum RPC request", reqBody)
 	}
 
-	respBody, err := c.do(ctx, bytes.NewReader(reqBody))
+	respBody, err := c.doWithFailover(ctx, req.Method, reqBody)
 	if err != nil {
 		return nil, errors.Wrap(err, "Client#do")
 	}
@@ -173,8 +313,8 @@ func (c *Client) Do(ctx context.Context, req *JSONRPCRequest) (*SuccessJSONRPCRe
 			}
 		}
 
-		if err == nil && c.logWriter != nil {
-			fmt.Fprintf(c.logWriter, "<= qtum RPC response\n%s\n", formattedBodyStr)
+		if err == nil {
+			c.dumpBody(ctx, "<= qtum RPC response", []byte(formattedBodyStr))
 		}
 	}
 
@@ -198,6 +338,34 @@ func (c *Client) Do(ctx context.Context, req *JSONRPCRequest) (*SuccessJSONRPCRe
 	return res, nil
 }
 
+// dumpBody writes a qtumd request or response to the log
+func (c *Client) dumpBody(ctx context.Context, message string, body []byte) {
+	if c.jsonLogs {
+		var value interface{} = json.RawMessage(body)
+		if !json.Valid(body) {
+			// a snipped response
+			value = string(body)
+		}
+		tracing.Logger(ctx, c.GetDebugLogger()).Log("msg", message, "body", value)
+		return
+	}
+
+	if c.logWriter != nil {
+		fmt.Fprintf(c.logWriter, "%s\n%s\n", message, body)
+	}
+}
+
+func observeRequest(method string, start time.Time, err error) {
+	metrics.QtumdDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	result := "success"
+	if err == ErrQtumWorkQueueDepth {
+		result = "work_queue_depth"
+	} else if err != nil {
+		result = "error"
+	}
+	metrics.QtumdRequests.WithLabelValues(method, result).Inc()
+}
+
 func (c *Client) NewRPCRequest(method string, params interface{}) (*JSONRPCRequest, error) {
 	paramsJSON, err := json.Marshal(params)
 	if err != nil {
@@ -206,29 +374,32 @@ func (c *Client) NewRPCRequest(method string, params interface{}) (*JSONRPCReque
 
 	c.idMutex.Lock()
 	c.id = c.id.Add(c.id, c.idStep)
+	id := c.id.String()
 	c.idMutex.Unlock()
 
 	return &JSONRPCRequest{
 		JSONRPC: RPCVersion,
-		ID:      json.RawMessage(`"` + c.id.String() + `"`),
+		ID:      json.RawMessage(`"` + id + `"`),
 		Method:  method,
 		Params:  paramsJSON,
 	}, nil
 }
 
-func (c *Client) do(ctx context.Context, body io.Reader) ([]byte, error) {
+func (c *Client) do(ctx context.Context, rpcURL string, body io.Reader) ([]byte, error) {
 	var req *http.Request
 	var err error
 	if ctx != nil {
-		req, err = http.NewRequestWithContext(ctx, http.MethodPost, c.URL, body)
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, rpcURL, body)
 	} else {
-		req, err = http.NewRequest(http.MethodPost, c.URL, body)
+		req, err = http.NewRequest(http.MethodPost, rpcURL, body)
 	}
 	if err != nil {
 		return nil, err
 	}
-
-	req.Close = true
+	tracing.Inject(ctx, req.Header)
+	if requestID := tracing.RequestIDFromContext(ctx); requestID != "" {
+		req.Header.Set(tracing.RequestIDHeader, requestID)
+	}
 
 	resp, err := c.doer.Do(req)
 	if err != nil {
@@ -326,6 +497,14 @@ func SetLogWriter(logWriter io.Writer) func(*Client) error {
 	}
 }
 
+// SetJSONLogs logs request and response dumps as fields of JSON log lines instead of writing them to the log writer
+func SetJSONLogs(jsonLogs bool) func(*Client) error {
+	return func(c *Client) error {
+		c.jsonLogs = jsonLogs
+		return nil
+	}
+}
+
 func SetLogger(l log.Logger) func(*Client) error {
 	return func(c *Client) error {
 		c.logger = log.WithPrefix(l, "component", "qtum.Client")
@@ -379,6 +558,61 @@ func SetMatureBlockHeight(height *int) func(*Client) error {
 	}
 }
 
+// SetCoalescing lets identical read only requests made at the same time share one request to qtumd
+func SetCoalescing(enabled bool) func(*Client) error {
+	return func(c *Client) error {
+		if !enabled {
+			c.coalescer = nil
+		} else if c.coalescer == nil {
+			c.coalescer = newCoalescer()
+		}
+		return nil
+	}
+}
+
+// SetCoalesceTTL also reuses successful coalesced responses for requests made up to ttl after they complete
+func SetCoalesceTTL(ttl time.Duration) func(*Client) error {
+	return func(c *Client) error {
+		if ttl < 0 {
+			return errors.New("coalesce ttl cannot be negative")
+		}
+		if c.coalescer != nil {
+			c.coalescer.ttl = ttl
+		}
+		return nil
+	}
+}
+
+// SetUpstreams adds more qtumd nodes to spread read only calls over
+// the node passed to NewClient stays the only one that gets wallet and send calls
+func SetUpstreams(rpcURLs []string) func(*Client) error {
+	return func(c *Client) error {
+		c.upstreamURLs = rpcURLs
+		return nil
+	}
+}
+
+// SetUpstreamMaxLag is how many blocks a qtumd node can fall behind before it stops getting reads
+func SetUpstreamMaxLag(blocks int64) func(*Client) error {
+	return func(c *Client) error {
+		if blocks < 0 {
+			return errors.New("upstream max lag cannot be negative")
+		}
+		c.upstreamMaxLag = blocks
+		return nil
+	}
+}
+
+func SetUpstreamCheckInterval(interval time.Duration) func(*Client) error {
+	return func(c *Client) error {
+		if interval <= 0 {
+			return errors.New("upstream check interval must be positive")
+		}
+		c.upstreamCheckInterval = interval
+		return nil
+	}
+}
+
 func SetContext(ctx context.Context) func(*Client) error {
 	return func(c *Client) error {
 		c.ctx = ctx
@@ -410,9 +644,32 @@ func (c *Client) GetErrorLogger() log.Logger {
 }
 
 func (c *Client) IsDebugEnabled() bool {
+	c.settingsMutex.RLock()
+	defer c.settingsMutex.RUnlock()
 	return c.debug
 }
 
+// ReloadDebug turns debug logging on or off while requests are being handled
+func (c *Client) ReloadDebug(debug bool) {
+	c.settingsMutex.Lock()
+	defer c.settingsMutex.Unlock()
+	c.debug = debug
+}
+
+// GetAccounts is Accounts, safe to call while ReloadAccounts replaces them
+func (c *Client) GetAccounts() Accounts {
+	c.settingsMutex.RLock()
+	defer c.settingsMutex.RUnlock()
+	return c.Accounts
+}
+
+// ReloadAccounts replaces the accounts while requests are being handled
+func (c *Client) ReloadAccounts(accounts Accounts) {
+	c.settingsMutex.Lock()
+	defer c.settingsMutex.Unlock()
+	c.Accounts = accounts
+}
+
 func (c *Client) responseBodyToResult(body []byte) (*SuccessJSONRPCResult, error) {
 	var res *JSONRPCResult
 	if err := json.Unmarshal(body, &res); err != nil {
@@ -445,6 +702,23 @@ func computeBackoff(i int, random bool) time.Duration {
 	return time.Duration(backoffTimeInMilliseconds * float64(time.Millisecond))
 }
 
+// sleepContext sleeps for d, returning early with ctx's error if it is done first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if ctx == nil {
+		time.Sleep(d)
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
 func checkRPCURL(u string) error {
 	if u == "" {
 		return errors.New("RPC URL must be set")
@@ -0,0 +1,127 @@
+package qtum
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// read only methods where concurrent identical requests can safely share one response from qtumd
+var coalescableMethods = map[string]bool{
+	MethodGetBlockChainInfo:     true,
+	MethodGetBlockCount:         true,
+	MethodGetBlockHash:          true,
+	MethodGetBlockHeader:        true,
+	MethodGetBlock:              true,
+	MethodGetNetworkInfo:        true,
+	MethodGetPeerInfo:           true,
+	MethodGetStakingInfo:        true,
+	MethodGetRawTransaction:     true,
+	MethodGetTransaction:        true,
+	MethodGetTransactionReceipt: true,
+	MethodGetTransactionOut:     true,
+	MethodDecodeRawTransaction:  true,
+	MethodCallContract:          true,
+	MethodSearchLogs:            true,
+	MethodGetStorage:            true,
+	MethodGetAccountInfo:        true,
+	MethodGetAddressBalance:     true,
+	MethodGetAddressUTXOs:       true,
+	MethodGetHexAddress:         true,
+	MethodFromHexAddress:        true,
+	"getmininginfo":             true,
+	"getdifficulty":             true,
+	"getbestblockhash":          true,
+}
+
+type coalescedCall struct {
+	done   chan struct{}
+	result json.RawMessage
+	err    error
+
+	// callers still waiting for the result, the request is cancelled once they have all given up
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// coalescer makes identical requests issued at the same time share one upstream request
+// with a ttl, successful results are also reused for requests made shortly after
+type coalescer struct {
+	mutex sync.Mutex
+	calls map[string]*coalescedCall
+	ttl   time.Duration
+}
+
+func newCoalescer() *coalescer {
+	return &coalescer{
+		calls: make(map[string]*coalescedCall),
+	}
+}
+
+// do calls fn unless a call with the same key is already in flight, in which case it waits for that call's result
+// fn is given a context that is only cancelled when every caller waiting on it has given up
+func (co *coalescer) do(ctx context.Context, parent context.Context, key string, fn func(context.Context) (json.RawMessage, error)) (result json.RawMessage, shared bool, err error) {
+	co.mutex.Lock()
+	call, shared := co.calls[key]
+	if !shared {
+		if parent == nil {
+			parent = context.Background()
+		}
+		callCtx, cancel := context.WithCancel(parent)
+		call = &coalescedCall{
+			done:   make(chan struct{}),
+			cancel: cancel,
+		}
+		co.calls[key] = call
+		go co.run(callCtx, key, call, fn)
+	}
+	call.waiters++
+	co.mutex.Unlock()
+
+	var cancelled <-chan struct{}
+	if ctx != nil {
+		cancelled = ctx.Done()
+	}
+
+	select {
+	case <-call.done:
+		return call.result, shared, call.err
+	case <-cancelled:
+		co.mutex.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			call.cancel()
+			co.forgetLocked(key, call)
+		}
+		co.mutex.Unlock()
+		return nil, shared, ctx.Err()
+	}
+}
+
+func (co *coalescer) run(ctx context.Context, key string, call *coalescedCall, fn func(context.Context) (json.RawMessage, error)) {
+	call.result, call.err = fn(ctx)
+	close(call.done)
+
+	co.mutex.Lock()
+	defer co.mutex.Unlock()
+
+	if call.err != nil || co.ttl <= 0 {
+		call.cancel()
+		co.forgetLocked(key, call)
+		return
+	}
+
+	time.AfterFunc(co.ttl, func() {
+		co.mutex.Lock()
+		defer co.mutex.Unlock()
+		call.cancel()
+		co.forgetLocked(key, call)
+	})
+}
+
+func (co *coalescer) forgetLocked(key string, call *coalescedCall) {
+	if co.calls[key] == call {
+		delete(co.calls, key)
+	}
+}
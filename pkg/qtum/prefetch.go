@@ -0,0 +1,104 @@
+package qtum
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// prefetchedContextKey is where Prefetch keeps the results of its calls
+type prefetchedContextKey struct{}
+
+type prefetchedResult struct {
+	result json.RawMessage
+	err    error
+}
+
+// Prefetch makes calls in one batch, the same calls made later with the returned context are answered with their
+// results instead of going to qtumd
+// paths that make the same few calls for each of many items prefetch them, so the items take one round trip instead of one each
+// the calls' Result and Err are set like RequestBatch's, so what is needed next can be prefetched from them. If the batch
+// fails every call's Err is set and nothing is prefetched, the calls are then made one by one as usual
+func (c *Client) Prefetch(ctx context.Context, calls []*BatchRequest) context.Context {
+	if len(calls) == 0 {
+		return ctx
+	}
+	results := make([]json.RawMessage, len(calls))
+	requests := make([]*BatchRequest, len(calls))
+	for i, call := range calls {
+		requests[i] = &BatchRequest{Method: call.Method, Params: call.Params, Result: &results[i]}
+	}
+	if err := c.RequestBatch(ctx, requests); err != nil {
+		c.GetDebugLogger().Log("msg", "Failed to prefetch qtumd calls", "calls", len(calls), "err", err)
+		for _, call := range calls {
+			call.Err = err
+		}
+		return ctx
+	}
+
+	parent, _ := ctx.Value(prefetchedContextKey{}).(map[string]prefetchedResult)
+	prefetched := make(map[string]prefetchedResult, len(parent)+len(calls))
+	for key, result := range parent {
+		prefetched[key] = result
+	}
+	for i, call := range calls {
+		call.Err = requests[i].Err
+		if call.Err == nil && call.Result != nil {
+			if err := json.Unmarshal(results[i], call.Result); err != nil {
+				call.Err = errors.Wrap(err, "couldn't unmarshal response result field")
+			}
+		}
+		if key, err := prefetchKey(call.Method, call.Params); err == nil {
+			prefetched[key] = prefetchedResult{result: results[i], err: requests[i].Err}
+		}
+	}
+	return context.WithValue(ctx, prefetchedContextKey{}, prefetched)
+}
+
+// prefetched is the result of the call if it was prefetched for ctx
+func prefetched(ctx context.Context, method string, params interface{}) (prefetchedResult, bool) {
+	if ctx == nil {
+		return prefetchedResult{}, false
+	}
+	results, _ := ctx.Value(prefetchedContextKey{}).(map[string]prefetchedResult)
+	if len(results) == 0 {
+		return prefetchedResult{}, false
+	}
+	key, err := prefetchKey(method, params)
+	if err != nil {
+		return prefetchedResult{}, false
+	}
+	result, ok := results[key]
+	return result, ok
+}
+
+func prefetchKey(method string, params interface{}) (string, error) {
+	paramsJSON, err := json.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+	return method + ":" + string(paramsJSON), nil
+}
+
+// the calls made by the Method functions of the same names, for prefetching them
+
+func GetTransactionCall(txID string) *BatchRequest {
+	return &BatchRequest{Method: MethodGetTransaction, Params: &GetTransactionRequest{TxID: txID}, Result: new(GetTransactionResponse)}
+}
+
+func GetRawTransactionCall(txID string, hexEncoded bool) *BatchRequest {
+	return &BatchRequest{Method: MethodGetRawTransaction, Params: &GetRawTransactionRequest{TxID: txID, Verbose: !hexEncoded}, Result: new(GetRawTransactionResponse)}
+}
+
+func DecodeRawTransactionCall(hex string) *BatchRequest {
+	return &BatchRequest{Method: MethodDecodeRawTransaction, Params: DecodeRawTransactionRequest(hex), Result: new(DecodedRawTransactionResponse)}
+}
+
+func GetTransactionReceiptCall(txHash string) *BatchRequest {
+	return &BatchRequest{Method: MethodGetTransactionReceipt, Params: GetTransactionReceiptRequest(txHash), Result: new(GetTransactionReceiptResponse)}
+}
+
+func GetBlockCall(hash string) *BatchRequest {
+	return &BatchRequest{Method: MethodGetBlock, Params: &GetBlockRequest{Hash: hash}, Result: new(GetBlockResponse)}
+}
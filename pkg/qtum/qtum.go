@@ -19,6 +19,9 @@ type Qtum struct {
 	queryingChain    bool
 	queryingComplete chan bool
 	chain            string
+
+	capabilitiesMutex sync.RWMutex
+	capabilities      *Capabilities
 }
 
 const (
@@ -60,7 +63,7 @@ func (c *Qtum) detectChain() {
 
 	// detect chain we are pointing at
 	for i := 0; ; i++ {
-		blockchainInfo, err := c.GetBlockChainInfo()
+		blockchainInfo, err := c.GetBlockChainInfo(c.ctx)
 		if err == nil {
 			chain := strings.ToLower(blockchainInfo.Chain)
 			if utils.InStrSlice(AllChains, chain) {
@@ -124,7 +127,7 @@ func (c *Qtum) GenerateIfPossible() {
 		return
 	}
 
-	if _, generateErr := c.Generate(1, nil); generateErr != nil {
+	if _, generateErr := c.Generate(c.ctx, 1, nil); generateErr != nil {
 		c.GetErrorLogger().Log("Error generating new block", generateErr)
 	}
 }
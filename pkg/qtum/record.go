@@ -0,0 +1,330 @@
+package qtum
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+)
+
+// RecordedCall is a qtumd request and the response to it, a fixture file is a line of JSON for each one
+// batches are recorded as a call for each request in them, so replaying doesn't depend on how requests were batched
+type RecordedCall struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Status int             `json:"status"`
+	// the response as qtumd sent it, its id is replaced with the replayed request's
+	// a response that isn't JSON, like the body of a 401, is kept as a string and replayed as it is
+	Response json.RawMessage `json:"response"`
+}
+
+// SetRecording appends every request made to qtumd and the response to it to the fixture file at path
+func SetRecording(path string) func(*Client) error {
+	return func(c *Client) error {
+		c.recordingPath = path
+		return nil
+	}
+}
+
+// RecordingDoer passes requests on to another doer and records them with their responses
+// failing to record is logged, it never fails the request
+type RecordingDoer struct {
+	doer   doer
+	logger log.Logger
+
+	mutex sync.Mutex
+	out   io.Writer
+}
+
+func NewRecordingDoer(d doer, out io.Writer, logger log.Logger) *RecordingDoer {
+	return &RecordingDoer{doer: d, out: out, logger: logger}
+}
+
+// OpenRecordingDoer records to the fixture file at path, adding to it if it already exists
+func OpenRecordingDoer(d doer, path string, logger log.Logger) (*RecordingDoer, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to open recording")
+	}
+	return NewRecordingDoer(d, file, logger), nil
+}
+
+func (r *RecordingDoer) Do(req *http.Request) (*http.Response, error) {
+	var requestBody []byte
+	if req.Body != nil {
+		var err error
+		requestBody, err = ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(requestBody))
+	}
+
+	resp, err := r.doer.Do(req)
+	if err != nil {
+		// there is nothing to replay without a response
+		return nil, err
+	}
+
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(responseBody))
+
+	calls, err := recordedCalls(requestBody, resp.StatusCode, responseBody)
+	if err == nil {
+		err = r.write(calls)
+	}
+	if err != nil {
+		r.logger.Log("msg", "Failed to record qtumd response", "err", err)
+	}
+
+	return resp, nil
+}
+
+func (r *RecordingDoer) write(calls []RecordedCall) error {
+	var lines bytes.Buffer
+	for _, call := range calls {
+		line, err := json.Marshal(call)
+		if err != nil {
+			return err
+		}
+		lines.Write(line)
+		lines.WriteByte('\n')
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.out == nil {
+		// closed, responses to calls still in flight at shutdown are dropped
+		return nil
+	}
+	if _, err := r.out.Write(lines.Bytes()); err != nil {
+		return errors.Wrap(err, "Failed to write recording")
+	}
+	return nil
+}
+
+// Close stops recording and closes the fixture file if it's one
+func (r *RecordingDoer) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	out := r.out
+	r.out = nil
+	if closer, ok := out.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// recordedCalls splits a request and its response into a call for each request in a batch
+func recordedCalls(requestBody []byte, status int, responseBody []byte) ([]RecordedCall, error) {
+	if !isBatch(requestBody) {
+		var req JSONRPCRequest
+		if err := json.Unmarshal(requestBody, &req); err != nil {
+			return nil, err
+		}
+		response := json.RawMessage(responseBody)
+		if !json.Valid(responseBody) {
+			var err error
+			if response, err = json.Marshal(string(responseBody)); err != nil {
+				return nil, err
+			}
+		}
+		return []RecordedCall{{Method: req.Method, Params: req.Params, Status: status, Response: response}}, nil
+	}
+
+	var reqs []JSONRPCRequest
+	if err := json.Unmarshal(requestBody, &reqs); err != nil {
+		return nil, err
+	}
+	var responses []json.RawMessage
+	if err := json.Unmarshal(responseBody, &responses); err != nil {
+		return nil, errors.Wrap(err, "batch response isn't an array")
+	}
+
+	// batch responses can come back in any order
+	byID := make(map[string]json.RawMessage, len(responses))
+	for _, response := range responses {
+		var withID struct {
+			ID json.RawMessage `json:"id"`
+		}
+		if err := json.Unmarshal(response, &withID); err != nil {
+			return nil, err
+		}
+		byID[string(withID.ID)] = response
+	}
+
+	calls := make([]RecordedCall, 0, len(reqs))
+	for _, req := range reqs {
+		response, ok := byID[string(req.ID)]
+		if !ok {
+			return nil, errors.Errorf("no response to %s in the batch", req.Method)
+		}
+		calls = append(calls, RecordedCall{Method: req.Method, Params: req.Params, Status: status, Response: response})
+	}
+	return calls, nil
+}
+
+func isBatch(body []byte) bool {
+	body = bytes.TrimSpace(body)
+	return len(body) != 0 && body[0] == '['
+}
+
+// ReplayDoer answers requests with the responses in a fixture file instead of going to qtumd
+// calls with the same method and params are answered in the order they were recorded, the last one is repeated once they run out
+type ReplayDoer struct {
+	mutex sync.Mutex
+	calls map[string][]RecordedCall
+}
+
+// LoadReplayDoer replays the fixture file at path
+func LoadReplayDoer(path string) (*ReplayDoer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to open recording")
+	}
+	defer file.Close()
+
+	replay, err := NewReplayDoer(file)
+	if err != nil {
+		return nil, errors.Wrap(err, path)
+	}
+	return replay, nil
+}
+
+func NewReplayDoer(in io.Reader) (*ReplayDoer, error) {
+	replay := &ReplayDoer{calls: make(map[string][]RecordedCall)}
+
+	scanner := bufio.NewScanner(in)
+	// blocks can be large
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var call RecordedCall
+		if err := json.Unmarshal(scanner.Bytes(), &call); err != nil {
+			return nil, errors.Wrapf(err, "line %d", line)
+		}
+		key, err := replayKey(call.Method, call.Params)
+		if err != nil {
+			return nil, errors.Wrapf(err, "line %d", line)
+		}
+		replay.calls[key] = append(replay.calls[key], call)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return replay, nil
+}
+
+// replayKey is the same for params that only differ in formatting
+func replayKey(method string, params json.RawMessage) (string, error) {
+	canonical := []byte("null")
+	if len(bytes.TrimSpace(params)) != 0 {
+		decoder := json.NewDecoder(bytes.NewReader(params))
+		decoder.UseNumber()
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return "", errors.Wrap(err, "invalid params")
+		}
+		var err error
+		if canonical, err = json.Marshal(value); err != nil {
+			return "", err
+		}
+	}
+	return method + " " + string(canonical), nil
+}
+
+func (r *ReplayDoer) Do(req *http.Request) (*http.Response, error) {
+	requestBody, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isBatch(requestBody) {
+		var rpcReq JSONRPCRequest
+		if err := json.Unmarshal(requestBody, &rpcReq); err != nil {
+			return nil, err
+		}
+		call, response, err := r.replay(rpcReq)
+		if err != nil {
+			return nil, err
+		}
+		return replayResponse(call.Status, response), nil
+	}
+
+	var rpcReqs []JSONRPCRequest
+	if err := json.Unmarshal(requestBody, &rpcReqs); err != nil {
+		return nil, err
+	}
+	responses := make([]json.RawMessage, 0, len(rpcReqs))
+	for _, rpcReq := range rpcReqs {
+		_, response, err := r.replay(rpcReq)
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, response)
+	}
+	responseBody, err := json.Marshal(responses)
+	if err != nil {
+		return nil, err
+	}
+	// qtumd answers batches with 200 even if some of the requests failed
+	return replayResponse(http.StatusOK, responseBody), nil
+}
+
+// replay is the next recorded response to req, with req's id
+func (r *ReplayDoer) replay(req JSONRPCRequest) (RecordedCall, json.RawMessage, error) {
+	key, err := replayKey(req.Method, req.Params)
+	if err != nil {
+		return RecordedCall{}, nil, err
+	}
+
+	r.mutex.Lock()
+	calls := r.calls[key]
+	if len(calls) == 0 {
+		r.mutex.Unlock()
+		return RecordedCall{}, nil, errors.Errorf("No recorded response to %s", key)
+	}
+	call := calls[0]
+	if len(calls) > 1 {
+		r.calls[key] = calls[1:]
+	}
+	r.mutex.Unlock()
+
+	var raw string
+	if json.Unmarshal(call.Response, &raw) == nil {
+		return call, json.RawMessage(raw), nil
+	}
+
+	var response map[string]json.RawMessage
+	if err := json.Unmarshal(call.Response, &response); err != nil {
+		return RecordedCall{}, nil, errors.Wrapf(err, "recorded response to %s", req.Method)
+	}
+	response["id"] = req.ID
+	body, err := json.Marshal(response)
+	if err != nil {
+		return RecordedCall{}, nil, err
+	}
+	return call, body, nil
+}
+
+func replayResponse(status int, body []byte) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Body:       ioutil.NopCloser(bytes.NewReader(body)),
+	}
+}
@@ -150,6 +150,7 @@ type (
 			} `json:"bip9"`
 		} `json:"softforks"`
 		Verificationprogress float64 `json:"verificationprogress"`
+		Initialblockdownload bool    `json:"initialblockdownload"`
 	}
 )
 
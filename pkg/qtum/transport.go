@@ -0,0 +1,90 @@
+package qtum
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TransportConfig tunes the HTTP connections made to qtumd
+type TransportConfig struct {
+	// reuse connections between requests instead of opening a new one every time
+	KeepAlive bool
+	// idle connections kept open across every qtumd node, and for each node
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	// 0 means no limit, requests over the limit wait for a connection to free up
+	MaxConnsPerHost int
+	DialTimeout     time.Duration
+	// how long to wait for qtumd to start answering, 0 waits forever
+	// waitforlogs blocks until there are new logs so this needs to be longer than its timeout
+	ResponseTimeout time.Duration
+	IdleConnTimeout time.Duration
+	// PEM file with extra certificate authorities to trust when reaching qtumd over https
+	CAFile string
+}
+
+var DefaultTransportConfig = TransportConfig{
+	KeepAlive:           true,
+	MaxIdleConns:        100,
+	MaxIdleConnsPerHost: 16,
+	DialTimeout:         10 * time.Second,
+	// qtumd closes connections that are idle for 30 seconds (-rpcservertimeout)
+	// drop them first so a request is never sent on a connection qtumd is closing
+	IdleConnTimeout: 20 * time.Second,
+}
+
+func NewTransport(config TransportConfig) (*http.Transport, error) {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   config.DialTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		DisableKeepAlives:     !config.KeepAlive,
+		MaxIdleConns:          config.MaxIdleConns,
+		MaxIdleConnsPerHost:   config.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       config.MaxConnsPerHost,
+		IdleConnTimeout:       config.IdleConnTimeout,
+		ResponseHeaderTimeout: config.ResponseTimeout,
+		TLSHandshakeTimeout:   10 * time.Second,
+	}
+
+	if config.CAFile != "" {
+		pem, err := ioutil.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to read qtumd CA file %s", config.CAFile)
+		}
+
+		roots, err := x509.SystemCertPool()
+		if err != nil || roots == nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("No certificates found in qtumd CA file %s", config.CAFile)
+		}
+
+		transport.TLSClientConfig = &tls.Config{
+			RootCAs: roots,
+		}
+	}
+
+	return transport, nil
+}
+
+// SetTransport sends requests to qtumd over a transport built from config
+func SetTransport(config TransportConfig) func(*Client) error {
+	return func(c *Client) error {
+		transport, err := NewTransport(config)
+		if err != nil {
+			return err
+		}
+		c.doer = &http.Client{Transport: transport}
+		return nil
+	}
+}
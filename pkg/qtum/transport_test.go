@@ -0,0 +1,106 @@
+package qtum
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func newBlockCountServer(tls bool, connections *int32) *httptest.Server {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jsonrpc":"1.0","id":"1","result":42}`))
+	}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew && connections != nil {
+			atomic.AddInt32(connections, 1)
+		}
+	}
+	if tls {
+		server.StartTLS()
+	} else {
+		server.Start()
+	}
+	return server
+}
+
+func rpcURLFor(server *httptest.Server) string {
+	return strings.Replace(server.URL, "://", "://user:pass@", 1)
+}
+
+func TestTransportReusesConnections(t *testing.T) {
+	var connections int32
+	server := newBlockCountServer(false, &connections)
+	defer server.Close()
+
+	client, err := NewClient(false, rpcURLFor(server), SetTransport(DefaultTransportConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 5; i++ {
+		var result int64
+		if err := client.Request(MethodGetBlockCount, []interface{}{}, &result); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if connections := atomic.LoadInt32(&connections); connections != 1 {
+		t.Errorf("expected 1 connection to qtumd, got %d", connections)
+	}
+}
+
+func TestTransportTrustsCAFile(t *testing.T) {
+	server := newBlockCountServer(true, nil)
+	defer server.Close()
+
+	var result int64
+	client, err := NewClient(false, rpcURLFor(server))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Request(MethodGetBlockCount, []interface{}{}, &result); err == nil {
+		t.Fatal("expected the self signed certificate to be rejected without a CA file")
+	}
+
+	caFile, err := ioutil.TempFile("", "janus-qtumd-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(caFile.Name())
+	pem.Encode(caFile, &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	caFile.Close()
+
+	config := DefaultTransportConfig
+	config.CAFile = caFile.Name()
+	client, err = NewClient(false, rpcURLFor(server), SetTransport(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Request(MethodGetBlockCount, []interface{}{}, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result != 42 {
+		t.Errorf("expected 42, got %d", result)
+	}
+}
+
+func TestTransportRejectsEmptyCAFile(t *testing.T) {
+	caFile, err := ioutil.TempFile("", "janus-qtumd-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(caFile.Name())
+	caFile.Close()
+
+	config := DefaultTransportConfig
+	config.CAFile = caFile.Name()
+	if _, err := NewTransport(config); err == nil {
+		t.Error("expected a CA file without certificates to be rejected")
+	}
+}
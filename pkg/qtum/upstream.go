@@ -0,0 +1,360 @@
+package qtum
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math/rand"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/qtumproject/janus/pkg/metrics"
+	"github.com/qtumproject/janus/pkg/tracing"
+)
+
+var (
+	// DefaultUpstreamMaxLag is how many blocks a qtumd node can be behind the highest node before reads stop going to it
+	DefaultUpstreamMaxLag int64 = 2
+	// DefaultUpstreamCheckInterval is how often every qtumd node's block height is checked
+	DefaultUpstreamCheckInterval = 5 * time.Second
+
+	upstreamCheckTimeout = 5 * time.Second
+	// nodes failing more than this fraction of recent requests stop getting reads
+	upstreamMaxErrorRate = 0.5
+	// weight of the latest request in the latency and error rate moving averages
+	upstreamSmoothing = 0.2
+)
+
+var ErrNoUpstreamChecks = errors.New("qtumd node hasn't been checked yet")
+
+// read only methods that don't depend on a node's wallet, these can go to any healthy qtumd node
+// everything else (wallet calls, sending transactions, waitforlogs) sticks to the first node
+var loadBalancedMethods = map[string]bool{
+	MethodGetBlockChainInfo:     true,
+	MethodGetBlockCount:         true,
+	MethodGetBlockHash:          true,
+	MethodGetBlockHeader:        true,
+	MethodGetBlock:              true,
+	MethodGetNetworkInfo:        true,
+	MethodGetPeerInfo:           true,
+	MethodGetRawTransaction:     true,
+	MethodGetTransactionReceipt: true,
+	MethodGetTransactionOut:     true,
+	MethodDecodeRawTransaction:  true,
+	MethodCallContract:          true,
+	MethodSearchLogs:            true,
+	MethodGetStorage:            true,
+	MethodGetAccountInfo:        true,
+	MethodGetAddressBalance:     true,
+	MethodGetAddressUTXOs:       true,
+	MethodGetHexAddress:         true,
+	MethodFromHexAddress:        true,
+	"getmininginfo":             true,
+	"getdifficulty":             true,
+	"getbestblockhash":          true,
+}
+
+// UpstreamStatus is the health of one qtumd node
+type UpstreamStatus struct {
+	URL       string  `json:"url"`
+	Wallet    bool    `json:"wallet"`
+	Healthy   bool    `json:"healthy"`
+	Height    int64   `json:"height"`
+	Lag       int64   `json:"lag"`
+	LatencyMs int64   `json:"latencyMs"`
+	ErrorRate float64 `json:"errorRate"`
+	Error     string  `json:"error,omitempty"`
+}
+
+type upstream struct {
+	url string
+	// url without credentials, for logs and status
+	name string
+	// wallet and send calls only go to this node
+	wallet bool
+
+	mutex     sync.Mutex
+	checked   bool
+	checkErr  error
+	height    int64
+	latency   time.Duration
+	errorRate float64
+}
+
+func newUpstream(rpcURL string, wallet bool) (*upstream, error) {
+	if err := checkRPCURL(rpcURL); err != nil {
+		return nil, err
+	}
+
+	parsed, err := url.Parse(rpcURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to parse rpc url")
+	}
+	parsed.User = nil
+
+	return &upstream{
+		url:    rpcURL,
+		name:   parsed.String(),
+		wallet: wallet,
+	}, nil
+}
+
+// observe records how a request to this node went
+func (u *upstream) observe(latency time.Duration, failed bool) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	failure := 0.0
+	if failed {
+		failure = 1
+	}
+	u.errorRate = u.errorRate + upstreamSmoothing*(failure-u.errorRate)
+	if !failed {
+		if u.latency == 0 {
+			u.latency = latency
+		} else {
+			u.latency = u.latency + time.Duration(upstreamSmoothing*float64(latency-u.latency))
+		}
+	}
+}
+
+func (u *upstream) recordCheck(height int64, latency time.Duration, err error) {
+	u.observe(latency, err != nil)
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.checked = true
+	u.checkErr = err
+	if err == nil {
+		u.height = height
+	}
+}
+
+func (u *upstream) status(bestHeight int64, maxLag int64) UpstreamStatus {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	status := UpstreamStatus{
+		URL:       u.name,
+		Wallet:    u.wallet,
+		Height:    u.height,
+		Lag:       bestHeight - u.height,
+		LatencyMs: u.latency.Milliseconds(),
+		ErrorRate: u.errorRate,
+	}
+
+	switch {
+	case !u.checked:
+		status.Error = ErrNoUpstreamChecks.Error()
+	case u.checkErr != nil:
+		status.Error = u.checkErr.Error()
+	case status.Lag > maxLag:
+		status.Error = "stalled, behind the highest qtumd node"
+	case u.errorRate > upstreamMaxErrorRate:
+		status.Error = "too many failed requests"
+	default:
+		status.Healthy = true
+	}
+
+	return status
+}
+
+// score is lower for nodes that should get more reads
+func (u *upstream) score() float64 {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	return float64(u.latency) * (1 + 10*u.errorRate)
+}
+
+// upstreamPool routes requests between several qtumd nodes
+// the first node is the wallet node, wallet and send calls always go to it
+type upstreamPool struct {
+	upstreams     []*upstream
+	maxLag        int64
+	checkInterval time.Duration
+}
+
+func newUpstreamPool(urls []string, maxLag int64, checkInterval time.Duration) (*upstreamPool, error) {
+	pool := &upstreamPool{
+		maxLag:        maxLag,
+		checkInterval: checkInterval,
+	}
+	for i, rpcURL := range urls {
+		u, err := newUpstream(rpcURL, i == 0)
+		if err != nil {
+			return nil, err
+		}
+		pool.upstreams = append(pool.upstreams, u)
+	}
+	return pool, nil
+}
+
+func (p *upstreamPool) statuses() []UpstreamStatus {
+	bestHeight := int64(0)
+	for _, u := range p.upstreams {
+		u.mutex.Lock()
+		if u.checkErr == nil && u.height > bestHeight {
+			bestHeight = u.height
+		}
+		u.mutex.Unlock()
+	}
+
+	statuses := make([]UpstreamStatus, len(p.upstreams))
+	for i, u := range p.upstreams {
+		statuses[i] = u.status(bestHeight, p.maxLag)
+	}
+	return statuses
+}
+
+// candidates returns the nodes to try for method, in order
+func (p *upstreamPool) candidates(method string) []*upstream {
+	if !loadBalancedMethods[method] {
+		return p.upstreams[:1]
+	}
+
+	statuses := p.statuses()
+	healthy := []*upstream{}
+	unhealthy := []*upstream{}
+	for i, u := range p.upstreams {
+		if statuses[i].Healthy {
+			healthy = append(healthy, u)
+		} else {
+			unhealthy = append(unhealthy, u)
+		}
+	}
+
+	sortByScore(healthy)
+	if len(healthy) > 1 {
+		// pick the better of two random nodes so load is spread out without piling onto the single fastest node
+		i, j := rand.Intn(len(healthy)), rand.Intn(len(healthy))
+		if j < i {
+			i = j
+		}
+		healthy[0], healthy[i] = healthy[i], healthy[0]
+	}
+
+	// unhealthy nodes are a last resort, their health might just be out of date
+	sortByScore(unhealthy)
+	return append(healthy, unhealthy...)
+}
+
+func sortByScore(upstreams []*upstream) {
+	scores := make(map[*upstream]float64, len(upstreams))
+	for _, u := range upstreams {
+		scores[u] = u.score()
+	}
+	sort.SliceStable(upstreams, func(i, j int) bool {
+		return scores[upstreams[i]] < scores[upstreams[j]]
+	})
+}
+
+func (c *Client) watchUpstreams() {
+	ctx := c.GetContext()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	ticker := time.NewTicker(c.upstreams.checkInterval)
+	defer ticker.Stop()
+
+	for {
+		c.checkUpstreams(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkUpstreams gets every node's block height so nodes that are down or stalled stop getting reads
+func (c *Client) checkUpstreams(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, u := range c.upstreams.upstreams {
+		wg.Add(1)
+		go func(u *upstream) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, upstreamCheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			height, err := c.getUpstreamBlockCount(ctx, u)
+			u.recordCheck(height, time.Since(start), err)
+		}(u)
+	}
+	wg.Wait()
+
+	for _, status := range c.upstreams.statuses() {
+		healthy := 0.0
+		if status.Healthy {
+			healthy = 1
+		} else {
+			c.GetDebugLogger().Log("msg", "qtumd node is unhealthy", "upstream", status.URL, "err", status.Error)
+		}
+		metrics.QtumdUpstreamHealthy.WithLabelValues(status.URL).Set(healthy)
+		metrics.QtumdUpstreamHeight.WithLabelValues(status.URL).Set(float64(status.Height))
+	}
+}
+
+func (c *Client) getUpstreamBlockCount(ctx context.Context, u *upstream) (int64, error) {
+	req, err := c.NewRPCRequest(MethodGetBlockCount, []interface{}{})
+	if err != nil {
+		return 0, err
+	}
+	reqBody, err := json.Marshal(req)
+	if err != nil {
+		return 0, err
+	}
+
+	respBody, err := c.do(ctx, u.url, bytes.NewReader(reqBody))
+	if err != nil {
+		return 0, err
+	}
+	res, err := c.responseBodyToResult(respBody)
+	if err != nil {
+		return 0, err
+	}
+
+	var height int64
+	if err := json.Unmarshal(res.RawResult, &height); err != nil {
+		return 0, errors.Wrap(err, "couldn't unmarshal getblockcount result")
+	}
+	return height, nil
+}
+
+// doWithFailover sends reqBody to the best qtumd node for method, moving on to the next one if a node doesn't answer
+func (c *Client) doWithFailover(ctx context.Context, method string, reqBody []byte) ([]byte, error) {
+	if c.upstreams == nil {
+		return c.do(ctx, c.URL, bytes.NewReader(reqBody))
+	}
+
+	var respBody []byte
+	var err error
+	for _, u := range c.upstreams.candidates(method) {
+		start := time.Now()
+		respBody, err = c.do(ctx, u.url, bytes.NewReader(reqBody))
+		failed := err != nil || string(respBody) == ErrQtumWorkQueueDepth.Error()
+		u.observe(time.Since(start), failed)
+		if !failed {
+			return respBody, nil
+		}
+		if ctx != nil && ctx.Err() != nil {
+			break
+		}
+		tracing.Logger(ctx, c.GetDebugLogger()).Log("msg", "qtumd node failed, trying the next one", "upstream", u.name, "method", method, "err", err)
+	}
+
+	return respBody, err
+}
+
+// UpstreamStatuses returns the health of every qtumd node, or nil if there is only one
+func (c *Client) UpstreamStatuses() []UpstreamStatus {
+	if c.upstreams == nil {
+		return nil
+	}
+	return c.upstreams.statuses()
+}
@@ -0,0 +1,798 @@
+package qtumtest
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/pkg/errors"
+	"github.com/qtumproject/janus/pkg/qtum"
+	"github.com/qtumproject/janus/pkg/utils"
+)
+
+const (
+	// SatoshisPerQtum is how many satoshis are in a QTUM
+	SatoshisPerQtum = 100000000
+	// BlockReward is what the coinbase of every block pays the address it is mined to
+	BlockReward = 20000 * SatoshisPerQtum
+	// Fee is what the wallet pays for every transaction it sends
+	Fee = 100000
+	// GenesisTime is the genesis block's timestamp, every block after it is BlockSpacing seconds later
+	GenesisTime  = 1600000000
+	BlockSpacing = 32
+
+	// DefaultGasPrice is in satoshis
+	DefaultGasPrice = 40
+
+	// the codes qtumd answers errors with
+	errMisc                 = -1
+	errType                 = -3
+	errInvalidAddress       = -5
+	errInsufficientFunds    = -6
+	errInvalidParameter     = -8
+	errDeserialization      = -22
+	errVerify               = -25
+	errVerifyRejected       = -26
+	errVerifyAlreadyInChain = -27
+)
+
+// Error is an error qtumd would answer with
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d: %s", e.Code, e.Message)
+}
+
+func errorf(code int, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Input spends output Vout of transaction TxID
+type Input struct {
+	TxID string `json:"txid"`
+	Vout int    `json:"vout"`
+}
+
+// Output pays Value satoshis to Address, or calls or creates a contract when Contract is set
+type Output struct {
+	Address  string          `json:"address,omitempty"`
+	Value    int64           `json:"value"`
+	Contract *ContractOutput `json:"contract,omitempty"`
+}
+
+// ContractOutput is an OP_CALL output, or an OP_CREATE output when Address is empty
+type ContractOutput struct {
+	Address string `json:"address,omitempty"`
+	// Sender is the hex address in the OP_SENDER part of the script, empty without one
+	Sender   string `json:"sender,omitempty"`
+	Data     string `json:"data"`
+	GasLimit int64  `json:"gasLimit"`
+	// GasPrice is in satoshis
+	GasPrice int64 `json:"gasPrice"`
+}
+
+// Transaction is a transaction on the fake chain, its Hex is what decoderawtransaction accepts and isn't a real Qtum transaction
+type Transaction struct {
+	ID       string
+	Hex      string
+	Coinbase bool
+	Inputs   []Input
+	Outputs  []Output
+}
+
+// rawTransaction is what a transaction's Hex encodes
+type rawTransaction struct {
+	Coinbase bool     `json:"coinbase,omitempty"`
+	Nonce    int64    `json:"nonce"`
+	Inputs   []Input  `json:"inputs"`
+	Outputs  []Output `json:"outputs"`
+}
+
+func newTransaction(raw rawTransaction) *Transaction {
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to encode transaction: %s", err))
+	}
+	return &Transaction{
+		ID:       hashHex(encoded),
+		Hex:      hex.EncodeToString(encoded),
+		Coinbase: raw.Coinbase,
+		Inputs:   raw.Inputs,
+		Outputs:  raw.Outputs,
+	}
+}
+
+// DecodeTransaction decodes the Hex of a transaction
+func DecodeTransaction(txHex string) (*Transaction, error) {
+	encoded, err := hex.DecodeString(utils.RemoveHexPrefix(txHex))
+	if err != nil {
+		return nil, errorf(errDeserialization, "TX decode failed")
+	}
+	var raw rawTransaction
+	if err := json.Unmarshal(encoded, &raw); err != nil {
+		return nil, errorf(errDeserialization, "TX decode failed")
+	}
+	tx := newTransaction(raw)
+	if tx.Hex != strings.ToLower(utils.RemoveHexPrefix(txHex)) {
+		// not encoded the way we encode them, it would get a different id
+		return nil, errorf(errDeserialization, "TX decode failed")
+	}
+	return tx, nil
+}
+
+// Block is a block on the fake chain
+type Block struct {
+	Hash         string
+	Height       int64
+	Time         int64
+	Previous     string
+	Transactions []*Transaction
+}
+
+// Call is a contract call, or the creation of the contract at To when Create is set
+type Call struct {
+	// From is a hex address
+	From   string
+	To     string
+	Create bool
+	// Data is the hex call data, or the bytecode of a creation
+	Data     string
+	Value    int64
+	GasLimit int64
+}
+
+// Result is what running a Call does
+type Result struct {
+	Output  string
+	Logs    []qtum.Log
+	GasUsed uint64
+	// Excepted is "None" if the call succeeded, e.g. "Revert" if it didn't
+	Excepted        string
+	ExceptedMessage string
+	// Storage is written to the contract's storage when a transaction making the call is mined
+	Storage map[string]string
+}
+
+// Executor runs contract calls in place of the EVM
+type Executor func(contract *Contract, call Call) Result
+
+// DefaultExecutor succeeds without doing anything, contract creations deploy their bytecode as it is
+func DefaultExecutor(contract *Contract, call Call) Result {
+	return Result{GasUsed: 21000, Excepted: "None"}
+}
+
+// Contract is a contract deployed on the fake chain
+type Contract struct {
+	Address string
+	Code    string
+	Storage map[string]string
+}
+
+func (c *Contract) copy() *Contract {
+	storage := make(map[string]string, len(c.Storage))
+	for slot, value := range c.Storage {
+		storage[slot] = value
+	}
+	return &Contract{Address: c.Address, Code: c.Code, Storage: storage}
+}
+
+// effect is what a mined contract output did, kept so the contracts can be rebuilt after a reorg
+type effect struct {
+	created *Contract
+	address string
+	storage map[string]string
+}
+
+// UTXO is an unspent output
+type UTXO struct {
+	Input
+	Output
+	// Height is -1 for outputs of transactions in the mempool
+	Height   int64
+	Coinbase bool
+}
+
+// Chain is an in-memory chain with a wallet that owns every address
+// blocks are only added by mining them, so the chain can be grown and reorganized as tests need
+type Chain struct {
+	mutex  sync.Mutex
+	name   string
+	prefix byte
+
+	blocks  []*Block
+	mempool []*Transaction
+	// the block each mined transaction is in
+	mined     map[string]*Block
+	receipts  map[string][]qtum.TransactionReceipt
+	effects   map[string][]effect
+	contracts map[string]*Contract
+	executor  Executor
+
+	// blocks ever mined, so blocks that replace reorganized ones get new hashes
+	minedCount int64
+	nonce      int64
+	// closed and replaced whenever the chain changes
+	changed chan struct{}
+}
+
+// NewChain creates a chain with a genesis block, name is qtum.ChainMain, qtum.ChainTest or qtum.ChainRegTest
+func NewChain(name string) (*Chain, error) {
+	var prefix qtum.HexAddressPrefix
+	switch name {
+	case qtum.ChainMain:
+		prefix = qtum.PrefixMainChainAddress
+	case qtum.ChainTest, qtum.ChainRegTest:
+		prefix = qtum.PrefixTestChainAddress
+	default:
+		return nil, errors.Errorf("Unsupported chain %q", name)
+	}
+	prefixBytes, err := prefix.AsBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Chain{
+		name:      name,
+		prefix:    prefixBytes[0],
+		mined:     make(map[string]*Block),
+		receipts:  make(map[string][]qtum.TransactionReceipt),
+		effects:   make(map[string][]effect),
+		contracts: make(map[string]*Contract),
+		executor:  DefaultExecutor,
+		changed:   make(chan struct{}),
+	}
+	c.mine(c.Address(qtum.ZeroAddress))
+	return c, nil
+}
+
+// Name is the chain getblockchaininfo returns
+func (c *Chain) Name() string {
+	return c.name
+}
+
+// SetExecutor changes how contract calls are run
+func (c *Chain) SetExecutor(executor Executor) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.executor = executor
+}
+
+// Address is the base58 address of a hex address
+func (c *Chain) Address(hexAddress string) string {
+	decoded, err := hex.DecodeString(utils.RemoveHexPrefix(hexAddress))
+	if err != nil || len(decoded) != 20 {
+		panic(fmt.Sprintf("Invalid hex address %q", hexAddress))
+	}
+	return base58.CheckEncode(decoded, c.prefix)
+}
+
+// HexAddress is the hex address of a base58 address
+func (c *Chain) HexAddress(address string) (string, error) {
+	decoded, version, err := base58.CheckDecode(address)
+	if err != nil || version != c.prefix || len(decoded) != 20 {
+		return "", errorf(errInvalidAddress, "Invalid Qtum address")
+	}
+	return hex.EncodeToString(decoded), nil
+}
+
+// NewAddress is a base58 address made from seed, the same seed always gives the same address
+func (c *Chain) NewAddress(seed string) string {
+	return base58.CheckEncode(btcutil.Hash160([]byte(seed)), c.prefix)
+}
+
+// Height is the height of the tip
+func (c *Chain) Height() int64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.height()
+}
+
+func (c *Chain) height() int64 {
+	return int64(len(c.blocks)) - 1
+}
+
+// Block is the block at height, nil if there is none
+func (c *Chain) Block(height int64) *Block {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if height < 0 || height > c.height() {
+		return nil
+	}
+	return c.blocks[height]
+}
+
+func (c *Chain) blockByHash(hash string) *Block {
+	for _, block := range c.blocks {
+		if block.Hash == hash {
+			return block
+		}
+	}
+	return nil
+}
+
+// Mempool is the transactions waiting to be mined
+func (c *Chain) Mempool() []*Transaction {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return append([]*Transaction{}, c.mempool...)
+}
+
+// Contract is the contract at a hex address, nil if there is none
+func (c *Chain) Contract(address string) *Contract {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	contract, ok := c.contracts[utils.RemoveHexPrefix(address)]
+	if !ok {
+		return nil
+	}
+	return contract.copy()
+}
+
+// Receipts are the results of the contract outputs of a mined transaction
+func (c *Chain) Receipts(txID string) []qtum.TransactionReceipt {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.receipts[txID]
+}
+
+// Mine mines n blocks paying their rewards to address, the first one includes the mempool
+func (c *Chain) Mine(n int, address string) ([]*Block, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, err := c.HexAddress(address); err != nil {
+		return nil, err
+	}
+
+	blocks := make([]*Block, n)
+	for i := range blocks {
+		blocks[i] = c.mine(address)
+	}
+	return blocks, nil
+}
+
+// Reorg replaces the top depth blocks with n new ones paying their rewards to address
+// the transactions in the replaced blocks go back to the mempool and are mined again if they are still valid
+func (c *Chain) Reorg(depth int, n int, address string) ([]*Block, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if depth < 1 || int64(depth) > c.height() {
+		return nil, errors.Errorf("Can't reorg %d blocks of a chain %d blocks high", depth, c.height())
+	}
+	if _, err := c.HexAddress(address); err != nil {
+		return nil, err
+	}
+
+	replaced := c.blocks[len(c.blocks)-depth:]
+	c.blocks = c.blocks[:len(c.blocks)-depth]
+
+	var mempool []*Transaction
+	for _, block := range replaced {
+		for _, tx := range block.Transactions {
+			delete(c.mined, tx.ID)
+			delete(c.receipts, tx.ID)
+			delete(c.effects, tx.ID)
+			if !tx.Coinbase {
+				mempool = append(mempool, tx)
+			}
+		}
+	}
+	c.mempool = append(mempool, c.mempool...)
+	c.rebuildContracts()
+
+	blocks := make([]*Block, n)
+	for i := range blocks {
+		blocks[i] = c.mine(address)
+	}
+	if n == 0 {
+		c.notify()
+	}
+	return blocks, nil
+}
+
+func (c *Chain) rebuildContracts() {
+	c.contracts = make(map[string]*Contract)
+	for _, block := range c.blocks {
+		for _, tx := range block.Transactions {
+			c.applyEffects(c.effects[tx.ID])
+		}
+	}
+}
+
+func (c *Chain) applyEffects(effects []effect) {
+	for _, e := range effects {
+		if e.created != nil {
+			c.contracts[e.created.Address] = e.created.copy()
+		}
+		if contract, ok := c.contracts[e.address]; ok {
+			for slot, value := range e.storage {
+				contract.Storage[slot] = value
+			}
+		}
+	}
+}
+
+func (c *Chain) notify() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// changes is closed the next time the chain changes
+func (c *Chain) changes() <-chan struct{} {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.changed
+}
+
+func (c *Chain) mine(address string) *Block {
+	c.minedCount++
+	height := int64(len(c.blocks))
+	block := &Block{
+		Height: height,
+		Time:   GenesisTime + height*BlockSpacing,
+	}
+	if height > 0 {
+		block.Previous = c.blocks[height-1].Hash
+	}
+
+	coinbase := newTransaction(rawTransaction{
+		Coinbase: true,
+		Nonce:    c.minedCount,
+		Outputs:  []Output{{Address: address, Value: BlockReward}},
+	})
+	block.Transactions = []*Transaction{coinbase}
+
+	// the mempool transactions that can still be mined, others are dropped like a node would
+	spent := c.spent(false)
+	for _, tx := range c.mempool {
+		if c.checkInputs(tx, spent) != nil {
+			continue
+		}
+		for _, input := range tx.Inputs {
+			spent[input] = true
+		}
+		block.Transactions = append(block.Transactions, tx)
+	}
+	c.mempool = nil
+
+	ids := make([]string, len(block.Transactions))
+	for i, tx := range block.Transactions {
+		ids[i] = tx.ID
+	}
+	block.Hash = hashHex([]byte(fmt.Sprintf("%s %d %d %s", block.Previous, height, c.minedCount, strings.Join(ids, " "))))
+
+	c.blocks = append(c.blocks, block)
+	var cumulativeGasUsed uint64
+	for i, tx := range block.Transactions {
+		c.mined[tx.ID] = block
+		cumulativeGasUsed = c.execute(block, i, tx, cumulativeGasUsed)
+	}
+
+	c.notify()
+	return block
+}
+
+// execute runs the contract outputs of a transaction being mined
+func (c *Chain) execute(block *Block, index int, tx *Transaction, cumulativeGasUsed uint64) uint64 {
+	for n, output := range tx.Outputs {
+		if output.Contract == nil {
+			continue
+		}
+
+		call, contract := c.contractCall(tx, n)
+		result := c.run(contract, call)
+		cumulativeGasUsed += result.GasUsed
+
+		receipt := qtum.TransactionReceipt{
+			BlockHash:         block.Hash,
+			BlockNumber:       uint64(block.Height),
+			TransactionHash:   tx.ID,
+			TransactionIndex:  uint64(index),
+			From:              call.From,
+			CumulativeGasUsed: cumulativeGasUsed,
+			GasUsed:           result.GasUsed,
+			ContractAddress:   call.To,
+			Excepted:          result.Excepted,
+			Log:               result.Logs,
+			OutputIndex:       int64(n),
+		}
+		if !call.Create {
+			receipt.To = call.To
+		}
+		if receipt.Log == nil {
+			receipt.Log = []qtum.Log{}
+		}
+		c.receipts[tx.ID] = append(c.receipts[tx.ID], receipt)
+
+		if result.Excepted != "None" {
+			continue
+		}
+		e := effect{address: call.To, storage: result.Storage}
+		if call.Create {
+			e.created = &Contract{Address: call.To, Code: call.Data, Storage: make(map[string]string)}
+		}
+		c.effects[tx.ID] = append(c.effects[tx.ID], e)
+		c.applyEffects([]effect{e})
+	}
+	return cumulativeGasUsed
+}
+
+// contractCall is the call made by contract output n of tx and the contract it calls
+func (c *Chain) contractCall(tx *Transaction, n int) (Call, *Contract) {
+	output := tx.Outputs[n].Contract
+	call := Call{
+		From:     output.Sender,
+		To:       output.Address,
+		Data:     output.Data,
+		Value:    tx.Outputs[n].Value,
+		GasLimit: output.GasLimit,
+	}
+	if call.From == "" && len(tx.Inputs) != 0 {
+		if spent := c.output(tx.Inputs[0]); spent != nil && spent.Address != "" {
+			call.From, _ = c.HexAddress(spent.Address)
+		}
+	}
+
+	if output.Address == "" {
+		call.Create = true
+		call.To = ContractAddress(tx.ID, n)
+		return call, &Contract{Address: call.To, Code: output.Data, Storage: make(map[string]string)}
+	}
+	contract, ok := c.contracts[output.Address]
+	if !ok {
+		// calls to addresses without a contract do nothing
+		contract = &Contract{Address: output.Address, Storage: make(map[string]string)}
+	}
+	return call, contract
+}
+
+func (c *Chain) run(contract *Contract, call Call) Result {
+	result := c.executor(contract.copy(), call)
+	if result.Excepted == "" {
+		result.Excepted = "None"
+	}
+	for i := range result.Logs {
+		if result.Logs[i].Address == "" {
+			result.Logs[i].Address = call.To
+		}
+		result.Logs[i].Index = i
+	}
+	return result
+}
+
+// CallContract runs a call against the tip without mining anything, like callcontract
+func (c *Chain) CallContract(call Call) (Result, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	contract, ok := c.contracts[call.To]
+	if !ok {
+		return Result{}, errorf(errInvalidAddress, "contract address does not exist")
+	}
+	return c.run(contract, call), nil
+}
+
+// ContractAddress is the address of the contract created by output n of a transaction
+func ContractAddress(txID string, n int) string {
+	id, err := hex.DecodeString(txID)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid transaction id %q", txID))
+	}
+	vout := make([]byte, 4)
+	binary.LittleEndian.PutUint32(vout, uint32(n))
+	return hex.EncodeToString(btcutil.Hash160(append(id, vout...)))
+}
+
+// Transaction is a transaction in the chain or the mempool and the block it is in, nil if it hasn't been mined
+func (c *Chain) Transaction(txID string) (*Transaction, *Block) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.transaction(txID)
+}
+
+func (c *Chain) transaction(txID string) (*Transaction, *Block) {
+	if block, ok := c.mined[txID]; ok {
+		for _, tx := range block.Transactions {
+			if tx.ID == txID {
+				return tx, block
+			}
+		}
+	}
+	for _, tx := range c.mempool {
+		if tx.ID == txID {
+			return tx, nil
+		}
+	}
+	return nil, nil
+}
+
+func (c *Chain) output(input Input) *Output {
+	tx, _ := c.transaction(input.TxID)
+	if tx == nil || input.Vout < 0 || input.Vout >= len(tx.Outputs) {
+		return nil
+	}
+	return &tx.Outputs[input.Vout]
+}
+
+// spent is the outputs spent by the chain, and the mempool if mempool is set
+func (c *Chain) spent(mempool bool) map[Input]bool {
+	spent := make(map[Input]bool)
+	for _, block := range c.blocks {
+		for _, tx := range block.Transactions {
+			for _, input := range tx.Inputs {
+				spent[input] = true
+			}
+		}
+	}
+	if mempool {
+		for _, tx := range c.mempool {
+			for _, input := range tx.Inputs {
+				spent[input] = true
+			}
+		}
+	}
+	return spent
+}
+
+// checkInputs checks tx only spends outputs that exist and aren't in spent
+func (c *Chain) checkInputs(tx *Transaction, spent map[Input]bool) error {
+	if tx.Coinbase || len(tx.Inputs) == 0 {
+		return errorf(errVerify, "bad-txns-vin-empty")
+	}
+	for _, input := range tx.Inputs {
+		if spent[input] || c.output(input) == nil {
+			return errorf(errVerify, "bad-txns-inputs-missingorspent")
+		}
+	}
+	return nil
+}
+
+// UTXOs are the unspent outputs paying address, or every address if it is empty
+// with mempool set they include the outputs of mempool transactions and leave out what the mempool spends
+func (c *Chain) UTXOs(address string, mempool bool) []UTXO {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.utxos(address, mempool)
+}
+
+func (c *Chain) utxos(address string, mempool bool) []UTXO {
+	spent := c.spent(mempool)
+	var utxos []UTXO
+	add := func(tx *Transaction, height int64) {
+		for n, output := range tx.Outputs {
+			input := Input{TxID: tx.ID, Vout: n}
+			if spent[input] || output.Address == "" || (address != "" && output.Address != address) {
+				continue
+			}
+			utxos = append(utxos, UTXO{Input: input, Output: output, Height: height, Coinbase: tx.Coinbase})
+		}
+	}
+	for _, block := range c.blocks {
+		for _, tx := range block.Transactions {
+			add(tx, block.Height)
+		}
+	}
+	if mempool {
+		for _, tx := range c.mempool {
+			add(tx, -1)
+		}
+	}
+	return utxos
+}
+
+// Submit adds a transaction to the mempool, like sendrawtransaction
+func (c *Chain) Submit(tx *Transaction) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.submit(tx)
+}
+
+func (c *Chain) submit(tx *Transaction) error {
+	if existing, block := c.transaction(tx.ID); existing != nil {
+		if block != nil {
+			return errorf(errVerifyAlreadyInChain, "Transaction already in block chain")
+		}
+		return errorf(errVerifyAlreadyInChain, "txn-already-in-mempool")
+	}
+	if err := c.checkInputs(tx, c.spent(true)); err != nil {
+		return err
+	}
+	var in, out int64
+	for _, input := range tx.Inputs {
+		in += c.output(input).Value
+	}
+	for _, output := range tx.Outputs {
+		if output.Value < 0 {
+			return errorf(errVerifyRejected, "bad-txns-vout-negative")
+		}
+		out += output.Value
+	}
+	if out > in {
+		return errorf(errVerifyRejected, "bad-txns-in-belowout")
+	}
+	c.mempool = append(c.mempool, tx)
+	c.notify()
+	return nil
+}
+
+// Send creates and submits a transaction from the wallet with outputs, spending the outputs of from or of any address if
+// from is empty and returning the change to the address of the first output spent
+func (c *Chain) Send(from string, outputs []Output) (*Transaction, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// gas is paid up front and never refunded
+	var needed int64 = Fee
+	for _, output := range outputs {
+		needed += output.Value
+		if output.Contract != nil {
+			needed += output.Contract.GasLimit * output.Contract.GasPrice
+		}
+	}
+
+	var inputs []Input
+	var total int64
+	var change string
+	for _, utxo := range c.utxos(from, true) {
+		inputs = append(inputs, utxo.Input)
+		total += utxo.Value
+		if change == "" {
+			change = utxo.Address
+		}
+		if total >= needed {
+			break
+		}
+	}
+	if total < needed {
+		return nil, errorf(errInsufficientFunds, "Insufficient funds")
+	}
+
+	changeOutputs := outputs
+	if total > needed {
+		changeOutputs = append(append([]Output{}, outputs...), Output{Address: change, Value: total - needed})
+	}
+	// the sender of contract outputs is the address paying for them
+	senderHex, _ := c.HexAddress(change)
+	for i, output := range changeOutputs {
+		if output.Contract != nil && output.Contract.Sender == "" {
+			contract := *output.Contract
+			contract.Sender = senderHex
+			changeOutputs[i].Contract = &contract
+		}
+	}
+
+	tx := c.newTransaction(inputs, changeOutputs)
+	if err := c.submit(tx); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// NewTransaction makes a transaction that can be submitted, it doesn't check anything
+func (c *Chain) NewTransaction(inputs []Input, outputs []Output) *Transaction {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.newTransaction(inputs, outputs)
+}
+
+func (c *Chain) newTransaction(inputs []Input, outputs []Output) *Transaction {
+	c.nonce++
+	return newTransaction(rawTransaction{Nonce: c.nonce, Inputs: inputs, Outputs: outputs})
+}
+
+// hashHex is a double sha256 hash, like block and transaction ids
+func hashHex(data []byte) string {
+	hash := sha256.Sum256(data)
+	hash = sha256.Sum256(hash[:])
+	return hex.EncodeToString(hash[:])
+}
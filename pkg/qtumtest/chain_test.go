@@ -0,0 +1,130 @@
+package qtumtest
+
+import (
+	"testing"
+
+	"github.com/qtumproject/janus/pkg/qtum"
+)
+
+func TestReorg(t *testing.T) {
+	chain, err := NewChain(qtum.ChainRegTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	miner, other := chain.NewAddress("miner"), chain.NewAddress("other")
+	chain.Mine(1, miner)
+	chain.Mine(1, other)
+
+	// spends the coinbase of the block that gets replaced, so it can't be mined again
+	orphaned, err := chain.Send(other, []Output{{Address: miner, Value: SatoshisPerQtum}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, err := chain.Send(miner, []Output{{Address: other, Value: SatoshisPerQtum}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks, err := chain.Mine(1, miner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks[0].Transactions) != 3 {
+		t.Fatalf("expected both transactions to be mined, got %d transactions", len(blocks[0].Transactions))
+	}
+	replaced := chain.Block(2).Hash
+
+	if _, err := chain.Reorg(2, 3, miner); err != nil {
+		t.Fatal(err)
+	}
+	if chain.Height() != 4 || chain.Block(2).Hash == replaced || chain.Block(2).Previous != chain.Block(1).Hash {
+		t.Errorf("expected blocks 2 to 4 to be new blocks")
+	}
+	if tx, block := chain.Transaction(kept.ID); tx == nil || block != chain.Block(2) {
+		t.Errorf("expected the transaction to be mined again in the first new block, got %v", block)
+	}
+	if tx, _ := chain.Transaction(orphaned.ID); tx != nil {
+		t.Errorf("expected the transaction spending a replaced coinbase to be dropped")
+	}
+	if len(chain.UTXOs(other, true)) != 1 {
+		t.Errorf("expected other to only have what it was sent, got %+v", chain.UTXOs(other, true))
+	}
+
+	if _, err := chain.Reorg(5, 1, miner); err == nil {
+		t.Error("expected reorganizing more blocks than there are to fail")
+	}
+}
+
+func TestReorgUndoesContracts(t *testing.T) {
+	chain, err := NewChain(qtum.ChainRegTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain.SetExecutor(testExecutor)
+	miner := chain.NewAddress("miner")
+	chain.Mine(1, miner)
+
+	created, err := chain.Send(miner, []Output{{Contract: &ContractOutput{Data: "6000", GasLimit: 100000, GasPrice: DefaultGasPrice}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain.Mine(1, miner)
+	contract := ContractAddress(created.ID, 0)
+	if chain.Contract(contract) == nil || len(chain.Receipts(created.ID)) != 1 {
+		t.Fatal("expected the contract to be created")
+	}
+
+	// without a new block the creation waits in the mempool again
+	if _, err := chain.Reorg(1, 0, miner); err != nil {
+		t.Fatal(err)
+	}
+	if chain.Contract(contract) != nil || chain.Receipts(created.ID) != nil || len(chain.Mempool()) != 1 {
+		t.Error("expected the creation to be undone")
+	}
+	chain.Mine(1, miner)
+	if chain.Contract(contract) == nil || chain.Receipts(created.ID)[0].BlockHash != chain.Block(2).Hash {
+		t.Error("expected the contract to be created again")
+	}
+}
+
+func TestRawTransactions(t *testing.T) {
+	s, qtumClient := newTestServer(t)
+	chain := s.Chain()
+	miner, other := chain.NewAddress("miner"), chain.NewAddress("other")
+	blocks, _ := chain.Mine(1, miner)
+	coinbase := blocks[0].Transactions[0].ID
+
+	var rawTx string
+	inputs := []qtum.RawTxInputs{{TxID: coinbase, Vout: 0}}
+	if err := qtumClient.Request(qtum.MethodCreateRawTx, []interface{}{inputs, map[string]string{other: "1.5", miner: "19998"}}, &rawTx); err != nil {
+		t.Fatal(err)
+	}
+	var signed qtum.SignRawTxResponse
+	if err := qtumClient.Request(qtum.MethodSignRawTx, []string{rawTx}, &signed); err != nil || !signed.Complete {
+		t.Fatalf("expected the wallet to sign, got %+v %v", signed, err)
+	}
+	sent, err := qtumClient.SendRawTransaction(qtumClient.GetContext(), &qtum.SendRawTransactionRequest{signed.Hex})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := qtumClient.DecodeRawTransaction(qtumClient.GetContext(), signed.Hex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.ID != sent.Result || len(decoded.Vouts) != 2 {
+		t.Fatalf("unexpected decoded transaction %+v", decoded)
+	}
+	for _, vout := range decoded.Vouts {
+		if vout.ScriptPubKey.Addresses[0] == other && vout.ValueSatoshi.IntPart() != 150000000 {
+			t.Errorf("expected 1.5 QTUM to be sent, got %s", vout.Value)
+		}
+	}
+
+	if _, err := qtumClient.SendRawTransaction(qtumClient.GetContext(), &qtum.SendRawTransactionRequest{signed.Hex}); err != qtum.ErrVerifyAlreadyInChain {
+		t.Errorf("expected sending the transaction twice to fail, got %v", err)
+	}
+	chain.Mine(1, miner)
+	if balance, err := qtumClient.GetAddressBalance(qtumClient.GetContext(), &qtum.GetAddressBalanceRequest{Address: other}); err != nil || balance.Balance != 150000000 {
+		t.Errorf("expected other to have 1.5 QTUM, got %+v %v", balance, err)
+	}
+}
@@ -0,0 +1,1325 @@
+package qtumtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/pkg/errors"
+	"github.com/qtumproject/janus/pkg/qtum"
+	"github.com/qtumproject/janus/pkg/utils"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	defaultSendToContractGasLimit = 250000
+	defaultCreateContractGasLimit = 2500000
+	// qtumd waits for this many confirmations when waitforlogs isn't given any
+	defaultWaitForLogsMinConf = 6
+
+	blockVersion    = 536870912
+	blockBits       = "207fffff"
+	blockDifficulty = 4.656542373906925e-10
+)
+
+type handler func(s *Server, ctx context.Context, p params) (interface{}, error)
+
+// the qtumd methods the fake serves
+var handlers = map[string]handler{
+	qtum.MethodGetBlockCount:         getBlockCount,
+	"getbestblockhash":               getBestBlockHash,
+	qtum.MethodGetBlockHash:          getBlockHash,
+	qtum.MethodGetBlockHeader:        getBlockHeader,
+	qtum.MethodGetBlock:              getBlock,
+	qtum.MethodGetBlockChainInfo:     getBlockChainInfo,
+	qtum.MethodGetNetworkInfo:        getNetworkInfo,
+	qtum.MethodGetPeerInfo:           getPeerInfo,
+	qtum.MethodGetStakingInfo:        getStakingInfo,
+	qtum.MethodGetRawTransaction:     getRawTransaction,
+	qtum.MethodGetTransaction:        getTransaction,
+	qtum.MethodDecodeRawTransaction:  decodeRawTransaction,
+	qtum.MethodGetTransactionOut:     getTransactionOut,
+	qtum.MethodGetTransactionReceipt: getTransactionReceipt,
+	qtum.MethodSearchLogs:            searchLogs,
+	qtum.MethodWaitForLogs:           waitForLogs,
+	qtum.MethodCallContract:          callContract,
+	qtum.MethodGetAccountInfo:        getAccountInfo,
+	qtum.MethodGetStorage:            getStorage,
+	qtum.MethodGetAddressUTXOs:       getAddressUTXOs,
+	qtum.MethodGetAddressBalance:     getAddressBalance,
+	qtum.MethodListUnspent:           listUnspent,
+	qtum.MethodGetHexAddress:         getHexAddress,
+	qtum.MethodFromHexAddress:        fromHexAddress,
+	qtum.MethodSendToAddress:         sendToAddress,
+	qtum.MethodSendToContract:        sendToContract,
+	qtum.MethodCreateContract:        createContract,
+	qtum.MethodGenerateToAddress:     generateToAddress,
+	qtum.MethodCreateRawTx:           createRawTransaction,
+	qtum.MethodSignRawTx:             signRawTransaction,
+	qtum.MethodSendRawTx:             sendRawTransaction,
+}
+
+// params are the positional parameters of a request
+type params []json.RawMessage
+
+func (p params) has(i int) bool {
+	return i < len(p) && string(p[i]) != "null"
+}
+
+func (p params) get(i int, v interface{}) error {
+	if !p.has(i) {
+		return errorf(errMisc, "Missing parameter %d", i+1)
+	}
+	if err := json.Unmarshal(p[i], v); err != nil {
+		return errorf(errType, "Parameter %d: %s", i+1, err)
+	}
+	return nil
+}
+
+func (p params) optional(i int, v interface{}) error {
+	if !p.has(i) {
+		return nil
+	}
+	return p.get(i, v)
+}
+
+// number parses a number that may be given as a string, like amounts and gas limits, def if it is missing
+func (p params) number(i int, def decimal.Decimal) (decimal.Decimal, error) {
+	if !p.has(i) {
+		return def, nil
+	}
+	return parseNumber(p[i])
+}
+
+func parseNumber(raw json.RawMessage) (decimal.Decimal, error) {
+	n, err := decimal.NewFromString(strings.Trim(string(raw), `"`))
+	if err != nil || n.IsNegative() {
+		return decimal.Decimal{}, errorf(errType, "Invalid amount %s", raw)
+	}
+	return n, nil
+}
+
+// satoshis converts an amount in QTUM
+func satoshis(amount decimal.Decimal) int64 {
+	return amount.Shift(8).IntPart()
+}
+
+// qtumAmount is an amount in satoshis as a QTUM JSON number
+func qtumAmount(satoshis int64) json.Number {
+	return json.Number(decimal.New(satoshis, -8).String())
+}
+
+func normalizeHex(s string) string {
+	return strings.ToLower(utils.RemoveHexPrefix(s))
+}
+
+// fakeHash makes up a hash for the fields qtumd has that the fake doesn't model
+func fakeHash(parts ...interface{}) string {
+	return hashHex([]byte(fmt.Sprint(parts...)))
+}
+
+func getBlockCount(s *Server, ctx context.Context, p params) (interface{}, error) {
+	return s.chain.Height(), nil
+}
+
+func getBestBlockHash(s *Server, ctx context.Context, p params) (interface{}, error) {
+	return s.chain.Block(s.chain.Height()).Hash, nil
+}
+
+func getBlockHash(s *Server, ctx context.Context, p params) (interface{}, error) {
+	var height int64
+	if err := p.get(0, &height); err != nil {
+		return nil, err
+	}
+	block := s.chain.Block(height)
+	if block == nil {
+		return nil, errorf(errInvalidParameter, "Block height out of range")
+	}
+	return block.Hash, nil
+}
+
+func (c *Chain) header(block *Block) qtum.GetBlockHeaderResponse {
+	header := qtum.GetBlockHeaderResponse{
+		Hash:              block.Hash,
+		Confirmations:     int(c.height() - block.Height + 1),
+		Height:            int(block.Height),
+		Version:           blockVersion,
+		VersionHex:        fmt.Sprintf("%08x", blockVersion),
+		Merkleroot:        fakeHash("merkleroot", block.Hash),
+		Time:              uint64(block.Time),
+		Mediantime:        int(block.Time),
+		Bits:              blockBits,
+		Difficulty:        blockDifficulty,
+		Chainwork:         fmt.Sprintf("%064x", block.Height+1),
+		HashStateRoot:     fakeHash("hashStateRoot", block.Hash),
+		HashUTXORoot:      fakeHash("hashUTXORoot", block.Hash),
+		Previousblockhash: block.Previous,
+		Flags:             "proof-of-work",
+		Proofhash:         block.Hash,
+		Modifier:          fakeHash("modifier", block.Hash),
+	}
+	return header
+}
+
+func getBlockHeader(s *Server, ctx context.Context, p params) (interface{}, error) {
+	var hash string
+	if err := p.get(0, &hash); err != nil {
+		return nil, err
+	}
+	verbose := true
+	if err := p.optional(1, &verbose); err != nil {
+		return nil, err
+	}
+
+	c := s.chain
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	block := c.blockByHash(hash)
+	if block == nil {
+		return nil, errorf(errInvalidAddress, "Block not found")
+	}
+	if !verbose {
+		return hex.EncodeToString([]byte(block.Hash)), nil
+	}
+	return c.header(block), nil
+}
+
+func getBlock(s *Server, ctx context.Context, p params) (interface{}, error) {
+	var hash string
+	if err := p.get(0, &hash); err != nil {
+		return nil, err
+	}
+	verbosity := 1
+	if p.has(1) {
+		// verbosity can also be given as a bool
+		var verbose bool
+		if err := json.Unmarshal(p[1], &verbose); err == nil {
+			verbosity = 0
+			if verbose {
+				verbosity = 1
+			}
+		} else if err := p.get(1, &verbosity); err != nil {
+			return nil, err
+		}
+	}
+
+	c := s.chain
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	block := c.blockByHash(hash)
+	if block == nil {
+		return nil, errorf(errInvalidAddress, "Block not found")
+	}
+
+	size := 0
+	for _, tx := range block.Transactions {
+		size += len(tx.Hex) / 2
+	}
+	if verbosity == 0 {
+		return hex.EncodeToString([]byte(block.Hash)), nil
+	}
+
+	header := c.header(block)
+	resp := qtum.GetBlockResponse{
+		Hash:              header.Hash,
+		Confirmations:     header.Confirmations,
+		Strippedsize:      size,
+		Size:              size,
+		Weight:            size * 4,
+		Height:            header.Height,
+		Version:           header.Version,
+		VersionHex:        header.VersionHex,
+		Merkleroot:        header.Merkleroot,
+		HashStateRoot:     header.HashStateRoot,
+		HashUTXORoot:      header.HashUTXORoot,
+		Time:              int(block.Time),
+		Mediantime:        header.Mediantime,
+		Bits:              header.Bits,
+		Difficulty:        header.Difficulty,
+		Chainwork:         header.Chainwork,
+		Previousblockhash: header.Previousblockhash,
+		Flags:             header.Flags,
+		Proofhash:         header.Proofhash,
+		Modifier:          header.Modifier,
+	}
+	if block.Height < c.height() {
+		resp.Nextblockhash = c.blocks[block.Height+1].Hash
+	}
+	for _, tx := range block.Transactions {
+		resp.Txs = append(resp.Txs, tx.ID)
+	}
+	if verbosity == 1 {
+		return resp, nil
+	}
+
+	// verbosity 2 decodes the transactions
+	txs := make([]interface{}, len(block.Transactions))
+	for i, tx := range block.Transactions {
+		txs[i] = c.rawTransaction(tx, nil)
+	}
+	return struct {
+		qtum.GetBlockResponse
+		Txs []interface{} `json:"tx"`
+	}{resp, txs}, nil
+}
+
+func getBlockChainInfo(s *Server, ctx context.Context, p params) (interface{}, error) {
+	c := s.chain
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	tip := c.blocks[c.height()]
+	return map[string]interface{}{
+		"chain":                c.name,
+		"blocks":               tip.Height,
+		"headers":              tip.Height,
+		"bestblockhash":        tip.Hash,
+		"difficulty":           blockDifficulty,
+		"mediantime":           tip.Time,
+		"verificationprogress": 1,
+		"initialblockdownload": false,
+		"chainwork":            fmt.Sprintf("%064x", tip.Height+1),
+		"size_on_disk":         0,
+		"pruned":               false,
+		"softforks":            map[string]interface{}{},
+		"warnings":             "",
+	}, nil
+}
+
+func getNetworkInfo(s *Server, ctx context.Context, p params) (interface{}, error) {
+	return map[string]interface{}{
+		"version":            190101,
+		"subversion":         "/Satoshi:0.19.1/",
+		"protocolversion":    70018,
+		"localservices":      "0000000000000409",
+		"localservicesnames": []string{"NETWORK", "WITNESS", "NETWORK_LIMITED"},
+		"localrelay":         true,
+		"timeoffset":         0,
+		"networkactive":      true,
+		"connections":        0,
+		"networks":           []interface{}{},
+		"relayfee":           0.004,
+		"incrementalfee":     0.001,
+		"localaddresses":     []interface{}{},
+		"warnings":           "",
+	}, nil
+}
+
+func getPeerInfo(s *Server, ctx context.Context, p params) (interface{}, error) {
+	return []interface{}{}, nil
+}
+
+func getStakingInfo(s *Server, ctx context.Context, p params) (interface{}, error) {
+	return map[string]interface{}{
+		"enabled":         false,
+		"staking":         false,
+		"errors":          "",
+		"currentblocktx":  0,
+		"pooledtx":        len(s.chain.Mempool()),
+		"difficulty":      blockDifficulty,
+		"search-interval": 0,
+		"weight":          0,
+		"netstakeweight":  0,
+		"expectedtime":    0,
+	}, nil
+}
+
+// script is the scriptPubKey of an output
+func (c *Chain) script(output Output) map[string]interface{} {
+	if output.Contract == nil {
+		hash160, _ := c.HexAddress(output.Address)
+		return map[string]interface{}{
+			"asm":       fmt.Sprintf("OP_DUP OP_HASH160 %s OP_EQUALVERIFY OP_CHECKSIG", hash160),
+			"hex":       "76a914" + hash160 + "88ac",
+			"reqSigs":   1,
+			"type":      "pubkeyhash",
+			"addresses": []string{output.Address},
+		}
+	}
+
+	contract := output.Contract
+	data := contract.Data
+	if data == "" {
+		data = "0"
+	}
+	asm := fmt.Sprintf("4 %d %d %s", contract.GasLimit, contract.GasPrice, data)
+	scriptType := "create"
+	if contract.Address == "" {
+		asm += " OP_CREATE"
+	} else {
+		asm += fmt.Sprintf(" %s OP_CALL", contract.Address)
+		scriptType = "call"
+	}
+	if contract.Sender != "" {
+		asm = fmt.Sprintf("1 %s 00 OP_SENDER %s", contract.Sender, asm)
+		scriptType += "_sender"
+	}
+	return map[string]interface{}{
+		"asm":  asm,
+		"hex":  hex.EncodeToString([]byte(asm)),
+		"type": scriptType,
+	}
+}
+
+// rawTransaction is a transaction the way getrawtransaction and decoderawtransaction describe it
+// with a block it also has the fields getrawtransaction adds for mined transactions
+func (c *Chain) rawTransaction(tx *Transaction, block *Block) map[string]interface{} {
+	vins := make([]map[string]interface{}, len(tx.Inputs))
+	for i, input := range tx.Inputs {
+		vin := map[string]interface{}{
+			"txid":      input.TxID,
+			"vout":      input.Vout,
+			"scriptSig": map[string]string{"asm": "", "hex": ""},
+			"sequence":  4294967295,
+		}
+		if spent := c.output(input); spent != nil {
+			vin["value"] = qtumAmount(spent.Value)
+			vin["valueSat"] = spent.Value
+			vin["address"] = spent.Address
+		}
+		vins[i] = vin
+	}
+	if tx.Coinbase {
+		vins = []map[string]interface{}{{"coinbase": "00", "sequence": 4294967295}}
+	}
+
+	vouts := make([]map[string]interface{}, len(tx.Outputs))
+	for n, output := range tx.Outputs {
+		vouts[n] = map[string]interface{}{
+			"value":        qtumAmount(output.Value),
+			"valueSat":     output.Value,
+			"n":            n,
+			"scriptPubKey": c.script(output),
+		}
+	}
+
+	size := len(tx.Hex) / 2
+	raw := map[string]interface{}{
+		"txid":     tx.ID,
+		"hash":     tx.ID,
+		"version":  2,
+		"size":     size,
+		"vsize":    size,
+		"weight":   size * 4,
+		"locktime": 0,
+		"vin":      vins,
+		"vout":     vouts,
+		"hex":      tx.Hex,
+	}
+	if block != nil {
+		raw["blockhash"] = block.Hash
+		raw["confirmations"] = c.height() - block.Height + 1
+		raw["time"] = block.Time
+		raw["blocktime"] = block.Time
+	}
+	return raw
+}
+
+func getRawTransaction(s *Server, ctx context.Context, p params) (interface{}, error) {
+	var txID string
+	if err := p.get(0, &txID); err != nil {
+		return nil, err
+	}
+	var verbose bool
+	if p.has(1) {
+		// verbose can also be given as a number
+		var n int
+		if err := json.Unmarshal(p[1], &n); err == nil {
+			verbose = n != 0
+		} else if err := p.get(1, &verbose); err != nil {
+			return nil, err
+		}
+	}
+
+	c := s.chain
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	tx, block := c.transaction(txID)
+	if tx == nil {
+		return nil, errorf(errInvalidAddress, "No such mempool or blockchain transaction. Use gettransaction for wallet transactions.")
+	}
+	if !verbose {
+		return tx.Hex, nil
+	}
+	return c.rawTransaction(tx, block), nil
+}
+
+func decodeRawTransaction(s *Server, ctx context.Context, p params) (interface{}, error) {
+	var txHex string
+	if err := p.get(0, &txHex); err != nil {
+		return nil, err
+	}
+	tx, err := DecodeTransaction(txHex)
+	if err != nil {
+		return nil, err
+	}
+
+	c := s.chain
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	raw := c.rawTransaction(tx, nil)
+	delete(raw, "hex")
+	return raw, nil
+}
+
+func getTransaction(s *Server, ctx context.Context, p params) (interface{}, error) {
+	var txID string
+	if err := p.get(0, &txID); err != nil {
+		return nil, err
+	}
+
+	c := s.chain
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	tx, block := c.transaction(txID)
+	if tx == nil {
+		return nil, errorf(errInvalidAddress, "Invalid or non-wallet transaction id")
+	}
+
+	// the wallet owns every address, so every output is received and the amount is what the transaction costs
+	var in, out int64
+	for _, input := range tx.Inputs {
+		if spent := c.output(input); spent != nil {
+			in += spent.Value
+		}
+	}
+	details := []map[string]interface{}{}
+	for n, output := range tx.Outputs {
+		out += output.Value
+		if output.Address == "" {
+			continue
+		}
+		category := "receive"
+		if tx.Coinbase {
+			category = "generate"
+		}
+		details = append(details, map[string]interface{}{
+			"address":  output.Address,
+			"category": category,
+			"amount":   qtumAmount(output.Value),
+			"label":    "",
+			"vout":     n,
+		})
+	}
+
+	resp := map[string]interface{}{
+		"amount":             qtumAmount(0),
+		"confirmations":      0,
+		"txid":               tx.ID,
+		"walletconflicts":    []string{},
+		"time":               c.blocks[c.height()].Time,
+		"timereceived":       c.blocks[c.height()].Time,
+		"bip125-replaceable": "no",
+		"details":            details,
+		"hex":                tx.Hex,
+	}
+	if tx.Coinbase {
+		resp["generated"] = true
+		resp["amount"] = qtumAmount(out)
+	} else {
+		resp["fee"] = qtumAmount(out - in)
+	}
+	if block != nil {
+		for i, blockTx := range block.Transactions {
+			if blockTx == tx {
+				resp["blockindex"] = i
+			}
+		}
+		resp["confirmations"] = c.height() - block.Height + 1
+		resp["blockhash"] = block.Hash
+		resp["blocktime"] = block.Time
+		resp["time"] = block.Time
+		resp["timereceived"] = block.Time
+	}
+	return resp, nil
+}
+
+func getTransactionOut(s *Server, ctx context.Context, p params) (interface{}, error) {
+	var txID string
+	var n int
+	if err := p.get(0, &txID); err != nil {
+		return nil, err
+	}
+	if err := p.get(1, &n); err != nil {
+		return nil, err
+	}
+	mempool := true
+	if err := p.optional(2, &mempool); err != nil {
+		return nil, err
+	}
+
+	c := s.chain
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	tx, block := c.transaction(txID)
+	input := Input{TxID: txID, Vout: n}
+	if tx == nil || (block == nil && !mempool) || n < 0 || n >= len(tx.Outputs) || c.spent(mempool)[input] {
+		return nil, nil
+	}
+
+	confirmations := int64(0)
+	if block != nil {
+		confirmations = c.height() - block.Height + 1
+	}
+	return map[string]interface{}{
+		"bestblock":     c.blocks[c.height()].Hash,
+		"confirmations": confirmations,
+		"value":         qtumAmount(tx.Outputs[n].Value),
+		"scriptPubKey":  c.script(tx.Outputs[n]),
+		"coinbase":      tx.Coinbase,
+		"coinstake":     false,
+	}, nil
+}
+
+func getTransactionReceipt(s *Server, ctx context.Context, p params) (interface{}, error) {
+	var txID string
+	if err := p.get(0, &txID); err != nil {
+		return nil, err
+	}
+	receipts := s.chain.Receipts(txID)
+	if receipts == nil {
+		return []interface{}{}, nil
+	}
+	return receipts, nil
+}
+
+// logFilter picks logs by the address of the contract logging them and their topics
+type logFilter struct {
+	addresses []string
+	// nil matches any topic at that position
+	topics [][]string
+}
+
+func parseLogFilter(addresses []string, topics []json.RawMessage) (logFilter, error) {
+	var filter logFilter
+	for _, address := range addresses {
+		filter.addresses = append(filter.addresses, normalizeHex(address))
+	}
+	for _, raw := range topics {
+		var topic interface{}
+		if err := json.Unmarshal(raw, &topic); err != nil {
+			return logFilter{}, errorf(errType, "Invalid topic %s", raw)
+		}
+		var any []string
+		switch topic := topic.(type) {
+		case nil:
+		case string:
+			any = []string{normalizeHex(topic)}
+		case []interface{}:
+			for _, t := range topic {
+				s, ok := t.(string)
+				if !ok {
+					return logFilter{}, errorf(errType, "Invalid topic %s", raw)
+				}
+				any = append(any, normalizeHex(s))
+			}
+		default:
+			return logFilter{}, errorf(errType, "Invalid topic %s", raw)
+		}
+		filter.topics = append(filter.topics, any)
+	}
+	return filter, nil
+}
+
+func (f logFilter) matches(log qtum.Log) bool {
+	if len(f.addresses) != 0 && !contains(f.addresses, normalizeHex(log.Address)) {
+		return false
+	}
+	for i, any := range f.topics {
+		if any == nil {
+			continue
+		}
+		if i >= len(log.Topics) || !contains(any, normalizeHex(log.Topics[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// receipts are the receipts of the blocks from from to to with a log matching filter
+func (c *Chain) matchingReceipts(from, to int64, filter logFilter) []qtum.TransactionReceipt {
+	receipts := []qtum.TransactionReceipt{}
+	if from < 0 {
+		from = 0
+	}
+	for height := from; height <= to && height <= c.height(); height++ {
+		for _, tx := range c.blocks[height].Transactions {
+			for _, receipt := range c.receipts[tx.ID] {
+				for _, log := range receipt.Log {
+					if filter.matches(log) {
+						receipts = append(receipts, receipt)
+						break
+					}
+				}
+			}
+		}
+	}
+	return receipts
+}
+
+// blockNumber parses a block number that may also be "latest" or negative for the tip
+func (c *Chain) blockNumber(raw json.RawMessage) (int64, error) {
+	if strings.Trim(string(raw), `"`) == "latest" {
+		return c.height(), nil
+	}
+	var height int64
+	if err := json.Unmarshal(raw, &height); err != nil {
+		return 0, errorf(errType, "Invalid block number %s", raw)
+	}
+	if height < 0 {
+		return c.height(), nil
+	}
+	return height, nil
+}
+
+func searchLogs(s *Server, ctx context.Context, p params) (interface{}, error) {
+	var search struct {
+		Addresses []string `json:"addresses"`
+	}
+	var topics struct {
+		Topics []json.RawMessage `json:"topics"`
+	}
+	var minconf int64
+	if err := p.optional(2, &search); err != nil {
+		return nil, err
+	}
+	if err := p.optional(3, &topics); err != nil {
+		return nil, err
+	}
+	if err := p.optional(4, &minconf); err != nil {
+		return nil, err
+	}
+	filter, err := parseLogFilter(search.Addresses, topics.Topics)
+	if err != nil {
+		return nil, err
+	}
+	if !p.has(0) || !p.has(1) {
+		return nil, errorf(errMisc, "searchlogs requires fromBlock and toBlock")
+	}
+
+	c := s.chain
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	from, err := c.blockNumber(p[0])
+	if err != nil {
+		return nil, err
+	}
+	to, err := c.blockNumber(p[1])
+	if err != nil {
+		return nil, err
+	}
+	if minconf > 0 && to > c.height()-minconf+1 {
+		to = c.height() - minconf + 1
+	}
+	return c.matchingReceipts(from, to, filter), nil
+}
+
+func waitForLogs(s *Server, ctx context.Context, p params) (interface{}, error) {
+	var filter struct {
+		Addresses []string          `json:"addresses"`
+		Topics    []json.RawMessage `json:"topics"`
+	}
+	minconf := int64(defaultWaitForLogsMinConf)
+	if err := p.optional(2, &filter); err != nil {
+		return nil, err
+	}
+	if err := p.optional(3, &minconf); err != nil {
+		return nil, err
+	}
+	logFilter, err := parseLogFilter(filter.Addresses, filter.Topics)
+	if err != nil {
+		return nil, err
+	}
+
+	c := s.chain
+	c.mutex.Lock()
+	// without a fromBlock only logs in blocks mined from now on are returned
+	from := c.height() + 1
+	if p.has(0) {
+		if from, err = c.blockNumber(p[0]); err != nil {
+			c.mutex.Unlock()
+			return nil, err
+		}
+	}
+	to := int64(-1)
+	if p.has(1) {
+		if to, err = c.blockNumber(p[1]); err != nil {
+			c.mutex.Unlock()
+			return nil, err
+		}
+	}
+	c.mutex.Unlock()
+
+	for {
+		c.mutex.Lock()
+		last := c.height() - minconf + 1
+		if minconf <= 0 {
+			last = c.height()
+		}
+		if to >= 0 && last > to {
+			last = to
+		}
+
+		entries := []qtum.WaitForLogsEntry{}
+		for _, receipt := range c.matchingReceipts(from, last, logFilter) {
+			for _, log := range receipt.Log {
+				if !logFilter.matches(log) {
+					continue
+				}
+				entries = append(entries, qtum.WaitForLogsEntry{
+					BlockHash:         receipt.BlockHash,
+					BlockNumber:       receipt.BlockNumber,
+					Bloom:             fakeHash("bloom", receipt.TransactionHash),
+					TransactionHash:   receipt.TransactionHash,
+					TransactionIndex:  receipt.TransactionIndex,
+					From:              receipt.From,
+					To:                receipt.To,
+					CumulativeGasUsed: receipt.CumulativeGasUsed,
+					GasUsed:           receipt.GasUsed,
+					ContractAddress:   log.Address,
+					Data:              log.Data,
+					Topics:            log.Topics,
+					Excepted:          receipt.Excepted,
+					OutputIndex:       receipt.OutputIndex,
+				})
+			}
+		}
+		changes := c.changed
+		c.mutex.Unlock()
+
+		if len(entries) != 0 || (to >= 0 && last >= to) {
+			return qtum.WaitForLogsResponse{
+				Entries:   entries,
+				Count:     uint64(len(entries)),
+				NextBlock: uint64(last + 1),
+			}, nil
+		}
+
+		select {
+		case <-changes:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-s.done:
+			return nil, errorf(errMisc, "Shutting down")
+		}
+	}
+}
+
+// hexAddress accepts a base58 or a hex address
+func (c *Chain) hexAddress(address string) (string, error) {
+	if utils.IsEthHexAddress(address) || len(address) == 40 {
+		if _, err := hex.DecodeString(utils.RemoveHexPrefix(address)); err == nil {
+			return normalizeHex(address), nil
+		}
+	}
+	return c.HexAddress(address)
+}
+
+func callContract(s *Server, ctx context.Context, p params) (interface{}, error) {
+	var address, data, sender string
+	if err := p.get(0, &address); err != nil {
+		return nil, err
+	}
+	if err := p.get(1, &data); err != nil {
+		return nil, err
+	}
+	if err := p.optional(2, &sender); err != nil {
+		return nil, err
+	}
+	gasLimit, err := p.number(3, decimal.New(defaultCreateContractGasLimit, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	call := Call{To: normalizeHex(address), Data: normalizeHex(data), GasLimit: gasLimit.IntPart()}
+	if sender != "" {
+		if call.From, err = s.chain.hexAddress(sender); err != nil {
+			return nil, err
+		}
+	}
+	result, err := s.chain.CallContract(call)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp qtum.CallContractResponse
+	resp.Address = call.To
+	resp.ExecutionResult.GasUsed = int(result.GasUsed)
+	resp.ExecutionResult.Excepted = result.Excepted
+	resp.ExecutionResult.ExceptedMessage = result.ExceptedMessage
+	resp.ExecutionResult.Output = result.Output
+	resp.TransactionReceipt.StateRoot = fakeHash("stateRoot", s.chain.Height())
+	resp.TransactionReceipt.GasUsed = int(result.GasUsed)
+	resp.TransactionReceipt.Bloom = fakeHash("bloom", call)
+	resp.TransactionReceipt.Log = []interface{}{}
+	for _, log := range result.Logs {
+		resp.TransactionReceipt.Log = append(resp.TransactionReceipt.Log, log)
+	}
+	return resp, nil
+}
+
+// storage is a contract's storage the way getaccountinfo and getstorage return it, keyed by the hash of the slot
+func (contract *Contract) storage() qtum.GetStorageResponse {
+	storage := qtum.GetStorageResponse{}
+	for slot, value := range contract.Storage {
+		slotBytes, _ := hex.DecodeString(normalizeHex(slot))
+		key := hex.EncodeToString(crypto.Keccak256(slotBytes))
+		storage[key] = map[string]string{normalizeHex(slot): normalizeHex(value)}
+	}
+	return storage
+}
+
+func getAccountInfo(s *Server, ctx context.Context, p params) (interface{}, error) {
+	var address string
+	if err := p.get(0, &address); err != nil {
+		return nil, err
+	}
+	contract := s.chain.Contract(normalizeHex(address))
+	if contract == nil {
+		return nil, errorf(errInvalidAddress, "Address does not exist")
+	}
+	storage, err := json.Marshal(contract.storage())
+	if err != nil {
+		return nil, err
+	}
+	return qtum.GetAccountInfoResponse{
+		Address: contract.Address,
+		Storage: storage,
+		Code:    contract.Code,
+	}, nil
+}
+
+func getStorage(s *Server, ctx context.Context, p params) (interface{}, error) {
+	var address string
+	if err := p.get(0, &address); err != nil {
+		return nil, err
+	}
+	contract := s.chain.Contract(normalizeHex(address))
+	if contract == nil {
+		return nil, errorf(errInvalidAddress, "Address does not exist")
+	}
+	return contract.storage(), nil
+}
+
+// addresses accepts a single address or {"addresses": [...]}
+func (p params) addresses(i int) ([]string, error) {
+	var address string
+	if p.has(i) && json.Unmarshal(p[i], &address) == nil {
+		return []string{address}, nil
+	}
+	var request struct {
+		Addresses []string `json:"addresses"`
+	}
+	if err := p.get(i, &request); err != nil {
+		return nil, err
+	}
+	return request.Addresses, nil
+}
+
+func getAddressUTXOs(s *Server, ctx context.Context, p params) (interface{}, error) {
+	addresses, err := p.addresses(0)
+	if err != nil {
+		return nil, err
+	}
+
+	c := s.chain
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	utxos := []map[string]interface{}{}
+	for _, address := range addresses {
+		if _, err := c.HexAddress(address); err != nil {
+			return nil, err
+		}
+		for _, utxo := range c.utxos(address, false) {
+			utxos = append(utxos, map[string]interface{}{
+				"address":     utxo.Address,
+				"txid":        utxo.TxID,
+				"outputIndex": utxo.Vout,
+				"script":      c.script(utxo.Output)["hex"],
+				"satoshis":    utxo.Value,
+				"height":      utxo.Height,
+				"isStake":     false,
+			})
+		}
+	}
+	return utxos, nil
+}
+
+func getAddressBalance(s *Server, ctx context.Context, p params) (interface{}, error) {
+	addresses, err := p.addresses(0)
+	if err != nil {
+		return nil, err
+	}
+
+	c := s.chain
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	var balance, received int64
+	for _, address := range addresses {
+		if _, err := c.HexAddress(address); err != nil {
+			return nil, err
+		}
+		for _, utxo := range c.utxos(address, false) {
+			balance += utxo.Value
+		}
+		for _, block := range c.blocks {
+			for _, tx := range block.Transactions {
+				for _, output := range tx.Outputs {
+					if output.Address == address {
+						received += output.Value
+					}
+				}
+			}
+		}
+	}
+	return qtum.GetAddressBalanceResponse{Balance: uint64(balance), Received: uint64(received)}, nil
+}
+
+func listUnspent(s *Server, ctx context.Context, p params) (interface{}, error) {
+	minconf, maxconf := int64(1), int64(9999999)
+	var addresses []string
+	if err := p.optional(0, &minconf); err != nil {
+		return nil, err
+	}
+	if err := p.optional(1, &maxconf); err != nil {
+		return nil, err
+	}
+	if err := p.optional(2, &addresses); err != nil {
+		return nil, err
+	}
+
+	c := s.chain
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	unspent := []map[string]interface{}{}
+	for _, utxo := range c.utxos("", true) {
+		confirmations := int64(0)
+		if utxo.Height >= 0 {
+			confirmations = c.height() - utxo.Height + 1
+		}
+		if confirmations < minconf || confirmations > maxconf || (len(addresses) != 0 && !contains(addresses, utxo.Address)) {
+			continue
+		}
+		unspent = append(unspent, map[string]interface{}{
+			"address":       utxo.Address,
+			"txid":          utxo.TxID,
+			"vout":          utxo.Vout,
+			"amount":        qtumAmount(utxo.Value),
+			"label":         "",
+			"confirmations": confirmations,
+			"scriptPubKey":  c.script(utxo.Output)["hex"],
+			"spendable":     true,
+			"solvable":      true,
+			"safe":          true,
+		})
+	}
+	return unspent, nil
+}
+
+func getHexAddress(s *Server, ctx context.Context, p params) (interface{}, error) {
+	var address string
+	if err := p.get(0, &address); err != nil {
+		return nil, err
+	}
+	return s.chain.HexAddress(address)
+}
+
+func fromHexAddress(s *Server, ctx context.Context, p params) (interface{}, error) {
+	var address string
+	if err := p.get(0, &address); err != nil {
+		return nil, err
+	}
+	decoded, err := hex.DecodeString(utils.RemoveHexPrefix(address))
+	if err != nil || len(decoded) != 20 {
+		return nil, errorf(errInvalidAddress, "Invalid pubkeyhash hex size (should be 40 hex chars)")
+	}
+	return s.chain.Address(address), nil
+}
+
+func sendToAddress(s *Server, ctx context.Context, p params) (interface{}, error) {
+	var address, sender string
+	if err := p.get(0, &address); err != nil {
+		return nil, err
+	}
+	if err := p.optional(9, &sender); err != nil {
+		return nil, err
+	}
+	if _, err := s.chain.HexAddress(address); err != nil {
+		return nil, err
+	}
+	amount, err := p.number(1, decimal.Zero)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.chain.Send(sender, []Output{{Address: address, Value: satoshis(amount)}})
+	if err != nil {
+		return nil, err
+	}
+	return tx.ID, nil
+}
+
+// contractOutput parses the amount, gas limit, gas price and sender of sendtocontract and createcontract from position i
+// only sendtocontract takes an amount
+func (s *Server) contractOutput(p params, i int, withAmount bool, contract *ContractOutput, defaultGasLimit int64) (int64, string, error) {
+	amount := decimal.Zero
+	var err error
+	if withAmount {
+		if amount, err = p.number(i, decimal.Zero); err != nil {
+			return 0, "", err
+		}
+		i++
+	}
+	gasLimit, err := p.number(i, decimal.New(defaultGasLimit, 0))
+	if err != nil {
+		return 0, "", err
+	}
+	gasPrice, err := p.number(i+1, decimal.New(DefaultGasPrice, -8))
+	if err != nil {
+		return 0, "", err
+	}
+	var sender string
+	if err := p.optional(i+2, &sender); err != nil {
+		return 0, "", err
+	}
+	if sender != "" {
+		if contract.Sender, err = s.chain.HexAddress(sender); err != nil {
+			return 0, "", err
+		}
+	}
+	contract.GasLimit = gasLimit.IntPart()
+	contract.GasPrice = satoshis(gasPrice)
+	return satoshis(amount), sender, nil
+}
+
+func sendToContract(s *Server, ctx context.Context, p params) (interface{}, error) {
+	var address, data string
+	if err := p.get(0, &address); err != nil {
+		return nil, err
+	}
+	if err := p.get(1, &data); err != nil {
+		return nil, err
+	}
+	contract := &ContractOutput{Address: normalizeHex(address), Data: normalizeHex(data)}
+	amount, sender, err := s.contractOutput(p, 2, true, contract, defaultSendToContractGasLimit)
+	if err != nil {
+		return nil, err
+	}
+	if s.chain.Contract(contract.Address) == nil {
+		return nil, errorf(errInvalidAddress, "contract address does not exist")
+	}
+
+	tx, err := s.chain.Send(sender, []Output{{Value: amount, Contract: contract}})
+	if err != nil {
+		return nil, err
+	}
+	sent := tx.Outputs[0].Contract.Sender
+	return qtum.SendToContractResponse{Txid: tx.ID, Sender: s.chain.Address(sent), Hash160: sent}, nil
+}
+
+func createContract(s *Server, ctx context.Context, p params) (interface{}, error) {
+	var bytecode string
+	if err := p.get(0, &bytecode); err != nil {
+		return nil, err
+	}
+	contract := &ContractOutput{Data: normalizeHex(bytecode)}
+	_, sender, err := s.contractOutput(p, 1, false, contract, defaultCreateContractGasLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.chain.Send(sender, []Output{{Contract: contract}})
+	if err != nil {
+		return nil, err
+	}
+	sent := tx.Outputs[0].Contract.Sender
+	return qtum.CreateContractResponse{
+		Txid:    tx.ID,
+		Sender:  s.chain.Address(sent),
+		Hash160: sent,
+		Address: ContractAddress(tx.ID, 0),
+	}, nil
+}
+
+func generateToAddress(s *Server, ctx context.Context, p params) (interface{}, error) {
+	var n int
+	var address string
+	if err := p.get(0, &n); err != nil {
+		return nil, err
+	}
+	if err := p.get(1, &address); err != nil {
+		return nil, err
+	}
+	blocks, err := s.chain.Mine(n, address)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]string, len(blocks))
+	for i, block := range blocks {
+		hashes[i] = block.Hash
+	}
+	return hashes, nil
+}
+
+// rawOutputs parses the outputs of createrawtransaction, either {"address": amount, ...} or a list of them
+// where {"contract": {...}} is a contract call or creation
+func (c *Chain) rawOutputs(raw json.RawMessage) ([]Output, error) {
+	var list []json.RawMessage
+	if err := json.Unmarshal(raw, &list); err != nil {
+		list = []json.RawMessage{raw}
+	}
+
+	var outputs []Output
+	for _, object := range list {
+		entries, err := orderedObject(object)
+		if err != nil {
+			return nil, errorf(errType, "Invalid outputs %s", raw)
+		}
+		for _, entry := range entries {
+			key, value := entry.key, entry.value
+			if key != "contract" {
+				if _, err := c.HexAddress(key); err != nil {
+					return nil, errorf(errInvalidAddress, "Invalid Qtum address: %s", key)
+				}
+				amount, err := parseNumber(value)
+				if err != nil {
+					return nil, err
+				}
+				outputs = append(outputs, Output{Address: key, Value: satoshis(amount)})
+				continue
+			}
+
+			var request struct {
+				ContractAddress string          `json:"contractAddress"`
+				Data            string          `json:"data"`
+				ByteCode        string          `json:"bytecode"`
+				Amount          json.RawMessage `json:"amount"`
+				GasLimit        json.RawMessage `json:"gasLimit"`
+				GasPrice        json.RawMessage `json:"gasPrice"`
+				SenderAddress   string          `json:"senderaddress"`
+			}
+			if err := json.Unmarshal(value, &request); err != nil {
+				return nil, errorf(errType, "Invalid contract output %s", value)
+			}
+			contract := &ContractOutput{
+				Address:  normalizeHex(request.ContractAddress),
+				Data:     normalizeHex(request.Data),
+				GasLimit: defaultSendToContractGasLimit,
+				GasPrice: DefaultGasPrice,
+			}
+			if request.ByteCode != "" {
+				contract.Data = normalizeHex(request.ByteCode)
+				contract.GasLimit = defaultCreateContractGasLimit
+			}
+			output := Output{Contract: contract}
+			for _, number := range []struct {
+				raw   json.RawMessage
+				set   func(decimal.Decimal)
+				label string
+			}{
+				{request.Amount, func(n decimal.Decimal) { output.Value = satoshis(n) }, "amount"},
+				{request.GasLimit, func(n decimal.Decimal) { contract.GasLimit = n.IntPart() }, "gasLimit"},
+				{request.GasPrice, func(n decimal.Decimal) { contract.GasPrice = satoshis(n) }, "gasPrice"},
+			} {
+				if len(number.raw) == 0 || string(number.raw) == "null" {
+					continue
+				}
+				n, err := parseNumber(number.raw)
+				if err != nil {
+					return nil, errorf(errType, "Invalid contract %s %s", number.label, number.raw)
+				}
+				number.set(n)
+			}
+			if request.SenderAddress != "" {
+				sender, err := c.HexAddress(request.SenderAddress)
+				if err != nil {
+					return nil, err
+				}
+				contract.Sender = sender
+			}
+			outputs = append(outputs, output)
+		}
+	}
+	return outputs, nil
+}
+
+type objectEntry struct {
+	key   string
+	value json.RawMessage
+}
+
+// orderedObject parses a JSON object keeping the order of its keys, which is the order of the outputs
+func orderedObject(raw json.RawMessage) ([]objectEntry, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil, errors.New("not an object")
+	}
+	var entries []objectEntry
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		entries = append(entries, objectEntry{key: token.(string), value: value})
+	}
+	return entries, nil
+}
+
+func createRawTransaction(s *Server, ctx context.Context, p params) (interface{}, error) {
+	var inputs []Input
+	if err := p.get(0, &inputs); err != nil {
+		return nil, err
+	}
+	if !p.has(1) {
+		return nil, errorf(errMisc, "Missing parameter 2")
+	}
+	outputs, err := s.chain.rawOutputs(p[1])
+	if err != nil {
+		return nil, err
+	}
+	return s.chain.NewTransaction(inputs, outputs).Hex, nil
+}
+
+func signRawTransaction(s *Server, ctx context.Context, p params) (interface{}, error) {
+	var txHex string
+	if err := p.get(0, &txHex); err != nil {
+		return nil, err
+	}
+	tx, err := DecodeTransaction(txHex)
+	if err != nil {
+		return nil, err
+	}
+	// the wallet owns every address, so it can sign anything
+	return map[string]interface{}{"hex": tx.Hex, "complete": true}, nil
+}
+
+func sendRawTransaction(s *Server, ctx context.Context, p params) (interface{}, error) {
+	var txHex string
+	if err := p.get(0, &txHex); err != nil {
+		return nil, err
+	}
+	tx, err := DecodeTransaction(txHex)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.chain.Submit(tx); err != nil {
+		return nil, err
+	}
+	return tx.ID, nil
+}
@@ -0,0 +1,197 @@
+// Package qtumtest is a fake qtumd for tests, serving the RPCs Janus uses from an in-memory chain
+// that tests grow, reorganize and run contracts on as they need
+package qtumtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/qtumproject/janus/pkg/qtum"
+)
+
+const (
+	// RPCUser and RPCPassword are what RPCURL logs in with, the fake accepts anything
+	RPCUser     = "qtum"
+	RPCPassword = "testpasswd"
+
+	errMethodNotFound = -32601
+	errParse          = -32700
+)
+
+// Server is a fake qtumd listening on a local port
+type Server struct {
+	*httptest.Server
+
+	chain            *Chain
+	autoMineInterval time.Duration
+	autoMineAddress  string
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type Option func(*Server) error
+
+// SetChain serves chain instead of a new regtest chain
+func SetChain(chain *Chain) Option {
+	return func(s *Server) error {
+		s.chain = chain
+		return nil
+	}
+}
+
+// SetAutoMine mines a block paying address every interval, like a node that isn't alone on the network
+func SetAutoMine(interval time.Duration, address string) Option {
+	return func(s *Server) error {
+		if interval <= 0 {
+			return errors.Errorf("Invalid auto mine interval %s", interval)
+		}
+		s.autoMineInterval = interval
+		s.autoMineAddress = address
+		return nil
+	}
+}
+
+// NewServer starts a fake qtumd, tests must Close it
+func NewServer(opts ...Option) (*Server, error) {
+	s := &Server{done: make(chan struct{})}
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.chain == nil {
+		chain, err := NewChain(qtum.ChainRegTest)
+		if err != nil {
+			return nil, err
+		}
+		s.chain = chain
+	}
+	if s.autoMineInterval > 0 {
+		if _, err := s.chain.HexAddress(s.autoMineAddress); err != nil {
+			return nil, errors.Wrap(err, "Invalid auto mine address")
+		}
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	if s.autoMineInterval > 0 {
+		go s.autoMine()
+	}
+	return s, nil
+}
+
+// Chain is the chain the server serves
+func (s *Server) Chain() *Chain {
+	return s.chain
+}
+
+// RPCURL is the URL to give Janus as its qtumd
+func (s *Server) RPCURL() string {
+	return strings.Replace(s.URL, "://", "://"+RPCUser+":"+RPCPassword+"@", 1)
+}
+
+// Close ends waitforlogs requests that are waiting and stops the server
+func (s *Server) Close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.Server.Close()
+	})
+}
+
+func (s *Server) autoMine() {
+	ticker := time.NewTicker(s.autoMineInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.chain.Mine(1, s.autoMineAddress)
+		case <-s.done:
+			return
+		}
+	}
+}
+
+type request struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params params          `json:"params"`
+}
+
+type response struct {
+	Result interface{}     `json:"result"`
+	Error  *Error          `json:"error"`
+	ID     json.RawMessage `json:"id"`
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if trimmed := bytes.TrimSpace(body); len(trimmed) != 0 && trimmed[0] == '[' {
+		var requests []request
+		if err := json.Unmarshal(body, &requests); err != nil {
+			writeJSON(w, http.StatusInternalServerError, response{Error: errorf(errParse, "Parse error")})
+			return
+		}
+		// a batch always succeeds, each response has its own error
+		responses := make([]response, len(requests))
+		for i, req := range requests {
+			responses[i] = s.handle(r, req)
+		}
+		writeJSON(w, http.StatusOK, responses)
+		return
+	}
+
+	var req request
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeJSON(w, http.StatusInternalServerError, response{Error: errorf(errParse, "Parse error")})
+		return
+	}
+	resp := s.handle(r, req)
+	status := http.StatusOK
+	if resp.Error != nil {
+		status = http.StatusInternalServerError
+		if resp.Error.Code == errMethodNotFound {
+			status = http.StatusNotFound
+		}
+	}
+	writeJSON(w, status, resp)
+}
+
+func (s *Server) handle(r *http.Request, req request) response {
+	resp := response{ID: req.ID}
+	handler, ok := handlers[req.Method]
+	if !ok {
+		resp.Error = errorf(errMethodNotFound, "Method not found")
+		return resp
+	}
+
+	result, err := handler(s, r.Context(), req.Params)
+	if err != nil {
+		rpcErr, ok := err.(*Error)
+		if !ok {
+			rpcErr = errorf(errMisc, "%s", err)
+		}
+		resp.Error = rpcErr
+		return resp
+	}
+	resp.Result = result
+	return resp
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
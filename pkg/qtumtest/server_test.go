@@ -0,0 +1,228 @@
+package qtumtest
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qtumproject/janus/pkg/eth"
+	"github.com/qtumproject/janus/pkg/notifier"
+	"github.com/qtumproject/janus/pkg/qtum"
+	"github.com/qtumproject/janus/pkg/transformer"
+	"github.com/qtumproject/janus/pkg/utils"
+)
+
+var (
+	testTopic  = strings.Repeat("ab", 32)
+	testOutput = strings.Repeat("00", 31) + "2a"
+)
+
+// testExecutor stores 0x2a in slot 0 when creating a contract, logs testTopic when called with c0ffee and returns testOutput
+func testExecutor(contract *Contract, call Call) Result {
+	if call.Create {
+		return Result{GasUsed: 50000, Storage: map[string]string{strings.Repeat("00", 32): testOutput}}
+	}
+	result := Result{GasUsed: 30000, Output: testOutput}
+	if call.Data == "c0ffee" {
+		result.Logs = []qtum.Log{{Topics: []string{testTopic}, Data: testOutput}}
+	}
+	return result
+}
+
+func newTestServer(t *testing.T, opts ...Option) (*Server, *qtum.Qtum) {
+	s, err := NewServer(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	miner := s.Chain().NewAddress("miner")
+	client, err := qtum.NewClient(false, s.RPCURL(), qtum.SetContext(ctx), qtum.SetGenerateToAddress(miner))
+	if err != nil {
+		t.Fatal(err)
+	}
+	qtumClient, err := qtum.New(client, qtum.ChainRegTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, qtumClient
+}
+
+func ethRequest(t *testing.T, janus *transformer.Transformer, result interface{}, method string, params ...interface{}) {
+	t.Helper()
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, jsonErr := janus.Transform(&eth.JSONRPCRequest{JSONRPC: "2.0", Method: method, Params: rawParams}, nil)
+	if jsonErr != nil {
+		t.Fatalf("%s failed: %s", method, jsonErr.Message())
+	}
+	// go through JSON like a client would
+	encoded, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(encoded, result); err != nil {
+		t.Fatalf("%s returned %s: %s", method, encoded, err)
+	}
+}
+
+func TestJanusAgainstTheFake(t *testing.T) {
+	s, qtumClient := newTestServer(t)
+	chain := s.Chain()
+	chain.SetExecutor(testExecutor)
+	miner := chain.NewAddress("miner")
+	if _, err := chain.Mine(2, miner); err != nil {
+		t.Fatal(err)
+	}
+	minerHex, _ := chain.HexAddress(miner)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	janus, err := transformer.New(qtumClient, transformer.DefaultProxies(qtumClient, notifier.NewAgent(ctx, qtumClient, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var blockNumber string
+	ethRequest(t, janus, &blockNumber, "eth_blockNumber")
+	if blockNumber != "0x2" {
+		t.Errorf("expected block 0x2, got %s", blockNumber)
+	}
+
+	send := func(tx map[string]string) eth.GetTransactionReceiptResponse {
+		t.Helper()
+		tx["from"] = utils.AddHexPrefix(minerHex)
+		tx["gas"] = "0x3d090"
+		tx["gasPrice"] = "0x28"
+		var txHash string
+		ethRequest(t, janus, &txHash, "eth_sendTransaction", tx)
+		// on regtest Janus mines a block after sending a transaction
+		var receipt eth.GetTransactionReceiptResponse
+		ethRequest(t, janus, &receipt, "eth_getTransactionReceipt", txHash)
+		if receipt.TransactionHash != txHash || receipt.Status != "0x1" {
+			t.Fatalf("unexpected receipt %+v", receipt)
+		}
+		return receipt
+	}
+
+	created := send(map[string]string{"data": "0x6000"})
+	if created.ContractAddress == "" || created.BlockNumber != "0x3" {
+		t.Fatalf("expected a contract created in block 3, got %+v", created)
+	}
+	var stored string
+	ethRequest(t, janus, &stored, "eth_getStorageAt", created.ContractAddress, "0x0", "latest")
+	if utils.RemoveHexPrefix(stored) != testOutput {
+		t.Errorf("expected the created contract's storage, got %s", stored)
+	}
+
+	called := send(map[string]string{"to": created.ContractAddress, "data": "0xc0ffee"})
+	if len(called.Logs) != 1 || called.Logs[0].Topics[0] != utils.AddHexPrefix(testTopic) {
+		t.Fatalf("expected the call to log, got %+v", called.Logs)
+	}
+
+	var logs []eth.Log
+	ethRequest(t, janus, &logs, "eth_getLogs", map[string]interface{}{"fromBlock": "0x0", "toBlock": "latest", "address": created.ContractAddress})
+	if len(logs) != 1 || logs[0].TransactionHash != called.TransactionHash {
+		t.Errorf("expected the call's log, got %+v", logs)
+	}
+
+	var output string
+	ethRequest(t, janus, &output, "eth_call", map[string]string{"from": utils.AddHexPrefix(minerHex), "to": created.ContractAddress, "data": "0x01"}, "latest")
+	if utils.RemoveHexPrefix(output) != testOutput {
+		t.Errorf("expected the executor's output, got %s", output)
+	}
+
+	var tx eth.GetTransactionByHashResponse
+	ethRequest(t, janus, &tx, "eth_getTransactionByHash", called.TransactionHash)
+	if tx.To != created.ContractAddress || tx.BlockHash != called.BlockHash {
+		t.Errorf("unexpected transaction %+v", tx)
+	}
+
+	var block eth.GetBlockByNumberResponse
+	ethRequest(t, janus, &block, "eth_getBlockByNumber", "latest", false)
+	if block.Hash != utils.AddHexPrefix(chain.Block(4).Hash) || block.Number != "0x4" {
+		t.Errorf("expected block 4, got %+v", block)
+	}
+}
+
+func TestWaitForLogs(t *testing.T) {
+	s, qtumClient := newTestServer(t)
+	chain := s.Chain()
+	chain.SetExecutor(testExecutor)
+	miner := chain.NewAddress("miner")
+	chain.Mine(1, miner)
+	created, err := chain.Send(miner, []Output{{Contract: &ContractOutput{Data: "6000", GasLimit: 100000, GasPrice: DefaultGasPrice}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain.Mine(1, miner)
+	contract := ContractAddress(created.ID, 0)
+
+	result := make(chan *qtum.WaitForLogsResponse)
+	go func() {
+		resp, err := qtumClient.WaitForLogs(context.Background(), &qtum.WaitForLogsRequest{
+			FromBlock: nil,
+			ToBlock:   nil,
+			Filter:    qtum.WaitForLogsFilter{Addresses: &[]string{contract}},
+		})
+		if err != nil {
+			t.Error(err)
+		}
+		result <- resp
+	}()
+
+	select {
+	case <-result:
+		t.Fatal("expected waitforlogs to wait for a log")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	called, err := chain.Send(miner, []Output{{Contract: &ContractOutput{Address: contract, Data: "c0ffee", GasLimit: 100000, GasPrice: DefaultGasPrice}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chain.Mine(1, miner)
+
+	select {
+	case resp := <-result:
+		if resp == nil || resp.Count != 1 || resp.Entries[0].TransactionHash != called.ID || resp.NextBlock != 4 {
+			t.Errorf("unexpected waitforlogs response %+v", resp)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("waitforlogs didn't return the log")
+	}
+}
+
+func TestErrors(t *testing.T) {
+	_, qtumClient := newTestServer(t)
+
+	var hash qtum.GetBlockHashResponse
+	if err := qtumClient.Request(qtum.MethodGetBlockHash, []int64{100}, &hash); err != qtum.ErrInvalidParameter {
+		t.Errorf("expected a height out of range to be an invalid parameter, got %v", err)
+	}
+	var result interface{}
+	if err := qtumClient.Request(qtum.MethodDecodeRawTransaction, []string{"00"}, &result); err != qtum.ErrDeserializationError {
+		t.Errorf("expected a deserialization error, got %v", err)
+	}
+	if err := qtumClient.Request("getnothing", []string{}, &result); err == nil {
+		t.Error("expected an unknown method to fail")
+	}
+
+	var genesis, missing qtum.GetBlockHashResponse
+	requests := []*qtum.BatchRequest{
+		{Method: qtum.MethodGetBlockHash, Params: []int64{0}, Result: &genesis},
+		{Method: qtum.MethodGetBlockHash, Params: []int64{1}, Result: &missing},
+	}
+	if err := qtumClient.RequestBatch(nil, requests); err != nil {
+		t.Fatal(err)
+	}
+	if requests[0].Err != nil || string(genesis) == "" || requests[1].Err != qtum.ErrInvalidParameter {
+		t.Errorf("expected each request of a batch to get its own response, got %q %v %v", genesis, requests[0].Err, requests[1].Err)
+	}
+}
@@ -0,0 +1,190 @@
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/qtumproject/janus/pkg/eth"
+)
+
+var (
+	// DefaultBurst is how many tokens a client can spend at once after being idle
+	DefaultBurst float64 = 50
+	// DefaultCost is what a request costs when its method has no cost of its own
+	DefaultCost float64 = 1
+	// DefaultCosts are the methods that take a lot more qtumd work than a typical request
+	// a method with a ":full" suffix is what it costs when full transactions are requested
+	DefaultCosts = map[string]float64{
+		"eth_getLogs":               10,
+		"eth_getFilterLogs":         10,
+		"eth_getBlockByNumber:full": 10,
+		"eth_getBlockByHash:full":   10,
+		"eth_call":                  2,
+		"eth_estimateGas":           2,
+	}
+	// how often buckets of clients that went quiet are dropped
+	pruneInterval = time.Minute
+)
+
+// methods whose second parameter asks for full transactions
+var fullTransactionMethods = map[string]bool{
+	"eth_getBlockByNumber": true,
+	"eth_getBlockByHash":   true,
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter is a token bucket rate limiter with a bucket per client
+// every client's bucket refills at rate tokens a second up to burst, each request takes its method's cost out of it
+type Limiter struct {
+	ctx context.Context
+	now func() time.Time
+
+	mutex   sync.Mutex
+	rate    float64
+	burst   float64
+	costs   map[string]float64
+	buckets map[string]*bucket
+}
+
+type Option func(*Limiter) error
+
+// New makes a limiter that lets each client spend rate tokens a second
+func New(ctx context.Context, rate float64, opts ...Option) (*Limiter, error) {
+	if rate <= 0 {
+		return nil, errors.New("rate limit must be positive")
+	}
+
+	l := &Limiter{
+		ctx:     ctx,
+		now:     time.Now,
+		rate:    rate,
+		burst:   DefaultBurst,
+		costs:   DefaultCosts,
+		buckets: make(map[string]*bucket),
+	}
+
+	for _, opt := range opts {
+		if err := opt(l); err != nil {
+			return nil, err
+		}
+	}
+
+	go l.pruneIdleBuckets()
+
+	return l, nil
+}
+
+func SetBurst(burst float64) Option {
+	return func(l *Limiter) error {
+		if burst < 1 {
+			return errors.New("rate limit burst must be at least 1")
+		}
+		l.burst = burst
+		return nil
+	}
+}
+
+// SetCosts overrides what some methods cost, methods that aren't in costs keep their default cost
+func SetCosts(costs map[string]float64) Option {
+	return func(l *Limiter) error {
+		merged := make(map[string]float64, len(DefaultCosts)+len(costs))
+		for method, cost := range DefaultCosts {
+			merged[method] = cost
+		}
+		for method, cost := range costs {
+			if cost < 0 {
+				return errors.Errorf("cost of %s cannot be negative", method)
+			}
+			merged[method] = cost
+		}
+		l.costs = merged
+		return nil
+	}
+}
+
+// Allow takes req's cost out of key's bucket
+// if there aren't enough tokens nothing is taken and retryAfter is how long until there will be
+func (l *Limiter) Allow(key string, req *eth.JSONRPCRequest) (retryAfter time.Duration, ok bool) {
+	cost := l.Cost(req)
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	// a request costing more than the burst could never go through, it empties the bucket instead
+	cost = math.Min(cost, l.burst)
+
+	now := l.now()
+	b, found := l.buckets[key]
+	if !found {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < cost {
+		return time.Duration((cost - b.tokens) / l.rate * float64(time.Second)), false
+	}
+	b.tokens -= cost
+	return 0, true
+}
+
+// Cost is how many tokens req takes
+func (l *Limiter) Cost(req *eth.JSONRPCRequest) float64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if fullTransactionMethods[req.Method] && wantsFullTransactions(req.Params) {
+		if cost, ok := l.costs[req.Method+":full"]; ok {
+			return cost
+		}
+	}
+	if cost, ok := l.costs[req.Method]; ok {
+		return cost
+	}
+	return DefaultCost
+}
+
+func wantsFullTransactions(params json.RawMessage) bool {
+	var args []json.RawMessage
+	if err := json.Unmarshal(params, &args); err != nil || len(args) < 2 {
+		return false
+	}
+	return bytes.Equal(bytes.TrimSpace(args[1]), []byte("true"))
+}
+
+func (l *Limiter) pruneIdleBuckets() {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.ctx.Done():
+			return
+		case <-ticker.C:
+			l.prune()
+		}
+	}
+}
+
+// prune drops the buckets that have refilled, they are the same as a new one
+func (l *Limiter) prune() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
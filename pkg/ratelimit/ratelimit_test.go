@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/qtumproject/janus/pkg/eth"
+)
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func newTestLimiter(t *testing.T, rate float64, opts ...Option) (*Limiter, *testClock) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	limiter, err := New(ctx, rate, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := &testClock{now: time.Unix(1600000000, 0)}
+	limiter.now = clock.Now
+	return limiter, clock
+}
+
+func request(method string, params ...interface{}) *eth.JSONRPCRequest {
+	rawParams, _ := json.Marshal(params)
+	return &eth.JSONRPCRequest{Method: method, Params: rawParams}
+}
+
+func TestLimiterRefillsBuckets(t *testing.T) {
+	limiter, clock := newTestLimiter(t, 2, SetBurst(3))
+
+	for i := 0; i < 3; i++ {
+		if _, ok := limiter.Allow("a", request("eth_chainId")); !ok {
+			t.Fatalf("expected request %d to be allowed", i)
+		}
+	}
+	retryAfter, ok := limiter.Allow("a", request("eth_chainId"))
+	if ok {
+		t.Fatal("expected the bucket to be empty")
+	}
+	if retryAfter != 500*time.Millisecond {
+		t.Errorf("expected to retry after 500ms, got %s", retryAfter)
+	}
+
+	// other clients have their own bucket
+	if _, ok := limiter.Allow("b", request("eth_chainId")); !ok {
+		t.Error("expected another client to be allowed")
+	}
+
+	clock.now = clock.now.Add(500 * time.Millisecond)
+	if _, ok := limiter.Allow("a", request("eth_chainId")); !ok {
+		t.Error("expected a token to have been refilled")
+	}
+}
+
+func TestLimiterChargesMethodCosts(t *testing.T) {
+	limiter, _ := newTestLimiter(t, 1, SetBurst(20), SetCosts(map[string]float64{"eth_call": 5}))
+
+	costs := map[*eth.JSONRPCRequest]float64{
+		request("eth_chainId"):                        DefaultCost,
+		request("eth_call"):                           5,
+		request("eth_getLogs"):                        DefaultCosts["eth_getLogs"],
+		request("eth_getBlockByNumber", "0x1", false): DefaultCost,
+		request("eth_getBlockByNumber", "0x1", true):  DefaultCosts["eth_getBlockByNumber:full"],
+	}
+	for req, want := range costs {
+		if got := limiter.Cost(req); got != want {
+			t.Errorf("expected %s%s to cost %v, got %v", req.Method, req.Params, want, got)
+		}
+	}
+
+	// a request costing more than the burst still goes through on a full bucket
+	limiter, _ = newTestLimiter(t, 1, SetBurst(5))
+	if _, ok := limiter.Allow("a", request("eth_getLogs")); !ok {
+		t.Error("expected an expensive request to be allowed on a full bucket")
+	}
+	if _, ok := limiter.Allow("a", request("eth_chainId")); ok {
+		t.Error("expected the expensive request to have emptied the bucket")
+	}
+}
+
+func TestLimiterPrunesRefilledBuckets(t *testing.T) {
+	limiter, clock := newTestLimiter(t, 1, SetBurst(2))
+
+	limiter.Allow("a", request("eth_chainId"))
+	limiter.Allow("b", request("eth_chainId"))
+	clock.now = clock.now.Add(500 * time.Millisecond)
+	limiter.Allow("b", request("eth_chainId"))
+
+	clock.now = clock.now.Add(500 * time.Millisecond)
+	limiter.prune()
+
+	if _, ok := limiter.buckets["a"]; ok {
+		t.Error("expected the refilled bucket to be pruned")
+	}
+	if _, ok := limiter.buckets["b"]; !ok {
+		t.Error("expected the bucket that is still refilling to be kept")
+	}
+}
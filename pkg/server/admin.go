@@ -0,0 +1,48 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/qtumproject/janus/pkg/eth"
+)
+
+// AdminTokenHeader is where admin_ requests send the admin token, websockets send it on the upgrade request
+const AdminTokenHeader = "X-Admin-Token"
+
+func isAdminMethod(method string) bool {
+	return strings.HasPrefix(method, "admin_")
+}
+
+// SetAdminToken requires admin_ requests to send token in the X-Admin-Token header, an empty token disables
+// the admin_ methods
+func SetAdminToken(token string) Option {
+	return func(p *Server) error {
+		p.adminToken = token
+		return nil
+	}
+}
+
+// isAdmin is whether req has the admin token
+func (s *Server) isAdmin(req *http.Request) bool {
+	if s.adminToken == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(req.Header.Get(AdminTokenHeader)), []byte(s.adminToken)) == 1
+}
+
+// authorizeAdmin checks that the client can call the admin_ method
+func (c *myCtx) authorizeAdmin(method string) eth.JSONRPCError {
+	if c.admin {
+		return nil
+	}
+	if !c.adminEnabled {
+		return eth.NewJSONRPCError(
+			eth.MethodNotFoundErrorCode,
+			"The method "+method+" is disabled, start Janus with --admin-token to enable it",
+			nil,
+		)
+	}
+	return eth.NewInvalidRequestError("The method " + method + " requires a valid " + AdminTokenHeader + " header")
+}
@@ -0,0 +1,30 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/qtumproject/janus/pkg/eth"
+)
+
+func TestWebhookMethodsNeedTheAdminToken(t *testing.T) {
+	const listWebhooks = `{"jsonrpc":"2.0","id":1,"method":"admin_listWebhooks","params":[]}`
+
+	s := newTestServer(t)
+	_, body := s.post(t, listWebhooks, nil)
+	if response := decodeResponse(t, body); response.Error == nil || response.Error.Code != eth.MethodNotFoundErrorCode {
+		t.Errorf("expected the webhook methods to be disabled without an admin token, got %s", body)
+	}
+
+	s = newTestServer(t, SetAdminToken("secret"))
+	for _, token := range []string{"", "wrong"} {
+		_, body = s.post(t, listWebhooks, map[string]string{AdminTokenHeader: token})
+		if response := decodeResponse(t, body); response.Error == nil || response.Error.Code != eth.InvalidRequestErrorCode {
+			t.Errorf("expected admin_listWebhooks with token %q to be refused, got %s", token, body)
+		}
+	}
+
+	_, body = s.post(t, listWebhooks, map[string]string{AdminTokenHeader: "secret"})
+	if response := decodeResponse(t, body); response.Error != nil {
+		t.Errorf("expected admin_listWebhooks with the admin token to succeed, got %s", body)
+	}
+}
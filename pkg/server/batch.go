@@ -0,0 +1,198 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/pkg/errors"
+	"github.com/qtumproject/janus/pkg/auth"
+	"github.com/qtumproject/janus/pkg/eth"
+)
+
+const (
+	// DefaultBatchConcurrency is how many requests of a batch are run at the same time
+	DefaultBatchConcurrency = 10
+	// DefaultMaxBatchSize is how many requests a batch can have
+	DefaultMaxBatchSize = 1000
+)
+
+// code of the replies to errors that aren't JSON-RPC errors
+const internalErrorCode = 100
+
+func (s *Server) batchRequestsMiddleware(h echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		myctx := c.Get("myctx")
+		cc, ok := myctx.(*myCtx)
+		if !ok {
+			return errors.New("Could not find myctx")
+		}
+
+		reqBody := []byte{}
+		if c.Request().Body != nil {
+			var err error
+			reqBody, err = ioutil.ReadAll(c.Request().Body)
+			if err != nil {
+				return errors.Wrap(err, "Failed to read request body")
+			}
+		}
+		c.Request().Body = ioutil.NopCloser(bytes.NewBuffer(reqBody)) // Reset
+
+		if !isBatchRequests(reqBody) {
+			return h(c)
+		}
+
+		response, retryAfter := s.transformBatch(cc, reqBody)
+		// a response only has one Retry-After, by then all of the rate limited requests can be retried
+		if retryAfter > 0 {
+			setRetryAfter(c.Response().Header(), retryAfter)
+		}
+		return c.JSON(http.StatusOK, response)
+	}
+}
+
+// transformBatch runs a batch from any transport, it's either refused as a whole with an error response
+// or every request gets its own response
+// retryAfter is the longest a rate limited request of the batch has to wait
+func (s *Server) transformBatch(cc *myCtx, batch []byte) (response interface{}, retryAfter time.Duration) {
+	// requests are parsed one by one so an invalid one only fails itself
+	var rawReqs []json.RawMessage
+	if err := json.Unmarshal(batch, &rawReqs); err != nil {
+		return batchError(nil, eth.NewInvalidMessageError("Invalid batch: "+err.Error())), 0
+	}
+	if len(rawReqs) == 0 {
+		return batchError(nil, eth.NewInvalidRequestError("Empty batch")), 0
+	}
+	if s.maxBatchSize > 0 && len(rawReqs) > s.maxBatchSize {
+		return batchError(nil, eth.NewInvalidRequestError(
+			fmt.Sprintf("Batch of %d requests is larger than the limit of %d", len(rawReqs), s.maxBatchSize),
+		)), 0
+	}
+
+	concurrency := s.batchConcurrency
+	if s.mutex != nil {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		concurrency = 1
+	}
+
+	return cc.transformBatch(rawReqs, concurrency)
+}
+
+// methods that change the connection they're sent on, a batch with one of them runs in order one request at a time
+var connectionMethods = map[string]bool{
+	auth.AuthenticateMethod: true,
+	"qtum_resumeSession":    true,
+}
+
+// transformBatch runs the requests of a batch, at most concurrency of them at a time
+// every request gets its own response in the same order, one failing doesn't affect the others
+func (c *myCtx) transformBatch(rawReqs []json.RawMessage, concurrency int) ([]*eth.JSONRPCResult, time.Duration) {
+	rpcReqs := make([]*eth.JSONRPCRequest, len(rawReqs))
+	for i, rawReq := range rawReqs {
+		// an invalid request stays nil
+		if err := json.Unmarshal(rawReq, &rpcReqs[i]); err != nil {
+			rpcReqs[i] = nil
+		}
+		if rpcReqs[i] != nil && connectionMethods[rpcReqs[i].Method] {
+			concurrency = 1
+		}
+	}
+
+	results := make([]*eth.JSONRPCResult, len(rpcReqs))
+	retryAfters := make([]time.Duration, len(rpcReqs))
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, concurrency)
+	for i := range rpcReqs {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			results[i], retryAfters[i] = c.transformBatchRequest(rpcReqs[i])
+		}(i)
+	}
+	wg.Wait()
+
+	var retryAfter time.Duration
+	for _, after := range retryAfters {
+		if after > retryAfter {
+			retryAfter = after
+		}
+	}
+	return results, retryAfter
+}
+
+// transformRequest runs a request that isn't part of a batch, rawReq is invalid if it doesn't parse
+func (c *myCtx) transformRequest(rawReq json.RawMessage) (*eth.JSONRPCResult, time.Duration) {
+	var rpcReq *eth.JSONRPCRequest
+	if err := json.Unmarshal(rawReq, &rpcReq); err != nil {
+		rpcReq = nil
+	}
+	return c.transformBatchRequest(rpcReq)
+}
+
+// transformBatchRequest runs one request of a batch, or of a websocket or IPC message
+// any error it runs into ends up in its response, a nil rpcReq is an invalid request
+func (c *myCtx) transformBatchRequest(rpcReq *eth.JSONRPCRequest) (result *eth.JSONRPCResult, retryAfter time.Duration) {
+	if c.websocket {
+		atomic.AddInt32(&c.websocketRequests, 1)
+	}
+	if rpcReq == nil {
+		return batchError(json.RawMessage("null"), eth.NewInvalidRequestError("Invalid request")), 0
+	}
+
+	// a panic outside of the handler's goroutine would take down the whole server
+	defer func() {
+		if r := recover(); r != nil {
+			c.GetErrorLogger().Log("msg", "Panic in batch request", "method", rpcReq.Method, "err", r)
+			result = batchError(rpcReq.ID, eth.NewJSONRPCError(internalErrorCode, fmt.Sprint(r), nil))
+		}
+	}()
+
+	c.GetLogger().Log("msg", "proxy RPC", "method", rpcReq.Method)
+
+	var response interface{}
+	var jsonErr eth.JSONRPCError
+	if c.websocket && rpcReq.Method == auth.AuthenticateMethod {
+		response, jsonErr = c.authenticate(rpcReq)
+	} else {
+		response, retryAfter, jsonErr = c.limitedTransform(rpcReq, c)
+	}
+	if jsonErr != nil {
+		if err := jsonErr.Error(); err != nil {
+			jsonErr = eth.NewJSONRPCError(internalErrorCode, err.Error(), nil)
+		}
+		c.GetErrorLogger().Log("method", rpcReq.Method, "err", jsonErr.Message())
+		return batchError(rpcReq.ID, jsonErr), retryAfter
+	}
+
+	// Allow transformer to return an explicit JSON error
+	if jerr, isJSONErr := response.(eth.JSONRPCError); isJSONErr {
+		return batchError(rpcReq.ID, jerr), 0
+	}
+
+	result, err := eth.NewJSONRPCResult(rpcReq.ID, response)
+	if err != nil {
+		c.GetErrorLogger().Log("method", rpcReq.Method, "err", err.Error())
+		return batchError(rpcReq.ID, eth.NewJSONRPCError(internalErrorCode, err.Error(), nil)), 0
+	}
+	return result, 0
+}
+
+func batchError(id json.RawMessage, err eth.JSONRPCError) *eth.JSONRPCResult {
+	return &eth.JSONRPCResult{
+		JSONRPC: eth.RPCVersion,
+		ID:      id,
+		Error:   err,
+	}
+}
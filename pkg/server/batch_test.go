@@ -0,0 +1,290 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo"
+	"github.com/qtumproject/janus/pkg/auth"
+	"github.com/qtumproject/janus/pkg/eth"
+	"github.com/qtumproject/janus/pkg/qtumtest"
+	"github.com/qtumproject/janus/pkg/ratelimit"
+	"github.com/qtumproject/janus/pkg/transformer"
+)
+
+// sleepProxy returns its first param after sleeping for its second param's milliseconds
+type sleepProxy struct{}
+
+func (p *sleepProxy) Method() string {
+	return "test_sleep"
+}
+
+func (p *sleepProxy) Request(ctx context.Context, req *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
+	var params []int
+	if err := json.Unmarshal(req.Params, &params); err != nil || len(params) != 2 {
+		return nil, eth.NewInvalidParamsError("expected a value and a sleep")
+	}
+	time.Sleep(time.Duration(params[1]) * time.Millisecond)
+	return params[0], nil
+}
+
+type panicProxy struct{}
+
+func (p *panicProxy) Method() string {
+	return "test_panic"
+}
+
+func (p *panicProxy) Request(context.Context, *eth.JSONRPCRequest, echo.Context) (interface{}, eth.JSONRPCError) {
+	panic("test panic")
+}
+
+func newBatchTestServer(t *testing.T, opts ...Option) *testServer {
+	t.Helper()
+	qtumd, err := qtumtest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(qtumd.Close)
+	return newTestServerFor(t, qtumd, []transformer.ETHProxy{&sleepProxy{}, &panicProxy{}}, opts...)
+}
+
+func postBatch(t *testing.T, s *testServer, body string) (*http.Response, []*rpcResponse) {
+	t.Helper()
+	resp, responseBody := s.post(t, body, nil)
+	var responses []*rpcResponse
+	if err := json.Unmarshal(responseBody, &responses); err != nil {
+		t.Fatalf("Expected a batch response, got %s: %s", responseBody, err)
+	}
+	return resp, responses
+}
+
+func TestBatchKeepsRequestOrder(t *testing.T) {
+	s := newBatchTestServer(t, SetBatchConcurrency(4))
+
+	// earlier requests take longer, so they finish last
+	count := 8
+	requests := make([]string, count)
+	for i := range requests {
+		requests[i] = fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"test_sleep","params":[%d,%d]}`, i, i*10, (count-i)*10)
+	}
+	start := time.Now()
+	_, responses := postBatch(t, s, "["+strings.Join(requests, ",")+"]")
+
+	if len(responses) != count {
+		t.Fatalf("Expected %d responses, got %d", count, len(responses))
+	}
+	for i, response := range responses {
+		if string(response.ID) != fmt.Sprint(i) || string(response.Result) != fmt.Sprint(i*10) || response.Error != nil {
+			t.Errorf("Response %d out of order or failed: id %s result %s", i, response.ID, response.Result)
+		}
+	}
+	// 360ms of sleeps run 4 at a time
+	if elapsed := time.Since(start); elapsed > 300*time.Millisecond {
+		t.Errorf("Expected the requests to run concurrently, took %s", elapsed)
+	}
+}
+
+func TestBatchInvalidRequestOnlyFailsItself(t *testing.T) {
+	s := newBatchTestServer(t)
+
+	_, responses := postBatch(t, s, `[
+		{"jsonrpc":"2.0","id":1,"method":"test_sleep","params":[1,0]},
+		"not a request",
+		{"jsonrpc":"2.0","id":3,"method":"test_sleep","params":[3,0]}
+	]`)
+
+	if len(responses) != 3 {
+		t.Fatalf("Expected 3 responses, got %d", len(responses))
+	}
+	if responses[0].Error != nil || string(responses[0].Result) != "1" || responses[2].Error != nil || string(responses[2].Result) != "3" {
+		t.Errorf("Expected the valid requests to succeed, got %+v and %+v", responses[0], responses[2])
+	}
+	if responses[1].Error == nil || responses[1].Error.Code != eth.InvalidRequestErrorCode || string(responses[1].ID) != "null" {
+		t.Errorf("Expected an invalid request error with a null id, got %+v", responses[1])
+	}
+}
+
+func TestBatchPanicBecomesError(t *testing.T) {
+	s := newBatchTestServer(t)
+
+	_, responses := postBatch(t, s, `[
+		{"jsonrpc":"2.0","id":1,"method":"test_panic","params":[]},
+		{"jsonrpc":"2.0","id":2,"method":"test_sleep","params":[2,0]}
+	]`)
+
+	if len(responses) != 2 {
+		t.Fatalf("Expected 2 responses, got %d", len(responses))
+	}
+	if responses[0].Error == nil || responses[0].Error.Code != internalErrorCode || !strings.Contains(responses[0].Error.Message, "test panic") || string(responses[0].ID) != "1" {
+		t.Errorf("Expected the panic to become an error, got %+v", responses[0])
+	}
+	if responses[1].Error != nil || string(responses[1].Result) != "2" {
+		t.Errorf("Expected the other request to succeed, got %+v", responses[1])
+	}
+}
+
+func TestBatchSizeLimits(t *testing.T) {
+	s := newBatchTestServer(t, SetMaxBatchSize(2))
+
+	request := `{"jsonrpc":"2.0","id":1,"method":"test_sleep","params":[1,0]}`
+	tests := []struct {
+		batch   string
+		message string
+	}{
+		{"[]", "Empty batch"},
+		{"[" + strings.Join([]string{request, request, request}, ",") + "]", "Batch of 3 requests is larger than the limit of 2"},
+	}
+	for _, test := range tests {
+		_, body := s.post(t, test.batch, nil)
+		response := decodeResponse(t, body)
+		if response.Error == nil || response.Error.Code != eth.InvalidRequestErrorCode || response.Error.Message != test.message {
+			t.Errorf("Expected batch %s to be rejected with %q, got %s", test.batch, test.message, body)
+		}
+	}
+
+	_, responses := postBatch(t, s, "["+request+","+request+"]")
+	if len(responses) != 2 {
+		t.Errorf("Expected a batch at the limit to be run, got %d responses", len(responses))
+	}
+}
+
+func TestBatchRetryAfter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	limiter, err := ratelimit.New(ctx, 0.001, ratelimit.SetBurst(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newBatchTestServer(t, SetRateLimiter(limiter))
+
+	resp, responses := postBatch(t, s, `[
+		{"jsonrpc":"2.0","id":1,"method":"test_sleep","params":[1,0]},
+		{"jsonrpc":"2.0","id":2,"method":"test_sleep","params":[2,0]},
+		{"jsonrpc":"2.0","id":3,"method":"test_sleep","params":[3,0]}
+	]`)
+
+	limited := 0
+	for _, response := range responses {
+		if response.Error != nil {
+			if response.Error.Code != eth.LimitExceededErrorCode {
+				t.Errorf("Unexpected error %+v", response.Error)
+			}
+			limited++
+		}
+	}
+	if limited != 1 {
+		t.Errorf("Expected 1 of 3 requests to be rate limited, got %d", limited)
+	}
+	if resp.Header.Get("Retry-After") == "" {
+		t.Error("Expected a Retry-After header when a request of the batch is rate limited")
+	}
+}
+
+// dialWebsocket opens a websocket connection to s
+func dialWebsocket(t *testing.T, s *testServer) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.http.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// websocketCall sends message and returns the response to it
+func websocketCall(t *testing.T, conn *websocket.Conn, message string) []byte {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(message)); err != nil {
+		t.Fatal(err)
+	}
+	_, response, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+func TestWebsocketBatch(t *testing.T) {
+	s := newBatchTestServer(t, SetMaxBatchSize(3), SetBatchConcurrency(4))
+	conn := dialWebsocket(t, s)
+
+	var responses []*rpcResponse
+	body := websocketCall(t, conn, `[
+		{"jsonrpc":"2.0","id":1,"method":"test_sleep","params":[1,100]},
+		"not a request",
+		{"jsonrpc":"2.0","id":3,"method":"test_panic","params":[]}
+	]`)
+	if err := json.Unmarshal(body, &responses); err != nil || len(responses) != 3 {
+		t.Fatalf("Expected 3 responses, got %s", body)
+	}
+	if responses[0].Error != nil || string(responses[0].Result) != "1" {
+		t.Errorf("Expected the first request to succeed, got %+v", responses[0])
+	}
+	if responses[1].Error == nil || responses[1].Error.Code != eth.InvalidRequestErrorCode {
+		t.Errorf("Expected an invalid request error, got %+v", responses[1])
+	}
+	if responses[2].Error == nil || responses[2].Error.Code != internalErrorCode || string(responses[2].ID) != "3" {
+		t.Errorf("Expected the panic to become an error, got %+v", responses[2])
+	}
+
+	request := `{"jsonrpc":"2.0","id":1,"method":"test_sleep","params":[1,0]}`
+	body = websocketCall(t, conn, "["+strings.Join([]string{request, request, request, request}, ",")+"]")
+	if response := decodeResponse(t, body); response.Error == nil || response.Error.Message != "Batch of 4 requests is larger than the limit of 3" {
+		t.Errorf("Expected the batch to be rejected, got %s", body)
+	}
+
+	body = websocketCall(t, conn, `"not a request"`)
+	if response := decodeResponse(t, body); response.Error == nil || response.Error.Code != eth.InvalidRequestErrorCode {
+		t.Errorf("Expected an invalid request error, got %s", body)
+	}
+
+	// the connection still works
+	body = websocketCall(t, conn, request)
+	if response := decodeResponse(t, body); response.Error != nil || string(response.Result) != "1" {
+		t.Errorf("Expected the request to succeed, got %s", body)
+	}
+}
+
+func TestWebsocketBatchAuthenticates(t *testing.T) {
+	authenticator, err := auth.New(&auth.Config{Keys: []auth.Key{{Name: "test", Key: "secret", Methods: []string{"test_*"}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newBatchTestServer(t, SetAuthenticator(authenticator))
+
+	var responses []*rpcResponse
+	conn := dialWebsocket(t, s)
+	body := websocketCall(t, conn, `[
+		{"jsonrpc":"2.0","id":1,"method":"qtum_authenticate","params":["secret"]},
+		{"jsonrpc":"2.0","id":2,"method":"test_sleep","params":[2,0]}
+	]`)
+	if err := json.Unmarshal(body, &responses); err != nil || len(responses) != 2 {
+		t.Fatalf("Expected 2 responses, got %s", body)
+	}
+	if responses[0].Error != nil || responses[1].Error != nil || string(responses[1].Result) != "2" {
+		t.Errorf("Expected the batch to run with the key it authenticated with, got %s", body)
+	}
+	body = websocketCall(t, conn, `[{"jsonrpc":"2.0","id":3,"method":"qtum_authenticate","params":["secret"]}]`)
+	if err := json.Unmarshal(body, &responses); err != nil || len(responses) != 1 || responses[0].Error == nil {
+		t.Errorf("Expected authenticating again to be refused, got %s", body)
+	}
+
+	conn = dialWebsocket(t, s)
+	body = websocketCall(t, conn, `[
+		{"jsonrpc":"2.0","id":1,"method":"test_sleep","params":[1,0]},
+		{"jsonrpc":"2.0","id":2,"method":"qtum_authenticate","params":["secret"]}
+	]`)
+	if err := json.Unmarshal(body, &responses); err != nil || len(responses) != 2 {
+		t.Fatalf("Expected 2 responses, got %s", body)
+	}
+	if responses[0].Error == nil || responses[1].Error == nil || responses[1].Error.Message != "qtum_authenticate has to be the first message" {
+		t.Errorf("Expected qtum_authenticate to only work as the first request, got %s", body)
+	}
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo"
+	"github.com/pkg/errors"
+)
+
+// SetTrustedProxies trusts the X-Forwarded-For and X-Real-IP headers of requests from these IP addresses or CIDR ranges
+// without them the client IP is always the address of the peer, as anyone can send those headers
+func SetTrustedProxies(proxies []string) Option {
+	return func(p *Server) error {
+		trusted := make([]*net.IPNet, 0, len(proxies))
+		for _, proxy := range proxies {
+			proxy = strings.TrimSpace(proxy)
+			if proxy == "" {
+				continue
+			}
+			if !strings.Contains(proxy, "/") {
+				ip := net.ParseIP(proxy)
+				if ip == nil {
+					return errors.Errorf("Invalid trusted proxy %s", proxy)
+				}
+				bits := 8 * net.IPv6len
+				if ip.To4() != nil {
+					ip = ip.To4()
+					bits = 8 * net.IPv4len
+				}
+				trusted = append(trusted, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+				continue
+			}
+			_, network, err := net.ParseCIDR(proxy)
+			if err != nil {
+				return errors.Wrapf(err, "Invalid trusted proxy %s", proxy)
+			}
+			trusted = append(trusted, network)
+		}
+		p.trustedProxies = trusted
+		return nil
+	}
+}
+
+func (s *Server) isTrustedProxy(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range s.trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP is the IP address rate limits and connections are tracked by
+// X-Forwarded-For is followed back from the peer through the trusted proxies, the first address that isn't one is the client
+func (s *Server) clientIP(req *http.Request) string {
+	peer := remoteIP(req)
+	if !s.isTrustedProxy(net.ParseIP(peer)) {
+		return peer
+	}
+
+	if forwardedFor := req.Header.Get(echo.HeaderXForwardedFor); forwardedFor != "" {
+		forwarded := strings.Split(forwardedFor, ",")
+		client := peer
+		for i := len(forwarded) - 1; i >= 0; i-- {
+			client = strings.TrimSpace(forwarded[i])
+			if !s.isTrustedProxy(net.ParseIP(client)) {
+				break
+			}
+		}
+		return client
+	}
+	if realIP := strings.TrimSpace(req.Header.Get(echo.HeaderXRealIP)); realIP != "" {
+		return realIP
+	}
+	return peer
+}
+
+// remoteIP is the address of the peer the request came from
+func remoteIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/qtumproject/janus/pkg/eth"
+	"github.com/qtumproject/janus/pkg/ratelimit"
+)
+
+func TestClientIP(t *testing.T) {
+	s := &Server{}
+	if err := SetTrustedProxies([]string{"10.0.0.0/8", "192.168.1.1"})(s); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		remoteAddr   string
+		forwardedFor string
+		realIP       string
+		wantClientIP string
+	}{
+		// anyone can send the headers, they only count coming from a trusted proxy
+		{"203.0.113.7:5000", "198.51.100.1", "198.51.100.2", "203.0.113.7"},
+		{"10.1.2.3:5000", "", "", "10.1.2.3"},
+		{"10.1.2.3:5000", "198.51.100.1", "198.51.100.2", "198.51.100.1"},
+		{"10.1.2.3:5000", "", "198.51.100.2", "198.51.100.2"},
+		// addresses the client put in front of its own are skipped
+		{"10.1.2.3:5000", "1.1.1.1, 198.51.100.1, 192.168.1.1", "", "198.51.100.1"},
+		{"192.168.1.1:5000", "10.0.0.1", "", "10.0.0.1"},
+	}
+	for _, test := range tests {
+		req, err := http.NewRequest(http.MethodPost, "/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.RemoteAddr = test.remoteAddr
+		if test.forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", test.forwardedFor)
+		}
+		if test.realIP != "" {
+			req.Header.Set("X-Real-IP", test.realIP)
+		}
+		if got := s.clientIP(req); got != test.wantClientIP {
+			t.Errorf("client IP of %s with X-Forwarded-For %q and X-Real-IP %q\\nwant: %s\\ngot: %s", test.remoteAddr, test.forwardedFor, test.realIP, test.wantClientIP, got)
+		}
+	}
+
+	if err := SetTrustedProxies([]string{"10.0.0.0/33"})(s); err == nil {
+		t.Fatal("Expected an invalid CIDR range to be rejected")
+	}
+}
+
+func TestSpoofedForwardedForDoesNotResetRateLimit(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	limiter, err := ratelimit.New(ctx, 0.001, ratelimit.SetBurst(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newTestServer(t, SetRateLimiter(limiter))
+
+	request := `{"jsonrpc":"2.0","id":1,"method":"web3_clientVersion","params":[]}`
+	for i := 0; i < 3; i++ {
+		resp, body := s.post(t, request, map[string]string{
+			"X-Forwarded-For": fmt.Sprintf("198.51.100.%d", i),
+			"X-Real-IP":       fmt.Sprintf("203.0.113.%d", i),
+		})
+		response := decodeResponse(t, body)
+		if i == 0 {
+			if response.Error != nil {
+				t.Fatalf("Expected the first request to be allowed, got %s", body)
+			}
+			continue
+		}
+		if response.Error == nil || response.Error.Code != eth.LimitExceededErrorCode {
+			t.Fatalf("Expected request %d with a new X-Forwarded-For to be rate limited, got %s", i, body)
+		}
+		if resp.Header.Get("Retry-After") == "" {
+			t.Fatal("Expected a Retry-After header")
+		}
+	}
+}
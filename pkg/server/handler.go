@@ -1,8 +1,8 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
 	stdLog "log"
 	"net/http"
 	"sync"
@@ -11,8 +11,8 @@ import (
 	"github.com/labstack/echo"
 	"github.com/pkg/errors"
 	"github.com/qtumproject/janus/pkg/eth"
+	"github.com/qtumproject/janus/pkg/metrics"
 	"github.com/qtumproject/janus/pkg/notifier"
-	"github.com/qtumproject/janus/pkg/qtum"
 
 	"github.com/gorilla/websocket"
 )
@@ -35,7 +35,7 @@ func httpHandler(c echo.Context) error {
 	cc.GetLogger().Log("msg", "proxy RPC", "method", rpcReq.Method)
 
 	// level.Debug(cc.logger).Log("msg", "before call transformer#Transform")
-	result, err := cc.transformer.Transform(rpcReq, c)
+	result, err := cc.transform(rpcReq, c)
 	// level.Debug(cc.logger).Log("msg", "after call transformer#Transform")
 
 	if err != nil {
@@ -213,46 +213,30 @@ func isBatchRequests(msg json.RawMessage) bool {
 	return len(msg) != 0 && msg[0] == '['
 }
 
-func getRpcResponses(c echo.Context, cc *myCtx, rpcReqs []eth.JSONRPCRequest) ([]interface{}, error) {
-	responses := make([]interface{}, 0, len(rpcReqs))
-	for _, rpcReq := range rpcReqs {
-		cc.rpcReq = &rpcReq
-
-		result, jsonError := cc.transformer.Transform(&rpcReq, c)
-
-		response := result
-
-		if jsonError != nil {
-			if jsonError.Error() == nil {
-				cc.GetErrorLogger().Log("err", jsonError.Error())
-				response = jsonError
-			}
-		}
-
-		// Allow transformer to return an explicit JSON error
-		if jerr, isJSONErr := response.(eth.JSONRPCError); isJSONErr {
-			response = cc.GetJSONRPCError(jerr)
-		} else {
-			var err error
-			response, err = cc.GetJSONRPCResult(response)
-			if err != nil {
-				cc.GetErrorLogger().Log("err", err.Error())
-				return nil, err
-			}
-		}
-
-		responses = append(responses, response)
+// rpcMessageResponse answers a websocket or IPC message, a single request or a batch of them
+// batches are run the same way as over HTTP, see Server.transformBatch
+func (s *Server) rpcMessageResponse(cc *myCtx, req []byte) ([]byte, error) {
+	var response interface{}
+	if isBatchRequests(req) {
+		response, _ = s.transformBatch(cc, req)
+	} else {
+		response, _ = cc.transformRequest(req)
 	}
-	return responses, nil
+	return json.Marshal(response)
 }
 
-func websocketHandler(c echo.Context) error {
+func (s *Server) websocketHandler(c echo.Context) error {
 	myctx := c.Get("myctx")
 	cc, ok := myctx.(*myCtx)
 	if !ok {
 		return errors.New("Could not find myctx")
 	}
 
+	if !s.trackWebsocket() {
+		return cc.JSONRPCError(eth.ShutdownError)
+	}
+	defer s.websockets.Done()
+
 	h := http.Header{}
 	for _, sub := range websocket.Subprotocols(c.Request()) {
 		// pick first websocket protocol client asks for if they ask
@@ -265,6 +249,10 @@ func websocketHandler(c echo.Context) error {
 	} else {
 		cc.GetDebugLogger().Log("msg", "Got websocket request")
 	}
+	cc.websocket = true
+	metrics.WebsocketConnections.Inc()
+	defer metrics.WebsocketConnections.Dec()
+
 	closeOnce := sync.Once{}
 	close := func() {
 		closeOnce.Do(func() {
@@ -283,21 +271,29 @@ func websocketHandler(c echo.Context) error {
 		return err
 	}
 
-	defer func() {
-		stopPingPong()
-		close()
-		cc.GetDebugLogger().Log("msg", "Websocket connection closed")
-	}()
-
 	cc.GetDebugLogger().Log("msg", "Websocket connection opened")
 
+	// the notifier isn't tied to the request context so a resumable session can outlive this connection
 	notifier := notifier.NewNotifier(
-		ctx,
+		context.Background(),
 		close,
 		send,
 		cc.GetLogger(),
 	)
 	c.Set("notifier", notifier)
+	s.addClient(cc, notifier, "websocket")
+
+	watchCtx, stopWatchingShutdown := context.WithCancel(ctx)
+	go s.closeWebsocketOnShutdown(ws, &writeMutex, close, watchCtx.Done())
+
+	defer func() {
+		stopWatchingShutdown()
+		stopPingPong()
+		close()
+		notifier.Disconnected()
+		s.removeClient(cc)
+		cc.GetDebugLogger().Log("msg", "Websocket connection closed")
+	}()
 
 	for {
 		cc.GetDebugLogger().Log("msg", "reading websocket request")
@@ -306,34 +302,7 @@ func websocketHandler(c echo.Context) error {
 			cc.GetLogger().Log("msg", "Failed to read websocket message", "err", err)
 			return nil
 		}
-		var rpcReqs []eth.JSONRPCRequest
-
-		isBatchedRequest := isBatchRequests(req)
-		if !isBatchedRequest {
-			var rpcReq eth.JSONRPCRequest
-			json.Unmarshal(req, &rpcReq)
-			rpcReqs = append(rpcReqs, rpcReq)
-		} else {
-			json.Unmarshal(req, &rpcReqs)
-		}
-
-		responses, err := getRpcResponses(c, cc, rpcReqs)
-		if err != nil {
-			cc.GetErrorLogger().Log("err", err.Error())
-			return nil
-		}
-
-		var response interface{}
-		if isBatchedRequest {
-			response = responses
-		} else if len(responses) > 0 {
-			response = responses[0]
-		} else {
-			response = []interface{}{}
-		}
-
-		responseBytes, err := json.Marshal(response)
-
+		responseBytes, err := s.rpcMessageResponse(cc, req)
 		if err != nil {
 			cc.GetErrorLogger().Log("err", err.Error())
 			return nil
@@ -343,16 +312,13 @@ func websocketHandler(c echo.Context) error {
 
 		err = send(responseBytes)
 		if err == nil {
-			notifier.ResponseSent()
-
-			if cc.IsDebugEnabled() {
-				reqBody, err := qtum.ReformatJSON(req)
-				resBody, err := qtum.ReformatJSON(responseBytes)
-				if err == nil {
-					cc.GetDebugLogger().Log("msg", "ETH WEBSOCKET RPC")
-					fmt.Fprintf(cc.GetLogWriter(), "=> ETH request\n%s\n", reqBody)
-					fmt.Fprintf(cc.GetLogWriter(), "<= ETH response\n%s\n", resBody)
-				}
+			// qtum_resumeSession swaps the notifier for this connection
+			if current := currentNotifier(c); current != nil {
+				current.ResponseSent()
+			}
+
+			if cc.IsDebugEnabled() && json.Valid(req) && json.Valid(responseBytes) {
+				cc.dumpRPC("ETH WEBSOCKET RPC", req, responseBytes)
 			}
 
 		} else {
@@ -362,12 +328,43 @@ func websocketHandler(c echo.Context) error {
 	}
 }
 
+// addClient lets admins see the connection with admin_listConnections and close it with admin_disconnect
+func (s *Server) addClient(cc *myCtx, n *notifier.Notifier, transport string) {
+	if s.agent == nil {
+		return
+	}
+
+	client, err := s.agent.AddClient(n, transport, cc.clientIP)
+	if err != nil {
+		cc.GetErrorLogger().Log("msg", "Failed to track connection", "err", err)
+		return
+	}
+	if cc.apiKey != nil {
+		client.SetAPIKey(cc.apiKey.Name)
+	}
+	cc.client = client
+}
+
+func (s *Server) removeClient(cc *myCtx) {
+	if cc.client != nil {
+		s.agent.RemoveClient(cc.client)
+	}
+}
+
+func currentNotifier(c echo.Context) *notifier.Notifier {
+	current, ok := c.Get("notifier").(*notifier.Notifier)
+	if !ok {
+		return nil
+	}
+	return current
+}
+
 func errorHandler(err error, c echo.Context) {
 	myctx := c.Get("myctx")
 	cc, ok := myctx.(*myCtx)
 	if ok {
 		cc.GetErrorLogger().Log("err", err.Error())
-		if err := cc.JSONRPCError(eth.NewJSONRPCError(100, err.Error(), nil)); err != nil {
+		if err := cc.JSONRPCError(eth.NewJSONRPCError(internalErrorCode, err.Error(), nil)); err != nil {
 			cc.GetErrorLogger().Log("msg", "reply to client", "err", err.Error())
 		}
 		return
@@ -1,9 +1,18 @@
 package server
 
 import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
 	"time"
 
+	"github.com/heptiolabs/healthcheck"
+	"github.com/labstack/echo"
 	"github.com/pkg/errors"
+	"github.com/qtumproject/janus/pkg/params"
 	"github.com/qtumproject/janus/pkg/qtum"
 )
 
@@ -12,9 +21,21 @@ var ErrCannotGetConnectedChain = errors.New("Cannot detect chain qtumd is connec
 var ErrBlockSyncingSeemsStalled = errors.New("Block syncing seems stalled")
 var ErrLostLotsOfBlocks = errors.New("Lost a lot of blocks, expected block height to be higher")
 var ErrLostFewBlocks = errors.New("Lost a few blocks, expected block height to be higher")
+var ErrNoHealthyUpstreams = errors.New("No healthy qtumd nodes to send reads to")
+var ErrShuttingDown = errors.New("Janus is shutting down")
+
+// how long a health check's qtumd calls get before the check fails
+var healthCheckTimeout = 10 * time.Second
+
+// checkContext limits the qtumd calls of a health check, so a hung qtumd fails the check instead of hanging the endpoint
+func (s *Server) checkContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(s.qtumRPCClient.GetContext(), healthCheckTimeout)
+}
 
 func (s *Server) testConnectionToQtumd() error {
-	networkInfo, err := s.qtumRPCClient.GetNetworkInfo()
+	ctx, cancel := s.checkContext()
+	defer cancel()
+	networkInfo, err := s.qtumRPCClient.GetNetworkInfo(ctx)
 	if err == nil {
 		// chain can theoretically block forever if qtumd isn't up
 		// but then GetNetworkInfo would be erroring
@@ -42,10 +63,10 @@ func (s *Server) testConnectionToQtumd() error {
 	return err
 }
 
+// testLogEvents checks what qtumd was last found to support, qtumd-features keeps it up to date
 func (s *Server) testLogEvents() error {
-	_, err := s.qtumRPCClient.GetTransactionReceipt("0000000000000000000000000000000000000000000000000000000000000000")
-	if err == qtum.ErrInternalError {
-		return errors.Wrap(err, "-logevents might not be enabled")
+	if !s.qtumRPCClient.Supports(qtum.CapabilityLogEvents) {
+		return errors.New("-logevents is not enabled")
 	}
 	return nil
 }
@@ -71,7 +92,9 @@ func (s *Server) testBlocksSyncing() error {
 	}
 	defer s.blocksMutex.Unlock()
 
-	blockChainInfo, err := s.qtumRPCClient.GetBlockChainInfo()
+	ctx, cancel := s.checkContext()
+	defer cancel()
+	blockChainInfo, err := s.qtumRPCClient.GetBlockChainInfo(ctx)
 	if err != nil {
 		return err
 	}
@@ -110,3 +133,221 @@ func (s *Server) testBlocksSyncing() error {
 
 	return s.lastBlockStatus
 }
+
+func (s *Server) testUpstreams() error {
+	healthy := false
+	for _, status := range s.qtumRPCClient.UpstreamStatuses() {
+		if status.Wallet && !status.Healthy {
+			return errors.Errorf("wallet qtumd node %s is unhealthy: %s", status.URL, status.Error)
+		}
+		healthy = healthy || status.Healthy
+	}
+	if !healthy {
+		return ErrNoHealthyUpstreams
+	}
+	return nil
+}
+
+// readyWithUpstreams adds the health of every qtumd node to the full /ready response
+// unhealthy nodes other than the wallet node don't make Janus unready, reads just go to the other nodes
+func (s *Server) readyWithUpstreams(c echo.Context, health healthcheck.Handler) error {
+	checkResults, status, err := runChecks(health.ReadyEndpoint)
+	if err != nil {
+		return err
+	}
+	s.addUpstreamResults(checkResults)
+
+	c.Response().Header().Set(echo.HeaderContentType, "application/json; charset=utf-8")
+	c.Response().WriteHeader(status)
+	encoder := json.NewEncoder(c.Response())
+	encoder.SetIndent("", "    ")
+	return encoder.Encode(checkResults)
+}
+
+// runChecks gets the result of each check from a healthcheck endpoint, and the status code it responds with
+func runChecks(endpoint http.HandlerFunc) (map[string]string, int, error) {
+	recorder := httptest.NewRecorder()
+	endpoint(recorder, httptest.NewRequest(http.MethodGet, "/?full=1", nil))
+
+	checkResults := make(map[string]string)
+	if err := json.Unmarshal(recorder.Body.Bytes(), &checkResults); err != nil {
+		return nil, 0, err
+	}
+	return checkResults, recorder.Code, nil
+}
+
+func (s *Server) addUpstreamResults(checkResults map[string]string) {
+	for _, status := range s.qtumRPCClient.UpstreamStatuses() {
+		name := "qtumd-upstream " + status.URL
+		if status.Wallet {
+			name = name + " (wallet)"
+		}
+		if status.Healthy {
+			checkResults[name] = "OK"
+		} else {
+			checkResults[name] = status.Error
+		}
+	}
+}
+
+// healthResponse is what /health returns, the result of every check and the versions involved
+type healthResponse struct {
+	// ok, unready when a readiness check fails or down when a liveness check fails
+	Status   string            `json:"status"`
+	Chain    string            `json:"chain,omitempty"`
+	Versions healthVersions    `json:"versions"`
+	Live     map[string]string `json:"live"`
+	Ready    map[string]string `json:"ready"`
+	// the qtumd options Janus needs and whether qtumd was started with them, when qtumd-features last ran
+	Capabilities map[qtum.Capability]bool `json:"capabilities,omitempty"`
+}
+
+type healthVersions struct {
+	Janus string `json:"janus"`
+	Go    string `json:"go"`
+	// empty when qtumd can't be reached
+	Qtumd         string `json:"qtumd,omitempty"`
+	QtumdVersion  int64  `json:"qtumdVersion,omitempty"`
+	QtumdProtocol int64  `json:"qtumdProtocol,omitempty"`
+}
+
+// healthHandler serves /health, it responds 503 unless Janus is both live and ready
+// the checks that go to qtumd run in the background, this only collects their latest results
+func (s *Server) healthHandler(c echo.Context, health healthcheck.Handler) error {
+	live, liveStatus, err := runChecks(health.LiveEndpoint)
+	if err != nil {
+		return err
+	}
+	ready, readyStatus, err := runChecks(health.ReadyEndpoint)
+	if err != nil {
+		return err
+	}
+	s.addUpstreamResults(ready)
+	// the readiness endpoint runs the liveness checks too
+	for name := range live {
+		delete(ready, name)
+	}
+
+	response := healthResponse{
+		Status: "ok",
+		Versions: healthVersions{
+			Janus: params.VersionWithGitSha,
+			Go:    runtime.Version(),
+		},
+		Live:  live,
+		Ready: ready,
+	}
+	status := http.StatusOK
+	if liveStatus != http.StatusOK {
+		response.Status = "down"
+		status = http.StatusServiceUnavailable
+	} else if readyStatus != http.StatusOK {
+		response.Status = "unready"
+		status = http.StatusServiceUnavailable
+	}
+	// what the checks last found out about qtumd, so /health doesn't make qtumd calls of its own
+	if capabilities := s.qtumRPCClient.Capabilities(); capabilities != nil {
+		response.Capabilities = capabilities.Enabled
+		response.Versions.Qtumd = capabilities.Subversion
+		response.Versions.QtumdVersion = capabilities.Version
+		response.Versions.QtumdProtocol = capabilities.ProtocolVersion
+	}
+	s.qtumdChainMutex.RLock()
+	response.Chain = s.qtumdChain
+	s.qtumdChainMutex.RUnlock()
+
+	c.Response().Header().Set(echo.HeaderContentType, "application/json; charset=utf-8")
+	c.Response().WriteHeader(status)
+	encoder := json.NewEncoder(c.Response())
+	encoder.SetIndent("", "    ")
+	return encoder.Encode(response)
+}
+
+const (
+	// how often the checks that go to qtumd run, /live, /ready and /health return their latest results
+	livenessCheckInterval  = 10 * time.Second
+	readinessCheckInterval = 10 * time.Second
+	featuresCheckInterval  = time.Minute
+
+	// qtumd's verificationprogress is an estimate that stays just under 1 at the tip
+	minVerificationProgress = 0.9999
+
+	DefaultReadyMaxBlockLag = 10
+	DefaultReadyMaxTipAge   = 10 * time.Minute
+)
+
+// testInitialBlockDownload fails until qtumd has caught up with the chain
+func (s *Server) testInitialBlockDownload() error {
+	ctx, cancel := s.checkContext()
+	defer cancel()
+	info, err := s.qtumRPCClient.GetBlockChainInfo(ctx)
+	if err != nil {
+		return err
+	}
+	s.qtumdChainMutex.Lock()
+	s.qtumdChain = strings.ToLower(info.Chain)
+	s.qtumdChainMutex.Unlock()
+	// a regtest node stays in initial block download until it mines a block
+	if strings.ToLower(info.Chain) == qtum.ChainRegTest {
+		return nil
+	}
+	if info.Initialblockdownload || info.Verificationprogress < minVerificationProgress {
+		return errors.Errorf("qtumd is in initial block download, %.2f%% verified", info.Verificationprogress*100)
+	}
+	return nil
+}
+
+// testBlockLag fails when qtumd has more headers than blocks, it knows of blocks it hasn't got yet
+func (s *Server) testBlockLag() error {
+	ctx, cancel := s.checkContext()
+	defer cancel()
+	info, err := s.qtumRPCClient.GetBlockChainInfo(ctx)
+	if err != nil {
+		return err
+	}
+	if lag := info.Headers - info.Blocks; lag > s.readyMaxBlockLag {
+		return errors.Errorf("qtumd is %d blocks behind the headers it has", lag)
+	}
+	return nil
+}
+
+// testTipAge fails when qtumd's latest block is too old, it has probably lost touch with the network
+func (s *Server) testTipAge() error {
+	ctx, cancel := s.checkContext()
+	defer cancel()
+	info, err := s.qtumRPCClient.GetBlockChainInfo(ctx)
+	if err != nil {
+		return err
+	}
+	// nothing makes blocks on regtest unless asked to
+	if strings.ToLower(info.Chain) == qtum.ChainRegTest {
+		return nil
+	}
+	header, err := s.qtumRPCClient.GetBlockHeader(ctx, info.Bestblockhash)
+	if err != nil {
+		return err
+	}
+	if age := time.Since(time.Unix(int64(header.Time), 0)); age > s.readyMaxTipAge {
+		return errors.Errorf("qtumd's latest block is %s old", age.Round(time.Second))
+	}
+	return nil
+}
+
+// testFeatures fails when qtumd wasn't started with the options Janus needs
+// probing qtumd also updates the methods that are disabled for lack of an option
+func (s *Server) testFeatures() error {
+	ctx, cancel := s.checkContext()
+	defer cancel()
+	capabilities, err := s.qtumRPCClient.DetectCapabilities(ctx)
+	if err != nil {
+		return err
+	}
+	if missing := capabilities.Missing(); len(missing) != 0 {
+		options := make([]string, len(missing))
+		for i, capability := range missing {
+			options[i] = string(capability)
+		}
+		return errors.Errorf("qtumd needs to be started with %s", strings.Join(options, " "))
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/qtumproject/janus/pkg/eth"
+	"github.com/qtumproject/janus/pkg/notifier"
+	"github.com/qtumproject/janus/pkg/tracing"
+)
+
+// methods that sign with the loaded accounts or qtumd's wallet, SetSigningOverIPCOnly keeps them off the network
+var signingMethods = map[string]bool{
+	"eth_sendTransaction":    true,
+	"eth_signTransaction":    true,
+	"eth_sign":               true,
+	"personal_unlockAccount": true,
+}
+
+// SetIPCPath also serves JSON-RPC on a unix socket at path, one request or batch per line
+// access is controlled by the socket's permissions instead of API keys and rate limits, if there is an admin token
+// the admin_ methods are available without sending it
+func SetIPCPath(path string) Option {
+	return func(p *Server) error {
+		p.ipcPath = path
+		return nil
+	}
+}
+
+// SetSigningOverIPCOnly refuses the signing methods on every connection apart from IPC ones
+func SetSigningOverIPCOnly(ipcOnly bool) Option {
+	return func(p *Server) error {
+		p.signingOverIPCOnly = ipcOnly
+		return nil
+	}
+}
+
+// authorizeSigning checks that the client can call the signing method
+func (c *myCtx) authorizeSigning(method string) eth.JSONRPCError {
+	if !c.signingOverIPCOnly || c.ipc {
+		return nil
+	}
+	return eth.NewJSONRPCError(
+		eth.MethodNotFoundErrorCode,
+		"The method "+method+" is only available over IPC",
+		nil,
+	)
+}
+
+// listenIPC listens on the unix socket at path, replacing a socket left behind by a Janus that didn't shut down cleanly
+func listenIPC(path string) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, errors.Errorf("IPC path %s exists and isn't a socket", path)
+		}
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, errors.Errorf("IPC path %s is already in use", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, errors.Wrap(err, "Failed to remove stale IPC socket")
+		}
+	}
+
+	// the socket is created in a directory only the user Janus runs as can enter, and moved to path once it's private
+	// so there's no moment anyone else could connect to it
+	dir, err := ioutil.TempDir(filepath.Dir(path), ".ipc")
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create IPC socket directory")
+	}
+	defer os.RemoveAll(dir)
+	private := filepath.Join(dir, "s")
+
+	l, err := net.Listen("unix", private)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to listen on IPC socket")
+	}
+	if err := os.Chmod(private, 0600); err != nil {
+		l.Close()
+		return nil, errors.Wrap(err, "Failed to set IPC socket permissions")
+	}
+	// closing the listener would remove the path it was created at, not the one it was moved to
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	if err := os.Rename(private, path); err != nil {
+		l.Close()
+		return nil, errors.Wrap(err, "Failed to move IPC socket into place")
+	}
+	return &ipcListener{Listener: l, path: path}, nil
+}
+
+// ipcListener removes its socket when it's closed
+type ipcListener struct {
+	net.Listener
+	path      string
+	closeOnce sync.Once
+}
+
+func (l *ipcListener) Close() error {
+	err := l.Listener.Close()
+	l.closeOnce.Do(func() {
+		os.Remove(l.path)
+	})
+	return err
+}
+
+// serveIPC accepts IPC connections until the server starts closing connections
+func (s *Server) serveIPC(l net.Listener) {
+	go func() {
+		<-s.closingConnections
+		l.Close()
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			select {
+			case <-s.closingConnections:
+			default:
+				level.Error(s.logger).Log("msg", "Stopped accepting IPC connections", "err", err)
+			}
+			return
+		}
+		go s.ipcHandler(conn)
+	}
+}
+
+// ipcResponseWriter stands in for the HTTP response of an IPC connection's echo context, responses are written to the socket
+type ipcResponseWriter struct {
+	header http.Header
+}
+
+func (w *ipcResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *ipcResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (w *ipcResponseWriter) WriteHeader(int) {}
+
+// ipcHandler serves a connection like a websocket, with newline delimited messages
+func (s *Server) ipcHandler(conn net.Conn) {
+	var writeMutex sync.Mutex
+	send := func(value []byte) error {
+		writeMutex.Lock()
+		defer writeMutex.Unlock()
+		conn.SetWriteDeadline(time.Now().Add(writeWait))
+		_, err := conn.Write(append(value, '\n'))
+		return err
+	}
+
+	if !s.trackWebsocket() {
+		send(shutdownErrorMessage())
+		conn.Close()
+		return
+	}
+	defer s.websockets.Done()
+
+	requestID := tracing.RequestID("")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx = tracing.ContextWithRequestID(ctx, requestID)
+	ctx = tracing.ContextWithTracer(ctx, s.tracer, "")
+	req, err := http.NewRequest(http.MethodPost, "/", nil)
+	if err != nil {
+		level.Error(s.logger).Log("msg", "Failed to create IPC request", "err", err)
+		conn.Close()
+		return
+	}
+	c := s.echo.NewContext(req.WithContext(ctx), &ipcResponseWriter{header: http.Header{}})
+
+	// whoever can open the socket is trusted, so API keys and rate limits don't apply
+	// and the admin methods don't need the admin token, they still need Janus to have one to be enabled
+	cc := &myCtx{
+		Context:            c,
+		logWriter:          s.logWriter,
+		logger:             log.With(s.logger, "requestId", requestID),
+		jsonLogs:           s.jsonLogs,
+		transformer:        s.transformer,
+		adminEnabled:       s.adminToken != "",
+		admin:              s.adminToken != "",
+		ipc:                true,
+		signingOverIPCOnly: s.signingOverIPCOnly,
+	}
+	c.Set("myctx", cc)
+
+	closeOnce := sync.Once{}
+	close := func() {
+		closeOnce.Do(func() {
+			conn.Close()
+		})
+	}
+
+	cc.GetDebugLogger().Log("msg", "IPC connection opened")
+
+	notifier := notifier.NewNotifier(
+		context.Background(),
+		close,
+		send,
+		cc.GetLogger(),
+	)
+	c.Set("notifier", notifier)
+	s.addClient(cc, notifier, "ipc")
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-s.closingConnections:
+			send(shutdownErrorMessage())
+			close()
+		}
+	}()
+
+	defer func() {
+		close()
+		notifier.Disconnected()
+		s.removeClient(cc)
+		cc.GetDebugLogger().Log("msg", "IPC connection closed")
+	}()
+
+	decoder := json.NewDecoder(bufio.NewReader(conn))
+	for {
+		var req json.RawMessage
+		if err := decoder.Decode(&req); err != nil {
+			if _, ok := err.(*json.SyntaxError); ok {
+				send(errorMessage(eth.NewInvalidMessageError(err.Error())))
+			}
+			cc.GetDebugLogger().Log("msg", "Failed to read IPC message", "err", err)
+			return
+		}
+
+		responseBytes, err := s.rpcMessageResponse(cc, req)
+		if err != nil {
+			cc.GetErrorLogger().Log("err", err.Error())
+			return
+		}
+
+		cc.GetDebugLogger().Log("response", string(responseBytes))
+
+		if err := send(responseBytes); err != nil {
+			cc.GetErrorLogger().Log("err", err.Error())
+			return
+		}
+		// qtum_resumeSession swaps the notifier for this connection
+		if current := currentNotifier(c); current != nil {
+			current.ResponseSent()
+		}
+
+		if cc.IsDebugEnabled() && json.Valid(responseBytes) {
+			cc.dumpRPC("ETH IPC RPC", req, responseBytes)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qtumproject/janus/pkg/eth"
+)
+
+// ipcClient is a connection to the IPC endpoint of a test server
+type ipcClient struct {
+	conn     net.Conn
+	messages chan string
+}
+
+func dialIPC(t *testing.T, s *testServer) *ipcClient {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "janus-ipc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "janus.ipc")
+
+	l, err := listenIPC(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go s.serveIPC(l)
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	client := &ipcClient{conn: conn, messages: make(chan string, 10)}
+	go func() {
+		defer close(client.messages)
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			client.messages <- scanner.Text()
+		}
+	}()
+	return client
+}
+
+func (c *ipcClient) send(t *testing.T, message string) {
+	t.Helper()
+	if _, err := c.conn.Write([]byte(message + "\n")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// nextMessage is the next line the server sent
+func (c *ipcClient) nextMessage(t *testing.T) string {
+	t.Helper()
+	select {
+	case message, ok := <-c.messages:
+		if !ok {
+			t.Fatal("IPC connection closed")
+		}
+		return message
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for an IPC message")
+	}
+	return ""
+}
+
+func (c *ipcClient) next(t *testing.T) *rpcNotification {
+	t.Helper()
+	message := c.nextMessage(t)
+	var notification rpcNotification
+	if err := json.Unmarshal([]byte(message), &notification); err != nil {
+		t.Fatalf("Failed to unmarshal message %s: %s", message, err)
+	}
+	return &notification
+}
+
+func TestIPCSubscription(t *testing.T) {
+	s := newMinedTestServer(t)
+	client := dialIPC(t, s)
+
+	client.send(t, `{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`)
+	if response := client.next(t); response.Error != nil || string(response.Result) != `"0x1"` {
+		t.Fatalf("Expected block number 0x1, got %+v", response)
+	}
+
+	client.send(t, `{"jsonrpc":"2.0","id":2,"method":"eth_subscribe","params":["newHeads"]}`)
+	subscribed := client.next(t)
+	var subscriptionID string
+	if err := json.Unmarshal(subscribed.Result, &subscriptionID); err != nil || subscriptionID == "" || string(subscribed.ID) != "2" {
+		t.Fatalf("Expected the eth_subscribe response, got %+v", subscribed)
+	}
+
+	// the agent takes the tip it first sees as already sent
+	time.Sleep(200 * time.Millisecond)
+	if _, err := s.qtumd.Chain().Mine(1, s.qtumd.Chain().NewAddress("miner")); err != nil {
+		t.Fatal(err)
+	}
+	if head := client.next(t); head.Method != "eth_subscription" || head.Params.Subscription != subscriptionID {
+		t.Fatalf("Expected a newHeads notification for %s, got %+v", subscriptionID, head)
+	}
+
+	// a message that isn't JSON gets an error and the connection is closed
+	client.send(t, `{"jsonrpc":`)
+	client.send(t, `}`)
+	if response := client.next(t); response.Error == nil {
+		t.Fatalf("Expected an error for invalid JSON, got %+v", response)
+	}
+	select {
+	case _, ok := <-client.messages:
+		if ok {
+			t.Fatal("Expected the connection to be closed after invalid JSON")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the connection to close")
+	}
+}
+
+func TestIPCShutdown(t *testing.T) {
+	s := newTestServer(t)
+	client := dialIPC(t, s)
+
+	client.send(t, `{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`)
+	client.next(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	s.Shutdown(ctx)
+	if shutdown := client.next(t); shutdown.Error == nil || shutdown.Error.Code != eth.ShutdownErrorCode {
+		t.Fatalf("Expected the shutdown error, got %+v", shutdown)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("Shutdown timed out waiting for the IPC connection")
+	}
+}
+
+func TestSigningOverIPCOnly(t *testing.T) {
+	s := newTestServer(t, SetSigningOverIPCOnly(true))
+	client := dialIPC(t, s)
+
+	request := `{"jsonrpc":"2.0","id":1,"method":"eth_sign","params":["0x7926223070547d2d15b2ef5e7383e541c338ffe9","0xdeadbeef"]}`
+	_, body := s.post(t, request, nil)
+	response := decodeResponse(t, body)
+	if response.Error == nil || response.Error.Code != eth.MethodNotFoundErrorCode || response.Error.Message != "The method eth_sign is only available over IPC" {
+		t.Fatalf("Expected eth_sign to be refused over HTTP, got %s", body)
+	}
+
+	// over IPC it gets as far as looking for the account
+	client.send(t, request)
+	if response := client.next(t); response.Error != nil && response.Error.Code == eth.MethodNotFoundErrorCode {
+		t.Fatalf("Expected eth_sign to be allowed over IPC, got %+v", response.Error)
+	}
+
+	// other methods are still available over HTTP
+	_, body = s.post(t, `{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`, nil)
+	if response := decodeResponse(t, body); response.Error != nil {
+		t.Fatalf("Expected eth_blockNumber to work over HTTP, got %s", body)
+	}
+}
+
+func TestIPCSocketIsPrivate(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("who can connect is down to the directory's ACL on Windows")
+	}
+	dir, err := ioutil.TempDir("", "janus-ipc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "janus.ipc")
+
+	l, err := listenIPC(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm&0077 != 0 {
+		t.Errorf("Expected only the owner to have access to the socket, got %s", perm)
+	}
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("Expected the socket to accept connections where it was moved to: %s", err)
+	}
+	conn.Close()
+
+	l.Close()
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected the socket and the directory it was created in to be removed, found %s", entries[0].Name())
+	}
+}
+
+func TestIPCAdminMethodsNeedAnAdminToken(t *testing.T) {
+	const listConnections = `{"jsonrpc":"2.0","id":1,"method":"admin_listConnections","params":[]}`
+
+	client := dialIPC(t, newTestServer(t))
+	client.send(t, listConnections)
+	if response := client.next(t); response.Error == nil || response.Error.Code != eth.MethodNotFoundErrorCode {
+		t.Errorf("Expected the admin methods to be disabled without an admin token, got %+v", response)
+	}
+
+	// IPC clients don't send the token
+	client = dialIPC(t, newTestServer(t, SetAdminToken("secret")))
+	client.send(t, listConnections)
+	if response := client.next(t); response.Error != nil {
+		t.Errorf("Expected admin_listConnections to succeed over IPC, got %+v", response.Error)
+	}
+}
+
+func TestIPCBatch(t *testing.T) {
+	client := dialIPC(t, newTestServer(t, SetMaxBatchSize(2)))
+
+	request := `{"jsonrpc":"2.0","id":1,"method":"web3_clientVersion","params":[]}`
+	client.send(t, "["+request+`,"not a request"]`)
+	message := client.nextMessage(t)
+	var responses []*rpcResponse
+	if err := json.Unmarshal([]byte(message), &responses); err != nil || len(responses) != 2 {
+		t.Fatalf("Expected 2 responses, got %s", message)
+	}
+	if responses[0].Error != nil || responses[1].Error == nil || responses[1].Error.Code != eth.InvalidRequestErrorCode {
+		t.Errorf("Expected only the invalid request to fail, got %s", message)
+	}
+
+	client.send(t, "["+strings.Join([]string{request, request, request}, ",")+"]")
+	if response := client.next(t); response.Error == nil || response.Error.Message != "Batch of 3 requests is larger than the limit of 2" {
+		t.Errorf("Expected the batch to be rejected, got %+v", response)
+	}
+}
@@ -2,13 +2,23 @@ package server
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
 
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	"github.com/labstack/echo"
+	"github.com/qtumproject/janus/pkg/auth"
 	"github.com/qtumproject/janus/pkg/eth"
+	"github.com/qtumproject/janus/pkg/metrics"
+	"github.com/qtumproject/janus/pkg/notifier"
+	"github.com/qtumproject/janus/pkg/qtum"
+	"github.com/qtumproject/janus/pkg/ratelimit"
 	"github.com/qtumproject/janus/pkg/transformer"
 )
 
@@ -17,7 +27,122 @@ type myCtx struct {
 	rpcReq      *eth.JSONRPCRequest
 	logWriter   io.Writer
 	logger      log.Logger
+	jsonLogs    bool
 	transformer *transformer.Transformer
+	rateLimiter *ratelimit.Limiter
+	// limiters of the API key rate classes, a nil limiter means the class isn't limited
+	rateClasses map[string]*ratelimit.Limiter
+
+	// nil when API keys are off
+	auth      *auth.Authenticator
+	apiKey    *auth.Key
+	apiKeyErr error
+	// the IP address rate limits are applied to, see Server.clientIP
+	clientIP string
+	// whether this is a websocket connection, and how many requests it has sent, qtum_authenticate has to be the first
+	websocket         bool
+	websocketRequests int32
+
+	// whether there is an admin token, and if the client sent it
+	adminEnabled bool
+	admin        bool
+	// whether this is an IPC connection, and if the signing methods are only available over IPC
+	ipc                bool
+	signingOverIPCOnly bool
+	// nil unless this is a websocket, IPC or server-sent events connection
+	client *notifier.Client
+}
+
+// transform runs rpcReq through the transformer, unless the client's API key doesn't allow it
+// or the client has gone over its rate limit
+// echoCtx is the context of the request rpcReq came in on
+func (c *myCtx) transform(rpcReq *eth.JSONRPCRequest, echoCtx echo.Context) (interface{}, eth.JSONRPCError) {
+	result, retryAfter, err := c.limitedTransform(rpcReq, echoCtx)
+	if retryAfter > 0 {
+		setRetryAfter(echoCtx.Response().Header(), retryAfter)
+	}
+	return result, err
+}
+
+// limitedTransform is transform without touching the response, so requests can share echoCtx
+// retryAfter is how long until a rate limited request can be retried, 0 if it wasn't rate limited
+func (c *myCtx) limitedTransform(rpcReq *eth.JSONRPCRequest, echoCtx echo.Context) (result interface{}, retryAfter time.Duration, err eth.JSONRPCError) {
+	if isAdminMethod(rpcReq.Method) {
+		if err := c.authorizeAdmin(rpcReq.Method); err != nil {
+			return nil, 0, err
+		}
+	}
+	if signingMethods[rpcReq.Method] {
+		if err := c.authorizeSigning(rpcReq.Method); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if c.auth != nil {
+		if c.apiKeyErr != nil {
+			return nil, 0, eth.NewInvalidRequestError(c.apiKeyErr.Error())
+		}
+		if err := c.apiKey.Authorize(rpcReq); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if limiter, key := c.rateLimit(); limiter != nil {
+		if retryAfter, ok := limiter.Allow(key, rpcReq); !ok {
+			metrics.RateLimitedRequests.Inc()
+			return nil, retryAfter, eth.NewLimitExceededError(retryAfter)
+		}
+	}
+
+	result, err = c.transformer.Transform(rpcReq, echoCtx)
+	if accounts, ok := result.(eth.AccountsResponse); ok && c.apiKey != nil {
+		result = c.apiKey.FilterAccounts(accounts)
+	}
+	return result, 0, err
+}
+
+func setRetryAfter(header http.Header, retryAfter time.Duration) {
+	header.Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+}
+
+// rateLimit picks the limiter for the client's requests and the key of its bucket
+func (c *myCtx) rateLimit() (*ratelimit.Limiter, string) {
+	if c.apiKey == nil {
+		return c.rateLimiter, c.clientIP
+	}
+
+	limiter := c.rateLimiter
+	if c.apiKey.RateClass != "" {
+		limiter = c.rateClasses[c.apiKey.RateClass]
+	}
+	return limiter, c.apiKey.RateLimitKey(c.clientIP)
+}
+
+// authenticate handles qtum_authenticate, which swaps a websocket connection's API key for the one in its params
+// it has to be the first message so a connection can't switch between keys
+func (c *myCtx) authenticate(rpcReq *eth.JSONRPCRequest) (interface{}, eth.JSONRPCError) {
+	if c.auth == nil {
+		return nil, eth.NewMethodNotFoundError(rpcReq.Method)
+	}
+	if atomic.LoadInt32(&c.websocketRequests) > 1 {
+		return nil, eth.NewInvalidRequestError(auth.AuthenticateMethod + " has to be the first message")
+	}
+
+	var params []string
+	if err := json.Unmarshal(rpcReq.Params, &params); err != nil || len(params) != 1 || params[0] == "" {
+		return nil, eth.NewInvalidParamsError("expected an API key")
+	}
+
+	key, err := c.auth.Authenticate(params[0])
+	if err != nil {
+		return nil, eth.NewInvalidRequestError(err.Error())
+	}
+	c.apiKey, c.apiKeyErr = key, nil
+	if c.client != nil {
+		c.client.SetAPIKey(key.Name)
+	}
+	c.GetDebugLogger().Log("msg", "Websocket authenticated", "apiKey", key.Name)
+	return true, nil
 }
 
 func (c *myCtx) GetJSONRPCResult(result interface{}) (*eth.JSONRPCResult, error) {
@@ -57,6 +182,23 @@ func (c *myCtx) JSONRPCError(err eth.JSONRPCError) error {
 	return nil
 }
 
+// dumpRPC logs a request and the response to it, both have to be valid JSON
+func (c *myCtx) dumpRPC(message string, req []byte, res []byte) {
+	if c.jsonLogs {
+		c.GetDebugLogger().Log("msg", message, "request", json.RawMessage(req), "response", json.RawMessage(res))
+		return
+	}
+
+	reqBody, reqErr := qtum.ReformatJSON(req)
+	resBody, resErr := qtum.ReformatJSON(res)
+	if reqErr != nil || resErr != nil {
+		return
+	}
+	c.GetDebugLogger().Log("msg", message)
+	fmt.Fprintf(c.logWriter, "=> ETH request\n%s\n", reqBody)
+	fmt.Fprintf(c.logWriter, "<= ETH response\n%s\n", resBody)
+}
+
 func (c *myCtx) SetLogWriter(logWriter io.Writer) {
 	c.logWriter = logWriter
 }
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/labstack/echo"
+	"github.com/pkg/errors"
+)
+
+// Endpoints for qtumd's -blocknotify and -walletnotify hooks so we don't have to wait for the next poll, eg:
+// -blocknotify="curl -s http://localhost:23889/notify/block/%s"
+// -walletnotify="curl -s http://localhost:23889/notify/tx/%s"
+
+func (s *Server) notifyBlockHandler(c echo.Context) error {
+	hash := c.Param("hash")
+	if !isHash(hash) {
+		return c.String(http.StatusBadRequest, "invalid block hash")
+	}
+
+	s.logger.Log("msg", "qtumd notified of new block", "hash", hash)
+	s.agent.NotifyNewBlock()
+
+	return c.NoContent(http.StatusOK)
+}
+
+func (s *Server) notifyTransactionHandler(c echo.Context) error {
+	txid := c.Param("txid")
+	if !isHash(txid) {
+		return c.String(http.StatusBadRequest, "invalid transaction id")
+	}
+
+	s.logger.Log("msg", "qtumd notified of wallet transaction", "txid", txid)
+	// walletnotify fires when a wallet transaction enters the mempool and again when it is mined
+	// we don't support newPendingTransactions yet, so the only thing to do is check for a new head
+	s.agent.NotifyNewBlock()
+
+	return c.NoContent(http.StatusOK)
+}
+
+// authorizeNotify keeps anyone else from making Janus poll qtumd over and over, only qtumd's hooks on the same host
+// and requests with the admin token can notify
+func (s *Server) authorizeNotify(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// clientIP is only taken from forwarded headers when they come from a trusted proxy
+		ip := net.ParseIP(s.clientIP(c.Request()))
+		if (ip == nil || !ip.IsLoopback()) && !s.isAdmin(c.Request()) {
+			return c.String(http.StatusForbidden, "notify endpoints are only available from localhost or with the admin token")
+		}
+		return next(c)
+	}
+}
+
+func (s *Server) registerNotifyEndpoints(e *echo.Echo) error {
+	if s.agent == nil {
+		return errors.New("notify endpoints require a notifier agent")
+	}
+
+	e.GET("/notify/block/:hash", s.notifyBlockHandler, s.authorizeNotify)
+	e.POST("/notify/block/:hash", s.notifyBlockHandler, s.authorizeNotify)
+	e.GET("/notify/tx/:txid", s.notifyTransactionHandler, s.authorizeNotify)
+	e.POST("/notify/tx/:txid", s.notifyTransactionHandler, s.authorizeNotify)
+
+	return nil
+}
+
+func isHash(hash string) bool {
+	if len(hash) != 64 {
+		return false
+	}
+	for _, c := range hash {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const notifyHash = "bba11e1bacc69ba535d478cf1f2e542da3735a517b0b8eebaf7e6bb25eeb48c5"
+
+// notify calls a notify endpoint of s, returning the status code
+func (s *testServer) notify(t *testing.T, path string, headers map[string]string) int {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, s.http.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestNotifyValidatesHashes(t *testing.T) {
+	s := newTestServer(t)
+	for path, status := range map[string]int{
+		"/notify/block/" + notifyHash:                  http.StatusOK,
+		"/notify/block/" + strings.ToUpper(notifyHash): http.StatusOK,
+		"/notify/tx/" + notifyHash:                     http.StatusOK,
+		"/notify/block/" + notifyHash[1:]:              http.StatusBadRequest,
+		"/notify/block/" + notifyHash[1:] + "g":        http.StatusBadRequest,
+		"/notify/tx/0x" + notifyHash[2:]:               http.StatusBadRequest,
+	} {
+		if got := s.notify(t, path, nil); got != status {
+			t.Errorf("expected %s to be %d, got %d", path, status, got)
+		}
+	}
+}
+
+func TestNotifyOnlyFromLocalhostOrWithTheAdminToken(t *testing.T) {
+	// the requests come through a proxy on localhost, for a client that isn't
+	s := newTestServer(t, SetTrustedProxies([]string{"127.0.0.1", "::1"}), SetAdminToken("secret"))
+	remote := map[string]string{"X-Forwarded-For": "203.0.113.7"}
+	if status := s.notify(t, "/notify/block/"+notifyHash, remote); status != http.StatusForbidden {
+		t.Errorf("expected a remote client to be refused, got %d", status)
+	}
+	if status := s.notify(t, "/notify/tx/"+notifyHash, remote); status != http.StatusForbidden {
+		t.Errorf("expected a remote client to be refused, got %d", status)
+	}
+
+	remote[AdminTokenHeader] = "secret"
+	if status := s.notify(t, "/notify/block/"+notifyHash, remote); status != http.StatusOK {
+		t.Errorf("expected a remote client with the admin token to be let through, got %d", status)
+	}
+
+	// without trusted proxies forwarded headers are ignored, the client is the peer on localhost
+	s = newTestServer(t)
+	if status := s.notify(t, "/notify/block/"+notifyHash, map[string]string{"X-Forwarded-For": "203.0.113.7"}); status != http.StatusOK {
+		t.Errorf("expected the peer's address to be used, got %d", status)
+	}
+}
+
+func TestNotifyWakesTheAgent(t *testing.T) {
+	// heads are only found by polling if the test runs for an hour
+	interval := newHeadsInterval
+	newHeadsInterval = time.Hour
+	defer func() { newHeadsInterval = interval }()
+	s := newMinedTestServer(t)
+
+	resp, err := http.Get(s.http.URL + ssePath + "?params=" + url.QueryEscape(`["newHeads"]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	events := sseEvents(t, resp)
+	nextEvent(t, events)
+
+	// the agent takes the tip it first sees as already sent
+	time.Sleep(200 * time.Millisecond)
+	blocks, err := s.qtumd.Chain().Mine(1, s.qtumd.Chain().NewAddress("miner"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := s.notify(t, "/notify/block/"+blocks[0].Hash, nil); status != http.StatusOK {
+		t.Fatalf("expected the notification to be accepted, got %d", status)
+	}
+
+	head := nextEvent(t, events)
+	var block struct {
+		Hash string `json:"hash"`
+	}
+	if err := json.Unmarshal(head.Params.Result, &block); err != nil || block.Hash != "0x"+blocks[0].Hash {
+		t.Errorf("expected a newHeads notification for the mined block, got %s", head.Params.Result)
+	}
+}
@@ -1,13 +1,10 @@
 package server
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
-	"fmt"
 	"io"
-	"io/ioutil"
-	"net/http"
-	"net/http/httptest"
+	"net"
 	"sync"
 	"time"
 
@@ -17,8 +14,12 @@ import (
 	"github.com/labstack/echo"
 	"github.com/labstack/echo/middleware"
 	"github.com/pkg/errors"
-	"github.com/qtumproject/janus/pkg/eth"
+	"github.com/qtumproject/janus/pkg/auth"
+	"github.com/qtumproject/janus/pkg/metrics"
+	"github.com/qtumproject/janus/pkg/notifier"
 	"github.com/qtumproject/janus/pkg/qtum"
+	"github.com/qtumproject/janus/pkg/ratelimit"
+	"github.com/qtumproject/janus/pkg/tracing"
 	"github.com/qtumproject/janus/pkg/transformer"
 )
 
@@ -26,14 +27,48 @@ type Server struct {
 	address       string
 	transformer   *transformer.Transformer
 	qtumRPCClient *qtum.Qtum
+	agent         *notifier.Agent
+	rateLimiter   *ratelimit.Limiter
+	rateClasses   map[string]*ratelimit.Limiter
+	auth          *auth.Authenticator
+	adminToken    string
 	logWriter     io.Writer
 	logger        log.Logger
+	jsonLogs      bool
+	tracer        *tracing.Tracer
 	httpsKey      string
 	httpsCert     string
-	debug         bool
 	mutex         *sync.Mutex
 	echo          *echo.Echo
 
+	// proxies whose X-Forwarded-For and X-Real-IP headers are used for the client IP
+	trustedProxies []*net.IPNet
+
+	// unix socket to also serve on (empty for none), and whether the signing methods are only available on it
+	ipcPath            string
+	signingOverIPCOnly bool
+
+	// how many requests of a batch run at the same time, and how many a batch can have (0 means no limit)
+	batchConcurrency int
+	maxBatchSize     int
+
+	// closed when Shutdown is called, and once connections start being closed after the shutdown delay
+	shuttingDown         chan struct{}
+	closingConnections   chan struct{}
+	shutdownOnce         sync.Once
+	closeConnectionsOnce sync.Once
+	shutdownDelay        time.Duration
+	websockets           sync.WaitGroup
+	websocketsMutex      sync.Mutex
+
+	// when qtumd is too far behind for /ready, a tip age of 0 doesn't check the tip
+	readyMaxBlockLag int64
+	readyMaxTipAge   time.Duration
+
+	// the chain qtumd said it was on when the checks last asked, for /health
+	qtumdChainMutex sync.RWMutex
+	qtumdChain      string
+
 	blocksMutex     sync.RWMutex
 	lastBlock       int64
 	nextBlockCheck  *time.Time
@@ -47,11 +82,17 @@ func New(
 	opts ...Option,
 ) (*Server, error) {
 	p := &Server{
-		logger:        log.NewNopLogger(),
-		echo:          echo.New(),
-		address:       addr,
-		qtumRPCClient: qtumRPCClient,
-		transformer:   transformer,
+		logger:             log.NewNopLogger(),
+		echo:               echo.New(),
+		address:            addr,
+		qtumRPCClient:      qtumRPCClient,
+		transformer:        transformer,
+		batchConcurrency:   DefaultBatchConcurrency,
+		maxBatchSize:       DefaultMaxBatchSize,
+		readyMaxBlockLag:   DefaultReadyMaxBlockLag,
+		readyMaxTipAge:     DefaultReadyMaxTipAge,
+		shuttingDown:       make(chan struct{}),
+		closingConnections: make(chan struct{}),
 	}
 
 	var err error
@@ -64,46 +105,81 @@ func New(
 	return p, nil
 }
 
-func (s *Server) Start() error {
+// setup adds the health checks, middleware and routes to the echo instance Start serves
+func (s *Server) setup() error {
 	logWriter := s.logWriter
 	e := s.echo
 
 	health := healthcheck.NewHandler()
-	health.AddLivenessCheck("qtumd-connection", func() error { return s.testConnectionToQtumd() })
+	// checks that go to qtumd run in the background, so polling /live, /ready and /health doesn't add to qtumd's load
+	checksCtx, stopChecks := context.WithCancel(context.Background())
+	go func() {
+		<-s.shuttingDown
+		stopChecks()
+	}()
+	health.AddLivenessCheck("qtumd-connection", healthcheck.AsyncWithContext(checksCtx, s.testConnectionToQtumd, livenessCheckInterval))
 	health.AddLivenessCheck("qtumd-logevents-enabled", func() error { return s.testLogEvents() })
-	health.AddLivenessCheck("qtumd-blocks-syncing", func() error { return s.testBlocksSyncing() })
+	health.AddLivenessCheck("qtumd-blocks-syncing", healthcheck.AsyncWithContext(checksCtx, s.testBlocksSyncing, livenessCheckInterval))
+	health.AddReadinessCheck("shutdown", s.shutdownReadinessCheck)
+	health.AddReadinessCheck("qtumd-initial-block-download", healthcheck.AsyncWithContext(checksCtx, s.testInitialBlockDownload, readinessCheckInterval))
+	health.AddReadinessCheck("qtumd-block-lag", healthcheck.AsyncWithContext(checksCtx, s.testBlockLag, readinessCheckInterval))
+	if s.readyMaxTipAge > 0 {
+		health.AddReadinessCheck("qtumd-tip-age", healthcheck.AsyncWithContext(checksCtx, s.testTipAge, readinessCheckInterval))
+	}
+	health.AddReadinessCheck("qtumd-features", healthcheck.AsyncWithContext(checksCtx, s.testFeatures, featuresCheckInterval))
+	if s.qtumRPCClient.UpstreamStatuses() != nil {
+		health.AddReadinessCheck("qtumd-upstreams", func() error { return s.testUpstreams() })
+	}
 
 	e.Use(middleware.CORS())
-	e.Use(middleware.BodyDump(func(c echo.Context, req []byte, res []byte) {
-		myctx := c.Get("myctx")
-		cc, ok := myctx.(*myCtx)
-		if !ok {
-			return
-		}
+	e.Use(middleware.BodyDumpWithConfig(middleware.BodyDumpConfig{
+		Skipper: skipBodyDump,
+		Handler: func(c echo.Context, req []byte, res []byte) {
+			myctx := c.Get("myctx")
+			cc, ok := myctx.(*myCtx)
+			if !ok {
+				return
+			}
 
-		if s.debug {
-			reqBody, reqErr := qtum.ReformatJSON(req)
-			resBody, resErr := qtum.ReformatJSON(res)
-			if reqErr == nil && resErr == nil {
-				cc.GetDebugLogger().Log("msg", "ETH RPC")
-				fmt.Fprintf(logWriter, "=> ETH request\n%s\n", reqBody)
-				fmt.Fprintf(logWriter, "<= ETH response\n%s\n", resBody)
-			} else if reqErr != nil {
-				cc.GetErrorLogger().Log("msg", "Error reformatting request json", "error", reqErr, "body", string(req))
-			} else {
-				cc.GetErrorLogger().Log("msg", "Error reformatting response json", "error", resErr, "body", string(res))
+			// debug mode can be reloaded, the transformer always has the current setting
+			if cc.IsDebugEnabled() {
+				if !json.Valid(req) {
+					cc.GetErrorLogger().Log("msg", "Error reformatting request json", "body", string(req))
+				} else if !json.Valid(res) {
+					cc.GetErrorLogger().Log("msg", "Error reformatting response json", "body", string(res))
+				} else {
+					cc.dumpRPC("ETH RPC", req, res)
+				}
 			}
-		}
+		},
 	}))
 
 	e.Use(func(h echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
+			// the request ID ties together the logs of a request, and qtumd's logs of the calls made for it
+			requestID := tracing.RequestID(c.Request().Header.Get(tracing.RequestIDHeader))
+			c.Response().Header().Set(tracing.RequestIDHeader, requestID)
+			ctx := tracing.ContextWithRequestID(c.Request().Context(), requestID)
+			ctx = tracing.ContextWithTracer(ctx, s.tracer, c.Request().Header.Get(tracing.TraceParentHeader))
+			c.SetRequest(c.Request().WithContext(ctx))
+
 			cc := &myCtx{
 				Context:     c,
 				logWriter:   logWriter,
-				logger:      s.logger,
+				logger:      log.With(s.logger, "requestId", requestID),
+				jsonLogs:    s.jsonLogs,
 				transformer: s.transformer,
+				rateLimiter: s.rateLimiter,
+				rateClasses: s.rateClasses,
+				auth:        s.auth,
+			}
+			if s.auth != nil {
+				cc.apiKey, cc.apiKeyErr = s.auth.Authenticate(auth.APIKeyFromRequest(c.Request()))
 			}
+			cc.clientIP = s.clientIP(c.Request())
+			cc.adminEnabled = s.adminToken != ""
+			cc.signingOverIPCOnly = s.signingOverIPCOnly
+			cc.admin = s.isAdmin(c.Request())
 
 			c.Set("myctx", cc)
 
@@ -112,7 +188,7 @@ func (s *Server) Start() error {
 	})
 
 	// support batch requests
-	e.Use(batchRequestsMiddleware)
+	e.Use(s.batchRequestsMiddleware)
 
 	e.HTTPErrorHandler = errorHandler
 	e.HideBanner = true
@@ -122,14 +198,31 @@ func (s *Server) Start() error {
 			return nil
 		})
 		e.GET("/ready", func(c echo.Context) error {
+			if c.QueryParam("full") == "1" && s.qtumRPCClient.UpstreamStatuses() != nil {
+				return s.readyWithUpstreams(c, health)
+			}
 			health.ReadyEndpoint(c.Response(), c.Request())
 			return nil
 		})
+		e.GET("/health", func(c echo.Context) error {
+			return s.healthHandler(c, health)
+		})
+	}
+
+	e.GET(metricsPath, echo.WrapHandler(metrics.Handler()))
+
+	if s.agent != nil {
+		if err := s.registerNotifyEndpoints(e); err != nil {
+			return err
+		}
+		if err := s.registerSSEEndpoints(e); err != nil {
+			return err
+		}
 	}
 
 	if s.mutex == nil {
 		e.POST("/*", httpHandler)
-		e.GET("/*", websocketHandler)
+		e.GET("/*", s.websocketHandler)
 	} else {
 		level.Info(s.logger).Log("msg", "Processing RPC requests single threaded")
 		e.POST("/*", func(c echo.Context) error {
@@ -137,7 +230,15 @@ func (s *Server) Start() error {
 			defer s.mutex.Unlock()
 			return httpHandler(c)
 		})
-		e.GET("/*", websocketHandler)
+		e.GET("/*", s.websocketHandler)
+	}
+
+	return nil
+}
+
+func (s *Server) Start() error {
+	if err := s.setup(); err != nil {
+		return err
 	}
 
 	https := (s.httpsKey != "" && s.httpsCert != "")
@@ -145,14 +246,32 @@ func (s *Server) Start() error {
 	url := s.qtumRPCClient.URL
 	level.Info(s.logger).Log("listen", s.address, "qtum_rpc", url, "msg", "proxy started", "https", https)
 
+	if s.ipcPath != "" {
+		l, err := listenIPC(s.ipcPath)
+		if err != nil {
+			return err
+		}
+		defer l.Close()
+		level.Info(s.logger).Log("msg", "IPC endpoint started", "path", s.ipcPath)
+		go s.serveIPC(l)
+	}
+
 	if https {
 		level.Info(s.logger).Log("msg", "SSL enabled")
-		return e.StartTLS(s.address, s.httpsCert, s.httpsKey)
+		return ignoreServerClosed(s.echo.StartTLS(s.address, s.httpsCert, s.httpsKey))
 	} else {
-		return e.Start(s.address)
+		return ignoreServerClosed(s.echo.Start(s.address))
 	}
 }
 
+const metricsPath = "/metrics"
+
+func skipBodyDump(c echo.Context) bool {
+	// server-sent event streams never end, don't keep them all in memory
+	// and metrics aren't JSON so they can't be reformatted for the debug log
+	return c.Path() == ssePath || c.Path() == metricsPath
+}
+
 type Option func(*Server) error
 
 func SetLogWriter(logWriter io.Writer) Option {
@@ -169,9 +288,18 @@ func SetLogger(l log.Logger) Option {
 	}
 }
 
-func SetDebug(debug bool) Option {
+// SetJSONLogs logs requests and responses as fields of JSON log lines instead of writing them to the log writer
+func SetJSONLogs(jsonLogs bool) Option {
 	return func(p *Server) error {
-		p.debug = debug
+		p.jsonLogs = jsonLogs
+		return nil
+	}
+}
+
+// SetTracer traces every request, nil turns tracing off
+func SetTracer(tracer *tracing.Tracer) Option {
+	return func(p *Server) error {
+		p.tracer = tracer
 		return nil
 	}
 }
@@ -187,87 +315,90 @@ func SetSingleThreaded(singleThreaded bool) Option {
 	}
 }
 
-func SetHttps(key string, cert string) Option {
+func SetAgent(agent *notifier.Agent) Option {
 	return func(p *Server) error {
-		p.httpsKey = key
-		p.httpsCert = cert
+		p.agent = agent
 		return nil
 	}
 }
 
-func batchRequestsMiddleware(h echo.HandlerFunc) echo.HandlerFunc {
-	return func(c echo.Context) error {
-		myctx := c.Get("myctx")
-		cc, ok := myctx.(*myCtx)
-		if !ok {
-			return errors.New("Could not find myctx")
-		}
-
-		// Request
-		reqBody := []byte{}
-		if c.Request().Body != nil { // Read
-			var err error
-			reqBody, err = ioutil.ReadAll(c.Request().Body)
-			if err != nil {
-				panic(fmt.Sprintf("%v", err))
-			}
-		}
-		isBatchRequests := func(msg json.RawMessage) bool {
-			return len(msg) != 0 && msg[0] == '['
-		}
-		c.Request().Body = ioutil.NopCloser(bytes.NewBuffer(reqBody)) // Reset
+// SetRateLimiter limits how many requests each client can make, by IP address
+func SetRateLimiter(limiter *ratelimit.Limiter) Option {
+	return func(p *Server) error {
+		p.rateLimiter = limiter
+		return nil
+	}
+}
 
-		if !isBatchRequests(reqBody) {
-			return h(c)
-		}
+// SetAuthenticator requires requests to come with an API key that allows them
+func SetAuthenticator(authenticator *auth.Authenticator) Option {
+	return func(p *Server) error {
+		p.auth = authenticator
+		return nil
+	}
+}
 
-		var rpcReqs []*eth.JSONRPCRequest
-		if err := c.Bind(&rpcReqs); err != nil {
+// SetRateClasses sets the limiters of the API key rate classes
+func SetRateClasses(limiters map[string]*ratelimit.Limiter) Option {
+	return func(p *Server) error {
+		p.rateClasses = limiters
+		return nil
+	}
+}
 
-			return err
+// SetBatchConcurrency sets how many requests of a batch run at the same time
+func SetBatchConcurrency(concurrency int) Option {
+	return func(p *Server) error {
+		if concurrency < 1 {
+			return errors.New("batch concurrency must be at least 1")
 		}
+		p.batchConcurrency = concurrency
+		return nil
+	}
+}
 
-		results := make([]*eth.JSONRPCResult, 0, len(rpcReqs))
-
-		for _, req := range rpcReqs {
-			result, err := callHttpHandler(cc, req)
-			if err != nil {
-				return err
-			}
-
-			results = append(results, result)
+// SetMaxBatchSize sets how many requests a batch can have, 0 means no limit
+func SetMaxBatchSize(size int) Option {
+	return func(p *Server) error {
+		if size < 0 {
+			return errors.New("max batch size cannot be negative")
 		}
-
-		return c.JSON(http.StatusOK, results)
+		p.maxBatchSize = size
+		return nil
 	}
 }
 
-func callHttpHandler(cc *myCtx, req *eth.JSONRPCRequest) (*eth.JSONRPCResult, error) {
-	reqBytes, err := json.Marshal(req)
-	if err != nil {
-		return nil, err
+// SetReadyMaxBlockLag sets how many blocks qtumd can be behind the headers it has before /ready fails
+func SetReadyMaxBlockLag(lag int64) Option {
+	return func(p *Server) error {
+		if lag < 0 {
+			return errors.New("max block lag cannot be negative")
+		}
+		p.readyMaxBlockLag = lag
+		return nil
 	}
+}
 
-	httpreq := httptest.NewRequest(echo.POST, "/", ioutil.NopCloser(bytes.NewReader(reqBytes)))
-	httpreq.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
-	rec := httptest.NewRecorder()
-
-	newCtx := cc.Echo().NewContext(httpreq, rec)
-	myCtx := &myCtx{
-		Context:     newCtx,
-		logWriter:   cc.GetLogWriter(),
-		logger:      cc.logger,
-		transformer: cc.transformer,
-	}
-	newCtx.Set("myctx", myCtx)
-	if err = httpHandler(myCtx); err != nil {
-		errorHandler(err, myCtx)
+// SetReadyMaxTipAge sets how old qtumd's latest block can be before /ready fails, 0 doesn't check it
+func SetReadyMaxTipAge(age time.Duration) Option {
+	return func(p *Server) error {
+		p.readyMaxTipAge = age
+		return nil
 	}
+}
 
-	var result *eth.JSONRPCResult
-	if err = json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
-		return nil, err
+// SetShutdownDelay sets how long /ready fails for before Shutdown starts closing connections
+func SetShutdownDelay(delay time.Duration) Option {
+	return func(p *Server) error {
+		p.shutdownDelay = delay
+		return nil
 	}
+}
 
-	return result, nil
+func SetHttps(key string, cert string) Option {
+	return func(p *Server) error {
+		p.httpsKey = key
+		p.httpsCert = cert
+		return nil
+	}
 }
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/qtumproject/janus/pkg/notifier"
+	"github.com/qtumproject/janus/pkg/qtum"
+	"github.com/qtumproject/janus/pkg/qtumtest"
+	"github.com/qtumproject/janus/pkg/transformer"
+)
+
+// testServer is Janus in front of a fake qtumd, serving HTTP with httptest
+type testServer struct {
+	*Server
+	qtumd *qtumtest.Server
+	agent *notifier.Agent
+	http  *httptest.Server
+}
+
+func newTestServer(t *testing.T, opts ...Option) *testServer {
+	t.Helper()
+	qtumd, err := qtumtest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(qtumd.Close)
+	return newTestServerFor(t, qtumd, nil, opts...)
+}
+
+// newMinedTestServer is Janus in front of a fake qtumd that has mined a block, the agent ignores heads until there is one
+func newMinedTestServer(t *testing.T, opts ...Option) *testServer {
+	t.Helper()
+	qtumd, err := qtumtest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(qtumd.Close)
+	if _, err := qtumd.Chain().Mine(1, qtumd.Chain().NewAddress("miner")); err != nil {
+		t.Fatal(err)
+	}
+	return newTestServerFor(t, qtumd, nil, opts...)
+}
+
+// newHeadsInterval is how often the test servers' agent polls qtumd for new heads
+var newHeadsInterval = 50 * time.Millisecond
+
+// newTestServerFor puts Janus in front of qtumd, for tests that need the chain set up before Janus starts
+// proxies are served along with the default ones
+func newTestServerFor(t *testing.T, qtumd *qtumtest.Server, proxies []transformer.ETHProxy, opts ...Option) *testServer {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	client, err := qtum.NewClient(false, qtumd.RPCURL(), qtum.SetContext(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	qtumClient, err := qtum.New(client, qtum.ChainRegTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	agent := notifier.NewAgent(ctx, qtumClient, nil, notifier.SetNewHeadsInterval(newHeadsInterval))
+	t.Cleanup(agent.Stop)
+	janus, err := transformer.New(qtumClient, append(transformer.DefaultProxies(qtumClient, agent), proxies...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	agent.SetTransformer(janus)
+
+	opts = append([]Option{SetLogger(log.NewNopLogger()), SetAgent(agent)}, opts...)
+	s, err := New(qtumClient, janus, "", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.setup(); err != nil {
+		t.Fatal(err)
+	}
+	httpServer := httptest.NewServer(s.echo)
+	t.Cleanup(httpServer.Close)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		s.Shutdown(ctx)
+	})
+
+	return &testServer{Server: s, qtumd: qtumd, agent: agent, http: httpServer}
+}
+
+// post sends body to Janus with headers, returning the response and its body
+func (s *testServer) post(t *testing.T, body string, headers map[string]string) (*http.Response, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, s.http.URL, bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	responseBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, responseBody
+}
+
+// rpcResponse is a JSON-RPC response as a client sees it
+type rpcResponse struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int             `json:"code"`
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
+	} `json:"error"`
+}
+
+// rpcNotification is a JSON-RPC response or eth_subscription notification, as they're sent down a stream
+type rpcNotification struct {
+	rpcResponse
+	Method string `json:"method"`
+	Params struct {
+		Subscription string          `json:"subscription"`
+		Result       json.RawMessage `json:"result"`
+	} `json:"params"`
+}
+
+func decodeResponse(t *testing.T, body []byte) *rpcResponse {
+	t.Helper()
+	var response rpcResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		t.Fatalf("Failed to unmarshal response %s: %s", body, err)
+	}
+	return &response
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/gorilla/websocket"
+	"github.com/qtumproject/janus/pkg/eth"
+)
+
+// Shutdown stops the server gracefully
+// /ready fails straight away so load balancers stop sending clients here, after the shutdown delay
+// the listeners are closed, websocket and IPC clients and server-sent event streams are sent eth.ShutdownError and closed,
+// and in flight HTTP requests have until ctx is done to finish
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.shutdownOnce.Do(func() {
+		close(s.shuttingDown)
+	})
+	level.Info(s.logger).Log("msg", "Shutting down", "delay", s.shutdownDelay)
+
+	if s.shutdownDelay > 0 {
+		select {
+		case <-time.After(s.shutdownDelay):
+		case <-ctx.Done():
+		}
+	}
+
+	s.closeConnectionsOnce.Do(func() {
+		s.websocketsMutex.Lock()
+		close(s.closingConnections)
+		s.websocketsMutex.Unlock()
+	})
+
+	err := s.echo.Shutdown(ctx)
+
+	// websockets are hijacked from the HTTP server and IPC connections aren't its own, so it doesn't wait for them
+	websocketsClosed := make(chan struct{})
+	go func() {
+		s.websockets.Wait()
+		close(websocketsClosed)
+	}()
+	select {
+	case <-websocketsClosed:
+	case <-ctx.Done():
+		level.Warn(s.logger).Log("msg", "Timed out waiting for websocket connections to close")
+	}
+
+	level.Info(s.logger).Log("msg", "Shut down")
+	return err
+}
+
+func (s *Server) isShuttingDown() bool {
+	select {
+	case <-s.shuttingDown:
+		return true
+	default:
+		return false
+	}
+}
+
+// trackWebsocket counts a new websocket or IPC connection for Shutdown to wait on
+// it returns false once connections are being closed, the connection should be refused
+func (s *Server) trackWebsocket() bool {
+	s.websocketsMutex.Lock()
+	defer s.websocketsMutex.Unlock()
+
+	select {
+	case <-s.closingConnections:
+		return false
+	default:
+		s.websockets.Add(1)
+		return true
+	}
+}
+
+// shutdownErrorMessage is sent to websocket and IPC clients and server-sent event streams before they are closed
+func shutdownErrorMessage() []byte {
+	return errorMessage(eth.ShutdownError)
+}
+
+// errorMessage is a response to no request in particular
+func errorMessage(err eth.JSONRPCError) []byte {
+	message, _ := json.Marshal(&eth.JSONRPCResult{
+		JSONRPC: eth.RPCVersion,
+		Error:   err,
+		ID:      json.RawMessage("null"),
+	})
+	return message
+}
+
+// closeWebsocketOnShutdown tells the client the server is shutting down and closes ws once the server starts closing connections
+// it returns when done is closed, the connection having ended by itself
+func (s *Server) closeWebsocketOnShutdown(ws *websocket.Conn, writeMutex *sync.Mutex, close func(), done <-chan struct{}) {
+	select {
+	case <-done:
+		return
+	case <-s.closingConnections:
+	}
+
+	writeMutex.Lock()
+	ws.SetWriteDeadline(time.Now().Add(writeWait))
+	ws.WriteMessage(websocket.TextMessage, shutdownErrorMessage())
+	ws.WriteControl(
+		websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseGoingAway, eth.ShutdownError.Message()),
+		time.Now().Add(writeWait),
+	)
+	writeMutex.Unlock()
+	close()
+}
+
+// shutdownReadinessCheck fails /ready as soon as the server starts shutting down
+func (s *Server) shutdownReadinessCheck() error {
+	if s.isShuttingDown() {
+		return ErrShuttingDown
+	}
+	return nil
+}
+
+// ignoreServerClosed drops the error Start returns after a graceful shutdown
+func ignoreServerClosed(err error) error {
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
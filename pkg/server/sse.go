@@ -0,0 +1,191 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/pkg/errors"
+	"github.com/qtumproject/janus/pkg/eth"
+	"github.com/qtumproject/janus/pkg/metrics"
+	"github.com/qtumproject/janus/pkg/notifier"
+)
+
+// Server-Sent Events for clients that can't hold a websocket open
+// a stream carries a single eth_subscribe subscription, closing the stream unsubscribes
+//
+// GET /sse?params=["logs",{"address":"0x..."}] for EventSource
+// POST /sse with an eth_subscribe JSON-RPC request for everything else
+//
+// the first event is the eth_subscribe response, every event after that is an eth_subscription notification
+// exactly like they are sent over a websocket
+
+const (
+	ssePath = "/sse"
+	// comments are sent this often so proxies don't drop an idle stream
+	sseKeepAlivePeriod = 15 * time.Second
+)
+
+func (s *Server) sseHandler(c echo.Context) error {
+	myctx := c.Get("myctx")
+	cc, ok := myctx.(*myCtx)
+	if !ok {
+		return errors.New("Could not find myctx")
+	}
+
+	rpcReq, err := getSSESubscribeRequest(c)
+	if err != nil {
+		return cc.JSONRPCError(eth.NewInvalidRequestError(err.Error()))
+	}
+	cc.rpcReq = rpcReq
+
+	if rpcReq.Method != "eth_subscribe" {
+		return cc.JSONRPCError(eth.NewInvalidRequestError("only eth_subscribe is supported over server-sent events"))
+	}
+
+	flusher, ok := c.Response().Writer.(http.Flusher)
+	if !ok {
+		return errors.New("streaming is not supported by the response writer")
+	}
+
+	ctx, cancel := context.WithCancel(c.Request().Context())
+	defer cancel()
+
+	// the notifier can still be sending when the handler returns, the response mustn't be written to after that
+	var writeMutex sync.Mutex
+	closed := false
+	defer func() {
+		writeMutex.Lock()
+		closed = true
+		writeMutex.Unlock()
+	}()
+	write := func(value []byte) error {
+		writeMutex.Lock()
+		defer writeMutex.Unlock()
+		if closed {
+			return errors.New("stream closed")
+		}
+		if _, err := c.Response().Write(value); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	send := func(value []byte) error {
+		return write([]byte(fmt.Sprintf("data: %s\n\n", value)))
+	}
+
+	notifier := notifier.NewNotifier(
+		context.Background(),
+		cancel,
+		send,
+		cc.GetLogger(),
+	)
+	defer notifier.Disconnected()
+	c.Set("notifier", notifier)
+	s.addClient(cc, notifier, "sse")
+	defer s.removeClient(cc)
+
+	cc.GetLogger().Log("msg", "proxy SSE subscription")
+
+	result, jsonErr := cc.transform(rpcReq, c)
+	if jsonErr != nil {
+		cc.GetErrorLogger().Log("err", jsonErr.Error())
+		return cc.JSONRPCError(jsonErr)
+	}
+
+	response, err := cc.GetJSONRPCResult(result)
+	if err != nil {
+		return err
+	}
+	responseBytes, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentType, "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// stop nginx from buffering the stream
+	header.Set("X-Accel-Buffering", "no")
+	c.Response().WriteHeader(http.StatusOK)
+
+	if err := send(responseBytes); err != nil {
+		return nil
+	}
+	notifier.ResponseSent()
+
+	metrics.SSEStreams.Inc()
+	defer metrics.SSEStreams.Dec()
+
+	cc.GetDebugLogger().Log("msg", "SSE stream opened")
+	defer cc.GetDebugLogger().Log("msg", "SSE stream closed")
+
+	keepAlive := time.NewTicker(sseKeepAlivePeriod)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-notifier.Context().Done():
+			return nil
+		case <-s.closingConnections:
+			send(shutdownErrorMessage())
+			return nil
+		case <-keepAlive.C:
+			if err := write([]byte(": keepalive\n\n")); err != nil {
+				return nil
+			}
+		}
+	}
+}
+
+func getSSESubscribeRequest(c echo.Context) (*eth.JSONRPCRequest, error) {
+	if c.Request().Method == http.MethodGet {
+		params := c.QueryParam("params")
+		if params == "" {
+			return nil, errors.New("params query parameter is required")
+		}
+		id := c.QueryParam("id")
+		if id == "" {
+			id = "1"
+		}
+		if !json.Valid([]byte(id)) {
+			id = fmt.Sprintf("%q", id)
+		}
+
+		return &eth.JSONRPCRequest{
+			JSONRPC: eth.RPCVersion,
+			ID:      json.RawMessage(id),
+			Method:  "eth_subscribe",
+			Params:  json.RawMessage(params),
+		}, nil
+	}
+
+	var rpcReq *eth.JSONRPCRequest
+	if err := json.NewDecoder(c.Request().Body).Decode(&rpcReq); err != nil {
+		return nil, errors.Wrap(err, "json decoder issue")
+	}
+	if rpcReq == nil {
+		return nil, errors.New("empty request")
+	}
+
+	return rpcReq, nil
+}
+
+func (s *Server) registerSSEEndpoints(e *echo.Echo) error {
+	if s.agent == nil {
+		return errors.New("server-sent events require a notifier agent")
+	}
+
+	e.GET(ssePath, s.sseHandler)
+	e.POST(ssePath, s.sseHandler)
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/qtumproject/janus/pkg/eth"
+)
+
+// sseEvents reads the data of each event of a server-sent event stream
+func sseEvents(t *testing.T, resp *http.Response) <-chan string {
+	events := make(chan string, 10)
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			if line := scanner.Text(); strings.HasPrefix(line, "data: ") {
+				events <- strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+	return events
+}
+
+func nextEvent(t *testing.T, events <-chan string) *rpcNotification {
+	t.Helper()
+	select {
+	case event, ok := <-events:
+		if !ok {
+			t.Fatal("Stream ended")
+		}
+		var notification rpcNotification
+		if err := json.Unmarshal([]byte(event), &notification); err != nil {
+			t.Fatalf("Failed to unmarshal event %s: %s", event, err)
+		}
+		return &notification
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for an event")
+	}
+	return nil
+}
+
+func TestSSENewHeads(t *testing.T) {
+	s := newMinedTestServer(t)
+
+	resp, err := http.Get(s.http.URL + ssePath + "?id=7&params=" + url.QueryEscape(`["newHeads"]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	events := sseEvents(t, resp)
+
+	subscribed := nextEvent(t, events)
+	var subscriptionID string
+	if err := json.Unmarshal(subscribed.Result, &subscriptionID); err != nil || subscriptionID == "" || string(subscribed.ID) != "7" {
+		t.Fatalf("Expected the eth_subscribe response first, got %+v", subscribed)
+	}
+
+	// the agent takes the tip it first sees as already sent
+	time.Sleep(200 * time.Millisecond)
+	if _, err := s.qtumd.Chain().Mine(1, s.qtumd.Chain().NewAddress("miner")); err != nil {
+		t.Fatal(err)
+	}
+	head := nextEvent(t, events)
+	if head.Method != "eth_subscription" || head.Params.Subscription != subscriptionID {
+		t.Fatalf("Expected a newHeads notification for %s, got %+v", subscriptionID, head)
+	}
+	var block struct {
+		Number string `json:"number"`
+	}
+	if err := json.Unmarshal(head.Params.Result, &block); err != nil || block.Number != "0x2" {
+		t.Fatalf("Expected block 0x2, got %s", head.Params.Result)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	s.Shutdown(ctx)
+	shutdown := nextEvent(t, events)
+	if shutdown.Error == nil || shutdown.Error.Code != eth.ShutdownError.Code() {
+		t.Fatalf("Expected the shutdown error, got %+v", shutdown)
+	}
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Fatal("Expected the stream to end after the shutdown error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the stream to end")
+	}
+}
+
+func TestSSEOnlySubscribes(t *testing.T) {
+	s := newTestServer(t)
+
+	req, err := http.NewRequest(http.MethodPost, s.http.URL+ssePath, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber","params":[]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var response rpcResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Error == nil || response.Error.Code != eth.InvalidRequestErrorCode {
+		t.Fatalf("Expected eth_blockNumber to be refused, got %+v", response)
+	}
+}
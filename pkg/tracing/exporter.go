@@ -0,0 +1,303 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+)
+
+var (
+	// DefaultOTLPBatchSize is the most spans sent to the collector in one request
+	DefaultOTLPBatchSize = 512
+	// DefaultOTLPFlushInterval is how long spans wait to be sent when there aren't enough for a full batch
+	DefaultOTLPFlushInterval = 5 * time.Second
+	// spans waiting to be sent, more are dropped until the collector catches up
+	otlpQueueSize = 4096
+)
+
+// StdoutExporter writes every span to a writer as a line of JSON
+type StdoutExporter struct {
+	mutex  sync.Mutex
+	writer io.Writer
+}
+
+func NewStdoutExporter(writer io.Writer) *StdoutExporter {
+	return &StdoutExporter{writer: writer}
+}
+
+type stdoutSpan struct {
+	TraceID      string                 `json:"traceId"`
+	SpanID       string                 `json:"spanId"`
+	ParentSpanID string                 `json:"parentSpanId,omitempty"`
+	Name         string                 `json:"name"`
+	Kind         SpanKind               `json:"kind"`
+	Start        time.Time              `json:"start"`
+	End          time.Time              `json:"end"`
+	Attributes   map[string]interface{} `json:"attributes,omitempty"`
+	Error        string                 `json:"error,omitempty"`
+}
+
+func (e *StdoutExporter) ExportSpan(span *Span) {
+	out := stdoutSpan{
+		TraceID: span.traceID.String(),
+		SpanID:  span.spanID.String(),
+		Name:    span.name,
+		Kind:    span.kind,
+		Start:   span.startTime,
+		End:     span.endTime,
+		Error:   span.err,
+	}
+	if span.parentID != (SpanID{}) {
+		out.ParentSpanID = span.parentID.String()
+	}
+	if len(span.attributes) != 0 {
+		out.Attributes = make(map[string]interface{}, len(span.attributes))
+		for _, attribute := range span.attributes {
+			out.Attributes[attribute.key] = attribute.value
+		}
+	}
+
+	line, err := json.Marshal(out)
+	if err != nil {
+		return
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.writer.Write(append(line, '\n'))
+}
+
+func (e *StdoutExporter) Shutdown(context.Context) error {
+	return nil
+}
+
+// OTLPExporter sends spans in batches to an OpenTelemetry collector, with OTLP over HTTP in its JSON encoding
+type OTLPExporter struct {
+	url         string
+	client      *http.Client
+	logger      log.Logger
+	serviceName string
+
+	spans    chan *Span
+	flush    chan chan struct{}
+	stopOnce sync.Once
+	stopped  chan struct{}
+}
+
+// NewOTLPExporter starts sending spans to url, the collector's traces endpoint (e.g. http://localhost:4318/v1/traces)
+func NewOTLPExporter(url string, serviceName string, logger log.Logger) *OTLPExporter {
+	e := &OTLPExporter{
+		url:         url,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		logger:      log.WithPrefix(logger, "component", "tracing"),
+		serviceName: serviceName,
+		spans:       make(chan *Span, otlpQueueSize),
+		flush:       make(chan chan struct{}),
+		stopped:     make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+func (e *OTLPExporter) ExportSpan(span *Span) {
+	select {
+	case e.spans <- span:
+	default:
+		// the collector isn't keeping up, tracing mustn't slow down requests
+	}
+}
+
+// Shutdown sends the spans that are queued up and stops the exporter
+func (e *OTLPExporter) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	select {
+	case e.flush <- done:
+	case <-e.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (e *OTLPExporter) run() {
+	ticker := time.NewTicker(DefaultOTLPFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, DefaultOTLPBatchSize)
+	send := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := e.send(batch); err != nil {
+			e.logger.Log("msg", "Failed to send spans", "spans", len(batch), "err", err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case span := <-e.spans:
+			batch = append(batch, span)
+			if len(batch) >= DefaultOTLPBatchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case done := <-e.flush:
+			for drained := false; !drained; {
+				select {
+				case span := <-e.spans:
+					batch = append(batch, span)
+					if len(batch) >= DefaultOTLPBatchSize {
+						send()
+					}
+				default:
+					drained = true
+				}
+			}
+			send()
+			close(e.stopped)
+			close(done)
+			return
+		}
+	}
+}
+
+func (e *OTLPExporter) send(spans []*Span) error {
+	body, err := json.Marshal(e.request(spans))
+	if err != nil {
+		return err
+	}
+
+	resp, err := e.client.Post(e.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		return errors.Errorf("collector responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// the parts of the OTLP ExportTraceServiceRequest that are used
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              SpanKind        `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            *otlpStatus     `json:"status,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// status code of a span that failed
+const otlpStatusError = 2
+
+type otlpAttribute struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+func newOTLPAttribute(key string, value interface{}) otlpAttribute {
+	var v map[string]interface{}
+	switch value := value.(type) {
+	case bool:
+		v = map[string]interface{}{"boolValue": value}
+	case int:
+		v = map[string]interface{}{"intValue": strconv.FormatInt(int64(value), 10)}
+	case int64:
+		v = map[string]interface{}{"intValue": strconv.FormatInt(value, 10)}
+	case float64:
+		v = map[string]interface{}{"doubleValue": value}
+	case string:
+		v = map[string]interface{}{"stringValue": value}
+	default:
+		v = map[string]interface{}{"stringValue": fmt.Sprint(value)}
+	}
+	return otlpAttribute{Key: key, Value: v}
+}
+
+func (e *OTLPExporter) request(spans []*Span) *otlpRequest {
+	otlpSpans := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		s := otlpSpan{
+			TraceID:           span.traceID.String(),
+			SpanID:            span.spanID.String(),
+			Name:              span.name,
+			Kind:              span.kind,
+			StartTimeUnixNano: strconv.FormatInt(span.startTime.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.endTime.UnixNano(), 10),
+		}
+		if span.parentID != (SpanID{}) {
+			s.ParentSpanID = span.parentID.String()
+		}
+		for _, attribute := range span.attributes {
+			s.Attributes = append(s.Attributes, newOTLPAttribute(attribute.key, attribute.value))
+		}
+		if span.err != "" {
+			s.Status = &otlpStatus{Code: otlpStatusError, Message: span.err}
+		}
+		otlpSpans = append(otlpSpans, s)
+	}
+
+	return &otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: []otlpAttribute{newOTLPAttribute("service.name", e.serviceName)},
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "github.com/qtumproject/janus"},
+				Spans: otlpSpans,
+			}},
+		}},
+	}
+}
@@ -0,0 +1,282 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// the messages of an OTLP ExportTraceServiceRequest with all of their fields, from opentelemetry-proto's
+// collector/trace/v1/trace_service.proto, trace/v1/trace.proto, resource/v1/resource.proto and common/v1/common.proto
+// in the OTLP/JSON encoding: lowerCamelCase field names, 64 bit integers as decimal strings, enums as integers
+// and trace and span IDs as hex instead of base64
+type schemaExportTraceServiceRequest struct {
+	ResourceSpans []schemaResourceSpans `json:"resourceSpans"`
+}
+
+type schemaResourceSpans struct {
+	Resource   *schemaResource    `json:"resource"`
+	ScopeSpans []schemaScopeSpans `json:"scopeSpans"`
+	SchemaURL  string             `json:"schemaUrl"`
+}
+
+type schemaResource struct {
+	Attributes             []schemaKeyValue `json:"attributes"`
+	DroppedAttributesCount uint32           `json:"droppedAttributesCount"`
+}
+
+type schemaScopeSpans struct {
+	Scope     *schemaInstrumentationScope `json:"scope"`
+	Spans     []schemaSpan                `json:"spans"`
+	SchemaURL string                      `json:"schemaUrl"`
+}
+
+type schemaInstrumentationScope struct {
+	Name                   string           `json:"name"`
+	Version                string           `json:"version"`
+	Attributes             []schemaKeyValue `json:"attributes"`
+	DroppedAttributesCount uint32           `json:"droppedAttributesCount"`
+}
+
+type schemaSpan struct {
+	TraceID                string           `json:"traceId"`
+	SpanID                 string           `json:"spanId"`
+	TraceState             string           `json:"traceState"`
+	ParentSpanID           string           `json:"parentSpanId"`
+	Flags                  uint32           `json:"flags"`
+	Name                   string           `json:"name"`
+	Kind                   int32            `json:"kind"`
+	StartTimeUnixNano      string           `json:"startTimeUnixNano"`
+	EndTimeUnixNano        string           `json:"endTimeUnixNano"`
+	Attributes             []schemaKeyValue `json:"attributes"`
+	DroppedAttributesCount uint32           `json:"droppedAttributesCount"`
+	Events                 []schemaEvent    `json:"events"`
+	DroppedEventsCount     uint32           `json:"droppedEventsCount"`
+	Links                  []schemaLink     `json:"links"`
+	DroppedLinksCount      uint32           `json:"droppedLinksCount"`
+	Status                 *schemaStatus    `json:"status"`
+}
+
+type schemaEvent struct {
+	TimeUnixNano           string           `json:"timeUnixNano"`
+	Name                   string           `json:"name"`
+	Attributes             []schemaKeyValue `json:"attributes"`
+	DroppedAttributesCount uint32           `json:"droppedAttributesCount"`
+}
+
+type schemaLink struct {
+	TraceID                string           `json:"traceId"`
+	SpanID                 string           `json:"spanId"`
+	TraceState             string           `json:"traceState"`
+	Attributes             []schemaKeyValue `json:"attributes"`
+	DroppedAttributesCount uint32           `json:"droppedAttributesCount"`
+	Flags                  uint32           `json:"flags"`
+}
+
+type schemaStatus struct {
+	Message string `json:"message"`
+	Code    int32  `json:"code"`
+}
+
+type schemaKeyValue struct {
+	Key   string          `json:"key"`
+	Value *schemaAnyValue `json:"value"`
+}
+
+// AnyValue is a oneof, exactly one of the fields is set
+type schemaAnyValue struct {
+	StringValue *string          `json:"stringValue"`
+	BoolValue   *bool            `json:"boolValue"`
+	IntValue    *string          `json:"intValue"`
+	DoubleValue *float64         `json:"doubleValue"`
+	ArrayValue  *json.RawMessage `json:"arrayValue"`
+	KvlistValue *json.RawMessage `json:"kvlistValue"`
+	BytesValue  *string          `json:"bytesValue"`
+}
+
+// validateOTLP decodes body as an ExportTraceServiceRequest, failing on anything the schema doesn't allow
+func validateOTLP(t *testing.T, body []byte) *schemaExportTraceServiceRequest {
+	t.Helper()
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	var req schemaExportTraceServiceRequest
+	if err := decoder.Decode(&req); err != nil {
+		t.Fatalf("request doesn't match the OTLP schema: %s\n%s", err, body)
+	}
+
+	validateHex := func(what string, id string, size int) {
+		t.Helper()
+		decoded, err := hex.DecodeString(id)
+		if err != nil || len(decoded) != size {
+			t.Errorf("%s %q should be %d bytes of hex", what, id, size)
+		}
+	}
+	validateNanos := func(what string, nanos string) {
+		t.Helper()
+		if _, err := strconv.ParseUint(nanos, 10, 64); err != nil {
+			t.Errorf("%s %q should be a fixed64 as a decimal string", what, nanos)
+		}
+	}
+	validateAttributes := func(attributes []schemaKeyValue) {
+		t.Helper()
+		for _, attribute := range attributes {
+			if attribute.Key == "" || attribute.Value == nil {
+				t.Errorf("attribute %+v should have a key and a value", attribute)
+				continue
+			}
+			v := attribute.Value
+			set := 0
+			for _, isSet := range []bool{v.StringValue != nil, v.BoolValue != nil, v.IntValue != nil, v.DoubleValue != nil, v.ArrayValue != nil, v.KvlistValue != nil, v.BytesValue != nil} {
+				if isSet {
+					set++
+				}
+			}
+			if set != 1 {
+				t.Errorf("attribute %s should have exactly one value, has %d", attribute.Key, set)
+			}
+			if v.IntValue != nil {
+				if _, err := strconv.ParseInt(*v.IntValue, 10, 64); err != nil {
+					t.Errorf("attribute %s intValue %q should be an int64 as a decimal string", attribute.Key, *v.IntValue)
+				}
+			}
+		}
+	}
+
+	for _, resourceSpans := range req.ResourceSpans {
+		if resourceSpans.Resource != nil {
+			validateAttributes(resourceSpans.Resource.Attributes)
+		}
+		for _, scopeSpans := range resourceSpans.ScopeSpans {
+			for _, span := range scopeSpans.Spans {
+				validateHex("traceId", span.TraceID, 16)
+				validateHex("spanId", span.SpanID, 8)
+				if span.ParentSpanID != "" {
+					validateHex("parentSpanId", span.ParentSpanID, 8)
+				}
+				// SPAN_KIND_UNSPECIFIED to SPAN_KIND_CONSUMER
+				if span.Kind < 0 || span.Kind > 5 {
+					t.Errorf("span kind %d isn't a SpanKind", span.Kind)
+				}
+				validateNanos("startTimeUnixNano", span.StartTimeUnixNano)
+				validateNanos("endTimeUnixNano", span.EndTimeUnixNano)
+				validateAttributes(span.Attributes)
+				// STATUS_CODE_UNSET, STATUS_CODE_OK or STATUS_CODE_ERROR
+				if span.Status != nil && (span.Status.Code < 0 || span.Status.Code > 2) {
+					t.Errorf("status code %d isn't a StatusCode", span.Status.Code)
+				}
+			}
+		}
+	}
+	return &req
+}
+
+// an OTLP/JSON request in the shape of the example in opentelemetry-proto's examples/trace.json
+const otlpExampleRequest = `{
+	"resourceSpans": [{
+		"resource": {"attributes": [{"key": "service.name", "value": {"stringValue": "my.service"}}]},
+		"scopeSpans": [{
+			"scope": {"name": "my.library", "version": "1.0.0", "attributes": [{"key": "my.scope.attribute", "value": {"stringValue": "some scope attribute"}}]},
+			"spans": [{
+				"traceId": "5b8efff798038103d269b633813fc60c",
+				"spanId": "eee19b7ec3c1b174",
+				"parentSpanId": "eee19b7ec3c1b173",
+				"name": "I'm a server span",
+				"startTimeUnixNano": "1544712660000000000",
+				"endTimeUnixNano": "1544712661000000000",
+				"kind": 2,
+				"attributes": [
+					{"key": "my.span.attr", "value": {"stringValue": "some value"}},
+					{"key": "my.int.attr", "value": {"intValue": "5"}}
+				],
+				"status": {"code": 2, "message": "failed"}
+			}]
+		}]
+	}]
+}`
+
+func TestOTLPSchemaAcceptsExample(t *testing.T) {
+	// checks the schema above, so the encoding is checked against the real thing
+	req := validateOTLP(t, []byte(otlpExampleRequest))
+	if span := req.ResourceSpans[0].ScopeSpans[0].Spans[0]; span.Name != "I'm a server span" || span.Status.Code != 2 {
+		t.Errorf("decoded the example wrong: %+v", span)
+	}
+}
+
+func TestOTLPEncodingMatchesSchema(t *testing.T) {
+	exporter := &OTLPExporter{serviceName: "janus-test", logger: log.NewNopLogger()}
+	tracer := newTestTracer(t, exporter)
+	ctx := ContextWithTracer(context.Background(), tracer, "00-5b8efff798038103d269b633813fc60c-eee19b7ec3c1b173-01")
+
+	ctx, server := Start(ctx, "eth_call", SpanKindServer,
+		"rpc.method", "eth_call",
+		"janus.cached", false,
+		"rpc.jsonrpc.error_code", -32000,
+		"janus.block", int64(1<<40),
+		"janus.ratio", 0.5,
+		"janus.duration", time.Second,
+	)
+	_, client := Start(ctx, "callcontract", SpanKindClient)
+	client.End()
+	server.SetError("execution reverted")
+	server.End()
+
+	body, err := json.Marshal(exporter.request([]*Span{client, server}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := validateOTLP(t, body)
+
+	resource := req.ResourceSpans[0]
+	if name := resource.Resource.Attributes[0]; name.Key != "service.name" || *name.Value.StringValue != "janus-test" {
+		t.Errorf("expected the service name as a resource attribute, got %s", body)
+	}
+	spans := resource.ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %s", body)
+	}
+	sentClient, sentServer := spans[0], spans[1]
+	if sentServer.TraceID != "5b8efff798038103d269b633813fc60c" || sentServer.ParentSpanID != "eee19b7ec3c1b173" {
+		t.Errorf("expected the server span to continue the remote trace, got %+v", sentServer)
+	}
+	if sentClient.TraceID != sentServer.TraceID || sentClient.ParentSpanID != sentServer.SpanID {
+		t.Errorf("expected the client span to be a child of the server span, got %+v", sentClient)
+	}
+	if sentServer.Kind != 2 || sentClient.Kind != 3 {
+		t.Errorf("expected SPAN_KIND_SERVER and SPAN_KIND_CLIENT, got %d and %d", sentServer.Kind, sentClient.Kind)
+	}
+	if sentServer.StartTimeUnixNano != strconv.FormatInt(server.startTime.UnixNano(), 10) {
+		t.Errorf("expected the start time in nanoseconds, got %s", sentServer.StartTimeUnixNano)
+	}
+	if sentServer.Status == nil || sentServer.Status.Code != 2 || sentServer.Status.Message != "execution reverted" || sentClient.Status != nil {
+		t.Errorf("expected only the server span to have STATUS_CODE_ERROR, got %+v and %+v", sentServer.Status, sentClient.Status)
+	}
+
+	attributes := make(map[string]*schemaAnyValue)
+	for _, attribute := range sentServer.Attributes {
+		attributes[attribute.Key] = attribute.Value
+	}
+	if v := attributes["rpc.method"]; v == nil || v.StringValue == nil || *v.StringValue != "eth_call" {
+		t.Errorf("expected rpc.method as a stringValue, got %s", body)
+	}
+	if v := attributes["janus.cached"]; v == nil || v.BoolValue == nil || *v.BoolValue {
+		t.Errorf("expected janus.cached as a boolValue, got %s", body)
+	}
+	if v := attributes["rpc.jsonrpc.error_code"]; v == nil || v.IntValue == nil || *v.IntValue != "-32000" {
+		t.Errorf("expected rpc.jsonrpc.error_code as an intValue, got %s", body)
+	}
+	if v := attributes["janus.block"]; v == nil || v.IntValue == nil || *v.IntValue != "1099511627776" {
+		t.Errorf("expected janus.block as an intValue, got %s", body)
+	}
+	if v := attributes["janus.ratio"]; v == nil || v.DoubleValue == nil || *v.DoubleValue != 0.5 {
+		t.Errorf("expected janus.ratio as a doubleValue, got %s", body)
+	}
+	if v := attributes["janus.duration"]; v == nil || v.StringValue == nil || *v.StringValue != "1s" {
+		t.Errorf("expected other types as a stringValue, got %s", body)
+	}
+}
@@ -0,0 +1,81 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/go-kit/kit/log"
+)
+
+// RequestIDHeader is the header a client can send its own request ID in, responses always have one
+const RequestIDHeader = "X-Request-ID"
+
+// longest request ID taken from a client
+const maxRequestIDLength = 128
+
+type requestIDContextKey struct{}
+
+// NewRequestID makes a random request ID
+func NewRequestID() string {
+	var id [16]byte
+	rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// RequestID keeps a request ID sent by a client if it is safe to log, otherwise it makes a new one
+func RequestID(fromClient string) string {
+	if fromClient == "" || len(fromClient) > maxRequestIDLength {
+		return NewRequestID()
+	}
+	for _, c := range fromClient {
+		if !isRequestIDCharacter(c) {
+			return NewRequestID()
+		}
+	}
+	return fromClient
+}
+
+func isRequestIDCharacter(c rune) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') ||
+		c == '-' || c == '_' || c == '.' || c == ':'
+}
+
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, requestID)
+}
+
+// RequestIDFromContext is the ID of the request ctx belongs to, empty if there isn't one
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(requestIDContextKey{}).(string)
+	return requestID
+}
+
+// WithValuesOf is parent with the request ID and span of from, for work shared between requests that has to outlive from
+// it is only done when parent is, the request it is logged and traced under can give up without cancelling it
+func WithValuesOf(parent context.Context, from context.Context) context.Context {
+	if parent == nil {
+		parent = context.Background()
+	}
+	if requestID := RequestIDFromContext(from); requestID != "" {
+		parent = ContextWithRequestID(parent, requestID)
+	}
+	if span := SpanFromContext(from); span != nil {
+		parent = context.WithValue(parent, spanContextKey{}, span)
+	}
+	return parent
+}
+
+// Logger adds the request ID and trace ID of ctx to logger's logs, so they can be matched up with the request
+func Logger(ctx context.Context, logger log.Logger) log.Logger {
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		logger = log.With(logger, "requestId", requestID)
+	}
+	if span := SpanFromContext(ctx); span.Sampled() {
+		logger = log.With(logger, "traceId", span.TraceID().String())
+	}
+	return logger
+}
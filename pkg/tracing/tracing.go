@@ -0,0 +1,306 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TraceParentHeader is the W3C trace context header spans are propagated in
+const TraceParentHeader = "traceparent"
+
+// SpanKind is the OpenTelemetry span kind
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+)
+
+type TraceID [16]byte
+type SpanID [8]byte
+
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// Exporter sends finished spans somewhere
+type Exporter interface {
+	ExportSpan(*Span)
+	// Shutdown sends anything still buffered
+	Shutdown(context.Context) error
+}
+
+// Tracer starts the root spans of requests, every span started under one is sent to its exporter
+// it's a small stand in for the OpenTelemetry Go SDK, which needs a newer Go than Janus builds with (1.14)
+// and would bring gRPC and protobuf along for an exporter that only has to POST JSON
+type Tracer struct {
+	exporter    Exporter
+	sampleRatio float64
+}
+
+type Option func(*Tracer) error
+
+func New(exporter Exporter, opts ...Option) (*Tracer, error) {
+	if exporter == nil {
+		return nil, errors.New("exporter cannot be nil")
+	}
+
+	t := &Tracer{
+		exporter:    exporter,
+		sampleRatio: 1,
+	}
+
+	for _, opt := range opts {
+		if err := opt(t); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+// SetSampleRatio sets how many of the requests that don't come with a sampling decision of their own are traced
+func SetSampleRatio(ratio float64) Option {
+	return func(t *Tracer) error {
+		if ratio < 0 || ratio > 1 {
+			return errors.New("sample ratio must be between 0 and 1")
+		}
+		t.sampleRatio = ratio
+		return nil
+	}
+}
+
+// Shutdown sends the spans that haven't been exported yet
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	return t.exporter.Shutdown(ctx)
+}
+
+// sampled decides from the random trace ID, like OpenTelemetry's TraceIDRatioBased sampler
+func (t *Tracer) sampled(traceID TraceID) bool {
+	if t.sampleRatio >= 1 {
+		return true
+	}
+	return float64(bytesToUint64(traceID[8:])>>1) < t.sampleRatio*float64(uint64(1)<<63)
+}
+
+type attribute struct {
+	key   string
+	value interface{}
+}
+
+// Span is one traced operation, a nil span is valid and does nothing
+type Span struct {
+	tracer  *Tracer
+	sampled bool
+
+	traceID   TraceID
+	spanID    SpanID
+	parentID  SpanID
+	name      string
+	kind      SpanKind
+	startTime time.Time
+
+	// nothing changes once the span has ended
+	mutex      sync.Mutex
+	ended      bool
+	endTime    time.Time
+	attributes []attribute
+	// set when the operation failed
+	err string
+}
+
+type spanContextKey struct{}
+type tracerContextKey struct{}
+type remoteParentContextKey struct{}
+
+// ContextWithTracer makes spans started under ctx without a parent span root spans of t
+// remoteParent is the traceparent header the request came with, if any
+func ContextWithTracer(ctx context.Context, t *Tracer, remoteParent string) context.Context {
+	if t == nil {
+		return ctx
+	}
+	ctx = context.WithValue(ctx, tracerContextKey{}, t)
+	if remoteParent != "" {
+		ctx = context.WithValue(ctx, remoteParentContextKey{}, remoteParent)
+	}
+	return ctx
+}
+
+// SpanFromContext is the span ctx was started under, nil if there isn't one
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	span, _ := ctx.Value(spanContextKey{}).(*Span)
+	return span
+}
+
+// Start starts a span under the span in ctx, or a root span if ctx only has a tracer
+// spans are only recorded when ctx has one or the other, otherwise the returned span is nil
+// keyvals are attributes of the span, in the same alternating key value form go-kit logs take
+func Start(ctx context.Context, name string, kind SpanKind, keyvals ...interface{}) (context.Context, *Span) {
+	if ctx == nil {
+		return ctx, nil
+	}
+
+	span := &Span{
+		name:      name,
+		kind:      kind,
+		startTime: time.Now(),
+	}
+
+	if parent := SpanFromContext(ctx); parent != nil {
+		span.tracer = parent.tracer
+		span.sampled = parent.sampled
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID
+	} else if tracer, ok := ctx.Value(tracerContextKey{}).(*Tracer); ok {
+		span.tracer = tracer
+		remoteParent, _ := ctx.Value(remoteParentContextKey{}).(string)
+		if traceID, parentID, sampled, err := ParseTraceParent(remoteParent); err == nil {
+			span.traceID = traceID
+			span.parentID = parentID
+			span.sampled = sampled
+		} else {
+			rand.Read(span.traceID[:])
+			span.sampled = tracer.sampled(span.traceID)
+		}
+	} else {
+		return ctx, nil
+	}
+
+	rand.Read(span.spanID[:])
+	span.SetAttributes(keyvals...)
+
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+// SetAttributes adds attributes to the span, keyvals alternate between keys and values
+func (s *Span) SetAttributes(keyvals ...interface{}) {
+	if s == nil || !s.sampled {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.ended {
+		return
+	}
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		s.attributes = append(s.attributes, attribute{key: fmt.Sprint(keyvals[i]), value: keyvals[i+1]})
+	}
+}
+
+// SetError marks the operation as failed
+func (s *Span) SetError(message string) {
+	if s == nil || !s.sampled {
+		return
+	}
+
+	s.mutex.Lock()
+	if !s.ended {
+		s.err = message
+	}
+	s.mutex.Unlock()
+}
+
+// End finishes the span and exports it, only the first call does anything
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	if s.ended {
+		s.mutex.Unlock()
+		return
+	}
+	s.ended = true
+	s.endTime = time.Now()
+	s.mutex.Unlock()
+
+	if s.sampled {
+		s.tracer.exporter.ExportSpan(s)
+	}
+}
+
+// TraceParent is the W3C traceparent header of the span, for passing it on to the next service
+func (s *Span) TraceParent() string {
+	if s == nil {
+		return ""
+	}
+	flags := "00"
+	if s.sampled {
+		flags = "01"
+	}
+	return "00-" + s.traceID.String() + "-" + s.spanID.String() + "-" + flags
+}
+
+// TraceID is the ID of the trace the span is part of
+func (s *Span) TraceID() TraceID {
+	if s == nil {
+		return TraceID{}
+	}
+	return s.traceID
+}
+
+// Sampled is true if the span is being recorded
+func (s *Span) Sampled() bool {
+	return s != nil && s.sampled
+}
+
+// Inject sets the traceparent header of the span in ctx on header, so the service a request goes to can continue the trace
+func Inject(ctx context.Context, header http.Header) {
+	if span := SpanFromContext(ctx); span != nil {
+		header.Set(TraceParentHeader, span.TraceParent())
+	}
+}
+
+// ParseTraceParent parses a W3C traceparent header
+func ParseTraceParent(traceParent string) (traceID TraceID, parentID SpanID, sampled bool, err error) {
+	parts := strings.Split(strings.TrimSpace(traceParent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return traceID, parentID, false, errors.Errorf("invalid traceparent %q", traceParent)
+	}
+	if err := decodeHex(traceID[:], parts[1]); err != nil || traceID == (TraceID{}) {
+		return traceID, parentID, false, errors.Errorf("invalid trace id in traceparent %q", traceParent)
+	}
+	if err := decodeHex(parentID[:], parts[2]); err != nil || parentID == (SpanID{}) {
+		return traceID, parentID, false, errors.Errorf("invalid parent id in traceparent %q", traceParent)
+	}
+	var flags [1]byte
+	if err := decodeHex(flags[:], parts[3]); err != nil {
+		return traceID, parentID, false, errors.Errorf("invalid flags in traceparent %q", traceParent)
+	}
+	return traceID, parentID, flags[0]&1 == 1, nil
+}
+
+// decodeHex decodes lowercase hex that exactly fills into
+func decodeHex(into []byte, s string) error {
+	if len(s) != hex.EncodedLen(len(into)) || strings.ToLower(s) != s {
+		return errors.New("wrong length")
+	}
+	_, err := hex.Decode(into, []byte(s))
+	return err
+}
+
+func bytesToUint64(b []byte) uint64 {
+	var n uint64
+	for _, c := range b {
+		n = n<<8 | uint64(c)
+	}
+	return n
+}
@@ -0,0 +1,248 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// recorder keeps the spans exported to it
+type recorder struct {
+	mutex sync.Mutex
+	spans []*Span
+}
+
+func (r *recorder) ExportSpan(span *Span) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.spans = append(r.spans, span)
+}
+
+func (r *recorder) Shutdown(context.Context) error {
+	return nil
+}
+
+func (r *recorder) exported() []*Span {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]*Span(nil), r.spans...)
+}
+
+func newTestTracer(t *testing.T, exporter Exporter, opts ...Option) *Tracer {
+	tracer, err := New(exporter, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tracer
+}
+
+func TestParseTraceParent(t *testing.T) {
+	traceID, parentID, sampled, err := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if traceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || parentID.String() != "00f067aa0ba902b7" || !sampled {
+		t.Errorf("parsed the wrong trace context: %s %s %v", traceID, parentID, sampled)
+	}
+
+	invalid := []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01",
+	}
+	for _, traceParent := range invalid {
+		if _, _, _, err := ParseTraceParent(traceParent); err == nil {
+			t.Errorf("expected %q to be invalid", traceParent)
+		}
+	}
+}
+
+func TestSpansContinueRemoteTrace(t *testing.T) {
+	exporter := &recorder{}
+	tracer := newTestTracer(t, exporter)
+	ctx := ContextWithTracer(context.Background(), tracer, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	ctx, root := Start(ctx, "eth_blockNumber", SpanKindServer, "rpc.method", "eth_blockNumber")
+	_, child := Start(ctx, "qtumd getblockcount", SpanKindClient)
+	child.SetError("qtumd went away")
+	child.End()
+	root.End()
+	root.End()
+
+	spans := exporter.exported()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans to be exported, got %d", len(spans))
+	}
+	if root.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" || root.parentID.String() != "00f067aa0ba902b7" {
+		t.Errorf("expected the root span to continue the remote trace, got %s", root.TraceParent())
+	}
+	if child.TraceID() != root.TraceID() || child.parentID != root.spanID {
+		t.Errorf("expected the child span to be under the root span, got %s", child.TraceParent())
+	}
+	if child.err != "qtumd went away" {
+		t.Errorf("expected the child span to have failed, got %q", child.err)
+	}
+
+	header := http.Header{}
+	Inject(ctx, header)
+	if header.Get(TraceParentHeader) != root.TraceParent() {
+		t.Errorf("expected %s to be injected, got %s", root.TraceParent(), header.Get(TraceParentHeader))
+	}
+}
+
+func TestUnsampledSpansArentExported(t *testing.T) {
+	exporter := &recorder{}
+	ctx := ContextWithTracer(context.Background(), newTestTracer(t, exporter), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+
+	ctx, span := Start(ctx, "eth_chainId", SpanKindServer)
+	span.End()
+	if span.Sampled() || len(exporter.exported()) != 0 {
+		t.Error("expected a span under an unsampled remote parent to not be exported")
+	}
+	if !strings.HasSuffix(span.TraceParent(), "-00") {
+		t.Errorf("expected the unsampled decision to be passed on, got %s", span.TraceParent())
+	}
+
+	ctx = ContextWithTracer(context.Background(), newTestTracer(t, exporter, SetSampleRatio(0)), "")
+	_, span = Start(ctx, "eth_chainId", SpanKindServer)
+	span.End()
+	if len(exporter.exported()) != 0 {
+		t.Error("expected nothing to be sampled with a ratio of 0")
+	}
+
+	if _, span := Start(context.Background(), "eth_chainId", SpanKindServer); span != nil {
+		t.Error("expected no span without a tracer")
+	}
+	// a nil span does nothing
+	var nilSpan *Span
+	nilSpan.SetAttributes("key", "value")
+	nilSpan.SetError("error")
+	nilSpan.End()
+}
+
+func TestStdoutExporter(t *testing.T) {
+	var out bytes.Buffer
+	ctx := ContextWithTracer(context.Background(), newTestTracer(t, NewStdoutExporter(&out)), "")
+	_, span := Start(ctx, "eth_getBalance", SpanKindServer, "rpc.method", "eth_getBalance", "janus.cached", true)
+	span.End()
+
+	var exported stdoutSpan
+	if err := json.Unmarshal(out.Bytes(), &exported); err != nil {
+		t.Fatalf("expected a line of JSON, got %q: %s", out.String(), err)
+	}
+	if exported.Name != "eth_getBalance" || exported.TraceID != span.TraceID().String() || exported.ParentSpanID != "" {
+		t.Errorf("exported the wrong span: %s", out.String())
+	}
+	if exported.Attributes["rpc.method"] != "eth_getBalance" || exported.Attributes["janus.cached"] != true {
+		t.Errorf("expected the attributes to be exported, got %v", exported.Attributes)
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	requests := make(chan otlpRequest, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var req otlpRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Errorf("collector got invalid JSON: %s", err)
+		}
+		requests <- req
+	}))
+	defer collector.Close()
+
+	exporter := NewOTLPExporter(collector.URL+"/v1/traces", "janus-test", log.NewNopLogger())
+	tracer := newTestTracer(t, exporter)
+	ctx := ContextWithTracer(context.Background(), tracer, "")
+	_, span := Start(ctx, "eth_call", SpanKindServer, "rpc.jsonrpc.error_code", -32000)
+	span.SetError("execution reverted")
+	span.End()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := tracer.Shutdown(shutdownCtx); err != nil {
+		t.Fatal(err)
+	}
+
+	var req otlpRequest
+	select {
+	case req = <-requests:
+	default:
+		t.Fatal("expected the span to be sent on shutdown")
+	}
+
+	resource := req.ResourceSpans[0]
+	if resource.Resource.Attributes[0].Value["stringValue"] != "janus-test" {
+		t.Errorf("expected the service name to be sent, got %v", resource.Resource.Attributes)
+	}
+	sent := resource.ScopeSpans[0].Spans[0]
+	if sent.Name != "eth_call" || sent.TraceID != span.TraceID().String() || sent.Kind != SpanKindServer {
+		t.Errorf("sent the wrong span: %+v", sent)
+	}
+	if sent.Status == nil || sent.Status.Code != otlpStatusError || sent.Status.Message != "execution reverted" {
+		t.Errorf("expected the span to have failed, got %+v", sent.Status)
+	}
+	if sent.Attributes[0].Value["intValue"] != "-32000" {
+		t.Errorf("expected an int attribute, got %v", sent.Attributes[0].Value)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	if id := RequestID("client-request_1.2:3"); id != "client-request_1.2:3" {
+		t.Errorf("expected a safe request ID to be kept, got %s", id)
+	}
+	for _, unsafe := range []string{"", "has space", "new\nline", strings.Repeat("a", maxRequestIDLength+1)} {
+		if id := RequestID(unsafe); id == unsafe || len(id) != 32 {
+			t.Errorf("expected %q to be replaced, got %s", unsafe, id)
+		}
+	}
+
+	ctx := ContextWithRequestID(context.Background(), "abc")
+	if RequestIDFromContext(ctx) != "abc" || RequestIDFromContext(context.Background()) != "" {
+		t.Error("expected the request ID to be kept in the context")
+	}
+
+	var out bytes.Buffer
+	ctx = ContextWithTracer(ctx, newTestTracer(t, &recorder{}), "")
+	ctx, span := Start(ctx, "eth_blockNumber", SpanKindServer)
+	Logger(ctx, log.NewLogfmtLogger(&out)).Log("msg", "hello")
+	want := "requestId=abc traceId=" + span.TraceID().String() + " msg=hello\n"
+	if out.String() != want {
+		t.Errorf("expected %q, got %q", want, out.String())
+	}
+}
+
+func TestWithValuesOf(t *testing.T) {
+	from, cancel := context.WithCancel(ContextWithRequestID(context.Background(), "abc"))
+	from = ContextWithTracer(from, newTestTracer(t, &recorder{}), "")
+	from, span := Start(from, "qtumd getblockcount", SpanKindClient)
+
+	parent, cancelParent := context.WithCancel(context.Background())
+	defer cancelParent()
+	ctx := WithValuesOf(parent, from)
+	cancel()
+
+	if RequestIDFromContext(ctx) != "abc" || SpanFromContext(ctx) != span {
+		t.Error("expected the request ID and span to be carried over")
+	}
+	if ctx.Err() != nil {
+		t.Error("expected the context to outlive the one its values came from")
+	}
+	cancelParent()
+	if ctx.Err() == nil {
+		t.Error("expected the context to be done with its parent")
+	}
+}
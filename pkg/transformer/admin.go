@@ -0,0 +1,252 @@
+package transformer
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/labstack/echo"
+	"github.com/qtumproject/janus/pkg/eth"
+	"github.com/qtumproject/janus/pkg/notifier"
+	"github.com/qtumproject/janus/pkg/qtum"
+)
+
+// transformerAware proxies are given the transformer they are registered to
+type transformerAware interface {
+	setTransformer(t *Transformer)
+}
+
+type AdminStatusResponse struct {
+	Chain string `json:"chain"`
+	Debug bool   `json:"debug"`
+	// only set when there is more than one qtumd
+	Upstreams     []qtum.UpstreamStatus `json:"upstreams,omitempty"`
+	Connections   int                   `json:"connections"`
+	Subscriptions int                   `json:"subscriptions"`
+	Sessions      int                   `json:"sessions"`
+	Webhooks      int                   `json:"webhooks"`
+	Filters       int                   `json:"filters"`
+}
+
+// ProxyAdminStatus implements ETHProxy
+type ProxyAdminStatus struct {
+	*qtum.Qtum
+	*notifier.Agent
+	filter      *eth.FilterSimulator
+	transformer *Transformer
+}
+
+var _ ETHProxy = (*ProxyAdminStatus)(nil)
+
+func (p *ProxyAdminStatus) Method() string {
+	return "admin_status"
+}
+
+func (p *ProxyAdminStatus) setTransformer(t *Transformer) {
+	p.transformer = t
+}
+
+func (p *ProxyAdminStatus) Request(ctx context.Context, _ *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
+	return &AdminStatusResponse{
+		Chain:         p.Chain(),
+		Debug:         p.transformer.IsDebugEnabled(),
+		Upstreams:     p.UpstreamStatuses(),
+		Connections:   p.ClientCount(),
+		Subscriptions: p.SubscriptionCount(),
+		Sessions:      p.SessionCount(),
+		Webhooks:      len(p.WebhookStatuses()),
+		Filters:       len(p.filter.List()),
+	}, nil
+}
+
+// ProxyAdminListConnections implements ETHProxy
+type ProxyAdminListConnections struct {
+	*notifier.Agent
+}
+
+var _ ETHProxy = (*ProxyAdminListConnections)(nil)
+
+func (p *ProxyAdminListConnections) Method() string {
+	return "admin_listConnections"
+}
+
+func (p *ProxyAdminListConnections) Request(ctx context.Context, _ *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
+	return p.ClientStatuses(), nil
+}
+
+// ProxyAdminDisconnect implements ETHProxy
+type ProxyAdminDisconnect struct {
+	*notifier.Agent
+}
+
+var _ ETHProxy = (*ProxyAdminDisconnect)(nil)
+
+func (p *ProxyAdminDisconnect) Method() string {
+	return "admin_disconnect"
+}
+
+func (p *ProxyAdminDisconnect) Request(ctx context.Context, rawreq *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
+	var req eth.AdminDisconnectRequest
+	if err := unmarshalRequest(rawreq.Params, &req); err != nil {
+		return nil, eth.NewInvalidParamsError(err.Error())
+	}
+	if len(req) != 1 {
+		return nil, eth.NewInvalidParamsError("requires one argument: the connection id")
+	}
+
+	response := eth.AdminDisconnectResponse(p.DisconnectClient(req[0]))
+	return &response, nil
+}
+
+// ProxyAdminListFilters implements ETHProxy
+type ProxyAdminListFilters struct {
+	filter *eth.FilterSimulator
+}
+
+var _ ETHProxy = (*ProxyAdminListFilters)(nil)
+
+func (p *ProxyAdminListFilters) Method() string {
+	return "admin_listFilters"
+}
+
+func (p *ProxyAdminListFilters) Request(ctx context.Context, _ *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
+	return p.filter.List(), nil
+}
+
+// ProxyAdminDeleteFilter implements ETHProxy
+type ProxyAdminDeleteFilter struct {
+	filter *eth.FilterSimulator
+}
+
+var _ ETHProxy = (*ProxyAdminDeleteFilter)(nil)
+
+func (p *ProxyAdminDeleteFilter) Method() string {
+	return "admin_deleteFilter"
+}
+
+func (p *ProxyAdminDeleteFilter) Request(ctx context.Context, rawreq *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
+	var req eth.AdminDeleteFilterRequest
+	if err := unmarshalRequest(rawreq.Params, &req); err != nil {
+		return nil, eth.NewInvalidParamsError(err.Error())
+	}
+	if len(req) != 1 {
+		return nil, eth.NewInvalidParamsError("requires one argument: the filter id")
+	}
+
+	id, err := hexutil.DecodeUint64(req[0])
+	if err != nil {
+		return nil, eth.NewInvalidParamsError(err.Error())
+	}
+
+	response := eth.AdminDeleteFilterResponse(p.filter.Uninstall(id))
+	return &response, nil
+}
+
+// ProxyAdminGetFlags implements ETHProxy
+type ProxyAdminGetFlags struct {
+	*qtum.Qtum
+}
+
+var _ ETHProxy = (*ProxyAdminGetFlags)(nil)
+
+func (p *ProxyAdminGetFlags) Method() string {
+	return "admin_getFlags"
+}
+
+// unset flags are null
+func (p *ProxyAdminGetFlags) Request(ctx context.Context, _ *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
+	flags := make(map[string]interface{}, len(qtum.FlagTypes))
+	for name := range qtum.FlagTypes {
+		flags[name] = p.GetFlag(name)
+	}
+	return flags, nil
+}
+
+// ProxyAdminSetFlag implements ETHProxy
+type ProxyAdminSetFlag struct {
+	*qtum.Qtum
+}
+
+var _ ETHProxy = (*ProxyAdminSetFlag)(nil)
+
+func (p *ProxyAdminSetFlag) Method() string {
+	return "admin_setFlag"
+}
+
+func (p *ProxyAdminSetFlag) Request(ctx context.Context, rawreq *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
+	var req eth.AdminSetFlagRequest
+	if err := unmarshalRequest(rawreq.Params, &req); err != nil {
+		return nil, eth.NewInvalidParamsError(err.Error())
+	}
+
+	return p.request(&req)
+}
+
+func (p *ProxyAdminSetFlag) request(req *eth.AdminSetFlagRequest) (bool, eth.JSONRPCError) {
+	flagType, ok := qtum.FlagTypes[req.Name]
+	if !ok {
+		names := make([]string, 0, len(qtum.FlagTypes))
+		for name := range qtum.FlagTypes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return false, eth.NewInvalidParamsError("Unknown flag " + req.Name + ", expected one of " + strings.Join(names, ", "))
+	}
+
+	var value interface{}
+	if string(req.Value) != "null" {
+		var err error
+		switch flagType {
+		case qtum.FlagTypeString:
+			var s string
+			err = json.Unmarshal(req.Value, &s)
+			value = s
+		case qtum.FlagTypeBool:
+			var b bool
+			err = json.Unmarshal(req.Value, &b)
+			value = b
+		case qtum.FlagTypeInt:
+			var i int
+			err = json.Unmarshal(req.Value, &i)
+			value = i
+		}
+		if err != nil {
+			return false, eth.NewInvalidParamsError(req.Name + " has to be a " + string(flagType) + " or null")
+		}
+	}
+
+	p.SetFlag(req.Name, value)
+	p.GetDebugLogger().Log("msg", "Flag changed by admin", "flag", req.Name, "value", value)
+
+	return true, nil
+}
+
+// ProxyAdminSetDebug implements ETHProxy
+type ProxyAdminSetDebug struct {
+	transformer *Transformer
+}
+
+var _ ETHProxy = (*ProxyAdminSetDebug)(nil)
+
+func (p *ProxyAdminSetDebug) Method() string {
+	return "admin_setDebug"
+}
+
+func (p *ProxyAdminSetDebug) setTransformer(t *Transformer) {
+	p.transformer = t
+}
+
+func (p *ProxyAdminSetDebug) Request(ctx context.Context, rawreq *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
+	var req eth.AdminSetDebugRequest
+	if err := unmarshalRequest(rawreq.Params, &req); err != nil {
+		return nil, eth.NewInvalidParamsError(err.Error())
+	}
+	if len(req) != 1 {
+		return nil, eth.NewInvalidParamsError("requires one argument: true or false")
+	}
+
+	p.transformer.ReloadDebug(req[0])
+	return true, nil
+}
@@ -0,0 +1,141 @@
+package transformer
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/qtumproject/janus/pkg/eth"
+	"github.com/qtumproject/janus/pkg/internal"
+	"github.com/qtumproject/janus/pkg/notifier"
+	"github.com/qtumproject/janus/pkg/qtum"
+)
+
+func newAdminTestTransformer(t *testing.T, opts ...Option) (*Transformer, *qtum.Qtum) {
+	qtumClient, err := internal.CreateMockedClient(internal.NewDoerMappedMock())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	agent := notifier.NewAgent(ctx, qtumClient, nil)
+	transformer, err := New(qtumClient, DefaultProxies(qtumClient, agent), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return transformer, qtumClient
+}
+
+func adminRequest(t *testing.T, transformer *Transformer, method string, params ...interface{}) (interface{}, eth.JSONRPCError) {
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return transformer.Transform(&eth.JSONRPCRequest{JSONRPC: "2.0", Method: method, Params: rawParams}, nil)
+}
+
+func TestAdminStatusAndSetDebug(t *testing.T) {
+	reloaded := []bool{}
+	transformer, qtumClient := newAdminTestTransformer(t, SetDebugReloaded(func(debug bool) {
+		reloaded = append(reloaded, debug)
+	}))
+
+	if _, err := adminRequest(t, transformer, "admin_setDebug", true); err != nil {
+		t.Fatal(err)
+	}
+	if !transformer.IsDebugEnabled() || !qtumClient.IsDebugEnabled() || len(reloaded) != 1 || !reloaded[0] {
+		t.Error("expected debug mode to be turned on everywhere")
+	}
+
+	result, err := adminRequest(t, transformer, "admin_status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	status := result.(*AdminStatusResponse)
+	if status.Chain != "test" || !status.Debug || status.Upstreams != nil || status.Connections != 0 {
+		t.Errorf("unexpected status %+v", status)
+	}
+
+	if _, err := adminRequest(t, transformer, "admin_setDebug"); err == nil {
+		t.Error("expected admin_setDebug without an argument to fail")
+	}
+}
+
+func TestAdminFilters(t *testing.T) {
+	filter := eth.NewFilterSimulator()
+	blockFilter := filter.New(eth.NewBlockFilterTy)
+	blockFilter.Data.Store("lastBlockNumber", uint64(16))
+	filter.New(eth.NewPendingTransactionFilterTy)
+
+	list := &ProxyAdminListFilters{filter: filter}
+	result, err := list.Request(context.Background(), &eth.JSONRPCRequest{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	filters := result.([]eth.FilterStatus)
+	if len(filters) != 2 || filters[0].ID != "0x1" || filters[0].Type != "block" || filters[0].LastBlockNumber != "0x10" {
+		t.Fatalf("unexpected filters %+v", filters)
+	}
+	if filters[1].Type != "pendingTransaction" || filters[1].LastBlockNumber != "" {
+		t.Errorf("unexpected second filter %+v", filters[1])
+	}
+
+	deleteFilter := &ProxyAdminDeleteFilter{filter: filter}
+	for _, test := range []struct {
+		id      string
+		deleted bool
+	}{{"0x1", true}, {"0x1", false}} {
+		result, err := deleteFilter.Request(context.Background(), &eth.JSONRPCRequest{Params: json.RawMessage(`["` + test.id + `"]`)}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bool(*result.(*eth.AdminDeleteFilterResponse)) != test.deleted {
+			t.Errorf("expected deleting %s to return %v", test.id, test.deleted)
+		}
+	}
+	if len(filter.List()) != 1 {
+		t.Error("expected the filter to be deleted")
+	}
+}
+
+func TestAdminFlags(t *testing.T) {
+	transformer, qtumClient := newAdminTestTransformer(t)
+
+	if _, err := adminRequest(t, transformer, "admin_setFlag", qtum.FLAG_HIDE_QTUMD_LOGS, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := adminRequest(t, transformer, "admin_setFlag", qtum.FLAG_MATURE_BLOCK_HEIGHT_OVERRIDE, 100); err != nil {
+		t.Fatal(err)
+	}
+	if !qtumClient.GetFlagBool(qtum.FLAG_HIDE_QTUMD_LOGS) || qtumClient.GetMatureBlockHeight() != 100 {
+		t.Error("expected the flags to be set")
+	}
+
+	if _, err := adminRequest(t, transformer, "admin_setFlag", qtum.FLAG_MATURE_BLOCK_HEIGHT_OVERRIDE, nil); err != nil {
+		t.Fatal(err)
+	}
+	if qtumClient.GetMatureBlockHeight() != 2000 {
+		t.Error("expected null to unset the flag")
+	}
+
+	for _, params := range [][]interface{}{
+		{qtum.FLAG_IGNORE_UNKNOWN_TX, "yes"},
+		{qtum.FLAG_MATURE_BLOCK_HEIGHT_OVERRIDE, 1.5},
+		{"NOT_A_FLAG", true},
+		{qtum.FLAG_IGNORE_UNKNOWN_TX},
+	} {
+		if _, err := adminRequest(t, transformer, "admin_setFlag", params...); err == nil || err.Code() != eth.InvalidParamsErrorCode {
+			t.Errorf("expected admin_setFlag %v to be invalid, got %v", params, err)
+		}
+	}
+
+	result, err := adminRequest(t, transformer, "admin_getFlags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	flags := result.(map[string]interface{})
+	if len(flags) != len(qtum.FlagTypes) || flags[qtum.FLAG_HIDE_QTUMD_LOGS] != true || flags[qtum.FLAG_MATURE_BLOCK_HEIGHT_OVERRIDE] != nil {
+		t.Errorf("unexpected flags %v", flags)
+	}
+}
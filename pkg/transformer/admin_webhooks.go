@@ -0,0 +1,119 @@
+package transformer
+
+import (
+	"context"
+	"strings"
+
+	"github.com/labstack/echo"
+	"github.com/qtumproject/janus/pkg/eth"
+	"github.com/qtumproject/janus/pkg/notifier"
+	"github.com/qtumproject/janus/pkg/qtum"
+)
+
+// ProxyAdminAddWebhook implements ETHProxy
+type ProxyAdminAddWebhook struct {
+	*qtum.Qtum
+	*notifier.Agent
+}
+
+var _ ETHProxy = (*ProxyAdminAddWebhook)(nil)
+
+func (p *ProxyAdminAddWebhook) Method() string {
+	return "admin_addWebhook"
+}
+
+func (p *ProxyAdminAddWebhook) Request(ctx context.Context, rawreq *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
+	var req eth.AdminAddWebhookRequest
+	if err := unmarshalRequest(rawreq.Params, &req); err != nil {
+		return nil, eth.NewInvalidParamsError(err.Error())
+	}
+	for _, event := range req.Events {
+		if strings.EqualFold(event, "logs") && !p.Supports(qtum.CapabilityLogEvents) {
+			return nil, eth.NewNotSupportedError("A logs webhook", string(qtum.CapabilityLogEvents))
+		}
+	}
+
+	return p.request(&req)
+}
+
+func (p *ProxyAdminAddWebhook) request(req *eth.AdminAddWebhookRequest) (*eth.AdminAddWebhookResponse, eth.JSONRPCError) {
+	id, secret, err := p.AddWebhook(req.URL, req.Secret, req.Events, req.Filter())
+	if err != nil {
+		return nil, eth.NewCallbackError(err.Error())
+	}
+
+	return &eth.AdminAddWebhookResponse{
+		ID:     id,
+		Secret: secret,
+	}, nil
+}
+
+// ProxyAdminRemoveWebhook implements ETHProxy
+type ProxyAdminRemoveWebhook struct {
+	*qtum.Qtum
+	*notifier.Agent
+}
+
+var _ ETHProxy = (*ProxyAdminRemoveWebhook)(nil)
+
+func (p *ProxyAdminRemoveWebhook) Method() string {
+	return "admin_removeWebhook"
+}
+
+func (p *ProxyAdminRemoveWebhook) Request(ctx context.Context, rawreq *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
+	var req eth.AdminRemoveWebhookRequest
+	if err := unmarshalRequest(rawreq.Params, &req); err != nil {
+		return nil, eth.NewInvalidParamsError(err.Error())
+	}
+	if len(req) != 1 {
+		return nil, eth.NewInvalidParamsError("requires one argument: the webhook id")
+	}
+
+	response := eth.AdminRemoveWebhookResponse(p.RemoveWebhook(req[0]))
+	return &response, nil
+}
+
+// ProxyAdminGetWebhookStatus implements ETHProxy
+type ProxyAdminGetWebhookStatus struct {
+	*qtum.Qtum
+	*notifier.Agent
+}
+
+var _ ETHProxy = (*ProxyAdminGetWebhookStatus)(nil)
+
+func (p *ProxyAdminGetWebhookStatus) Method() string {
+	return "admin_getWebhookStatus"
+}
+
+func (p *ProxyAdminGetWebhookStatus) Request(ctx context.Context, rawreq *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
+	var req eth.AdminGetWebhookStatusRequest
+	if err := unmarshalRequest(rawreq.Params, &req); err != nil {
+		return nil, eth.NewInvalidParamsError(err.Error())
+	}
+	if len(req) != 1 {
+		return nil, eth.NewInvalidParamsError("requires one argument: the webhook id")
+	}
+
+	status, err := p.WebhookStatus(req[0])
+	if err != nil {
+		return nil, eth.NewCallbackError(err.Error())
+	}
+
+	return status, nil
+}
+
+// ProxyAdminListWebhooks implements ETHProxy
+type ProxyAdminListWebhooks struct {
+	*qtum.Qtum
+	*notifier.Agent
+}
+
+var _ ETHProxy = (*ProxyAdminListWebhooks)(nil)
+
+func (p *ProxyAdminListWebhooks) Method() string {
+	return "admin_listWebhooks"
+}
+
+func (p *ProxyAdminListWebhooks) Request(ctx context.Context, _ *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
+	return p.WebhookStatuses(), nil
+}
@@ -0,0 +1,141 @@
+package transformer
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/qtumproject/janus/pkg/cache"
+	"github.com/qtumproject/janus/pkg/eth"
+	"github.com/qtumproject/janus/pkg/metrics"
+	"github.com/qtumproject/janus/pkg/utils"
+)
+
+type cacheableMethod struct {
+	// cached responses are decoded into this so callers get the same type as an uncached response
+	response func() interface{}
+	// blocks have their own hash and number, everything else has the block it was included in
+	isBlock bool
+}
+
+// responses that can't change once their block is deep enough
+var cacheableMethods = map[string]cacheableMethod{
+	"eth_getBlockByHash": {
+		response: func() interface{} { return &eth.GetBlockByHashResponse{} },
+		isBlock:  true,
+	},
+	"eth_getBlockByNumber": {
+		response: func() interface{} { return &eth.GetBlockByNumberResponse{} },
+		isBlock:  true,
+	},
+	"eth_getTransactionByHash": {
+		response: func() interface{} { return &eth.GetTransactionByHashResponse{} },
+	},
+	"eth_getTransactionReceipt": {
+		response: func() interface{} { return &eth.GetTransactionReceiptResponse{} },
+	},
+}
+
+// SetResponseCache caches responses for blocks, transactions and receipts
+func SetResponseCache(c *cache.ResponseCache) func(*Transformer) error {
+	return func(t *Transformer) error {
+		t.cache = c
+		return nil
+	}
+}
+
+func responseCacheKey(req *eth.JSONRPCRequest) (string, bool) {
+	if _, ok := cacheableMethods[req.Method]; !ok {
+		return "", false
+	}
+
+	if req.Method == "eth_getBlockByNumber" {
+		// latest, pending and earliest move or aren't worth caching, only cache explicit block numbers
+		var params []json.RawMessage
+		if err := json.Unmarshal(req.Params, &params); err != nil || len(params) == 0 {
+			return "", false
+		}
+		var blockNumber string
+		if err := json.Unmarshal(params[0], &blockNumber); err != nil || !strings.HasPrefix(blockNumber, "0x") {
+			return "", false
+		}
+	}
+
+	var params bytes.Buffer
+	if err := json.Compact(&params, req.Params); err != nil {
+		return "", false
+	}
+
+	// every parameter of these methods is a hex string or a bool
+	return req.Method + ":" + strings.ToLower(params.String()), true
+}
+
+func (t *Transformer) getCachedResponse(req *eth.JSONRPCRequest) (interface{}, bool) {
+	if t.cache == nil {
+		return nil, false
+	}
+
+	key, ok := responseCacheKey(req)
+	if !ok {
+		return nil, false
+	}
+
+	value, ok := t.cache.Get(key)
+	if !ok {
+		metrics.CacheMisses.WithLabelValues(req.Method).Inc()
+		return nil, false
+	}
+
+	response := cacheableMethods[req.Method].response()
+	if err := json.Unmarshal(value, response); err != nil {
+		t.logger.Log("msg", "Failed to decode cached response", "method", req.Method, "err", err)
+		metrics.CacheMisses.WithLabelValues(req.Method).Inc()
+		return nil, false
+	}
+
+	metrics.CacheHits.WithLabelValues(req.Method).Inc()
+	return response, true
+}
+
+func (t *Transformer) cacheResponse(req *eth.JSONRPCRequest, response interface{}) {
+	if t.cache == nil {
+		return
+	}
+
+	key, ok := responseCacheKey(req)
+	if !ok {
+		return
+	}
+
+	value, err := json.Marshal(response)
+	if err != nil || string(value) == "null" {
+		// nothing found
+		return
+	}
+
+	var block struct {
+		Hash        string `json:"hash"`
+		Number      string `json:"number"`
+		BlockHash   string `json:"blockHash"`
+		BlockNumber string `json:"blockNumber"`
+	}
+	if err := json.Unmarshal(value, &block); err != nil {
+		return
+	}
+
+	hash, number := block.BlockHash, block.BlockNumber
+	if cacheableMethods[req.Method].isBlock {
+		hash, number = block.Hash, block.Number
+	}
+	if hash == "" || number == "" {
+		// pending transactions can still change
+		return
+	}
+
+	height, err := utils.DecodeBig(number)
+	if err != nil {
+		return
+	}
+
+	t.cache.Put(key, value, height.Int64(), hash)
+}
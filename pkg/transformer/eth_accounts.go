@@ -1,6 +1,8 @@
 package transformer
 
 import (
+	"context"
+
 	"github.com/labstack/echo"
 	"github.com/qtumproject/janus/pkg/eth"
 	"github.com/qtumproject/janus/pkg/qtum"
@@ -16,14 +18,14 @@ func (p *ProxyETHAccounts) Method() string {
 	return "eth_accounts"
 }
 
-func (p *ProxyETHAccounts) Request(_ *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
+func (p *ProxyETHAccounts) Request(ctx context.Context, _ *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
 	return p.request()
 }
 
 func (p *ProxyETHAccounts) request() (eth.AccountsResponse, eth.JSONRPCError) {
 	var accounts eth.AccountsResponse
 
-	for _, acc := range p.Accounts {
+	for _, acc := range p.GetAccounts() {
 		acc := qtum.Account{acc}
 		addr := acc.ToHexAddress()
 
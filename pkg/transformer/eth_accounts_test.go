@@ -1,6 +1,7 @@
 package transformer
 
 import (
+	"context"
 	"encoding/json"
 	"reflect"
 	"testing"
@@ -37,7 +38,7 @@ func TestAccountRequest(t *testing.T) {
 
 	//preparing proxy & executing request
 	proxyEth := ProxyETHAccounts{qtumClient}
-	got, jsonErr := proxyEth.Request(request, nil)
+	got, jsonErr := proxyEth.Request(context.Background(), request, nil)
 	if jsonErr != nil {
 		t.Fatal(jsonErr.Error())
 	}
@@ -1,6 +1,7 @@
 package transformer
 
 import (
+	"context"
 	"encoding/json"
 	"math/big"
 	"reflect"
@@ -34,7 +35,7 @@ func TestBlockNumberRequest(t *testing.T) {
 
 	//preparing proxy & executing request
 	proxyEth := ProxyETHBlockNumber{qtumClient}
-	got, jsonErr := proxyEth.Request(request, nil)
+	got, jsonErr := proxyEth.Request(context.Background(), request, nil)
 	if jsonErr != nil {
 		t.Fatal(jsonErr)
 	}
@@ -1,6 +1,7 @@
 package transformer
 
 import (
+	"context"
 	"math/big"
 
 	"github.com/labstack/echo"
@@ -18,29 +19,30 @@ func (p *ProxyETHCall) Method() string {
 	return "eth_call"
 }
 
-func (p *ProxyETHCall) Request(rawreq *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
+func (p *ProxyETHCall) Request(ctx context.Context, rawreq *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
 	var req eth.CallRequest
 	if err := unmarshalRequest(rawreq.Params, &req); err != nil {
 		// TODO: Is this correct error code?
 		return nil, eth.NewInvalidParamsError(err.Error())
 	}
 
-	return p.request(&req)
+	return p.request(ctx, &req)
 }
 
-func (p *ProxyETHCall) request(ethreq *eth.CallRequest) (interface{}, eth.JSONRPCError) {
+func (p *ProxyETHCall) request(ctx context.Context, ethreq *eth.CallRequest) (interface{}, eth.JSONRPCError) {
 	// eth req -> qtum req
-	qtumreq, jsonErr := p.ToRequest(ethreq)
+	qtumreq, jsonErr := p.ToRequest(ctx, ethreq)
 	if jsonErr != nil {
 		return nil, jsonErr
 	}
-	if qtumreq.GasLimit != nil && qtumreq.GasLimit.Cmp(big.NewInt(40000000)) > 0 {
+	gasCap := CurrentGasSettings().CallGasCap
+	if qtumreq.GasLimit != nil && qtumreq.GasLimit.Cmp(big.NewInt(gasCap)) > 0 {
 		qtumresp := eth.CallResponse("0x")
-		p.Qtum.GetLogger().Log("msg", "Caller gas above allowance, capping", "requested", qtumreq.GasLimit.Int64(), "cap", "40,000,000")
+		p.Qtum.GetLogger().Log("msg", "Caller gas above allowance, capping", "requested", qtumreq.GasLimit.Int64(), "cap", gasCap)
 		return &qtumresp, nil
 	}
 
-	qtumresp, err := p.CallContract(qtumreq)
+	qtumresp, err := p.CallContract(ctx, qtumreq)
 	if err != nil {
 		if err == qtum.ErrInvalidAddress {
 			qtumresp := eth.CallResponse("0x")
@@ -54,11 +56,11 @@ func (p *ProxyETHCall) request(ethreq *eth.CallRequest) (interface{}, eth.JSONRP
 	return p.ToResponse(qtumresp), nil
 }
 
-func (p *ProxyETHCall) ToRequest(ethreq *eth.CallRequest) (*qtum.CallContractRequest, eth.JSONRPCError) {
+func (p *ProxyETHCall) ToRequest(ctx context.Context, ethreq *eth.CallRequest) (*qtum.CallContractRequest, eth.JSONRPCError) {
 	from := ethreq.From
 	var err error
 	if utils.IsEthHexAddress(from) {
-		from, err = p.FromHexAddress(from)
+		from, err = p.FromHexAddress(ctx, from)
 		if err != nil {
 			return nil, eth.NewCallbackError(err.Error())
 		}
@@ -69,7 +71,7 @@ func (p *ProxyETHCall) ToRequest(ethreq *eth.CallRequest) (*qtum.CallContractReq
 		gasLimit = ethreq.Gas.Int
 	}
 
-	if gasLimit != nil && gasLimit.Int64() < MinimumGasLimit {
+	if gasLimit != nil && gasLimit.Int64() < CurrentGasSettings().MinimumGasLimit {
 		p.GetLogger().Log("msg", "Gas limit is too low", "gasLimit", gasLimit.String())
 	}
 
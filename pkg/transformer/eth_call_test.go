@@ -1,6 +1,7 @@
 package transformer
 
 import (
+	"context"
 	"encoding/json"
 	"reflect"
 	"testing"
@@ -74,7 +75,7 @@ func TestEthCallRequest(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	got, jsonErr := proxyEth.Request(requestRPC, nil)
+	got, jsonErr := proxyEth.Request(context.Background(), requestRPC, nil)
 	if jsonErr != nil {
 		t.Fatal(jsonErr)
 	}
@@ -158,7 +159,7 @@ func TestRetry(t *testing.T) {
 
 	before := time.Now()
 
-	got, jsonErr := proxyEth.Request(requestRPC, nil)
+	got, jsonErr := proxyEth.Request(context.Background(), requestRPC, nil)
 	if jsonErr != nil {
 		t.Fatal(jsonErr)
 	}
@@ -215,7 +216,7 @@ func TestEthCallRequestOnUnknownContract(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	got, jsonErr := proxyEth.Request(requestRPC, nil)
+	got, jsonErr := proxyEth.Request(context.Background(), requestRPC, nil)
 	if jsonErr != nil {
 		t.Fatal(jsonErr)
 	}
@@ -1,6 +1,7 @@
 package transformer
 
 import (
+	"context"
 	"math/big"
 	"strings"
 
@@ -18,17 +19,17 @@ func (p *ProxyETHChainId) Method() string {
 	return "eth_chainId"
 }
 
-func (p *ProxyETHChainId) Request(req *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
-	chainId, err := getChainId(p.Qtum)
+func (p *ProxyETHChainId) Request(ctx context.Context, req *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
+	chainId, err := getChainId(ctx, p.Qtum)
 	if err != nil {
 		return nil, err
 	}
 	return eth.ChainIdResponse(hexutil.EncodeBig(chainId)), nil
 }
 
-func getChainId(p *qtum.Qtum) (*big.Int, eth.JSONRPCError) {
+func getChainId(ctx context.Context, p *qtum.Qtum) (*big.Int, eth.JSONRPCError) {
 	var qtumresp *qtum.GetBlockChainInfoResponse
-	if err := p.Request(qtum.MethodGetBlockChainInfo, nil, &qtumresp); err != nil {
+	if err := p.RequestWithContext(ctx, qtum.MethodGetBlockChainInfo, nil, &qtumresp); err != nil {
 		return nil, eth.NewCallbackError(err.Error())
 	}
 
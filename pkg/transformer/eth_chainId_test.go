@@ -1,6 +1,7 @@
 package transformer
 
 import (
+	"context"
 	"encoding/json"
 	"reflect"
 	"testing"
@@ -49,7 +50,7 @@ func testChainIdsImpl(t *testing.T, chain string, expected string) {
 
 	//preparing proxy & executing request
 	proxyEth := ProxyETHChainId{qtumClient}
-	got, jsonErr := proxyEth.Request(request, nil)
+	got, jsonErr := proxyEth.Request(context.Background(), request, nil)
 	if jsonErr != nil {
 		t.Fatal(jsonErr)
 	}
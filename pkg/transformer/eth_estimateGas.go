@@ -1,6 +1,8 @@
 package transformer
 
 import (
+	"context"
+
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/labstack/echo"
 	"github.com/pkg/errors"
@@ -12,8 +14,6 @@ import (
 var NonContractVMGasLimit = "0x55f0"
 var ErrExecutionReverted = errors.New("execution reverted")
 
-var GAS_BUFFER = 1.10
-
 // ProxyETHEstimateGas implements ETHProxy
 type ProxyETHEstimateGas struct {
 	*ProxyETHCall
@@ -23,7 +23,7 @@ func (p *ProxyETHEstimateGas) Method() string {
 	return "eth_estimateGas"
 }
 
-func (p *ProxyETHEstimateGas) Request(rawreq *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
+func (p *ProxyETHEstimateGas) Request(ctx context.Context, rawreq *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
 	var ethreq eth.CallRequest
 	if jsonErr := unmarshalRequest(rawreq.Params, &ethreq); jsonErr != nil {
 		// TODO: Correct error code?
@@ -45,13 +45,13 @@ func (p *ProxyETHEstimateGas) Request(rawreq *eth.JSONRPCRequest, c echo.Context
 	ethreq.Gas = nil
 
 	// eth req -> qtum req
-	qtumreq, jsonErr := p.ToRequest(&ethreq)
+	qtumreq, jsonErr := p.ToRequest(ctx, &ethreq)
 	if jsonErr != nil {
 		return nil, jsonErr
 	}
 
 	// qtum [code: -5] Incorrect address occurs here
-	qtumresp, err := p.CallContract(qtumreq)
+	qtumresp, err := p.CallContract(ctx, qtumreq)
 	if err != nil {
 		return nil, eth.NewCallbackError(err.Error())
 	}
@@ -63,7 +63,7 @@ func (p *ProxyETHEstimateGas) toResp(qtumresp *qtum.CallContractResponse) (*eth.
 	if qtumresp.ExecutionResult.Excepted != "None" {
 		return nil, eth.NewCallbackError(ErrExecutionReverted.Error())
 	}
-	gas := eth.EstimateGasResponse(hexutil.EncodeUint64(uint64(float64(qtumresp.ExecutionResult.GasUsed) * GAS_BUFFER)))
+	gas := eth.EstimateGasResponse(hexutil.EncodeUint64(uint64(float64(qtumresp.ExecutionResult.GasUsed) * CurrentGasSettings().EstimateBuffer)))
 	p.GetDebugLogger().Log(p.Method(), gas)
 	return &gas, nil
 }
@@ -1,6 +1,7 @@
 package transformer
 
 import (
+	"context"
 	"encoding/json"
 	"reflect"
 	"testing"
@@ -65,7 +66,7 @@ func TestEstimateGasRequest(t *testing.T) {
 	//preparing proxy & executing request
 	proxyEth := ProxyETHCall{qtumClient}
 	proxyEthEstimateGas := ProxyETHEstimateGas{&proxyEth}
-	got, jsonErr := proxyEthEstimateGas.Request(requestRPC, nil)
+	got, jsonErr := proxyEthEstimateGas.Request(context.Background(), requestRPC, nil)
 	if jsonErr != nil {
 		t.Fatal(jsonErr)
 	}
@@ -136,7 +137,7 @@ func TestEstimateGasRequestExecutionReverted(t *testing.T) {
 	//preparing proxy & executing request
 	proxyEth := ProxyETHCall{qtumClient}
 	proxyEthEstimateGas := ProxyETHEstimateGas{&proxyEth}
-	_, got := proxyEthEstimateGas.Request(requestRPC, nil)
+	_, got := proxyEthEstimateGas.Request(context.Background(), requestRPC, nil)
 	if got == nil {
 		t.Fatal("Expected error")
 	}
@@ -206,7 +207,7 @@ func TestEstimateGasNonVMRequest(t *testing.T) {
 	//preparing proxy & executing request
 	proxyEth := ProxyETHCall{qtumClient}
 	proxyEthEstimateGas := ProxyETHEstimateGas{&proxyEth}
-	got, jsonErr := proxyEthEstimateGas.Request(requestRPC, nil)
+	got, jsonErr := proxyEthEstimateGas.Request(context.Background(), requestRPC, nil)
 	if jsonErr != nil {
 		t.Fatal(jsonErr)
 	}
@@ -221,3 +222,42 @@ func TestEstimateGasNonVMRequest(t *testing.T) {
 		)
 	}
 }
+
+func TestEstimateGasReloadedBuffer(t *testing.T) {
+	defer ReloadGasSettings(DefaultGasSettings)
+
+	settings := DefaultGasSettings
+	settings.EstimateBuffer = 1.5
+	if err := ReloadGasSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+
+	mockedClientDoer := internal.NewDoerMappedMock()
+	qtumClient, err := internal.CreateMockedClient(mockedClientDoer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proxyEthEstimateGas := ProxyETHEstimateGas{&ProxyETHCall{qtumClient}}
+	qtumresp := &qtum.CallContractResponse{}
+	qtumresp.ExecutionResult.GasUsed = 20000
+	qtumresp.ExecutionResult.Excepted = "None"
+	got, jsonErr := proxyEthEstimateGas.toResp(qtumresp)
+	if jsonErr != nil {
+		t.Fatal(jsonErr)
+	}
+
+	// 20000 * 1.5
+	want := eth.EstimateGasResponse("0x7530")
+	if *got != want {
+		t.Errorf("expected %s, got %s", want, *got)
+	}
+
+	settings.EstimateBuffer = 0.5
+	if err := ReloadGasSettings(settings); err == nil {
+		t.Error("expected a buffer below 1 to be rejected")
+	}
+	if CurrentGasSettings().EstimateBuffer != 1.5 {
+		t.Error("expected invalid gas settings to not replace the current ones")
+	}
+}
@@ -1,6 +1,7 @@
 package transformer
 
 import (
+	"context"
 	"encoding/json"
 	"reflect"
 	"testing"
@@ -24,7 +25,7 @@ func TestGasPriceRequest(t *testing.T) {
 
 	//preparing proxy & executing request
 	proxyEth := ProxyETHGasPrice{qtumClient}
-	got, jsonErr := proxyEth.Request(request, nil)
+	got, jsonErr := proxyEth.Request(context.Background(), request, nil)
 	if jsonErr != nil {
 		t.Fatal(jsonErr)
 	}
@@ -1,6 +1,7 @@
 package transformer
 
 import (
+	"context"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -19,7 +20,7 @@ func (p *ProxyETHGetBalance) Method() string {
 	return "eth_getBalance"
 }
 
-func (p *ProxyETHGetBalance) Request(rawreq *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
+func (p *ProxyETHGetBalance) Request(ctx context.Context, rawreq *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
 	var req eth.GetBalanceRequest
 	if err := unmarshalRequest(rawreq.Params, &req); err != nil {
 		// TODO: Correct error code?
@@ -30,7 +31,7 @@ func (p *ProxyETHGetBalance) Request(rawreq *eth.JSONRPCRequest, c echo.Context)
 	{
 		// is address a contract or an account?
 		qtumreq := qtum.GetAccountInfoRequest(addr)
-		qtumresp, err := p.GetAccountInfo(&qtumreq)
+		qtumresp, err := p.GetAccountInfo(ctx, &qtumreq)
 
 		// the address is a contract
 		if err == nil {
@@ -42,14 +43,17 @@ func (p *ProxyETHGetBalance) Request(rawreq *eth.JSONRPCRequest, c echo.Context)
 
 	{
 		// try account
-		base58Addr, err := p.FromHexAddress(addr)
+		if !p.Supports(qtum.CapabilityAddrIndex) {
+			return nil, eth.NewNotSupportedError("The balance of an account", string(qtum.CapabilityAddrIndex))
+		}
+		base58Addr, err := p.FromHexAddress(ctx, addr)
 		if err != nil {
 			p.GetDebugLogger().Log("method", p.Method(), "address", req.Address, "msg", "error parsing address", "error", err)
 			return nil, eth.NewCallbackError(err.Error())
 		}
 
 		qtumreq := qtum.GetAddressBalanceRequest{Address: base58Addr}
-		qtumresp, err := p.GetAddressBalance(&qtumreq)
+		qtumresp, err := p.GetAddressBalance(ctx, &qtumreq)
 		if err != nil {
 			if err == qtum.ErrInvalidAddress {
 				// invalid address should return 0x0
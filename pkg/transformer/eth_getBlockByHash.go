@@ -1,6 +1,7 @@
 package transformer
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -19,7 +20,7 @@ func (p *ProxyETHGetBlockByHash) Method() string {
 	return "eth_getBlockByHash"
 }
 
-func (p *ProxyETHGetBlockByHash) Request(rawreq *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
+func (p *ProxyETHGetBlockByHash) Request(ctx context.Context, rawreq *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
 	req := new(eth.GetBlockByHashRequest)
 	if err := unmarshalRequest(rawreq.Params, req); err != nil {
 		// TODO: Correct error code?
@@ -27,11 +28,11 @@ func (p *ProxyETHGetBlockByHash) Request(rawreq *eth.JSONRPCRequest, c echo.Cont
 	}
 	req.BlockHash = utils.RemoveHexPrefix(req.BlockHash)
 
-	return p.request(req)
+	return p.request(ctx, req)
 }
 
-func (p *ProxyETHGetBlockByHash) request(req *eth.GetBlockByHashRequest) (*eth.GetBlockByHashResponse, eth.JSONRPCError) {
-	blockHeader, err := p.GetBlockHeader(req.BlockHash)
+func (p *ProxyETHGetBlockByHash) request(ctx context.Context, req *eth.GetBlockByHashRequest) (*eth.GetBlockByHashResponse, eth.JSONRPCError) {
+	blockHeader, err := p.GetBlockHeader(ctx, req.BlockHash)
 	if err != nil {
 		if err == qtum.ErrInvalidAddress {
 			// unknown block hash should return {result: null}
@@ -41,7 +42,7 @@ func (p *ProxyETHGetBlockByHash) request(req *eth.GetBlockByHashRequest) (*eth.G
 		p.GetDebugLogger().Log("msg", "couldn't get block header", "blockHash", req.BlockHash)
 		return nil, eth.NewCallbackError("couldn't get block header")
 	}
-	block, err := p.GetBlock(req.BlockHash)
+	block, err := p.GetBlock(ctx, req.BlockHash)
 	if err != nil {
 		p.GetDebugLogger().Log("msg", "couldn't get block", "blockHash", req.BlockHash)
 		return nil, eth.NewCallbackError("couldn't get block")
@@ -122,8 +123,9 @@ func (p *ProxyETHGetBlockByHash) request(req *eth.GetBlockByHashRequest) (*eth.G
 	// so using that from the start instead of requesting each tx individually as done here would save a lot of back-and-forth
 
 	if req.FullTransaction {
-		for _, txHash := range block.Txs {
-			tx, err := getTransactionByHash(p.Qtum, txHash)
+		txs, errs := getTransactionsByHash(ctx, p.Qtum, block.Txs)
+		for i, txHash := range block.Txs {
+			tx, err := txs[i], errs[i]
 			if err != nil {
 				p.GetDebugLogger().Log("msg", "Couldn't get transaction by hash", "hash", txHash)
 				return nil, eth.NewCallbackError("couldn't get transaction by hash")
@@ -1,6 +1,7 @@
 package transformer
 
 import (
+	"context"
 	"encoding/json"
 	"reflect"
 	"testing"
@@ -46,7 +47,7 @@ func TestGetAccountInfoRequest(t *testing.T) {
 
 	//preparing proxy & executing request
 	proxyEth := ProxyETHGetCode{qtumClient}
-	got, jsonErr := proxyEth.Request(requestRPC, nil)
+	got, jsonErr := proxyEth.Request(context.Background(), requestRPC, nil)
 	if jsonErr != nil {
 		t.Fatal(jsonErr)
 	}
@@ -88,7 +89,7 @@ func TestGetCodeInvalidAddressRequest(t *testing.T) {
 
 	//preparing proxy & executing request
 	proxyEth := ProxyETHGetCode{qtumClient}
-	got, jsonErr := proxyEth.Request(requestRPC, nil)
+	got, jsonErr := proxyEth.Request(context.Background(), requestRPC, nil)
 	if jsonErr != nil {
 		t.Fatal(jsonErr)
 	}
@@ -1,6 +1,7 @@
 package transformer
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"testing"
@@ -17,7 +18,7 @@ func TestGetCompilersReturnsEmptyArray(t *testing.T) {
 	}
 
 	proxyEth := ETHGetCompilers{}
-	got, jsonErr := proxyEth.Request(request, nil)
+	got, jsonErr := proxyEth.Request(context.Background(), request, nil)
 	if jsonErr != nil {
 		t.Fatal(jsonErr)
 	}
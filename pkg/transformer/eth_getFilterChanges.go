@@ -1,6 +1,7 @@
 package transformer
 
 import (
+	"context"
 	"encoding/json"
 	"math/big"
 
@@ -22,7 +23,7 @@ func (p *ProxyETHGetFilterChanges) Method() string {
 	return "eth_getFilterChanges"
 }
 
-func (p *ProxyETHGetFilterChanges) Request(rawreq *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
+func (p *ProxyETHGetFilterChanges) Request(ctx context.Context, rawreq *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
 
 	filter, err := processFilter(p, rawreq)
 	if err != nil {
@@ -31,9 +32,12 @@ func (p *ProxyETHGetFilterChanges) Request(rawreq *eth.JSONRPCRequest, c echo.Co
 
 	switch filter.Type {
 	case eth.NewFilterTy:
-		return p.requestFilter(filter)
+		if !p.Supports(qtum.CapabilityLogEvents) {
+			return nil, eth.NewNotSupportedError("Getting the changes of a log filter", string(qtum.CapabilityLogEvents))
+		}
+		return p.requestFilter(ctx, filter)
 	case eth.NewBlockFilterTy:
-		return p.requestBlockFilter(filter)
+		return p.requestBlockFilter(ctx, filter)
 	case eth.NewPendingTransactionFilterTy:
 		fallthrough
 	default:
@@ -41,7 +45,7 @@ func (p *ProxyETHGetFilterChanges) Request(rawreq *eth.JSONRPCRequest, c echo.Co
 	}
 }
 
-func (p *ProxyETHGetFilterChanges) requestBlockFilter(filter *eth.Filter) (qtumresp eth.GetFilterChangesResponse, err eth.JSONRPCError) {
+func (p *ProxyETHGetFilterChanges) requestBlockFilter(ctx context.Context, filter *eth.Filter) (qtumresp eth.GetFilterChangesResponse, err eth.JSONRPCError) {
 	qtumresp = make(eth.GetFilterChangesResponse, 0)
 
 	_lastBlockNumber, ok := filter.Data.Load("lastBlockNumber")
@@ -50,7 +54,7 @@ func (p *ProxyETHGetFilterChanges) requestBlockFilter(filter *eth.Filter) (qtumr
 	}
 	lastBlockNumber := _lastBlockNumber.(uint64)
 
-	blockCountBigInt, blockErr := p.GetBlockCount()
+	blockCountBigInt, blockErr := p.GetBlockCount(ctx)
 	if blockErr != nil {
 		return qtumresp, eth.NewCallbackError(blockErr.Error())
 	}
@@ -58,15 +62,19 @@ func (p *ProxyETHGetFilterChanges) requestBlockFilter(filter *eth.Filter) (qtumr
 
 	differ := blockCount - lastBlockNumber
 
-	hashes := make(eth.GetFilterChangesResponse, differ)
-	for i := range hashes {
-		blockNumber := new(big.Int).SetUint64(lastBlockNumber + uint64(i) + 1)
+	blockNumbers := make([]*big.Int, differ)
+	for i := range blockNumbers {
+		blockNumbers[i] = new(big.Int).SetUint64(lastBlockNumber + uint64(i) + 1)
+	}
 
-		resp, err := p.GetBlockHash(blockNumber)
-		if err != nil {
-			return qtumresp, eth.NewCallbackError(err.Error())
-		}
+	// every new block's hash in one round trip
+	resps, hashesErr := p.GetBlockHashes(ctx, blockNumbers)
+	if hashesErr != nil {
+		return qtumresp, eth.NewCallbackError(hashesErr.Error())
+	}
 
+	hashes := make(eth.GetFilterChangesResponse, differ)
+	for i, resp := range resps {
 		hashes[i] = utils.AddHexPrefix(string(resp))
 	}
 
@@ -75,7 +83,7 @@ func (p *ProxyETHGetFilterChanges) requestBlockFilter(filter *eth.Filter) (qtumr
 	return
 }
 
-func (p *ProxyETHGetFilterChanges) requestFilter(filter *eth.Filter) (qtumresp eth.GetFilterChangesResponse, err eth.JSONRPCError) {
+func (p *ProxyETHGetFilterChanges) requestFilter(ctx context.Context, filter *eth.Filter) (qtumresp eth.GetFilterChangesResponse, err eth.JSONRPCError) {
 	qtumresp = make(eth.GetFilterChangesResponse, 0)
 
 	_lastBlockNumber, ok := filter.Data.Load("lastBlockNumber")
@@ -84,7 +92,7 @@ func (p *ProxyETHGetFilterChanges) requestFilter(filter *eth.Filter) (qtumresp e
 	}
 	lastBlockNumber := _lastBlockNumber.(uint64)
 
-	blockCountBigInt, blockErr := p.GetBlockCount()
+	blockCountBigInt, blockErr := p.GetBlockCount(ctx)
 	if blockErr != nil {
 		return qtumresp, eth.NewCallbackError(blockErr.Error())
 	}
@@ -101,11 +109,11 @@ func (p *ProxyETHGetFilterChanges) requestFilter(filter *eth.Filter) (qtumresp e
 		return nil, err
 	}
 
-	return p.doSearchLogs(searchLogsReq)
+	return p.doSearchLogs(ctx, searchLogsReq)
 }
 
-func (p *ProxyETHGetFilterChanges) doSearchLogs(req *qtum.SearchLogsRequest) (eth.GetFilterChangesResponse, eth.JSONRPCError) {
-	resp, err := conversion.SearchLogsAndFilterExtraTopics(p.Qtum, req)
+func (p *ProxyETHGetFilterChanges) doSearchLogs(ctx context.Context, req *qtum.SearchLogsRequest) (eth.GetFilterChangesResponse, eth.JSONRPCError) {
+	resp, err := conversion.SearchLogsAndFilterExtraTopics(ctx, p.Qtum, req)
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,7 @@
 package transformer
 
 import (
+	"context"
 	"encoding/json"
 	"math/big"
 	"reflect"
@@ -50,7 +51,7 @@ func TestGetFilterChangesRequest_EmptyResult(t *testing.T) {
 
 	//preparing proxy & executing request
 	proxyEth := ProxyETHGetFilterChanges{qtumClient, filterSimulator}
-	got, jsonErr := proxyEth.Request(requestRPC, nil)
+	got, jsonErr := proxyEth.Request(context.Background(), requestRPC, nil)
 	if jsonErr != nil {
 		t.Fatal(jsonErr)
 	}
@@ -96,7 +97,7 @@ func TestGetFilterChangesRequest_NoNewBlocks(t *testing.T) {
 
 	//preparing proxy & executing request
 	proxyEth := ProxyETHGetFilterChanges{qtumClient, filterSimulator}
-	got, jsonErr := proxyEth.Request(requestRPC, nil)
+	got, jsonErr := proxyEth.Request(context.Background(), requestRPC, nil)
 	if jsonErr != nil {
 		t.Fatal(jsonErr)
 	}
@@ -129,7 +130,7 @@ func TestGetFilterChangesRequest_NoSuchFilter(t *testing.T) {
 	//preparing proxy & executing request
 	filterSimulator := eth.NewFilterSimulator()
 	proxyEth := ProxyETHGetFilterChanges{qtumClient, filterSimulator}
-	got, jsonErr := proxyEth.Request(requestRPC, nil)
+	got, jsonErr := proxyEth.Request(context.Background(), requestRPC, nil)
 	expectedErr := eth.NewCallbackError("Invalid filter id")
 
 	if got != nil {
@@ -149,3 +150,56 @@ func TestGetFilterChangesRequest_NoSuchFilter(t *testing.T) {
 		)
 	}
 }
+
+func TestGetFilterChangesRequest_NewBlocks(t *testing.T) {
+	//prepare request
+	requestParams := []json.RawMessage{[]byte(`"0x1"`)}
+	requestRPC, err := internal.PrepareEthRPCRequest(1, requestParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//prepare client
+	mockedClientDoer := internal.NewDoerMappedMock()
+	qtumClient, err := internal.CreateMockedClient(mockedClientDoer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//preparing client response
+	getBlockCountResponse := qtum.GetBlockCountResponse{Int: big.NewInt(657657)}
+	err = mockedClientDoer.AddResponseWithRequestID(2, qtum.MethodGetBlockCount, getBlockCountResponse)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// both hashes are fetched in one batch
+	for _, hash := range []string{"aa", "bb"} {
+		err = mockedClientDoer.AddResponse(qtum.MethodGetBlockHash, qtum.GetBlockHashResponse(hash))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	//preparing filter
+	filterSimulator := eth.NewFilterSimulator()
+	filterSimulator.New(eth.NewBlockFilterTy)
+	_filter, _ := filterSimulator.Filter(1)
+	filter := _filter.(*eth.Filter)
+	filter.Data.Store("lastBlockNumber", uint64(657655))
+
+	//preparing proxy & executing request
+	proxyEth := ProxyETHGetFilterChanges{qtumClient, filterSimulator}
+	got, jsonErr := proxyEth.Request(context.Background(), requestRPC, nil)
+	if jsonErr != nil {
+		t.Fatal(jsonErr)
+	}
+
+	want := eth.GetFilterChangesResponse{"0xaa", "0xbb"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf(
+			"error\ninput: %s\nwant: %s\ngot: %s",
+			requestRPC,
+			string(internal.MustMarshalIndent(want, "", "  ")),
+			string(internal.MustMarshalIndent(got, "", "  ")),
+		)
+	}
+}
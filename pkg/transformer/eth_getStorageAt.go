@@ -1,6 +1,7 @@
 package transformer
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/labstack/echo"
@@ -18,7 +19,7 @@ func (p *ProxyETHGetStorageAt) Method() string {
 	return "eth_getStorageAt"
 }
 
-func (p *ProxyETHGetStorageAt) Request(rawreq *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
+func (p *ProxyETHGetStorageAt) Request(ctx context.Context, rawreq *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
 	var req eth.GetStorageRequest
 	if err := unmarshalRequest(rawreq.Params, &req); err != nil {
 		// TODO: Correct error code?
@@ -26,20 +27,20 @@ func (p *ProxyETHGetStorageAt) Request(rawreq *eth.JSONRPCRequest, c echo.Contex
 	}
 
 	qtumAddress := utils.RemoveHexPrefix(req.Address)
-	blockNumber, err := getBlockNumberByParam(p.Qtum, req.BlockNumber, false)
+	blockNumber, err := getBlockNumberByParam(ctx, p.Qtum, req.BlockNumber, false)
 	if err != nil {
 		p.GetDebugLogger().Log("msg", fmt.Sprintf("Failed to get block number by param for '%s'", req.BlockNumber), "err", err)
 		return nil, err
 	}
 
-	return p.request(&qtum.GetStorageRequest{
+	return p.request(ctx, &qtum.GetStorageRequest{
 		Address:     qtumAddress,
 		BlockNumber: blockNumber,
 	}, utils.RemoveHexPrefix(req.Index))
 }
 
-func (p *ProxyETHGetStorageAt) request(ethreq *qtum.GetStorageRequest, index string) (*eth.GetStorageResponse, eth.JSONRPCError) {
-	qtumresp, err := p.Qtum.GetStorage(ethreq)
+func (p *ProxyETHGetStorageAt) request(ctx context.Context, ethreq *qtum.GetStorageRequest, index string) (*eth.GetStorageResponse, eth.JSONRPCError) {
+	qtumresp, err := p.Qtum.GetStorage(ctx, ethreq)
 	if err != nil {
 		return nil, eth.NewCallbackError(err.Error())
 	}
@@ -1,6 +1,7 @@
 package transformer
 
 import (
+	"context"
 	"encoding/json"
 	"reflect"
 	"testing"
@@ -34,7 +35,7 @@ func TestGetStorageAtRequestWithNoLeadingZeros(t *testing.T) {
 
 	//preparing proxy & executing request
 	proxyEth := ProxyETHGetStorageAt{qtumClient}
-	got, jsonErr := proxyEth.Request(request, nil)
+	got, jsonErr := proxyEth.Request(context.Background(), request, nil)
 	if jsonErr != nil {
 		t.Fatal(jsonErr)
 	}
@@ -75,7 +76,7 @@ func TestGetStorageAtRequestWithLeadingZeros(t *testing.T) {
 
 	//preparing proxy & executing request
 	proxyEth := ProxyETHGetStorageAt{qtumClient}
-	got, jsonErr := proxyEth.Request(request, nil)
+	got, jsonErr := proxyEth.Request(context.Background(), request, nil)
 	if jsonErr != nil {
 		t.Fatal(jsonErr)
 	}
@@ -117,7 +118,7 @@ func TestGetStorageAtUnknownFieldRequest(t *testing.T) {
 
 	//preparing proxy & executing request
 	proxyEth := ProxyETHGetStorageAt{qtumClient}
-	got, jsonErr := proxyEth.Request(request, nil)
+	got, jsonErr := proxyEth.Request(context.Background(), request, nil)
 	if jsonErr != nil {
 		t.Fatal(jsonErr)
 	}
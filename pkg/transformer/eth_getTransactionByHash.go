@@ -1,8 +1,10 @@
 package transformer
 
 import (
+	"context"
 	"encoding/json"
 	"math/big"
+	"sync"
 
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/labstack/echo"
@@ -21,7 +23,7 @@ func (p *ProxyETHGetTransactionByHash) Method() string {
 	return "eth_getTransactionByHash"
 }
 
-func (p *ProxyETHGetTransactionByHash) Request(req *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
+func (p *ProxyETHGetTransactionByHash) Request(ctx context.Context, req *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
 	var txHash eth.GetTransactionByHashRequest
 	if err := json.Unmarshal(req.Params, &txHash); err != nil {
 		// TODO: Correct error code?
@@ -35,14 +37,18 @@ func (p *ProxyETHGetTransactionByHash) Request(req *eth.JSONRPCRequest, c echo.C
 	qtumReq := &qtum.GetTransactionRequest{
 		TxID: utils.RemoveHexPrefix(string(txHash)),
 	}
-	return p.request(qtumReq)
+	return p.request(ctx, qtumReq)
 }
 
-func (p *ProxyETHGetTransactionByHash) request(req *qtum.GetTransactionRequest) (*eth.GetTransactionByHashResponse, eth.JSONRPCError) {
-	ethTx, err := getTransactionByHash(p.Qtum, req.TxID)
+func (p *ProxyETHGetTransactionByHash) request(ctx context.Context, req *qtum.GetTransactionRequest) (*eth.GetTransactionByHashResponse, eth.JSONRPCError) {
+	ethTx, err := getTransactionByHash(ctx, p.Qtum, req.TxID)
 	if err != nil {
 		return nil, err
 	}
+	if ethTx == nil && !p.Supports(qtum.CapabilityTxIndex) {
+		// it may well be in a block, qtumd can't find it there
+		return nil, eth.NewNotSupportedError("Looking up a transaction that isn't in the wallet or mempool", string(qtum.CapabilityTxIndex))
+	}
 	return ethTx, nil
 }
 
@@ -52,20 +58,20 @@ func (p *ProxyETHGetTransactionByHash) request(req *qtum.GetTransactionRequest)
 // since it returns a lot of data including the equivalent of calling GetRawTransaction on every transaction in block.
 // The last point is of particular interest because GetRawTransaction doesn't by default work for every transaction.
 // This would mean fetching a lot of probably unnecessary data, but in this setup query response delay is reasonably the biggest bottleneck anyway
-func getTransactionByHash(p *qtum.Qtum, hash string) (*eth.GetTransactionByHashResponse, eth.JSONRPCError) {
-	qtumTx, err := p.GetTransaction(hash)
+func getTransactionByHash(ctx context.Context, p *qtum.Qtum, hash string) (*eth.GetTransactionByHashResponse, eth.JSONRPCError) {
+	qtumTx, err := p.GetTransaction(ctx, hash)
 	var ethTx *eth.GetTransactionByHashResponse
 	if err != nil {
 		if errors.Cause(err) != qtum.ErrInvalidAddress {
 			return nil, eth.NewCallbackError(err.Error())
 		}
 		var rawQtumTx *qtum.GetRawTransactionResponse
-		ethTx, rawQtumTx, err = getRewardTransactionByHash(p, hash)
+		ethTx, rawQtumTx, err = getRewardTransactionByHash(ctx, p, hash)
 		if err != nil {
 			if errors.Cause(err) == qtum.ErrInvalidAddress {
 				return nil, nil
 			}
-			rawTx, err := p.GetRawTransaction(hash, false)
+			rawTx, err := p.GetRawTransaction(ctx, hash, false)
 			if err != nil {
 				if errors.Cause(err) == qtum.ErrInvalidAddress {
 					return nil, nil
@@ -89,7 +95,7 @@ func getTransactionByHash(p *qtum.Qtum, hash string) (*eth.GetTransactionByHashR
 
 		// return ethTx, nil
 	}
-	qtumDecodedRawTx, err := p.DecodeRawTransaction(qtumTx.Hex)
+	qtumDecodedRawTx, err := p.DecodeRawTransaction(ctx, qtumTx.Hex)
 	if err != nil {
 		return nil, eth.NewCallbackError("couldn't get raw transaction")
 	}
@@ -112,7 +118,7 @@ func getTransactionByHash(p *qtum.Qtum, hash string) (*eth.GetTransactionByHashR
 	}
 
 	if !qtumTx.IsPending() { // otherwise, the following values must be nulls
-		blockNumber, err := getBlockNumberByHash(p, qtumTx.BlockHash)
+		blockNumber, err := getBlockNumberByHash(ctx, p, qtumTx.BlockHash)
 		if err != nil {
 			return nil, eth.NewCallbackError("couldn't get block number by hash")
 		}
@@ -174,7 +180,7 @@ func getTransactionByHash(p *qtum.Qtum, hash string) (*eth.GetTransactionByHashR
 	} else {
 		// TODO: Figure out if following code still cause issues in some cases, see next comment
 
-		ethTx.From, err = getNonContractTxSenderAddress(p, qtumDecodedRawTx)
+		ethTx.From, err = getNonContractTxSenderAddress(ctx, p, qtumDecodedRawTx)
 		if err != nil {
 			return nil, eth.NewCallbackError("Couldn't get non contract transaction sender address: " + err.Error())
 		}
@@ -216,11 +222,80 @@ func getTransactionByHash(p *qtum.Qtum, hash string) (*eth.GetTransactionByHashR
 	return ethTx, nil
 }
 
+// getTransactionsByHash gets several transactions
+// when the client batches requests they are fetched at the same time so their calls to qtumd share round trips
+func getTransactionsByHash(ctx context.Context, p *qtum.Qtum, hashes []string) ([]*eth.GetTransactionByHashResponse, []eth.JSONRPCError) {
+	txs := make([]*eth.GetTransactionByHashResponse, len(hashes))
+	errs := make([]eth.JSONRPCError, len(hashes))
+	if len(hashes) > 1 {
+		ctx = prefetchTransactions(ctx, p, hashes)
+	}
+
+	if !p.IsBatching() {
+		for i, hash := range hashes {
+			txs[i], errs[i] = getTransactionByHash(ctx, p, hash)
+			if errs[i] != nil {
+				break
+			}
+		}
+		return txs, errs
+	}
+
+	// no more at once than fit in one batch
+	slots := make(chan struct{}, qtum.DefaultMaxBatchSize)
+	var wg sync.WaitGroup
+	for i, hash := range hashes {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, hash string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			txs[i], errs[i] = getTransactionByHash(ctx, p, hash)
+		}(i, hash)
+	}
+	wg.Wait()
+
+	return txs, errs
+}
+
+// prefetchTransactions makes the calls getTransactionByHash makes for each of hashes in two batches
+// looking up the transactions, then decoding them and getting their blocks
+func prefetchTransactions(ctx context.Context, p *qtum.Qtum, hashes []string) context.Context {
+	lookups := make([]*qtum.BatchRequest, 0, 2*len(hashes))
+	for _, hash := range hashes {
+		lookups = append(lookups, qtum.GetTransactionCall(hash), qtum.GetRawTransactionCall(hash, false))
+	}
+	ctx = p.Prefetch(ctx, lookups)
+
+	var next []*qtum.BatchRequest
+	seen := make(map[string]bool)
+	add := func(call *qtum.BatchRequest, key string) {
+		if key != "" && !seen[call.Method+key] {
+			seen[call.Method+key] = true
+			next = append(next, call)
+		}
+	}
+	for _, lookup := range lookups {
+		if lookup.Err != nil {
+			continue
+		}
+		switch tx := lookup.Result.(type) {
+		case *qtum.GetTransactionResponse:
+			add(qtum.DecodeRawTransactionCall(tx.Hex), tx.Hex)
+			add(qtum.GetBlockCall(tx.BlockHash), tx.BlockHash)
+		case *qtum.GetRawTransactionResponse:
+			add(qtum.DecodeRawTransactionCall(tx.Hex), tx.Hex)
+			add(qtum.GetBlockCall(tx.BlockHash), tx.BlockHash)
+		}
+	}
+	return p.Prefetch(ctx, next)
+}
+
 // TODO: Does this need to return eth.JSONRPCError
 // TODO: discuss
 // ? There are `witness` transactions, that is not acquireable nither via `gettransaction`, nor `getrawtransaction`
-func getRewardTransactionByHash(p *qtum.Qtum, hash string) (*eth.GetTransactionByHashResponse, *qtum.GetRawTransactionResponse, error) {
-	rawQtumTx, err := p.GetRawTransaction(hash, false)
+func getRewardTransactionByHash(ctx context.Context, p *qtum.Qtum, hash string) (*eth.GetTransactionByHashResponse, *qtum.GetRawTransactionResponse, error) {
+	rawQtumTx, err := p.GetRawTransaction(ctx, hash, false)
 	if err != nil {
 		return nil, nil, errors.WithMessage(err, "couldn't get raw reward transaction")
 	}
@@ -251,13 +326,13 @@ func getRewardTransactionByHash(p *qtum.Qtum, hash string) (*eth.GetTransactionB
 		// geth returns null if the tx is pending
 		return nil, rawQtumTx, nil
 	} else {
-		blockIndex, err := getTransactionIndexInBlock(p, hash, rawQtumTx.BlockHash)
+		blockIndex, err := getTransactionIndexInBlock(ctx, p, hash, rawQtumTx.BlockHash)
 		if err != nil {
 			return nil, nil, errors.WithMessage(err, "couldn't get transaction index in block")
 		}
 		ethTx.TransactionIndex = hexutil.EncodeUint64(uint64(blockIndex))
 
-		blockNumber, err := getBlockNumberByHash(p, rawQtumTx.BlockHash)
+		blockNumber, err := getBlockNumberByHash(ctx, p, rawQtumTx.BlockHash)
 		if err != nil {
 			return nil, nil, errors.WithMessage(err, "couldn't get block number by hash")
 		}
@@ -269,7 +344,7 @@ func getRewardTransactionByHash(p *qtum.Qtum, hash string) (*eth.GetTransactionB
 	for i := range rawQtumTx.Vouts {
 		// TODO: discuss
 		// ! The response may be null, even if txout is presented
-		_, err := p.GetTransactionOut(hash, i, rawQtumTx.IsPending())
+		_, err := p.GetTransactionOut(ctx, hash, i, rawQtumTx.IsPending())
 		if err != nil {
 			return nil, nil, errors.WithMessage(err, "couldn't get transaction out")
 		}
@@ -287,13 +362,13 @@ func getRewardTransactionByHash(p *qtum.Qtum, hash string) (*eth.GetTransactionB
 	// P2Sh address(such as MUrenj2sPqEVTiNbHQ2RARiZYyTAAeKiDX) and BECH32 address (such as qc1qkt33x6hkrrlwlr6v59wptwy6zskyrjfe40y0lx)
 	if rawQtumTx.OP_SENDER != "" {
 		// addr, err := convertQtumAddress(rawQtumTx.OP_SENDER)
-		addr, err := p.Base58AddressToHex(rawQtumTx.OP_SENDER)
+		addr, err := p.Base58AddressToHex(ctx, rawQtumTx.OP_SENDER)
 		if err == nil {
 			ethTx.From = utils.AddHexPrefix(addr)
 		}
 	} else if len(rawQtumTx.Vins) > 0 && rawQtumTx.Vins[0].Address != "" {
 		// addr, err := convertQtumAddress(rawQtumTx.Vins[0].Address)
-		addr, err := p.Base58AddressToHex(rawQtumTx.Vins[0].Address)
+		addr, err := p.Base58AddressToHex(ctx, rawQtumTx.Vins[0].Address)
 		if err == nil {
 			ethTx.From = utils.AddHexPrefix(addr)
 		}
@@ -360,7 +435,7 @@ func getRewardTransactionByHash(p *qtum.Qtum, hash string) (*eth.GetTransactionB
 		ethTx.Value = hexutil.EncodeUint64(sentToInWei.Uint64())
 
 		if to != "" {
-			toAddress, err := p.Base58AddressToHex(to)
+			toAddress, err := p.Base58AddressToHex(ctx, to)
 			if err == nil {
 				ethTx.To = utils.AddHexPrefix(toAddress)
 			}
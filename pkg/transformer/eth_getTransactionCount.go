@@ -1,6 +1,7 @@
 package transformer
 
 import (
+	"context"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -18,14 +19,14 @@ func (p *ProxyETHTxCount) Method() string {
 	return "eth_getTransactionCount"
 }
 
-func (p *ProxyETHTxCount) Request(rawreq *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
+func (p *ProxyETHTxCount) Request(ctx context.Context, rawreq *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
 
 	/* not sure we need this. Need to figure out how to best unmarshal this in the future. For now this will work.
 	var req eth.GetTransactionCountRequest
 	if err := unmarshalRequest(rawreq.Params, &req); err != nil {
 		return nil, err
 	}*/
-	qtumresp, err := p.Qtum.GetTransactionCount("", "")
+	qtumresp, err := p.Qtum.GetTransactionCount(ctx, "", "")
 	if err != nil {
 		return nil, eth.NewCallbackError(err.Error())
 	}
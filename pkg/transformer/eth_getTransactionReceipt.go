@@ -1,6 +1,8 @@
 package transformer
 
 import (
+	"context"
+
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/labstack/echo"
 	"github.com/pkg/errors"
@@ -22,7 +24,7 @@ func (p *ProxyETHGetTransactionReceipt) Method() string {
 	return "eth_getTransactionReceipt"
 }
 
-func (p *ProxyETHGetTransactionReceipt) Request(rawreq *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
+func (p *ProxyETHGetTransactionReceipt) Request(ctx context.Context, rawreq *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
 	var req eth.GetTransactionReceiptRequest
 	if err := unmarshalRequest(rawreq.Params, &req); err != nil {
 		// TODO: Correct error code?
@@ -36,18 +38,26 @@ func (p *ProxyETHGetTransactionReceipt) Request(rawreq *eth.JSONRPCRequest, c ec
 		txHash  = utils.RemoveHexPrefix(string(req))
 		qtumReq = qtum.GetTransactionReceiptRequest(txHash)
 	)
-	return p.request(&qtumReq)
+	return p.request(ctx, &qtumReq)
 }
 
-func (p *ProxyETHGetTransactionReceipt) request(req *qtum.GetTransactionReceiptRequest) (*eth.GetTransactionReceiptResponse, eth.JSONRPCError) {
-	qtumReceipt, err := p.Qtum.GetTransactionReceipt(string(*req))
+func (p *ProxyETHGetTransactionReceipt) request(ctx context.Context, req *qtum.GetTransactionReceiptRequest) (*eth.GetTransactionReceiptResponse, eth.JSONRPCError) {
+	// the receipt and the transaction are both needed whether it's a block reward or not, they only take one round trip
+	ctx = p.Qtum.Prefetch(ctx, []*qtum.BatchRequest{
+		qtum.GetTransactionReceiptCall(string(*req)),
+		qtum.GetRawTransactionCall(string(*req), false),
+	})
+	qtumReceipt, err := p.Qtum.GetTransactionReceipt(ctx, string(*req))
 	if err != nil {
-		ethTx, _, getRewardTransactionErr := getRewardTransactionByHash(p.Qtum, string(*req))
+		ethTx, _, getRewardTransactionErr := getRewardTransactionByHash(ctx, p.Qtum, string(*req))
 		if getRewardTransactionErr != nil {
 			errCause := errors.Cause(err)
 			if errCause == qtum.EmptyResponseErr {
 				return nil, nil
 			}
+			if errCause == qtum.ErrInternalError && !p.Supports(qtum.CapabilityLogEvents) {
+				return nil, eth.NewNotSupportedError("Getting the receipt of a transaction that isn't a block reward", string(qtum.CapabilityLogEvents))
+			}
 			p.Qtum.GetDebugLogger().Log("msg", "Transaction does not exist", "txid", string(*req))
 			return nil, eth.NewCallbackError(err.Error())
 		}
@@ -101,12 +111,15 @@ func (p *ProxyETHGetTransactionReceipt) request(req *qtum.GetTransactionReceiptR
 	r := qtum.TransactionReceipt(*qtumReceipt)
 	ethReceipt.Logs = conversion.ExtractETHLogsFromTransactionReceipt(&r, r.Log)
 
-	qtumTx, err := p.Qtum.GetRawTransaction(qtumReceipt.TransactionHash, false)
+	qtumTx, err := p.Qtum.GetRawTransaction(ctx, qtumReceipt.TransactionHash, false)
 	if err != nil {
+		if errors.Cause(err) == qtum.ErrInvalidAddress && !p.Supports(qtum.CapabilityTxIndex) {
+			return nil, eth.NewNotSupportedError("Getting the receipt of a transaction that isn't in the wallet or mempool", string(qtum.CapabilityTxIndex))
+		}
 		p.GetDebugLogger().Log("msg", "couldn't get transaction", "err", err)
 		return nil, eth.NewCallbackError("couldn't get transaction")
 	}
-	decodedRawQtumTx, err := p.Qtum.DecodeRawTransaction(qtumTx.Hex)
+	decodedRawQtumTx, err := p.Qtum.DecodeRawTransaction(ctx, qtumTx.Hex)
 	if err != nil {
 		p.GetDebugLogger().Log("msg", "couldn't decode raw transaction", "err", err)
 		return nil, eth.NewCallbackError("couldn't decode raw transaction")
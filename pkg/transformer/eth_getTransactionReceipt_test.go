@@ -1,6 +1,7 @@
 package transformer
 
 import (
+	"context"
 	"encoding/json"
 	"reflect"
 	"testing"
@@ -8,6 +9,7 @@ import (
 	"github.com/qtumproject/janus/pkg/eth"
 	"github.com/qtumproject/janus/pkg/internal"
 	"github.com/qtumproject/janus/pkg/qtum"
+	"github.com/qtumproject/janus/pkg/qtumtest"
 	"github.com/qtumproject/janus/pkg/utils"
 )
 
@@ -46,7 +48,7 @@ func TestGetTransactionReceiptForNonVMTransaction(t *testing.T) {
 
 	//preparing proxy & executing request
 	proxyEth := ProxyETHGetTransactionReceipt{qtumClient}
-	got, jsonErr := proxyEth.Request(request, nil)
+	got, jsonErr := proxyEth.Request(context.Background(), request, nil)
 	if jsonErr != nil {
 		t.Fatal(jsonErr)
 	}
@@ -74,3 +76,50 @@ func TestGetTransactionReceiptForNonVMTransaction(t *testing.T) {
 		)
 	}
 }
+
+func TestGetTransactionReceiptBatchesItsLookups(t *testing.T) {
+	qtumd, err := qtumtest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer qtumd.Close()
+	chain := qtumd.Chain()
+	miner := chain.NewAddress("miner")
+	if _, err := chain.Mine(2, miner); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := chain.Send(miner, []qtumtest.Output{{Contract: &qtumtest.ContractOutput{Data: "6000", GasLimit: 100000, GasPrice: qtumtest.DefaultGasPrice}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := chain.Mine(1, miner); err != nil {
+		t.Fatal(err)
+	}
+
+	doer := &countingDoer{}
+	client, err := qtum.NewClient(false, qtumd.RPCURL(), qtum.SetDoer(doer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	qtumClient, err := qtum.New(client, qtum.ChainRegTest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request, err := internal.PrepareEthRPCRequest(1, []json.RawMessage{[]byte(`"0x` + tx.ID + `"`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy := &ProxyETHGetTransactionReceipt{qtumClient}
+	result, jsonErr := proxy.Request(context.Background(), request, nil)
+	if jsonErr != nil {
+		t.Fatal(jsonErr)
+	}
+	if receipt := result.(*eth.GetTransactionReceiptResponse); receipt == nil || receipt.TransactionHash != "0x"+tx.ID {
+		t.Fatalf("expected the receipt of %s, got %+v", tx.ID, receipt)
+	}
+
+	// the receipt and the transaction in one batch, then decoding the transaction
+	if doer.requests != 2 {
+		t.Errorf("expected 2 requests to qtumd, got %d", doer.requests)
+	}
+}
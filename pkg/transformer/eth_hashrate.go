@@ -1,6 +1,7 @@
 package transformer
 
 import (
+	"context"
 	"math"
 
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -18,12 +19,12 @@ func (p *ProxyETHHashrate) Method() string {
 	return "eth_hashrate"
 }
 
-func (p *ProxyETHHashrate) Request(_ *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
-	return p.request()
+func (p *ProxyETHHashrate) Request(ctx context.Context, _ *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
+	return p.request(ctx)
 }
 
-func (p *ProxyETHHashrate) request() (*eth.HashrateResponse, eth.JSONRPCError) {
-	qtumresp, err := p.Qtum.GetHashrate()
+func (p *ProxyETHHashrate) request(ctx context.Context) (*eth.HashrateResponse, eth.JSONRPCError) {
+	qtumresp, err := p.Qtum.GetHashrate(ctx)
 	if err != nil {
 		return nil, eth.NewCallbackError(err.Error())
 	}
@@ -1,6 +1,7 @@
 package transformer
 
 import (
+	"context"
 	"encoding/json"
 	"math"
 	"reflect"
@@ -36,7 +37,7 @@ func TestHashrateRequest(t *testing.T) {
 	}
 
 	proxyEth := ProxyETHHashrate{qtumClient}
-	got, jsonErr := proxyEth.Request(request, nil)
+	got, jsonErr := proxyEth.Request(context.Background(), request, nil)
 	if jsonErr != nil {
 		t.Fatal(jsonErr)
 	}
@@ -1,6 +1,7 @@
 package transformer
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"reflect"
@@ -45,7 +46,7 @@ func testPeerCountRequest(t *testing.T, clients int) {
 	}
 
 	proxyEth := ProxyNetPeerCount{qtumClient}
-	got, jsonErr := proxyEth.Request(request, nil)
+	got, jsonErr := proxyEth.Request(context.Background(), request, nil)
 	if jsonErr != nil {
 		t.Fatal(jsonErr)
 	}
@@ -1,6 +1,8 @@
 package transformer
 
 import (
+	"context"
+
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/labstack/echo"
 	"github.com/qtumproject/janus/pkg/eth"
@@ -16,12 +18,12 @@ func (p *ProxyETHNetVersion) Method() string {
 	return "net_version"
 }
 
-func (p *ProxyETHNetVersion) Request(_ *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
-	return p.request()
+func (p *ProxyETHNetVersion) Request(ctx context.Context, _ *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
+	return p.request(ctx)
 }
 
-func (p *ProxyETHNetVersion) request() (*eth.NetVersionResponse, eth.JSONRPCError) {
-	networkID, err := getChainId(p.Qtum)
+func (p *ProxyETHNetVersion) request(ctx context.Context) (*eth.NetVersionResponse, eth.JSONRPCError) {
+	networkID, err := getChainId(ctx, p.Qtum)
 	if err != nil {
 		return nil, err
 	}
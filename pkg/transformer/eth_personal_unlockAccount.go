@@ -1,6 +1,8 @@
 package transformer
 
 import (
+	"context"
+
 	"github.com/labstack/echo"
 	"github.com/qtumproject/janus/pkg/eth"
 )
@@ -12,6 +14,6 @@ func (p *ProxyETHPersonalUnlockAccount) Method() string {
 	return "personal_unlockAccount"
 }
 
-func (p *ProxyETHPersonalUnlockAccount) Request(req *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
+func (p *ProxyETHPersonalUnlockAccount) Request(ctx context.Context, req *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
 	return eth.PersonalUnlockAccountResponse(true), nil
 }
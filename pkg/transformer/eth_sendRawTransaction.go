@@ -1,6 +1,8 @@
 package transformer
 
 import (
+	"context"
+
 	"github.com/labstack/echo"
 	"github.com/qtumproject/janus/pkg/eth"
 	"github.com/qtumproject/janus/pkg/qtum"
@@ -18,7 +20,7 @@ func (p *ProxyETHSendRawTransaction) Method() string {
 	return "eth_sendRawTransaction"
 }
 
-func (p *ProxyETHSendRawTransaction) Request(req *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
+func (p *ProxyETHSendRawTransaction) Request(ctx context.Context, req *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
 	var params eth.SendRawTransactionRequest
 	if err := unmarshalRequest(req.Params, &params); err != nil {
 		// TODO: Correct error code?
@@ -29,21 +31,21 @@ func (p *ProxyETHSendRawTransaction) Request(req *eth.JSONRPCRequest, c echo.Con
 		return nil, eth.NewInvalidParamsError("invalid parameter: raw transaction hexed string is empty")
 	}
 
-	return p.request(params)
+	return p.request(ctx, params)
 }
 
-func (p *ProxyETHSendRawTransaction) request(params eth.SendRawTransactionRequest) (eth.SendRawTransactionResponse, eth.JSONRPCError) {
+func (p *ProxyETHSendRawTransaction) request(ctx context.Context, params eth.SendRawTransactionRequest) (eth.SendRawTransactionResponse, eth.JSONRPCError) {
 	var (
 		qtumHexedRawTx = utils.RemoveHexPrefix(params[0])
 		req            = qtum.SendRawTransactionRequest([1]string{qtumHexedRawTx})
 	)
 
-	qtumresp, err := p.Qtum.SendRawTransaction(&req)
+	qtumresp, err := p.Qtum.SendRawTransaction(ctx, &req)
 	if err != nil {
 		if err == qtum.ErrVerifyAlreadyInChain {
 			// already committed
 			// we need to send back the tx hash
-			rawTx, err := p.Qtum.DecodeRawTransaction(qtumHexedRawTx)
+			rawTx, err := p.Qtum.DecodeRawTransaction(ctx, qtumHexedRawTx)
 			if err != nil {
 				p.GetErrorLogger().Log("msg", "Error decoding raw transaction for duplicate raw transaction", "err", err)
 				return eth.SendRawTransactionResponse(""), eth.NewCallbackError(err.Error())
@@ -1,6 +1,8 @@
 package transformer
 
 import (
+	"context"
+
 	"github.com/labstack/echo"
 	"github.com/qtumproject/janus/pkg/eth"
 	"github.com/qtumproject/janus/pkg/qtum"
@@ -8,8 +10,6 @@ import (
 	"github.com/shopspring/decimal"
 )
 
-var MinimumGasLimit = int64(22000)
-
 // ProxyETHSendTransaction implements ETHProxy
 type ProxyETHSendTransaction struct {
 	*qtum.Qtum
@@ -19,7 +19,7 @@ func (p *ProxyETHSendTransaction) Method() string {
 	return "eth_sendTransaction"
 }
 
-func (p *ProxyETHSendTransaction) Request(rawreq *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
+func (p *ProxyETHSendTransaction) Request(ctx context.Context, rawreq *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
 	var req eth.SendTransactionRequest
 	err := unmarshalRequest(rawreq.Params, &req)
 	if err != nil {
@@ -27,7 +27,7 @@ func (p *ProxyETHSendTransaction) Request(rawreq *eth.JSONRPCRequest, c echo.Con
 		return nil, eth.NewInvalidParamsError(err.Error())
 	}
 
-	if req.Gas != nil && req.Gas.Int64() < MinimumGasLimit {
+	if req.Gas != nil && req.Gas.Int64() < CurrentGasSettings().MinimumGasLimit {
 		p.GetLogger().Log("msg", "Gas limit is too low", "gasLimit", req.Gas.String())
 	}
 
@@ -35,11 +35,11 @@ func (p *ProxyETHSendTransaction) Request(rawreq *eth.JSONRPCRequest, c echo.Con
 	var jsonErr eth.JSONRPCError
 
 	if req.IsCreateContract() {
-		result, jsonErr = p.requestCreateContract(&req)
+		result, jsonErr = p.requestCreateContract(ctx, &req)
 	} else if req.IsSendEther() {
-		result, jsonErr = p.requestSendToAddress(&req)
+		result, jsonErr = p.requestSendToAddress(ctx, &req)
 	} else if req.IsCallContract() {
-		result, jsonErr = p.requestSendToContract(&req)
+		result, jsonErr = p.requestSendToContract(ctx, &req)
 	} else {
 		return nil, eth.NewInvalidParamsError("Unknown operation")
 	}
@@ -51,7 +51,7 @@ func (p *ProxyETHSendTransaction) Request(rawreq *eth.JSONRPCRequest, c echo.Con
 	return result, jsonErr
 }
 
-func (p *ProxyETHSendTransaction) requestSendToContract(ethtx *eth.SendTransactionRequest) (*eth.SendTransactionResponse, eth.JSONRPCError) {
+func (p *ProxyETHSendTransaction) requestSendToContract(ctx context.Context, ethtx *eth.SendTransactionRequest) (*eth.SendTransactionResponse, eth.JSONRPCError) {
 	gasLimit, gasPrice, err := EthGasToQtum(ethtx)
 	if err != nil {
 		return nil, eth.NewInvalidParamsError(err.Error())
@@ -75,7 +75,7 @@ func (p *ProxyETHSendTransaction) requestSendToContract(ethtx *eth.SendTransacti
 	}
 
 	if from := ethtx.From; from != "" && utils.IsEthHexAddress(from) {
-		from, err = p.FromHexAddress(from)
+		from, err = p.FromHexAddress(ctx, from)
 		if err != nil {
 			return nil, eth.NewCallbackError(err.Error())
 		}
@@ -91,10 +91,10 @@ func (p *ProxyETHSendTransaction) requestSendToContract(ethtx *eth.SendTransacti
 	return &ethresp, nil
 }
 
-func (p *ProxyETHSendTransaction) requestSendToAddress(req *eth.SendTransactionRequest) (*eth.SendTransactionResponse, eth.JSONRPCError) {
+func (p *ProxyETHSendTransaction) requestSendToAddress(ctx context.Context, req *eth.SendTransactionRequest) (*eth.SendTransactionResponse, eth.JSONRPCError) {
 	getQtumWalletAddress := func(addr string) (string, error) {
 		if utils.IsEthHexAddress(addr) {
-			return p.FromHexAddress(utils.RemoveHexPrefix(addr))
+			return p.FromHexAddress(ctx, utils.RemoveHexPrefix(addr))
 		}
 		return addr, nil
 	}
@@ -139,7 +139,7 @@ func (p *ProxyETHSendTransaction) requestSendToAddress(req *eth.SendTransactionR
 	return &ethresp, nil
 }
 
-func (p *ProxyETHSendTransaction) requestCreateContract(req *eth.SendTransactionRequest) (*eth.SendTransactionResponse, eth.JSONRPCError) {
+func (p *ProxyETHSendTransaction) requestCreateContract(ctx context.Context, req *eth.SendTransactionRequest) (*eth.SendTransactionResponse, eth.JSONRPCError) {
 	gasLimit, gasPrice, err := EthGasToQtum(req)
 	if err != nil {
 		return nil, eth.NewInvalidParamsError(err.Error())
@@ -154,7 +154,7 @@ func (p *ProxyETHSendTransaction) requestCreateContract(req *eth.SendTransaction
 	if req.From != "" {
 		from := req.From
 		if utils.IsEthHexAddress(from) {
-			from, err = p.FromHexAddress(from)
+			from, err = p.FromHexAddress(ctx, from)
 			if err != nil {
 				return nil, eth.NewCallbackError(err.Error())
 			}
@@ -2,6 +2,7 @@ package transformer
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
 	"encoding/hex"
 	"fmt"
@@ -23,7 +24,7 @@ func (p *ProxyETHSign) Method() string {
 	return "eth_sign"
 }
 
-func (p *ProxyETHSign) Request(rawreq *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
+func (p *ProxyETHSign) Request(ctx context.Context, rawreq *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
 	var req eth.SignRequest
 	if err := unmarshalRequest(rawreq.Params, &req); err != nil {
 		p.GetDebugLogger().Log("method", p.Method(), "error", err)
@@ -33,7 +34,7 @@ func (p *ProxyETHSign) Request(rawreq *eth.JSONRPCRequest, c echo.Context) (inte
 
 	addr := utils.RemoveHexPrefix(req.Account)
 
-	acc := p.Qtum.Accounts.FindByHexAddress(addr)
+	acc := p.Qtum.GetAccounts().FindByHexAddress(addr)
 	if acc == nil {
 		p.GetDebugLogger().Log("method", p.Method(), "account", addr, "msg", "Unknown account")
 		return nil, eth.NewInvalidParamsError(fmt.Sprintf("No such account: %s", addr))
@@ -1,6 +1,7 @@
 package transformer
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -20,7 +21,7 @@ func (p *ProxyETHSignTransaction) Method() string {
 	return "eth_signTransaction"
 }
 
-func (p *ProxyETHSignTransaction) Request(rawreq *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
+func (p *ProxyETHSignTransaction) Request(ctx context.Context, rawreq *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
 	var req eth.SendTransactionRequest
 	if err := unmarshalRequest(rawreq.Params, &req); err != nil {
 		// TODO: Correct error code?
@@ -29,13 +30,13 @@ func (p *ProxyETHSignTransaction) Request(rawreq *eth.JSONRPCRequest, c echo.Con
 
 	if req.IsCreateContract() {
 		p.GetDebugLogger().Log("method", p.Method(), "msg", "transaction is a create contract request")
-		return p.requestCreateContract(&req)
+		return p.requestCreateContract(ctx, &req)
 	} else if req.IsSendEther() {
 		p.GetDebugLogger().Log("method", p.Method(), "msg", "transaction is a send ether request")
-		return p.requestSendToAddress(&req)
+		return p.requestSendToAddress(ctx, &req)
 	} else if req.IsCallContract() {
 		p.GetDebugLogger().Log("method", p.Method(), "msg", "transaction is a call contract request")
-		return p.requestSendToContract(&req)
+		return p.requestSendToContract(ctx, &req)
 	} else {
 		p.GetDebugLogger().Log("method", p.Method(), "msg", "transaction is an unknown request")
 	}
@@ -43,16 +44,16 @@ func (p *ProxyETHSignTransaction) Request(rawreq *eth.JSONRPCRequest, c echo.Con
 	return nil, eth.NewInvalidParamsError("Unknown operation")
 }
 
-func (p *ProxyETHSignTransaction) getRequiredUtxos(from string, neededAmount decimal.Decimal) ([]qtum.RawTxInputs, decimal.Decimal, error) {
+func (p *ProxyETHSignTransaction) getRequiredUtxos(ctx context.Context, from string, neededAmount decimal.Decimal) ([]qtum.RawTxInputs, decimal.Decimal, error) {
 	//convert address to qtum address
 	addr := utils.RemoveHexPrefix(from)
-	base58Addr, err := p.FromHexAddress(addr)
+	base58Addr, err := p.FromHexAddress(ctx, addr)
 	if err != nil {
 		return nil, decimal.Decimal{}, err
 	}
 	// need to get utxos with txid and vouts. In order to do this we get a list of unspent transactions and begin summing them up
 	var getaddressutxos *qtum.GetAddressUTXOsRequest = &qtum.GetAddressUTXOsRequest{Addresses: []string{base58Addr}}
-	qtumresp, err := p.GetAddressUTXOs(getaddressutxos)
+	qtumresp, err := p.GetAddressUTXOs(ctx, getaddressutxos)
 	if err != nil {
 		return nil, decimal.Decimal{}, err
 	}
@@ -83,7 +84,7 @@ func calculateNeededAmount(value, gasLimit, gasPrice decimal.Decimal) decimal.De
 	return value.Add(gasLimit.Mul(gasPrice))
 }
 
-func (p *ProxyETHSignTransaction) requestSendToContract(ethtx *eth.SendTransactionRequest) (string, eth.JSONRPCError) {
+func (p *ProxyETHSignTransaction) requestSendToContract(ctx context.Context, ethtx *eth.SendTransactionRequest) (string, eth.JSONRPCError) {
 	gasLimit, gasPrice, err := EthGasToQtum(ethtx)
 	if err != nil {
 		return "", eth.NewInvalidParamsError(err.Error())
@@ -104,7 +105,7 @@ func (p *ProxyETHSignTransaction) requestSendToContract(ethtx *eth.SendTransacti
 	}
 	neededAmount := calculateNeededAmount(amount, decimal.NewFromBigInt(gasLimit, 0), newGasPrice)
 
-	inputs, balance, err := p.getRequiredUtxos(ethtx.From, neededAmount)
+	inputs, balance, err := p.getRequiredUtxos(ctx, ethtx.From, neededAmount)
 	if err != nil {
 		return "", eth.NewCallbackError(err.Error())
 	}
@@ -123,7 +124,7 @@ func (p *ProxyETHSignTransaction) requestSendToContract(ethtx *eth.SendTransacti
 	}
 
 	if from := ethtx.From; from != "" && utils.IsEthHexAddress(from) {
-		from, err = p.FromHexAddress(from)
+		from, err = p.FromHexAddress(ctx, from)
 		if err != nil {
 			return "", eth.NewInvalidParamsError(err.Error())
 		}
@@ -132,7 +133,7 @@ func (p *ProxyETHSignTransaction) requestSendToContract(ethtx *eth.SendTransacti
 
 	fromAddr := utils.RemoveHexPrefix(ethtx.From)
 
-	acc := p.Qtum.Accounts.FindByHexAddress(strings.ToLower(fromAddr))
+	acc := p.Qtum.GetAccounts().FindByHexAddress(strings.ToLower(fromAddr))
 	if acc == nil {
 		return "", eth.NewInvalidParamsError(fmt.Sprintf("No such account: %s", fromAddr))
 	}
@@ -153,10 +154,10 @@ func (p *ProxyETHSignTransaction) requestSendToContract(ethtx *eth.SendTransacti
 	return utils.AddHexPrefix(resp.Hex), nil
 }
 
-func (p *ProxyETHSignTransaction) requestSendToAddress(req *eth.SendTransactionRequest) (string, eth.JSONRPCError) {
+func (p *ProxyETHSignTransaction) requestSendToAddress(ctx context.Context, req *eth.SendTransactionRequest) (string, eth.JSONRPCError) {
 	getQtumWalletAddress := func(addr string) (string, error) {
 		if utils.IsEthHexAddress(addr) {
-			return p.FromHexAddress(utils.RemoveHexPrefix(addr))
+			return p.FromHexAddress(ctx, utils.RemoveHexPrefix(addr))
 		}
 		return addr, nil
 	}
@@ -176,7 +177,7 @@ func (p *ProxyETHSignTransaction) requestSendToAddress(req *eth.SendTransactionR
 		return "", eth.NewInvalidParamsError(err.Error())
 	}
 
-	inputs, balance, err := p.getRequiredUtxos(req.From, amount)
+	inputs, balance, err := p.getRequiredUtxos(ctx, req.From, amount)
 	if err != nil {
 		return "", eth.NewCallbackError(err.Error())
 	}
@@ -204,7 +205,7 @@ func (p *ProxyETHSignTransaction) requestSendToAddress(req *eth.SendTransactionR
 	return utils.AddHexPrefix(resp.Hex), nil
 }
 
-func (p *ProxyETHSignTransaction) requestCreateContract(req *eth.SendTransactionRequest) (string, eth.JSONRPCError) {
+func (p *ProxyETHSignTransaction) requestCreateContract(ctx context.Context, req *eth.SendTransactionRequest) (string, eth.JSONRPCError) {
 	gasLimit, gasPrice, err := EthGasToQtum(req)
 	if err != nil {
 		return "", eth.NewInvalidParamsError(err.Error())
@@ -212,7 +213,7 @@ func (p *ProxyETHSignTransaction) requestCreateContract(req *eth.SendTransaction
 
 	from := req.From
 	if utils.IsEthHexAddress(from) {
-		from, err = p.FromHexAddress(from)
+		from, err = p.FromHexAddress(ctx, from)
 		if err != nil {
 			return "", eth.NewInvalidParamsError(err.Error())
 		}
@@ -231,7 +232,7 @@ func (p *ProxyETHSignTransaction) requestCreateContract(req *eth.SendTransaction
 	}
 	neededAmount := calculateNeededAmount(decimal.NewFromFloat(0.0), decimal.NewFromBigInt(gasLimit, 0), newGasPrice)
 
-	inputs, balance, err := p.getRequiredUtxos(req.From, neededAmount)
+	inputs, balance, err := p.getRequiredUtxos(ctx, req.From, neededAmount)
 	if err != nil {
 		return "", eth.NewCallbackError(err.Error())
 	}
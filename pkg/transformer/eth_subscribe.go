@@ -1,6 +1,8 @@
 package transformer
 
 import (
+	"context"
+
 	"github.com/labstack/echo"
 	"github.com/qtumproject/janus/pkg/eth"
 	"github.com/qtumproject/janus/pkg/notifier"
@@ -17,7 +19,7 @@ func (p *ETHSubscribe) Method() string {
 	return "eth_subscribe"
 }
 
-func (p *ETHSubscribe) Request(rawreq *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
+func (p *ETHSubscribe) Request(ctx context.Context, rawreq *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
 	notifier := getNotifier(c)
 	if notifier == nil {
 		p.GetLogger().Log("msg", "eth_subscribe only supported over websocket")
@@ -40,6 +42,9 @@ func (p *ETHSubscribe) Request(rawreq *eth.JSONRPCRequest, c echo.Context) (inte
 		// TODO: Correct error code?
 		return nil, eth.NewInvalidParamsError(err.Error())
 	}
+	if req.Method == "logs" && !p.Supports(qtum.CapabilityLogEvents) {
+		return nil, eth.NewNotSupportedError("Subscribing to logs", string(qtum.CapabilityLogEvents))
+	}
 
 	return p.request(&req, notifier)
 }
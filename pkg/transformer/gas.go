@@ -0,0 +1,61 @@
+package transformer
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// GasSettings are the gas rules Janus applies on top of qtumd's
+type GasSettings struct {
+	// eth_estimateGas multiplies the gas qtumd used by this
+	EstimateBuffer float64
+	// eth_call and eth_sendTransaction requests with a lower gas limit are logged
+	MinimumGasLimit int64
+	// eth_call requests with a higher gas limit return 0x without going to qtumd
+	CallGasCap int64
+}
+
+var DefaultGasSettings = GasSettings{
+	EstimateBuffer:  1.10,
+	MinimumGasLimit: 22000,
+	CallGasCap:      40000000,
+}
+
+var (
+	gasSettingsMutex sync.RWMutex
+	gasSettings      = DefaultGasSettings
+)
+
+// CurrentGasSettings are the gas settings requests are handled with
+func CurrentGasSettings() GasSettings {
+	gasSettingsMutex.RLock()
+	defer gasSettingsMutex.RUnlock()
+	return gasSettings
+}
+
+// Validate checks the settings make sense
+func (s GasSettings) Validate() error {
+	if s.EstimateBuffer < 1 {
+		return errors.New("gas estimate buffer cannot be less than 1")
+	}
+	if s.MinimumGasLimit < 0 {
+		return errors.New("minimum gas limit cannot be negative")
+	}
+	if s.CallGasCap <= 0 {
+		return errors.New("eth_call gas cap must be positive")
+	}
+	return nil
+}
+
+// ReloadGasSettings replaces the gas settings, requests already being handled keep the old ones
+func ReloadGasSettings(settings GasSettings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+
+	gasSettingsMutex.Lock()
+	defer gasSettingsMutex.Unlock()
+	gasSettings = settings
+	return nil
+}
@@ -1,6 +1,7 @@
 package transformer
 
 import (
+	"context"
 	"reflect"
 	"strconv"
 
@@ -20,7 +21,7 @@ func (p *ProxyQTUMGenerateToAddress) Method() string {
 	return "dev_generatetoaddress"
 }
 
-func (p *ProxyQTUMGenerateToAddress) Request(req *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
+func (p *ProxyQTUMGenerateToAddress) Request(ctx context.Context, req *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
 	if !p.CanGenerate() {
 		return nil, eth.NewInvalidRequestError("Can only generate on regtest")
 	}
@@ -35,10 +36,10 @@ func (p *ProxyQTUMGenerateToAddress) Request(req *eth.JSONRPCRequest, c echo.Con
 		return nil, eth.NewInvalidParamsError("require 2 arguments: blocks, the base58/hex address to mine rewards to")
 	}
 
-	return p.request(params)
+	return p.request(ctx, params)
 }
 
-func (p *ProxyQTUMGenerateToAddress) request(params []interface{}) (*[]string, eth.JSONRPCError) {
+func (p *ProxyQTUMGenerateToAddress) request(ctx context.Context, params []interface{}) (*[]string, eth.JSONRPCError) {
 	blocks := params[0]
 	generateTo, ok := params[1].(string)
 	if !ok {
@@ -64,14 +65,14 @@ func (p *ProxyQTUMGenerateToAddress) request(params []interface{}) (*[]string, e
 	}
 
 	hex := utils.RemoveHexPrefix(generateTo)
-	base58Address, err := p.FromHexAddress(hex)
+	base58Address, err := p.FromHexAddress(ctx, hex)
 	if err != nil {
 		// already base58?
 		base58Address = generateTo
 	}
 
 	var response []string
-	err = p.Client.Request(qtum.MethodGenerateToAddress, []interface{}{blocksInteger, base58Address}, &response)
+	err = p.Client.RequestWithContext(ctx, qtum.MethodGenerateToAddress, []interface{}{blocksInteger, base58Address}, &response)
 	if err != nil {
 		return nil, eth.NewInvalidRequestError(err.Error())
 	}
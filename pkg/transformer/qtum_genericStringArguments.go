@@ -1,6 +1,8 @@
 package transformer
 
 import (
+	"context"
+
 	"github.com/labstack/echo"
 	"github.com/qtumproject/janus/pkg/eth"
 	"github.com/qtumproject/janus/pkg/qtum"
@@ -18,7 +20,7 @@ func (p *ProxyQTUMGenericStringArguments) Method() string {
 	return p.prefix + "_" + p.method
 }
 
-func (p *ProxyQTUMGenericStringArguments) Request(req *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
+func (p *ProxyQTUMGenericStringArguments) Request(ctx context.Context, req *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
 	var params eth.StringsArguments
 	if err := unmarshalRequest(req.Params, &params); err != nil {
 		// TODO: Correct error code?
@@ -29,12 +31,12 @@ func (p *ProxyQTUMGenericStringArguments) Request(req *eth.JSONRPCRequest, c ech
 		return nil, eth.NewInvalidParamsError("require 1 argument: the base58 Qtum address")
 	}
 
-	return p.request(params)
+	return p.request(ctx, params)
 }
 
-func (p *ProxyQTUMGenericStringArguments) request(params eth.StringsArguments) (*string, eth.JSONRPCError) {
+func (p *ProxyQTUMGenericStringArguments) request(ctx context.Context, params eth.StringsArguments) (*string, eth.JSONRPCError) {
 	var response string
-	err := p.Client.Request(p.method, params, &response)
+	err := p.Client.RequestWithContext(ctx, p.method, params, &response)
 	if err != nil {
 		return nil, eth.NewInvalidRequestError(err.Error())
 	}
@@ -0,0 +1,94 @@
+package transformer
+
+import (
+	"context"
+
+	"github.com/labstack/echo"
+	"github.com/qtumproject/janus/pkg/eth"
+	"github.com/qtumproject/janus/pkg/notifier"
+	"github.com/qtumproject/janus/pkg/qtum"
+)
+
+// ProxyQTUMCreateSession implements ETHProxy
+// it makes the subscriptions on a websocket resumable after a reconnect
+type ProxyQTUMCreateSession struct {
+	*qtum.Qtum
+	*notifier.Agent
+}
+
+var _ ETHProxy = (*ProxyQTUMCreateSession)(nil)
+
+func (p *ProxyQTUMCreateSession) Method() string {
+	return "qtum_createSession"
+}
+
+func (p *ProxyQTUMCreateSession) Request(ctx context.Context, _ *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
+	notifier := getNotifier(c)
+	if notifier == nil {
+		p.GetLogger().Log("msg", "qtum_createSession only supported over websocket")
+		return nil, eth.NewMethodNotFoundError("qtum_createSession")
+	}
+
+	return p.request(notifier)
+}
+
+func (p *ProxyQTUMCreateSession) request(notifier *notifier.Notifier) (*eth.QtumCreateSessionResponse, eth.JSONRPCError) {
+	token, err := p.NewSession(notifier)
+	if err != nil {
+		return nil, eth.NewCallbackError(err.Error())
+	}
+
+	response := eth.QtumCreateSessionResponse(token)
+	return &response, nil
+}
+
+// ProxyQTUMResumeSession implements ETHProxy
+// it moves the subscriptions of a session created on an earlier websocket onto this one
+type ProxyQTUMResumeSession struct {
+	*qtum.Qtum
+	*notifier.Agent
+}
+
+var _ ETHProxy = (*ProxyQTUMResumeSession)(nil)
+
+func (p *ProxyQTUMResumeSession) Method() string {
+	return "qtum_resumeSession"
+}
+
+func (p *ProxyQTUMResumeSession) Request(ctx context.Context, rawreq *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
+	notifier := getNotifier(c)
+	if notifier == nil {
+		p.GetLogger().Log("msg", "qtum_resumeSession only supported over websocket")
+		return nil, eth.NewMethodNotFoundError("qtum_resumeSession")
+	}
+
+	var req eth.QtumResumeSessionRequest
+	if err := unmarshalRequest(rawreq.Params, &req); err != nil {
+		return nil, eth.NewInvalidParamsError(err.Error())
+	}
+	if len(req) != 1 {
+		return nil, eth.NewInvalidParamsError("requires one argument: the session token")
+	}
+
+	resumed, response, jsonErr := p.request(req[0], notifier)
+	if jsonErr != nil {
+		return nil, jsonErr
+	}
+
+	// the rest of this connection must go through the session's notifier
+	c.Set("notifier", resumed)
+
+	return response, nil
+}
+
+func (p *ProxyQTUMResumeSession) request(token string, current *notifier.Notifier) (*notifier.Notifier, *eth.QtumResumeSessionResponse, eth.JSONRPCError) {
+	resumed, dropped, err := p.ResumeSession(token, current)
+	if err != nil {
+		return nil, nil, eth.NewCallbackError(err.Error())
+	}
+
+	return resumed, &eth.QtumResumeSessionResponse{
+		Session: token,
+		Dropped: dropped,
+	}, nil
+}
@@ -1,12 +1,21 @@
 package transformer
 
 import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
 	"github.com/go-kit/kit/log"
 	"github.com/labstack/echo"
 	"github.com/pkg/errors"
+	"github.com/qtumproject/janus/pkg/cache"
 	"github.com/qtumproject/janus/pkg/eth"
+	"github.com/qtumproject/janus/pkg/metrics"
 	"github.com/qtumproject/janus/pkg/notifier"
 	"github.com/qtumproject/janus/pkg/qtum"
+	"github.com/qtumproject/janus/pkg/tracing"
 )
 
 type Transformer struct {
@@ -14,6 +23,55 @@ type Transformer struct {
 	debugMode    bool
 	logger       log.Logger
 	transformers map[string]ETHProxy
+	cache        *cache.ResponseCache
+	// how long a request may take, 0 means no limit
+	timeout        time.Duration
+	methodTimeouts map[string]time.Duration
+
+	// guards the settings that can be reloaded while requests are being handled, debugMode and the timeouts
+	settingsMutex sync.RWMutex
+	// called after debug mode is reloaded
+	debugReloaded func(debug bool)
+}
+
+// methods that need qtumd to be started with an option, they fail with a not supported error when it wasn't
+var requiredCapabilities = map[string]qtum.Capability{
+	"qtum_getUTXOs":     qtum.CapabilityAddrIndex,
+	"eth_getLogs":       qtum.CapabilityLogEvents,
+	"eth_getFilterLogs": qtum.CapabilityLogEvents,
+	"eth_newFilter":     qtum.CapabilityLogEvents,
+}
+
+// methods that partly work without an option, the requests that need it fail with a not supported error
+var partlyRequiredCapabilities = map[string][]qtum.Capability{
+	// contract balances come from getaccountinfo, account balances need the address index
+	"eth_getBalance": {qtum.CapabilityAddrIndex},
+	// block filters and newHeads don't need events
+	"eth_getFilterChanges": {qtum.CapabilityLogEvents},
+	"eth_subscribe":        {qtum.CapabilityLogEvents},
+	"admin_addWebhook":     {qtum.CapabilityLogEvents},
+	// wallet and mempool transactions are found without the transaction index
+	"eth_getTransactionByHash":  {qtum.CapabilityTxIndex},
+	"eth_getTransactionReceipt": {qtum.CapabilityLogEvents, qtum.CapabilityTxIndex},
+}
+
+// MethodsRequiring lists the methods that don't work, or only partly work, when qtumd lacks capability
+func MethodsRequiring(capability qtum.Capability) []string {
+	var methods []string
+	for method, c := range requiredCapabilities {
+		if c == capability {
+			methods = append(methods, method)
+		}
+	}
+	for method, capabilities := range partlyRequiredCapabilities {
+		for _, c := range capabilities {
+			if c == capability {
+				methods = append(methods, method)
+			}
+		}
+	}
+	sort.Strings(methods)
+	return methods
 }
 
 // New creates a new Transformer
@@ -55,6 +113,9 @@ func (t *Transformer) Register(p ETHProxy) error {
 	}
 
 	t.transformers[m] = p
+	if aware, ok := p.(transformerAware); ok {
+		aware.setTransformer(t)
+	}
 
 	return nil
 }
@@ -63,15 +124,74 @@ func (t *Transformer) Register(p ETHProxy) error {
 func (t *Transformer) Transform(req *eth.JSONRPCRequest, c echo.Context) (interface{}, eth.JSONRPCError) {
 	proxy, err := t.getProxy(req.Method)
 	if err != nil {
+		observeRequest(metrics.UnknownMethod, time.Now(), err)
 		return nil, err
 	}
-	resp, err := proxy.Request(req, c)
+	defer func(start time.Time) {
+		observeRequest(req.Method, start, err)
+	}(time.Now())
+
+	// qtumd calls made for this request are cancelled when the client goes away or the deadline passes
+	ctx := context.Background()
+	if c != nil {
+		ctx = c.Request().Context()
+	}
+
+	ctx, span := tracing.Start(ctx, req.Method, tracing.SpanKindServer, "rpc.system", "jsonrpc", "rpc.method", req.Method)
+	defer func() {
+		if err != nil {
+			span.SetAttributes("rpc.jsonrpc.error_code", err.Code())
+			span.SetError(err.Message())
+		}
+		span.End()
+	}()
+
+	if capability, ok := requiredCapabilities[req.Method]; ok && !t.qtumClient.Supports(capability) {
+		err = eth.NewNotSupportedError(req.Method, string(capability))
+		return nil, err
+	}
+
+	if cached, ok := t.getCachedResponse(req); ok {
+		span.SetAttributes("janus.cached", true)
+		return cached, nil
+	}
+
+	timeout := t.methodTimeout(req.Method)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	resp, err := proxy.Request(ctx, req, c)
 	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			err = eth.NewTimeoutError(req.Method, timeout)
+		}
 		return nil, err
 	}
+	t.cacheResponse(req, resp)
 	return resp, nil
 }
 
+// methodTimeout is how long method may take, 0 means no limit
+func (t *Transformer) methodTimeout(method string) time.Duration {
+	t.settingsMutex.RLock()
+	defer t.settingsMutex.RUnlock()
+	if timeout, ok := t.methodTimeouts[method]; ok {
+		return timeout
+	}
+	return t.timeout
+}
+
+func observeRequest(method string, start time.Time, err eth.JSONRPCError) {
+	metrics.RPCRequests.WithLabelValues(method).Inc()
+	metrics.RPCDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.RPCErrors.WithLabelValues(method, strconv.Itoa(err.Code())).Inc()
+	}
+}
+
 func (t *Transformer) getProxy(method string) (ETHProxy, eth.JSONRPCError) {
 	proxy, ok := t.transformers[method]
 	if !ok {
@@ -81,9 +201,42 @@ func (t *Transformer) getProxy(method string) (ETHProxy, eth.JSONRPCError) {
 }
 
 func (t *Transformer) IsDebugEnabled() bool {
+	t.settingsMutex.RLock()
+	defer t.settingsMutex.RUnlock()
 	return t.debugMode
 }
 
+// ReloadDebug turns debug mode on or off while requests are being handled, for the qtum client too
+func (t *Transformer) ReloadDebug(debug bool) {
+	t.settingsMutex.Lock()
+	t.debugMode = debug
+	debugReloaded := t.debugReloaded
+	t.settingsMutex.Unlock()
+
+	t.qtumClient.ReloadDebug(debug)
+	if debugReloaded != nil {
+		debugReloaded(debug)
+	}
+}
+
+// ReloadTimeouts replaces the request timeout and its per method overrides, requests already being handled keep their deadlines
+func (t *Transformer) ReloadTimeouts(timeout time.Duration, methodTimeouts map[string]time.Duration) error {
+	// the options validate the timeouts without touching t
+	reloaded := &Transformer{}
+	if err := SetRequestTimeout(timeout)(reloaded); err != nil {
+		return err
+	}
+	if err := SetMethodTimeouts(methodTimeouts)(reloaded); err != nil {
+		return err
+	}
+
+	t.settingsMutex.Lock()
+	defer t.settingsMutex.Unlock()
+	t.timeout = reloaded.timeout
+	t.methodTimeouts = reloaded.methodTimeouts
+	return nil
+}
+
 // DefaultProxies are the default proxy methods made available
 func DefaultProxies(qtumRPCClient *qtum.Qtum, agent *notifier.Agent) []ETHProxy {
 	filter := eth.NewFilterSimulator()
@@ -133,6 +286,22 @@ func DefaultProxies(qtumRPCClient *qtum.Qtum, agent *notifier.Agent) []ETHProxy
 
 		&ETHSubscribe{Qtum: qtumRPCClient, Agent: agent},
 		&ETHUnsubscribe{Qtum: qtumRPCClient, Agent: agent},
+		&ProxyQTUMCreateSession{Qtum: qtumRPCClient, Agent: agent},
+		&ProxyQTUMResumeSession{Qtum: qtumRPCClient, Agent: agent},
+
+		&ProxyAdminAddWebhook{Qtum: qtumRPCClient, Agent: agent},
+		&ProxyAdminRemoveWebhook{Qtum: qtumRPCClient, Agent: agent},
+		&ProxyAdminGetWebhookStatus{Qtum: qtumRPCClient, Agent: agent},
+		&ProxyAdminListWebhooks{Qtum: qtumRPCClient, Agent: agent},
+
+		&ProxyAdminStatus{Qtum: qtumRPCClient, Agent: agent, filter: filter},
+		&ProxyAdminListConnections{Agent: agent},
+		&ProxyAdminDisconnect{Agent: agent},
+		&ProxyAdminListFilters{filter: filter},
+		&ProxyAdminDeleteFilter{filter: filter},
+		&ProxyAdminGetFlags{Qtum: qtumRPCClient},
+		&ProxyAdminSetFlag{Qtum: qtumRPCClient},
+		&ProxyAdminSetDebug{},
 
 		&ProxyQTUMGetUTXOs{Qtum: qtumRPCClient},
 		&ProxyQTUMGenerateToAddress{Qtum: qtumRPCClient},
@@ -166,6 +335,38 @@ func SetDebug(debug bool) func(*Transformer) error {
 	}
 }
 
+// SetDebugReloaded is called with the new setting whenever debug mode is reloaded, e.g. by admin_setDebug
+func SetDebugReloaded(handler func(debug bool)) func(*Transformer) error {
+	return func(t *Transformer) error {
+		t.debugReloaded = handler
+		return nil
+	}
+}
+
+// SetRequestTimeout limits how long any request may take, 0 means no limit
+func SetRequestTimeout(timeout time.Duration) func(*Transformer) error {
+	return func(t *Transformer) error {
+		if timeout < 0 {
+			return errors.New("request timeout cannot be negative")
+		}
+		t.timeout = timeout
+		return nil
+	}
+}
+
+// SetMethodTimeouts overrides the request timeout for some methods, 0 means no limit for that method
+func SetMethodTimeouts(timeouts map[string]time.Duration) func(*Transformer) error {
+	return func(t *Transformer) error {
+		for method, timeout := range timeouts {
+			if timeout < 0 {
+				return errors.Errorf("timeout for %s cannot be negative", method)
+			}
+		}
+		t.methodTimeouts = timeouts
+		return nil
+	}
+}
+
 func SetLogger(l log.Logger) func(*Transformer) error {
 	return func(t *Transformer) error {
 		t.logger = log.WithPrefix(l, "component", "transformer")
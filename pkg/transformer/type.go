@@ -1,6 +1,7 @@
 package transformer
 
 import (
+	"context"
 	"errors"
 
 	"github.com/labstack/echo"
@@ -12,6 +13,6 @@ var UnmarshalRequestErr = errors.New("Input is invalid")
 type Option func(*Transformer) error
 
 type ETHProxy interface {
-	Request(*eth.JSONRPCRequest, echo.Context) (interface{}, eth.JSONRPCError)
+	Request(context.Context, *eth.JSONRPCRequest, echo.Context) (interface{}, eth.JSONRPCError)
 	Method() string
 }
@@ -2,6 +2,7 @@ package transformer
 
 import (
 	"bytes"
+	"context"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -138,7 +139,7 @@ func unmarshalRequest(data []byte, v interface{}) error {
 // TODO: Investigate if limitations on Qtum RPC command GetRawTransaction can cause issues here
 // Brief explanation: A default config Qtum node can only serve this command for transactions in the mempool, so it will likely break for SOME setup at SOME point.
 // However the same info can be found with getblock verbosity = 2, so maybe use that instead?
-func getNonContractTxSenderAddress(p *qtum.Qtum, tx *qtum.DecodedRawTransactionResponse) (string, error) {
+func getNonContractTxSenderAddress(ctx context.Context, p *qtum.Qtum, tx *qtum.DecodedRawTransactionResponse) (string, error) {
 	// We start by checking for OP_SENDER address data, since it by definition overrides UTXO origin address in this context.
 	// Also nice because it doesn't require another RPC call
 	hexAddress, err := tx.GetOpSenderAddress()
@@ -147,7 +148,7 @@ func getNonContractTxSenderAddress(p *qtum.Qtum, tx *qtum.DecodedRawTransactionR
 	}
 
 	// If no OP_SENDER address is found we fetch raw Tx struct, which contains address data for Vins
-	rawTx, err := p.GetRawTransaction(tx.ID, false)
+	rawTx, err := p.GetRawTransaction(ctx, tx.ID, false)
 
 	if err != nil {
 		return "", errors.New("Couldn't get raw Transaction data from Transaction ID: " + err.Error())
@@ -193,8 +194,8 @@ func findNonContractTxReceiverAddress(vouts []*qtum.DecodedRawTransactionOutV) (
 	return "", errors.New("not found")
 }
 
-func getBlockNumberByHash(p *qtum.Qtum, hash string) (uint64, error) {
-	block, err := p.GetBlock(hash)
+func getBlockNumberByHash(ctx context.Context, p *qtum.Qtum, hash string) (uint64, error) {
+	block, err := p.GetBlock(ctx, hash)
 	if err != nil {
 		return 0, errors.WithMessage(err, "couldn't get block")
 	}
@@ -202,8 +203,8 @@ func getBlockNumberByHash(p *qtum.Qtum, hash string) (uint64, error) {
 	return uint64(block.Height), nil
 }
 
-func getTransactionIndexInBlock(p *qtum.Qtum, txHash string, blockHash string) (int64, error) {
-	block, err := p.GetBlock(blockHash)
+func getTransactionIndexInBlock(ctx context.Context, p *qtum.Qtum, txHash string, blockHash string) (int64, error) {
+	block, err := p.GetBlock(ctx, blockHash)
 	if err != nil {
 		return -1, errors.WithMessage(err, "couldn't get block")
 	}
@@ -236,7 +237,7 @@ func formatQtumNonce(nonce int) string {
 // 	- string "earliest" for the genesis block
 // 	- string "pending" - for the pending state/transactions
 // Uses defaultVal to differntiate from a eth_getBlockByNumber req and eth_getLogs/eth_newFilter
-func getBlockNumberByRawParam(p *qtum.Qtum, rawParam json.RawMessage, defaultVal bool) (*big.Int, eth.JSONRPCError) {
+func getBlockNumberByRawParam(ctx context.Context, p *qtum.Qtum, rawParam json.RawMessage, defaultVal bool) (*big.Int, eth.JSONRPCError) {
 	var param string
 	if isBytesOfString(rawParam) {
 		param = string(rawParam[1 : len(rawParam)-1]) // trim \" runes
@@ -248,13 +249,13 @@ func getBlockNumberByRawParam(p *qtum.Qtum, rawParam json.RawMessage, defaultVal
 		return nil, eth.NewInvalidParamsError("invalid parameter format - string or integer is expected")
 	}
 
-	return getBlockNumberByParam(p, param, defaultVal)
+	return getBlockNumberByParam(ctx, p, param, defaultVal)
 }
 
-func getBlockNumberByParam(p *qtum.Qtum, param string, defaultVal bool) (*big.Int, eth.JSONRPCError) {
+func getBlockNumberByParam(ctx context.Context, p *qtum.Qtum, param string, defaultVal bool) (*big.Int, eth.JSONRPCError) {
 	if len(param) < 1 {
 		if defaultVal {
-			res, err := p.GetBlockChainInfo()
+			res, err := p.GetBlockChainInfo(ctx)
 			if err != nil {
 				return nil, eth.NewCallbackError(err.Error())
 			}
@@ -268,7 +269,7 @@ func getBlockNumberByParam(p *qtum.Qtum, param string, defaultVal bool) (*big.In
 
 	switch param {
 	case "latest":
-		res, err := p.GetBlockChainInfo()
+		res, err := p.GetBlockChainInfo(ctx)
 		if err != nil {
 			return nil, eth.NewCallbackError(err.Error())
 		}